@@ -0,0 +1,207 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// rulesim evaluates the firmware, feature control and DCM rules against a corpus of device contexts
+// and prints how many devices get each firmware config, feature set and DCM settings.
+//
+// The rules are either loaded from the database of the config file, like the cache of the server,
+// or from a JSON export written with -export:
+//
+//	rulesim -f xconfwebconfig.conf -export rules.json
+//	rulesim -f xconfwebconfig.conf -rules rules.json -corpus contexts.ndjson
+//
+// With -diff it compares the rules of one table in an older export with the rules of -rules instead,
+// the corpus is optional and estimates the devices whose evaluation of a changed rule changes:
+//
+//	rulesim -f xconfwebconfig.conf -rules new.json -diff old.json -table FirmwareRule4 -corpus contexts.ndjson
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	"github.com/rdkcentral/xconfwebconfig/dataapi"
+	"github.com/rdkcentral/xconfwebconfig/db"
+	xhttp "github.com/rdkcentral/xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultConfigFile = "/app/xconfwebconfig/xconfwebconfig.conf"
+)
+
+func main() {
+	configFile := flag.String("f", defaultConfigFile, "config file")
+	rulesFile := flag.String("rules", "", "JSON export of the rules, the rules are loaded from the database when empty")
+	exportFile := flag.String("export", "", "write the JSON export of the rules to this file")
+	corpusFile := flag.String("corpus", "", "NDJSON file of device contexts, - for stdin")
+	diffFile := flag.String("diff", "", "JSON export of the old rules to compare with the rules of -rules")
+	tableName := flag.String("table", "", "table of the rules to compare with -diff")
+	outputFile := flag.String("o", "", "write the report to this file, stdout when empty")
+	logLevel := flag.String("log-level", "warn", "log level")
+	flag.Parse()
+
+	var err error
+	if *diffFile != "" {
+		err = runDiff(*configFile, *rulesFile, *diffFile, *tableName, *corpusFile, *outputFile, *logLevel)
+	} else {
+		err = run(*configFile, *rulesFile, *exportFile, *corpusFile, *outputFile, *logLevel)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rulesim: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(configFile, rulesFile, exportFile, corpusFile, outputFile, logLevel string) error {
+	sc, err := setup(configFile, logLevel)
+	if err != nil {
+		return err
+	}
+
+	if rulesFile == "" {
+		server := xhttp.NewXconfServer(sc, false, nil)
+		dataapi.XconfSetup(server, mux.NewRouter())
+	} else {
+		var export db.CacheExport
+		if err := readJSONFile(rulesFile, &export); err != nil {
+			return err
+		}
+		if err := dataapi.SetupRuleSimulation(sc.Config, export); err != nil {
+			return err
+		}
+	}
+
+	if exportFile != "" {
+		export, err := db.GetCacheManager().ExportCache()
+		if err != nil {
+			return err
+		}
+		if err := writeJSONFile(exportFile, export); err != nil {
+			return err
+		}
+	}
+
+	if corpusFile == "" {
+		if exportFile != "" {
+			return nil
+		}
+		return fmt.Errorf("-corpus is required")
+	}
+	corpus, closeCorpus, err := openCorpus(corpusFile)
+	if err != nil {
+		return err
+	}
+	defer closeCorpus()
+	simulator := dataapi.NewRuleSimulator()
+	if err := simulator.SimulateCorpus(corpus); err != nil {
+		return err
+	}
+	return writeJSONFile(outputFile, simulator.Report())
+}
+
+func runDiff(configFile, rulesFile, diffFile, tableName, corpusFile, outputFile, logLevel string) error {
+	if rulesFile == "" || tableName == "" {
+		return fmt.Errorf("-diff requires -rules and -table")
+	}
+	sc, err := setup(configFile, logLevel)
+	if err != nil {
+		return err
+	}
+	var oldExport, newExport db.CacheExport
+	if err := readJSONFile(diffFile, &oldExport); err != nil {
+		return err
+	}
+	if err := readJSONFile(rulesFile, &newExport); err != nil {
+		return err
+	}
+	// the corpus is normalized with the new rules, like the requests after the change
+	if err := dataapi.SetupRuleSimulation(sc.Config, newExport); err != nil {
+		return err
+	}
+
+	var contexts []map[string]string
+	if corpusFile != "" {
+		corpus, closeCorpus, err := openCorpus(corpusFile)
+		if err != nil {
+			return err
+		}
+		defer closeCorpus()
+		if contexts, err = dataapi.ReadCorpus(corpus); err != nil {
+			return err
+		}
+	}
+	diff, err := dataapi.DiffRuleExports(tableName, oldExport, newExport, contexts)
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(outputFile, diff)
+}
+
+func setup(configFile, logLevel string) (*common.ServerConfig, error) {
+	level, err := log.ParseLevel(logLevel)
+	if err != nil {
+		return nil, err
+	}
+	log.SetOutput(os.Stderr)
+	log.SetLevel(level)
+	return common.NewServerConfig(configFile)
+}
+
+// openCorpus opens the corpus file, or stdin when name is -
+func openCorpus(name string) (io.Reader, func(), error) {
+	if name == "-" {
+		return os.Stdin, func() {}, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
+
+func readJSONFile(name string, v interface{}) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid JSON in %v: %v", name, err)
+	}
+	return nil
+}
+
+// writeJSONFile writes v as indented JSON to the file, or to stdout when name is empty
+func writeJSONFile(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if name == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(name, data, 0644)
+}
@@ -140,6 +140,15 @@ const (
 	REBOOT_IMMEDIATELY         = "rebootImmediately"
 	PROPERTIES                 = "properties"
 	MANDATORY_UPDATE           = "mandatoryUpdate"
+	FIRMWARE_SHA256            = "firmwareSha256"
+	FIRMWARE_SIZE              = "firmwareSize"
+	FIRMWARE_SIGNATURE         = "firmwareSignature"
+	MAINTENANCE_WINDOW_START   = "maintenanceWindowStart"
+	DELTA_FROM_VERSION         = "deltaFromVersion"
+	DELTA_FIRMWARE_FILENAME    = "deltaFirmwareFilename"
+	DELTA_FIRMWARE_LOCATION    = "deltaFirmwareLocation"
+	DELTA_FIRMWARE_SHA256      = "deltaFirmwareSha256"
+	DELTA_FIRMWARE_SIZE        = "deltaFirmwareSize"
 	FIRMWARE_VERSIONS          = "firmwareVersions"
 	REGULAR_EXPRESSIONS        = "regularExpressions"
 	ADDITIONAL_FW_VER_INFO     = "additionalFwVerInfo"
@@ -164,6 +173,7 @@ const (
 
 const (
 	NoPenetrationMetricsHeader = "X-No-Penetration-Metrics"
+	EvaluationTraceHeader      = "X-Evaluation-Trace"
 )
 
 func isValidType(namespacedListType string) bool {
@@ -0,0 +1,215 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package dataapi
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	xhttp "github.com/rdkcentral/xconfwebconfig/http"
+	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/util"
+)
+
+const (
+	ChangeLogFormatJson = "json"
+	ChangeLogFormatCsv  = "csv"
+
+	// NumberOfItemsHeader is the number of change logs that match the filters, before paging
+	NumberOfItemsHeader = "numberOfItems"
+)
+
+var configChangeLogCsvHeader = []string{
+	"updated", "estbMac", "model", "env", "firmwareVersion", "ruleId", "ruleType", "ruleName", "noop",
+	"filters", "configFirmwareVersion", "configFirmwareFilename", "configDownloadProtocol", "explanation",
+}
+
+// ConfigChangeLogQuery filters the config change logs of a device by their time and rule, and pages them.
+// From and To are epoch milliseconds and 0 when not set, a PageSize of 0 returns all logs.
+type ConfigChangeLogQuery struct {
+	From       int64
+	To         int64
+	RuleId     string
+	RuleType   string
+	PageNumber int
+	PageSize   int
+}
+
+// NewConfigChangeLogQuery reads the query params from, to, ruleId, ruleType, pageNumber and pageSize,
+// the times are epoch milliseconds or RFC 3339
+func NewConfigChangeLogQuery(values url.Values) (*ConfigChangeLogQuery, error) {
+	query := &ConfigChangeLogQuery{
+		RuleId:     values.Get("ruleId"),
+		RuleType:   values.Get("ruleType"),
+		PageNumber: 1,
+	}
+	var err error
+	if query.From, err = parseChangeLogTime(values.Get("from")); err != nil {
+		return nil, fmt.Errorf("from is invalid: %v", err)
+	}
+	if query.To, err = parseChangeLogTime(values.Get("to")); err != nil {
+		return nil, fmt.Errorf("to is invalid: %v", err)
+	}
+	if query.From > 0 && query.To > 0 && query.From > query.To {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+	if value := values.Get("pageNumber"); value != "" {
+		if query.PageNumber, err = strconv.Atoi(value); err != nil || query.PageNumber < 1 {
+			return nil, fmt.Errorf("pageNumber must be a positive number: %v", value)
+		}
+	}
+	if value := values.Get("pageSize"); value != "" {
+		if query.PageSize, err = strconv.Atoi(value); err != nil || query.PageSize < 1 {
+			return nil, fmt.Errorf("pageSize must be a positive number: %v", value)
+		}
+	}
+	return query, nil
+}
+
+func parseChangeLogTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return millis, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+	return util.GetTimestamp(t), nil
+}
+
+// Matches returns true when the log is in the time range and its rule or one of its filters has the rule id and type
+func (q *ConfigChangeLogQuery) Matches(changeLog *sharedef.ConfigChangeLog) bool {
+	if q.From > 0 && changeLog.Updated < q.From {
+		return false
+	}
+	if q.To > 0 && changeLog.Updated > q.To {
+		return false
+	}
+	if q.RuleId == "" && q.RuleType == "" {
+		return true
+	}
+	ruleInfos := append([]*sharedef.RuleInfo{changeLog.Rule}, changeLog.Filters...)
+	for _, ruleInfo := range ruleInfos {
+		if ruleInfo == nil {
+			continue
+		}
+		if (q.RuleId == "" || q.RuleId == ruleInfo.ID) && (q.RuleType == "" || strings.EqualFold(q.RuleType, ruleInfo.Type)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the page of the matching logs and the number of matching logs, the order is kept
+func (q *ConfigChangeLogQuery) Apply(changeLogs []*sharedef.ConfigChangeLog) ([]*sharedef.ConfigChangeLog, int) {
+	matched := []*sharedef.ConfigChangeLog{}
+	for _, changeLog := range changeLogs {
+		if q.Matches(changeLog) {
+			matched = append(matched, changeLog)
+		}
+	}
+	if q.PageSize == 0 {
+		return matched, len(matched)
+	}
+	start := (q.PageNumber - 1) * q.PageSize
+	if start >= len(matched) {
+		return []*sharedef.ConfigChangeLog{}, len(matched)
+	}
+	end := start + q.PageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[start:end], len(matched)
+}
+
+// getChangeLogFormat returns csv when the format query param or the Accept header asks for it
+func getChangeLogFormat(r *http.Request) (string, error) {
+	switch format := strings.ToLower(r.URL.Query().Get("format")); format {
+	case "":
+		if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+			return ChangeLogFormatCsv, nil
+		}
+		return ChangeLogFormatJson, nil
+	case ChangeLogFormatJson, ChangeLogFormatCsv:
+		return format, nil
+	default:
+		return "", fmt.Errorf("format must be %s or %s: %s", ChangeLogFormatJson, ChangeLogFormatCsv, format)
+	}
+}
+
+func writeConfigChangeLogsCsv(w http.ResponseWriter, headers map[string]string, changeLogs []*sharedef.ConfigChangeLog) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write(configChangeLogCsvHeader)
+	for _, changeLog := range changeLogs {
+		writer.Write(configChangeLogCsvRecord(changeLog))
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		xhttp.WriteXconfResponseAsText(w, http.StatusInternalServerError, []byte(err.Error()))
+		return
+	}
+	for k, v := range headers {
+		w.Header().Set(k, v)
+	}
+	xhttp.WriteResponseBytes(w, buffer.Bytes(), http.StatusOK, "text/csv")
+}
+
+func configChangeLogCsvRecord(changeLog *sharedef.ConfigChangeLog) []string {
+	record := make([]string, len(configChangeLogCsvHeader))
+	if changeLog.Updated > 0 {
+		record[0] = time.UnixMilli(changeLog.Updated).UTC().Format(time.RFC3339)
+	}
+	if input := changeLog.Input; input != nil {
+		record[1], record[2], record[3], record[4] = input.EstbMac, input.Model, input.Env, input.FirmwareVersion
+	}
+	if rule := changeLog.Rule; rule != nil {
+		record[5], record[6], record[7], record[8] = rule.ID, rule.Type, rule.Name, strconv.FormatBool(rule.NoOp)
+	}
+	filters := []string{}
+	for _, filter := range changeLog.Filters {
+		if filter != nil {
+			filters = append(filters, filter.Type+":"+filter.Name)
+		}
+	}
+	record[9] = strings.Join(filters, ";")
+	if config := changeLog.FirmwareConfig; config != nil {
+		record[10] = configProperty(config, common.FIRMWARE_VERSION)
+		record[11] = configProperty(config, common.FIRMWARE_FILENAME)
+		record[12] = configProperty(config, common.FIRMWARE_DOWNLOAD_PROTOCOL)
+	}
+	record[13] = changeLog.Explanation
+	return record
+}
+
+func configProperty(config *sharedef.FirmwareConfigFacade, name string) string {
+	if value, ok := config.Properties[name]; ok && value != nil {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package dataapi
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfigChangeLogs() []*sharedef.ConfigChangeLog {
+	return []*sharedef.ConfigChangeLog{
+		{
+			ID:      "log3",
+			Updated: 3000,
+			Input:   &sharedef.ConvertedContext{EstbMac: "AA:BB:CC:DD:EE:FF", Model: "X1", Env: "QA", FirmwareVersion: "1.0"},
+			Rule:    &sharedef.RuleInfo{ID: "rule2", Type: "MAC_RULE", Name: "mac rule"},
+			Filters: []*sharedef.RuleInfo{{ID: "filter1", Type: "TIME_FILTER", Name: "night"}},
+			FirmwareConfig: &sharedef.FirmwareConfigFacade{Properties: map[string]interface{}{
+				"firmwareVersion":          "2.0",
+				"firmwareFilename":         "X1_2.0.bin",
+				"firmwareDownloadProtocol": "http",
+			}},
+			Explanation: "Request: matched, config \"2.0\"",
+		},
+		{ID: "log2", Updated: 2000, Rule: &sharedef.RuleInfo{ID: "rule1", Type: "ENV_MODEL_RULE"}},
+		{ID: "log1", Updated: 1000, Rule: &sharedef.RuleInfo{ID: "rule1", Type: "ENV_MODEL_RULE"}},
+	}
+}
+
+func changeLogIds(changeLogs []*sharedef.ConfigChangeLog) []string {
+	ids := []string{}
+	for _, changeLog := range changeLogs {
+		ids = append(ids, changeLog.ID)
+	}
+	return ids
+}
+
+func TestConfigChangeLogQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+		total    int
+	}{
+		{"", []string{"log3", "log2", "log1"}, 3},
+		{"from=2000", []string{"log3", "log2"}, 2},
+		{"from=1500&to=2500", []string{"log2"}, 1},
+		{"to=1970-01-01T00:00:02Z", []string{"log2", "log1"}, 2},
+		{"ruleId=rule1", []string{"log2", "log1"}, 2},
+		{"ruleType=time_filter", []string{"log3"}, 1},
+		{"ruleId=rule1&ruleType=MAC_RULE", []string{}, 0},
+		{"pageSize=2", []string{"log3", "log2"}, 3},
+		{"pageSize=2&pageNumber=2", []string{"log1"}, 3},
+		{"pageSize=2&pageNumber=3", []string{}, 3},
+		{"ruleId=rule1&pageSize=1&pageNumber=2", []string{"log1"}, 2},
+	}
+	for _, test := range tests {
+		values, err := url.ParseQuery(test.query)
+		assert.NoError(t, err)
+		query, err := NewConfigChangeLogQuery(values)
+		assert.NoError(t, err, test.query)
+		changeLogs, total := query.Apply(testConfigChangeLogs())
+		assert.Equal(t, test.expected, changeLogIds(changeLogs), test.query)
+		assert.Equal(t, test.total, total, test.query)
+	}
+}
+
+func TestConfigChangeLogQueryInvalid(t *testing.T) {
+	for _, query := range []string{"from=yesterday", "to=x", "from=3000&to=1000", "pageSize=0", "pageNumber=-1", "pageSize=ten"} {
+		values, err := url.ParseQuery(query)
+		assert.NoError(t, err)
+		_, err = NewConfigChangeLogQuery(values)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestGetChangeLogFormat(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/estbfirmware/changelogs?mac=AA:BB:CC:DD:EE:FF", nil)
+	format, err := getChangeLogFormat(req)
+	assert.NoError(t, err)
+	assert.Equal(t, ChangeLogFormatJson, format)
+
+	req.Header.Set("Accept", "text/csv")
+	format, err = getChangeLogFormat(req)
+	assert.NoError(t, err)
+	assert.Equal(t, ChangeLogFormatCsv, format)
+
+	req = httptest.NewRequest(http.MethodGet, "/estbfirmware/changelogs?format=CSV", nil)
+	format, err = getChangeLogFormat(req)
+	assert.NoError(t, err)
+	assert.Equal(t, ChangeLogFormatCsv, format)
+
+	req = httptest.NewRequest(http.MethodGet, "/estbfirmware/changelogs?format=xml", nil)
+	_, err = getChangeLogFormat(req)
+	assert.Error(t, err)
+}
+
+func TestWriteConfigChangeLogsCsv(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeConfigChangeLogsCsv(recorder, map[string]string{NumberOfItemsHeader: "3"}, testConfigChangeLogs()[:2])
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/csv", recorder.Header().Get("Content-type"))
+	assert.Equal(t, "3", recorder.Header().Get(NumberOfItemsHeader))
+	records, err := csv.NewReader(strings.NewReader(recorder.Body.String())).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, configChangeLogCsvHeader, records[0])
+	assert.Equal(t, []string{
+		"1970-01-01T00:00:03Z", "AA:BB:CC:DD:EE:FF", "X1", "QA", "1.0", "rule2", "MAC_RULE", "mac rule", "false",
+		"TIME_FILTER:night", "2.0", "X1_2.0.bin", "http", "Request: matched, config \"2.0\"",
+	}, records[1])
+	assert.Equal(t, "rule1", records[2][5])
+	assert.Equal(t, "", records[2][1])
+}
+
+func TestGetEstbChangelogsPath_InvalidQuery(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/estbfirmware/changelogs?mac=AA:BB:CC:DD:EE:FF&pageSize=0", nil)
+	recorder := httptest.NewRecorder()
+
+	GetEstbChangelogsPath(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "pageSize")
+}
+
+func TestGetEstbChangelogsPath_Csv(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/estbfirmware/changelogs?mac=AA:BB:CC:DD:EE:FF&format=csv", nil)
+	recorder := httptest.NewRecorder()
+
+	GetEstbChangelogsPath(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/csv", recorder.Header().Get("Content-type"))
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), strings.Join(configChangeLogCsvHeader, ",")))
+}
@@ -20,11 +20,18 @@ package dataapi
 import (
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/rdkcentral/xconfwebconfig/common"
+	"github.com/rdkcentral/xconfwebconfig/dataapi/estbfirmware"
 	"github.com/rdkcentral/xconfwebconfig/db"
 	xhttp "github.com/rdkcentral/xconfwebconfig/http"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
+	"github.com/rdkcentral/xconfwebconfig/shared"
+	"github.com/rdkcentral/xconfwebconfig/shared/firmware"
+	"github.com/rdkcentral/xconfwebconfig/shared/logupload"
+	"github.com/rdkcentral/xconfwebconfig/shared/rfc"
 	"github.com/rdkcentral/xconfwebconfig/util"
 
 	"github.com/gorilla/mux"
@@ -61,3 +68,284 @@ func GetInfoStatistics(w http.ResponseWriter, r *http.Request) {
 	response, _ := util.JSONMarshal(stats)
 	xhttp.WriteXconfResponse(w, 200, response)
 }
+
+// PercentBucketResponse is the percentage bucket of a MAC address or an account id. A device fits
+// a PERCENT condition, or a percent range ending at P, with the same salt when Bucket <= P.
+type PercentBucketResponse struct {
+	Value  string  `json:"value"`
+	Salt   string  `json:"salt,omitempty"`
+	Bucket float64 `json:"bucket"`
+}
+
+// GetInfoPercentBucket computes the bucket of the mac or accountId query parameter, hashed with the optional salt
+func GetInfoPercentBucket(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	value := query.Get("mac")
+	if len(value) > 0 {
+		mac, err := util.MacAddrComplexFormat(value)
+		if err != nil {
+			xhttp.WriteXconfResponse(w, http.StatusBadRequest, []byte(fmt.Sprintf("\"invalid mac address: %s\"", value)))
+			return
+		}
+		value = mac
+	} else {
+		value = query.Get(common.ACCOUNT_ID)
+	}
+	if len(value) == 0 {
+		xhttp.WriteXconfResponse(w, http.StatusBadRequest, []byte("\"mac or accountId should be specified\""))
+		return
+	}
+
+	salt := query.Get("salt")
+	bucket, _ := re.GetSaltedPercentHash(value, salt)
+	response, _ := util.JSONMarshal(PercentBucketResponse{
+		Value:  value,
+		Salt:   salt,
+		Bucket: bucket,
+	})
+	xhttp.WriteXconfResponse(w, http.StatusOK, response)
+}
+
+// ConditionStatsResponse lists the evaluation counters of the conditions of compiled rules
+type ConditionStatsResponse struct {
+	Evaluations int64              `json:"evaluations"`
+	Conditions  []re.ConditionStat `json:"conditions"`
+}
+
+func GetInfoConditionStats(w http.ResponseWriter, r *http.Request) {
+	response := ConditionStatsResponse{
+		Conditions: re.GetConditionStats(),
+	}
+	for _, stat := range response.Conditions {
+		response.Evaluations += stat.Evaluations
+	}
+	res, _ := util.JSONMarshal(response)
+	xhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+// GetInfoRuleStats returns the rule counters, optionally of one ruleType only
+func GetInfoRuleStats(w http.ResponseWriter, r *http.Request) {
+	res, _ := util.JSONMarshal(getRuleStatsOfType(r.URL.Query().Get("ruleType")))
+	xhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+// DeleteInfoRuleStats clears the rule counters, optionally of one ruleType only, and returns
+// the counters as they were before
+func DeleteInfoRuleStats(w http.ResponseWriter, r *http.Request) {
+	ruleType := r.URL.Query().Get("ruleType")
+	stats := getRuleStatsOfType(ruleType)
+	if len(ruleType) == 0 {
+		re.ResetRuleStats()
+	} else {
+		re.ResetRuleStatsOfType(ruleType)
+	}
+	res, _ := util.JSONMarshal(stats)
+	xhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func getRuleStatsOfType(ruleType string) []re.RuleStat {
+	stats := []re.RuleStat{}
+	for _, stat := range re.GetRuleStats() {
+		if len(ruleType) == 0 || strings.EqualFold(ruleType, stat.RuleType) {
+			stats = append(stats, stat)
+		}
+	}
+	return stats
+}
+
+// RuleAnalysisResponse lists the rule issues found per rule kind
+type RuleAnalysisResponse struct {
+	FirmwareRules []re.RuleIssue `json:"firmwareRules"`
+	FeatureRules  []re.RuleIssue `json:"featureRules"`
+	DcmRules      []re.RuleIssue `json:"dcmRules"`
+}
+
+func GetInfoRuleAnalysis(w http.ResponseWriter, r *http.Request) {
+	analyzer := re.NewRuleAnalyzer(namespacedListExists)
+
+	firmwareRules, err := firmware.GetFirmwareRuleAllAsListDB()
+	if err != nil && err != common.NotFound {
+		xhttp.WriteXconfResponse(w, http.StatusInternalServerError, []byte(err.Error()))
+		return
+	}
+	response := RuleAnalysisResponse{
+		FirmwareRules: AnalyzeFirmwareRules(analyzer, firmwareRules),
+		FeatureRules:  AnalyzeFeatureRules(analyzer, rfc.GetSortedFeatureRules()),
+		DcmRules:      AnalyzeDcmRules(analyzer, logupload.GetDCMGenericRuleList()),
+	}
+	res, _ := util.JSONMarshal(response)
+	xhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func namespacedListExists(id string) bool {
+	list, err := shared.GetGenericNamedListOneDB(id)
+	return err == nil && list != nil
+}
+
+// AnalyzeFirmwareRules analyzes the active rules of each application type and rule type,
+// in the order the firmware evaluation walks them. Only the first matching rule of a rule or blocking
+// filter template and of ACTIVATION_VERSION is applied, but every matching rule of another define
+// properties template is applied and overwrites the properties of the rules before it
+func AnalyzeFirmwareRules(analyzer *re.RuleAnalyzer, rules []*firmware.FirmwareRule) []re.RuleIssue {
+	groups := map[string][]*firmware.FirmwareRule{}
+	for _, rule := range rules {
+		if rule.Active {
+			key := rule.ApplicationType + "/" + rule.Type
+			groups[key] = append(groups[key], rule)
+		}
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	issues := []re.RuleIssue{}
+	for _, key := range keys {
+		group := groups[key]
+		if group[0].Type == firmware.ENV_MODEL_RULE || group[0].Type == firmware.ACTIVATION_VERSION {
+			estbfirmware.SortByConditionsSize(group)
+		}
+		if group[0].Type != firmware.ACTIVATION_VERSION && firmwareRuleActionType(group[0]) == firmware.DEFINE_PROPERTIES_TEMPLATE {
+			// a rule only has no effect when a rule applied after it overwrites all it defines
+			xrules := make([]re.XRule, 0, len(group))
+			for i := len(group) - 1; i >= 0; i-- {
+				xrules = append(xrules, group[i])
+			}
+			issues = append(issues, analyzer.AnalyzeCovered(xrules, definePropertiesRuleCovers)...)
+			continue
+		}
+		xrules := make([]re.XRule, 0, len(group))
+		for _, rule := range group {
+			xrules = append(xrules, rule)
+		}
+		issues = append(issues, analyzer.Analyze(xrules)...)
+	}
+	return issues
+}
+
+// firmwareRuleActionType returns the action type of the template of a rule, or the template action type
+// of the rule's own action when the template is not found
+func firmwareRuleActionType(rule *firmware.FirmwareRule) firmware.ApplicableActionType {
+	if template, err := analysisGetFirmwareTemplateFunc(rule.Type); err == nil && template != nil && template.ApplicableAction != nil {
+		return template.ApplicableAction.ActionType
+	}
+	if rule.ApplicableAction != nil && rule.ApplicableAction.ActionType == firmware.DEFINE_PROPERTIES {
+		return firmware.DEFINE_PROPERTIES_TEMPLATE
+	}
+	return firmware.RULE_TEMPLATE
+}
+
+// definePropertiesRuleCovers returns true when later, a rule applied after rule, defines every property
+// and bypasses every filter that rule does, so later overwrites all rule defines
+func definePropertiesRuleCovers(later re.XRule, rule re.XRule) bool {
+	laterAction, action := later.(*firmware.FirmwareRule).ApplicableAction, rule.(*firmware.FirmwareRule).ApplicableAction
+	if action == nil {
+		return true
+	}
+	if laterAction == nil {
+		return false
+	}
+	for key := range action.Properties {
+		if _, ok := laterAction.Properties[key]; !ok {
+			return false
+		}
+	}
+	for _, filter := range action.ByPassFilters {
+		if !util.Contains(laterAction.ByPassFilters, filter) {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	analysisGetFirmwareTemplateFunc  = firmware.GetFirmwareRuleTemplateOneDB
+	analysisGetFeatureFunc           = rfc.GetOneFeature
+	analysisGetDeviceSettingsFunc    = logupload.GetOneDeviceSettings
+	analysisGetLogUploadSettingsFunc = logupload.GetOneLogUploadSettings
+	analysisGetVodSettingsFunc       = logupload.GetOneVodSettings
+)
+
+// AnalyzeFeatureRules analyzes the rules of each application type, rules must be sorted by priority.
+// The features of every matching rule are applied, so a rule is only shadowed by an earlier rule that
+// has all of its features
+func AnalyzeFeatureRules(analyzer *re.RuleAnalyzer, rules []*rfc.FeatureRule) []re.RuleIssue {
+	groups := map[string][]re.XRule{}
+	keys := []string{}
+	for _, rule := range rules {
+		if _, ok := groups[rule.ApplicationType]; !ok {
+			keys = append(keys, rule.ApplicationType)
+		}
+		groups[rule.ApplicationType] = append(groups[rule.ApplicationType], rule)
+	}
+	issues := []re.RuleIssue{}
+	for _, key := range keys {
+		issues = append(issues, analyzer.AnalyzeCovered(groups[key], featureRuleCovers)...)
+	}
+	return issues
+}
+
+// featureRuleCovers returns true when the features of earlier include a feature of the same name for each
+// feature of rule, the evaluation keeps the first feature of a name
+func featureRuleCovers(earlier re.XRule, rule re.XRule) bool {
+	names := featureNames(earlier.(*rfc.FeatureRule))
+	for name := range featureNames(rule.(*rfc.FeatureRule)) {
+		if _, ok := names[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func featureNames(rule *rfc.FeatureRule) map[string]struct{} {
+	names := map[string]struct{}{}
+	for _, id := range rule.FeatureIds {
+		if id == "" {
+			continue
+		}
+		if feature := analysisGetFeatureFunc(id); feature != nil {
+			names[feature.Name] = struct{}{}
+		}
+	}
+	return names
+}
+
+// AnalyzeDcmRules analyzes the rules of each application type in priority order. Matching rules are merged
+// until the settings are complete, so a rule is only shadowed by an earlier rule that has all of its settings
+func AnalyzeDcmRules(analyzer *re.RuleAnalyzer, rules []*logupload.DCMGenericRule) []re.RuleIssue {
+	sorted := append([]*logupload.DCMGenericRule{}, rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+	groups := map[string][]re.XRule{}
+	keys := []string{}
+	for _, rule := range sorted {
+		if _, ok := groups[rule.ApplicationType]; !ok {
+			keys = append(keys, rule.ApplicationType)
+		}
+		groups[rule.ApplicationType] = append(groups[rule.ApplicationType], rule)
+	}
+	issues := []re.RuleIssue{}
+	for _, key := range keys {
+		issues = append(issues, analyzer.AnalyzeCovered(groups[key], dcmRuleCovers)...)
+	}
+	return issues
+}
+
+// dcmRuleCovers returns true when earlier has the device and log upload settings and the VOD settings
+// that rule has, the evaluation takes each of them from the first matching rule that has them
+func dcmRuleCovers(earlier re.XRule, rule re.XRule) bool {
+	earlierDevice, earlierVod := dcmRuleSettings(earlier.GetId())
+	device, vod := dcmRuleSettings(rule.GetId())
+	return (earlierDevice || !device) && (earlierVod || !vod)
+}
+
+// dcmRuleSettings returns whether the rule has active device and log upload settings and whether it has VOD settings
+func dcmRuleSettings(id string) (bool, bool) {
+	deviceSettings := analysisGetDeviceSettingsFunc(id)
+	logUploadSettings := analysisGetLogUploadSettingsFunc(id)
+	device := deviceSettings != nil && logUploadSettings != nil && deviceSettings.SettingsAreActive && logUploadSettings.AreSettingsActive
+	vodSettings := analysisGetVodSettingsFunc(id)
+	return device, vodSettings != nil && vodSettings.Name != ""
+}
@@ -1,12 +1,17 @@
 package dataapi
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gorilla/mux"
 	"github.com/rdkcentral/xconfwebconfig/common"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
+	"github.com/rdkcentral/xconfwebconfig/shared/firmware"
+	"github.com/rdkcentral/xconfwebconfig/shared/logupload"
+	"github.com/rdkcentral/xconfwebconfig/shared/rfc"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -128,3 +133,288 @@ func TestGetInfoStatistics_ResponseStructure(t *testing.T) {
 	assert.True(t, len(body) > 0)
 	assert.True(t, body[0] == '{' || body[0] == '[')
 }
+
+// ============================================================================
+// GetInfoRuleAnalysis Tests
+// ============================================================================
+
+func parseTestRule(t *testing.T, text string) *re.Rule {
+	rule, err := re.ParseRule(text)
+	assert.NoError(t, err)
+	return rule
+}
+
+func TestGetInfoRuleAnalysis_ResponseStructure(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/info/ruleAnalysis", nil)
+	recorder := httptest.NewRecorder()
+
+	GetInfoRuleAnalysis(recorder, req)
+
+	assert.True(t, recorder.Code == http.StatusOK || recorder.Code == http.StatusInternalServerError)
+	if recorder.Code == http.StatusOK {
+		var response RuleAnalysisResponse
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	}
+}
+
+func TestAnalyzeFirmwareRules_GroupsByApplicationTypeAndRuleType(t *testing.T) {
+	rules := []*firmware.FirmwareRule{
+		{ID: "broad", Type: firmware.MAC_RULE, Active: true, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A"`)},
+		{ID: "narrow", Type: firmware.MAC_RULE, Active: true, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A" AND env IS "QA"`)},
+		{ID: "other-type", Type: firmware.IP_RULE, Active: true, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A" AND env IS "QA"`)},
+		{ID: "other-app", Type: firmware.MAC_RULE, Active: true, ApplicationType: "xhome", Rule: *parseTestRule(t, `model IS "A" AND env IS "QA"`)},
+		{ID: "inactive", Type: firmware.MAC_RULE, Active: false, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A" AND model IS "B"`)},
+	}
+	issues := AnalyzeFirmwareRules(re.NewRuleAnalyzer(nil), rules)
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, re.RuleIssueShadowed, issues[0].Type)
+	assert.Equal(t, "narrow", issues[0].RuleId)
+	assert.Equal(t, "broad", issues[0].RelatedRuleId)
+}
+
+func TestAnalyzeFirmwareRules_EnvModelRulesSortedByConditionsSize(t *testing.T) {
+	rules := []*firmware.FirmwareRule{
+		{ID: "broad", Type: firmware.ENV_MODEL_RULE, Active: true, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A"`)},
+		{ID: "narrow", Type: firmware.ENV_MODEL_RULE, Active: true, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A" AND env IS "QA"`)},
+	}
+	// the rule with more conditions is evaluated first, so nothing is shadowed
+	issues := AnalyzeFirmwareRules(re.NewRuleAnalyzer(nil), rules)
+	assert.Equal(t, 0, len(issues))
+}
+
+func TestAnalyzeFirmwareRules_DefinePropertiesShadowedOnlyWhenOverwritten(t *testing.T) {
+	saved := analysisGetFirmwareTemplateFunc
+	analysisGetFirmwareTemplateFunc = func(ruleType string) (*firmware.FirmwareRuleTemplate, error) {
+		return &firmware.FirmwareRuleTemplate{
+			ID:               ruleType,
+			ApplicableAction: &firmware.TemplateApplicableAction{ActionType: firmware.DEFINE_PROPERTIES_TEMPLATE},
+		}, nil
+	}
+	t.Cleanup(func() { analysisGetFirmwareTemplateFunc = saved })
+
+	properties := func(keys ...string) *firmware.ApplicableAction {
+		action := &firmware.ApplicableAction{ActionType: firmware.DEFINE_PROPERTIES, Properties: map[string]string{}}
+		for _, key := range keys {
+			action.Properties[key] = "value"
+		}
+		return action
+	}
+	// the rules are applied in this order, every matching rule overwrites the properties before it
+	rules := []*firmware.FirmwareRule{
+		// broad is applied after it and overwrites its only property
+		{ID: "narrow", Type: "PROPERTIES_RULE", Active: true, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A" AND env IS "QA"`), ApplicableAction: properties("rebootImmediately")},
+		{ID: "broad", Type: "PROPERTIES_RULE", Active: true, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A"`), ApplicableAction: properties("rebootImmediately", "firmwareLocation")},
+		// applied after broad, so it is live although broad matches every context it matches
+		{ID: "after", Type: "PROPERTIES_RULE", Active: true, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A" AND env IS "DEV"`), ApplicableAction: properties("rebootImmediately")},
+	}
+	issues := AnalyzeFirmwareRules(re.NewRuleAnalyzer(nil), rules)
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, re.RuleIssueShadowed, issues[0].Type)
+	assert.Equal(t, "narrow", issues[0].RuleId)
+	assert.Equal(t, "broad", issues[0].RelatedRuleId)
+
+	// a property broad does not define keeps narrow live
+	rules[0].ApplicableAction = properties("rebootImmediately", "downloadProtocol")
+	assert.Equal(t, 0, len(AnalyzeFirmwareRules(re.NewRuleAnalyzer(nil), rules)))
+}
+
+func TestAnalyzeFeatureAndDcmRules(t *testing.T) {
+	featureRules := []*rfc.FeatureRule{
+		{Id: "f1", ApplicationType: "stb", Rule: parseTestRule(t, `model IS "A" AND model IS "B"`)},
+		{Id: "f2", ApplicationType: "stb", Rule: parseTestRule(t, `estbMacAddress IN_LIST "missing"`)},
+	}
+	analyzer := re.NewRuleAnalyzer(func(id string) bool { return false })
+	issues := AnalyzeFeatureRules(analyzer, featureRules)
+	assert.Equal(t, 2, len(issues))
+	assert.Equal(t, re.RuleIssueContradiction, issues[0].Type)
+	assert.Equal(t, re.RuleIssueMissingList, issues[1].Type)
+
+	mockAnalysisDcmSettings(t, map[string]bool{"low": true, "high": true}, nil)
+	dcmRules := []*logupload.DCMGenericRule{
+		{ID: "low", Priority: 2, ApplicationType: "stb", Rule: *parseTestRule(t, `env IS "QA" AND model IS "A"`)},
+		{ID: "high", Priority: 1, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A" AND env IS "QA"`)},
+	}
+	issues = AnalyzeDcmRules(analyzer, dcmRules)
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "low", issues[0].RuleId)
+	assert.Equal(t, "high", issues[0].RelatedRuleId)
+}
+
+func TestAnalyzeFeatureRules_ShadowedOnlyWhenFeaturesAreCovered(t *testing.T) {
+	features := map[string]*rfc.Feature{
+		"id1": {ID: "id1", Name: "feature1"},
+		"id2": {ID: "id2", Name: "feature2"},
+		"id3": {ID: "id3", Name: "feature1"},
+	}
+	saved := analysisGetFeatureFunc
+	analysisGetFeatureFunc = func(id string) *rfc.Feature { return features[id] }
+	t.Cleanup(func() { analysisGetFeatureFunc = saved })
+
+	featureRules := []*rfc.FeatureRule{
+		{Id: "broad", ApplicationType: "stb", FeatureIds: []string{"id1"}, Rule: parseTestRule(t, `model IS "A"`)},
+		// adds feature2 to the features of broad, so it is live
+		{Id: "more", ApplicationType: "stb", FeatureIds: []string{"id2"}, Rule: parseTestRule(t, `model IS "A" AND env IS "QA"`)},
+		// feature1 is already applied by broad
+		{Id: "same-name", ApplicationType: "stb", FeatureIds: []string{"id3"}, Rule: parseTestRule(t, `model IS "A" AND env IS "DEV"`)},
+	}
+	issues := AnalyzeFeatureRules(re.NewRuleAnalyzer(nil), featureRules)
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, re.RuleIssueShadowed, issues[0].Type)
+	assert.Equal(t, "same-name", issues[0].RuleId)
+	assert.Equal(t, "broad", issues[0].RelatedRuleId)
+}
+
+func TestAnalyzeDcmRules_ShadowedOnlyWhenSettingsAreCovered(t *testing.T) {
+	mockAnalysisDcmSettings(t, map[string]bool{"broad": true, "device": true}, map[string]bool{"vod": true})
+	dcmRules := []*logupload.DCMGenericRule{
+		{ID: "broad", Priority: 1, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A"`)},
+		// the VOD settings are still taken from this rule
+		{ID: "vod", Priority: 2, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A" AND env IS "QA"`)},
+		// the device settings are already taken from broad
+		{ID: "device", Priority: 3, ApplicationType: "stb", Rule: *parseTestRule(t, `model IS "A" AND env IS "DEV"`)},
+	}
+	issues := AnalyzeDcmRules(re.NewRuleAnalyzer(nil), dcmRules)
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "device", issues[0].RuleId)
+	assert.Equal(t, "broad", issues[0].RelatedRuleId)
+}
+
+func mockAnalysisDcmSettings(t *testing.T, device map[string]bool, vod map[string]bool) {
+	savedDevice, savedLogUpload, savedVod := analysisGetDeviceSettingsFunc, analysisGetLogUploadSettingsFunc, analysisGetVodSettingsFunc
+	analysisGetDeviceSettingsFunc = func(id string) *logupload.DeviceSettings {
+		if !device[id] {
+			return nil
+		}
+		return &logupload.DeviceSettings{ID: id, Name: id, SettingsAreActive: true}
+	}
+	analysisGetLogUploadSettingsFunc = func(id string) *logupload.LogUploadSettings {
+		if !device[id] {
+			return nil
+		}
+		return &logupload.LogUploadSettings{ID: id, Name: id, AreSettingsActive: true}
+	}
+	analysisGetVodSettingsFunc = func(id string) *logupload.VodSettings {
+		if !vod[id] {
+			return nil
+		}
+		return &logupload.VodSettings{ID: id, Name: id}
+	}
+	t.Cleanup(func() {
+		analysisGetDeviceSettingsFunc, analysisGetLogUploadSettingsFunc, analysisGetVodSettingsFunc = savedDevice, savedLogUpload, savedVod
+	})
+}
+
+func TestGetInfoPercentBucket(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/info/percentBucket?mac=7a860ac26d7b", nil)
+	recorder := httptest.NewRecorder()
+	GetInfoPercentBucket(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response PercentBucketResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "7A:86:0A:C2:6D:7B", response.Value)
+	expected, _ := re.GetPercentHash("7A:86:0A:C2:6D:7B")
+	assert.Equal(t, expected, response.Bucket)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/info/percentBucket?accountId=12345&salt=exp1", nil)
+	recorder = httptest.NewRecorder()
+	GetInfoPercentBucket(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	expected, _ = re.GetSaltedPercentHash("12345", "exp1")
+	assert.Equal(t, "exp1", response.Salt)
+	assert.Equal(t, expected, response.Bucket)
+}
+
+func TestGetInfoPercentBucket_BadRequest(t *testing.T) {
+	for _, url := range []string{"/api/info/percentBucket", "/api/info/percentBucket?mac=invalid"} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		recorder := httptest.NewRecorder()
+		GetInfoPercentBucket(recorder, req)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, url)
+	}
+}
+
+func TestGetInfoConditionStats(t *testing.T) {
+	processor := re.NewRuleProcessor()
+	rule := &re.Rule{
+		Condition: re.NewCondition(re.NewFreeArg(re.StandardFreeArgTypeString, "statsModel"), re.StandardOperationIs, re.NewFixedArg("X1")),
+	}
+	xrule := &firmware.FirmwareRule{ID: "stats-rule", Rule: *rule}
+	index := re.NewRuleIndex(processor, []re.XRule{xrule}, "none")
+	assert.True(t, index.Evaluate(xrule, map[string]string{"statsModel": "X1"}, nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/info/conditionStats", nil)
+	recorder := httptest.NewRecorder()
+	GetInfoConditionStats(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response ConditionStatsResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.True(t, response.Evaluations >= 1)
+	found := false
+	for _, stat := range response.Conditions {
+		if stat.Condition == `"statsModel" IS "X1"` {
+			found = true
+			assert.Equal(t, int64(1), stat.Matches)
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestGetInfoRuleStats(t *testing.T) {
+	re.ResetRuleStats()
+	defer re.ResetRuleStats()
+	firmwareRule := &firmware.FirmwareRule{ID: "fw-rule", Name: "fw"}
+	re.RecordRuleEvaluation(firmwareRule, true)
+	re.RecordRuleApplied(firmwareRule)
+	featureRule := &rfc.FeatureRule{Id: "feature-rule"}
+	re.RecordRuleEvaluation(featureRule, false)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/info/ruleStats?ruleType=firmwareRule", nil)
+	recorder := httptest.NewRecorder()
+	GetInfoRuleStats(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var stats []re.RuleStat
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &stats))
+	assert.Equal(t, 1, len(stats))
+	assert.Equal(t, "fw-rule", stats[0].Id)
+	assert.Equal(t, int64(1), stats[0].Applied)
+
+	// reading the counters keeps them
+	req = httptest.NewRequest(http.MethodGet, "/api/info/ruleStats?reset=true", nil)
+	recorder = httptest.NewRecorder()
+	GetInfoRuleStats(recorder, req)
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &stats))
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, 2, len(re.GetRuleStats()))
+}
+
+func TestDeleteInfoRuleStats(t *testing.T) {
+	re.ResetRuleStats()
+	defer re.ResetRuleStats()
+	firmwareRule := &firmware.FirmwareRule{ID: "fw-rule", Name: "fw"}
+	re.RecordRuleEvaluation(firmwareRule, true)
+	featureRule := &rfc.FeatureRule{Id: "feature-rule"}
+	re.RecordRuleEvaluation(featureRule, false)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/info/ruleStats?ruleType=firmwareRule", nil)
+	recorder := httptest.NewRecorder()
+	DeleteInfoRuleStats(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var stats []re.RuleStat
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &stats))
+	assert.Equal(t, 1, len(stats))
+	assert.Equal(t, "fw-rule", stats[0].Id)
+	// the counters of the other rule types are kept
+	remaining := re.GetRuleStats()
+	assert.Equal(t, 1, len(remaining))
+	assert.Equal(t, "feature-rule", remaining[0].Id)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/info/ruleStats", nil)
+	recorder = httptest.NewRecorder()
+	DeleteInfoRuleStats(recorder, req)
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &stats))
+	assert.Equal(t, 1, len(stats))
+	assert.Equal(t, 0, len(re.GetRuleStats()))
+}
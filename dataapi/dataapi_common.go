@@ -18,6 +18,7 @@
 package dataapi
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -58,6 +59,33 @@ func GetClientCertExpiryHeaderValue(r *http.Request) string {
 	return r.Header.Get(common.CLIENT_CERT_EXPIRY_HEADER)
 }
 
+// NewRuleTracerForRequest returns a tracer when evaluation tracing is enabled in the config and
+// requested with the X-Evaluation-Trace header and the evaluation trace token as bearer token,
+// otherwise nil so rules are evaluated without tracing
+func NewRuleTracerForRequest(r *http.Request) *re.RuleTracer {
+	if Xc == nil || !isBearerTokenAuthorized(r, Xc.EvaluationTraceToken) {
+		return nil
+	}
+	return newRuleTracerForAuthorizedRequest(r)
+}
+
+// newRuleTracerForAuthorizedRequest is NewRuleTracerForRequest for a request authorized by its handler
+func newRuleTracerForAuthorizedRequest(r *http.Request) *re.RuleTracer {
+	if Xc == nil || !Xc.EvaluationTraceEnabled || r.Header.Get(common.EvaluationTraceHeader) != "true" {
+		return nil
+	}
+	return re.NewRuleTracer()
+}
+
+// isBearerTokenAuthorized returns true when the request has the token as a bearer token, never when token is empty
+func isBearerTokenAuthorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	requestToken, ok := strings.CutPrefix(r.Header.Get(common.HeaderAuthorization), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(requestToken), []byte(token)) == 1
+}
+
 func AddClientProtocolToContextMap(contextMap map[string]string, clientProtocolHeader string) {
 	switch clientProtocolHeader {
 	case common.XCONF_HTTPS_VALUE:
@@ -51,6 +51,46 @@ func TestGetClientCertExpiryHeaderValue(t *testing.T) {
 	}
 }
 
+func TestNewRuleTracerForRequest(t *testing.T) {
+	origXc := Xc
+	defer func() { Xc = origXc }()
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		token    string
+		header   string
+		expected bool
+	}{
+		{"Enabled and requested", true, "secret", "true", true},
+		{"Enabled not requested", true, "secret", "", false},
+		{"Enabled other value", true, "secret", "yes", false},
+		{"Disabled and requested", false, "secret", "true", false},
+		{"Wrong token", true, "other", "true", false},
+		{"No token", true, "", "true", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Xc = &XconfConfigs{EvaluationTraceEnabled: tt.enabled, EvaluationTraceToken: "secret"}
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set(common.EvaluationTraceHeader, tt.header)
+			if tt.token != "" {
+				req.Header.Set(common.HeaderAuthorization, "Bearer "+tt.token)
+			}
+			tracer := NewRuleTracerForRequest(req)
+			assert.Equal(t, tt.expected, tracer != nil)
+		})
+	}
+
+	// without a configured token nobody gets a trace
+	Xc = &XconfConfigs{EvaluationTraceEnabled: true}
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(common.EvaluationTraceHeader, "true")
+	req.Header.Set(common.HeaderAuthorization, "Bearer ")
+	assert.Nil(t, NewRuleTracerForRequest(req))
+}
+
 func TestAddClientProtocolToContextMap(t *testing.T) {
 	tests := []struct {
 		name     string
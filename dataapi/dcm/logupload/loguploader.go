@@ -34,6 +34,7 @@ import (
 type LogUploadRuleBase struct {
 	//DcmRuleDAO           ds.CachedSimpleDao
 	RuleProcessorFactory re.RuleProcessorFactory
+	Tracer               *re.RuleTracer // optional, records the evaluation of every DCM rule
 }
 
 func NewLogUploadRuleBase() *LogUploadRuleBase {
@@ -46,8 +47,11 @@ func (l *LogUploadRuleBase) Eval(context map[string]string, fields log.Fields) *
 	settings := logupload.NewSettings(1)
 	rules := l.getSortedDcmRules()
 	for _, rule := range rules {
-		if string(rule.ApplicationType) == context[common.APPLICATION_TYPE] && l.RuleProcessorFactory.RuleProcessor().Evaluate(&rule.Rule, context, log.Fields{}) {
+		if string(rule.ApplicationType) == context[common.APPLICATION_TYPE] && l.Tracer.Evaluate(l.RuleProcessorFactory.RuleProcessor(), rule, context, log.Fields{}) {
 			logupload.CopySettings(settings, l.GetSettings(rule.ID), rule, context, fields)
+			if _, ok := settings.RuleIDs[rule.ID]; ok {
+				re.RecordRuleApplied(rule)
+			}
 		}
 		if settings.AreFull() {
 			return settings
@@ -98,10 +98,19 @@ func GetSettingsRuleByTypeForContext(settingType string, contextMap map[string]s
 		for _, rule := range settingRules {
 			ruleProcessorFactory := re.NewRuleProcessorFactory()
 			// TODO: please add log.Fields to this method
-			if contextMap[common.APPLICATION_TYPE] == rule.GetApplicationType() && ruleProcessorFactory.Processor.Evaluate(&rule.Rule, contextMap, log.Fields{}) {
+			if contextMap[common.APPLICATION_TYPE] != rule.GetApplicationType() {
+				continue
+			}
+			matched := ruleProcessorFactory.Processor.Evaluate(&rule.Rule, contextMap, log.Fields{})
+			re.RecordRuleEvaluation(rule, matched)
+			if matched {
 				rules = append(rules, *rule)
 			}
 		}
 	}
-	return GetMaxRule(rules)
+	maxRule := GetMaxRule(rules)
+	if maxRule != nil {
+		re.RecordRuleApplied(maxRule)
+	}
+	return maxRule
 }
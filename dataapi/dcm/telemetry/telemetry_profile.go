@@ -34,6 +34,7 @@ import (
 type TelemetryProfileService struct {
 	//RuleProcessorFactory		ev.RuleProcessorFactory
 	CacheUpdateWindowSize int64
+	Tracer                *re.RuleTracer // optional, records the evaluation of telemetry rules
 }
 
 var NewRuleProcessorFactoryFunc = re.NewRuleProcessorFactory
@@ -162,7 +163,11 @@ func (t *TelemetryProfileService) GetTelemetryRuleForContext(context map[string]
 	//all type of []*TelemetryRule
 	all := logupload.GetTelemetryRuleList()
 	rules := t.ProcessEntityRules(all, context)
-	return t.GetMaxRule(rules)
+	maxRule := t.GetMaxRule(rules)
+	if maxRule != nil {
+		re.RecordRuleApplied(maxRule)
+	}
+	return maxRule
 }
 
 func (t *TelemetryProfileService) ProcessEntityRules(telemetryRuleList []*logupload.TelemetryRule, context map[string]string) []*logupload.TelemetryRule {
@@ -171,7 +176,7 @@ func (t *TelemetryProfileService) ProcessEntityRules(telemetryRuleList []*logupl
 	for _, rule := range telemetryRuleList {
 
 		// TODO: please add log.Fields to this method
-		if context["applicationType"] == rule.GetApplicationType() && ruleProcessorFactory.Processor.Evaluate(&rule.Rule, context, log.Fields{}) {
+		if context["applicationType"] == rule.GetApplicationType() && t.Tracer.Evaluate(ruleProcessorFactory.Processor, rule, context, log.Fields{}) {
 			newTelemetryRuleList = append(newTelemetryRuleList, rule)
 		}
 	}
@@ -244,7 +249,7 @@ func (t *TelemetryProfileService) ProcessTelemetryTwoRules(context map[string]st
 	processor := ruleProcessorFactory.Processor
 	matched := []*logupload.TelemetryTwoRule{}
 	for _, tRule := range all {
-		if processor.Evaluate(&tRule.Rule, context, log.Fields{}) {
+		if t.Tracer.Evaluate(processor, tRule, context, log.Fields{}) {
 			matched = append(matched, tRule)
 		}
 	}
@@ -261,7 +266,9 @@ func (t *TelemetryProfileService) ProcessTelemetryTwoRulesForAS(context map[stri
 	processor := ruleProcessorFactory.Processor
 	matched := []*logupload.TelemetryTwoRule{}
 	for _, tRule := range all {
-		if processor.Evaluate(&tRule.Rule, context, log.Fields{}) {
+		isMatched := processor.Evaluate(&tRule.Rule, context, log.Fields{})
+		re.RecordRuleEvaluation(tRule, isMatched)
+		if isMatched {
 			matched = append(matched, tRule)
 		}
 	}
@@ -278,6 +285,7 @@ func (t *TelemetryProfileService) GetTelemetryTwoProfileByTelemetryRules(telemet
 			!telemetryTwoRule.NoOp &&
 			len(telemetryTwoRule.BoundTelemetryIDs) > 0 {
 			telemetryRuleNames = append(telemetryRuleNames, telemetryTwoRule.Name)
+			re.RecordRuleApplied(telemetryTwoRule)
 			for _, boundTelemetryId := range telemetryTwoRule.BoundTelemetryIDs {
 				if len(boundTelemetryId) < 1 {
 					continue
@@ -24,7 +24,6 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -35,6 +34,7 @@ import (
 	"github.com/rdkcentral/xconfwebconfig/common"
 	"github.com/rdkcentral/xconfwebconfig/db"
 	xhttp "github.com/rdkcentral/xconfwebconfig/http"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
 	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
 	"github.com/rdkcentral/xconfwebconfig/shared/firmware"
 	"github.com/rdkcentral/xconfwebconfig/util"
@@ -97,20 +97,11 @@ func IsMacPresentAndValid(queryParams url.Values) (bool, string, string) {
 	return true, mac, errorStr
 }
 
+// GetTimeInLocalTimezone sets the context time to the zoneless local time of the device, the SCHEDULE
+// operation and the maintenance windows read the timeZoneOffset with the same parser
 func GetTimeInLocalTimezone(currentTime time.Time, contextMap map[string]string) {
-	if contextMap[common.TIME_ZONE_OFFSET] != "" {
-		offsetList := strings.Split(contextMap[common.TIME_ZONE_OFFSET], ":")
-		if len(offsetList) == 2 {
-			hours, err1 := strconv.Atoi(offsetList[0])
-			mins, err2 := strconv.Atoi(offsetList[1])
-			if err1 == nil && err2 == nil && hours >= -23 && hours <= 23 && mins >= 0 && mins <= 59 {
-				currentTime = currentTime.Add(time.Hour * time.Duration(hours))
-				if hours < 0 {
-					mins *= -1
-				}
-				currentTime = currentTime.Add(time.Minute * time.Duration(mins))
-			}
-		}
+	if location, ok := re.ParseTimeZoneOffset(contextMap[common.TIME_ZONE_OFFSET]); ok {
+		currentTime = currentTime.In(location)
 	}
 	contextMap[common.TIME] = currentTime.Format(common.DATE_TIME_FORMATTER)
 }
@@ -146,12 +137,26 @@ func GetExplanation(contextMap map[string]string, evaluationResult *estbfirmware
 	if evaluationResult.MatchedRule == nil {
 		fmt.Fprintf(&explanation, "Request: %s\\ndid not match any rule.", input.String())
 	} else {
-		if evaluationResult.FirmwareConfig == nil && evaluationResult.Blocked {
+		if evaluationResult.FirmwareConfig == nil && evaluationResult.RolloutHalt != nil {
+			fmt.Fprintf(&explanation, "Request: %s\\n matched %s %s: %s\\n and blocked because the rollout is halted: %s", input.String(), evaluationResult.MatchedRule.Type, evaluationResult.MatchedRule.ID, evaluationResult.MatchedRule.Name, evaluationResult.RolloutHalt)
+		} else if evaluationResult.FirmwareConfig == nil && evaluationResult.Blocked {
 			fmt.Fprintf(&explanation, "Request: %s\\n matched %s %s: %s\\n and blocked by Distribution percent in %s", input.String(), evaluationResult.MatchedRule.Type, evaluationResult.MatchedRule.ID, evaluationResult.MatchedRule.Name, evaluationResult.MatchedRule.ApplicableAction)
 		} else if evaluationResult.FirmwareConfig == nil {
 			fmt.Fprintf(&explanation, "Request: %s\\n matched NO OP %s %s: %s\\n received NO config.", input.String(), evaluationResult.MatchedRule.Type, evaluationResult.MatchedRule.ID, evaluationResult.MatchedRule.Name)
 		} else {
 			fmt.Fprintf(&explanation, "Request: %s\\n matched %s %s: %s\\n received config: %+v", input.String(), evaluationResult.MatchedRule.Type, evaluationResult.MatchedRule.ID, evaluationResult.MatchedRule.Name, evaluationResult.FirmwareConfig)
+			if evaluationResult.UpgradePath != nil {
+				fmt.Fprintf(&explanation, "\\n as the next hop of upgrade path %s", evaluationResult.UpgradePath)
+			}
+			if evaluationResult.RolloutHalt != nil {
+				fmt.Fprintf(&explanation, "\\n because the rollout is halted: %s", evaluationResult.RolloutHalt)
+			}
+			if version := evaluationResult.AppliedVersionInfo[estbfirmware.DELTA_FROM_VERSION]; version != "" {
+				fmt.Fprintf(&explanation, "\\n with the delta image from %s and the full image as the fallback", version)
+			}
+			for _, decision := range evaluationResult.LocationDecisions {
+				fmt.Fprintf(&explanation, "\\n download location %s", decision)
+			}
 			if len(evaluationResult.AppliedFilters) > 0 {
 				filter := evaluationResult.AppliedFilters[len(evaluationResult.AppliedFilters)-1]
 				var filterString string
@@ -175,6 +180,8 @@ func GetExplanation(contextMap map[string]string, evaluationResult *estbfirmware
 					filterString = fmt.Sprintf("SINGLETON_%s %s", stringUtils.SubstringBeforeLast(filter.(coreef.DownloadLocationRoundRobinFilterValue).ID, "_VALUE"), filter.(coreef.DownloadLocationRoundRobinFilterValue).ID)
 				case firmware.RuleAction:
 					filterString = fmt.Sprintf("DistributionPercent in %s", filter)
+				case *estbfirmware.MaintenanceWindowDecision:
+					filterString = filter.(*estbfirmware.MaintenanceWindowDecision).String()
 				case coreef.PercentageBean:
 					percentageBean := filter.(coreef.PercentageBean)
 					filterString = fmt.Sprintf("DistributedEnvModelPercentage{id=%s, name=%s, firmwareCheckRequired=%s, lastKnownGood=%s, intermediateVersion=%s, firmwareVersions=%s}", percentageBean.ID, percentageBean.Name, percentageBean.FirmwareVersions, percentageBean.LastKnownGood, percentageBean.IntermediateVersion, percentageBean.FirmwareVersions)
@@ -216,6 +223,15 @@ func IsAllowedRequest(contextMap map[string]string, clientProtocolHeader string)
 
 // AddEstbFirmwareContext ..
 func AddEstbFirmwareContext(ws *xhttp.XconfServer, r *http.Request, contextMap map[string]string, usePartnerAppType bool, shouldAddIp bool, vargs ...log.Fields) error {
+	return addEstbFirmwareContext(ws, r, contextMap, usePartnerAppType, shouldAddIp, false, vargs...)
+}
+
+// AddEstbFirmwareContextNoStats is AddEstbFirmwareContext without counting the account lookups in the metrics, for dry runs
+func AddEstbFirmwareContextNoStats(ws *xhttp.XconfServer, r *http.Request, contextMap map[string]string, usePartnerAppType bool, shouldAddIp bool, vargs ...log.Fields) error {
+	return addEstbFirmwareContext(ws, r, contextMap, usePartnerAppType, shouldAddIp, true, vargs...)
+}
+
+func addEstbFirmwareContext(ws *xhttp.XconfServer, r *http.Request, contextMap map[string]string, usePartnerAppType bool, shouldAddIp bool, noStats bool, vargs ...log.Fields) error {
 	var err error
 	var localToken *xhttp.SatToken
 	var fields log.Fields
@@ -242,7 +258,9 @@ func AddEstbFirmwareContext(ws *xhttp.XconfServer, r *http.Request, contextMap m
 
 	if Xc.EnableXacGroupService {
 		if util.IsUnknownValue(contextMap[common.ACCOUNT_ID]) || contextMap[common.ACCOUNT_ID] == "" || util.IsUnknownValue(contextMap[common.PARTNER_ID]) {
-			xhttp.IncreaseUnknownIdCounter(contextMap[common.MODEL], contextMap[common.PARTNER_ID])
+			if !noStats {
+				xhttp.IncreaseUnknownIdCounter(contextMap[common.MODEL], contextMap[common.PARTNER_ID])
+			}
 			if util.IsValidMacAddress(contextMap[common.ESTB_MAC]) {
 				macPart := util.RemoveNonAlphabeticSymbols(contextMap[common.ESTB_MAC])
 				xAccountId, err = ws.GroupServiceConnector.GetAccountIdData(macPart, fields)
@@ -297,7 +315,9 @@ func AddEstbFirmwareContext(ws *xhttp.XconfServer, r *http.Request, contextMap m
 						for key, val := range ap {
 							contextMap[key] = val
 						}
-						xhttp.IncreaseGrpServiceFetchCounter(contextMap[common.MODEL], contextMap[common.PARTNER_ID])
+						if !noStats {
+							xhttp.IncreaseGrpServiceFetchCounter(contextMap[common.MODEL], contextMap[common.PARTNER_ID])
+						}
 						log.WithFields(fields).Debug("AddEstbFirmwareContext AcntId,AccntProduct successfully retrieved from Grp Svc")
 					} else {
 						log.WithFields(fields).Error("AddEstbFirmwareContext: Failed to unmarshal only AccountProducts")
@@ -306,17 +326,23 @@ func AddEstbFirmwareContext(ws *xhttp.XconfServer, r *http.Request, contextMap m
 			}
 		} else {
 			log.WithFields(log.Fields{"error": err}).Errorf("Error getting accountId info from Grp Svc")
-			xhttp.IncreaseGrpServiceNotFoundResponseCounter(contextMap[common.MODEL], contextMap[common.PARTNER_ID])
+			if !noStats {
+				xhttp.IncreaseGrpServiceNotFoundResponseCounter(contextMap[common.MODEL], contextMap[common.PARTNER_ID])
+			}
 		}
 	}
 
 	if Xc.EnableAccountService && util.IsUnknownValue(contextMap[common.PARTNER_ID]) {
 		log.WithFields(fields).Debugf("Fallback Trying via Old Account Service,Failed to Get AccountId via Grp Svc due to Flag Disabled or err")
-		xhttp.IncreaseUnknownIdCounter(contextMap[common.MODEL], contextMap[common.PARTNER_ID])
+		if !noStats {
+			xhttp.IncreaseUnknownIdCounter(contextMap[common.MODEL], contextMap[common.PARTNER_ID])
+		}
 		partnerId := GetPartnerFromAccountServiceByHostMac(ws, contextMap[common.ESTB_MAC], satToken, fields)
 		if partnerId != "" {
 			contextMap[common.PARTNER_ID] = partnerId
-			xhttp.IncreaseAccountFetchCounter(contextMap[common.MODEL], contextMap[common.PARTNER_ID])
+			if !noStats {
+				xhttp.IncreaseAccountFetchCounter(contextMap[common.MODEL], contextMap[common.PARTNER_ID])
+			}
 		}
 	}
 	coastTags := AddContextFromTaggingService(ws, contextMap, satToken, "", false, fields)
@@ -338,10 +364,10 @@ func GetMissingAndEmptyQueryParams(contextMap map[string]string, missingFields *
 	}
 }
 
+// LogPreDisplayCleanup removes the id of a change log before it is returned, its time is kept for the CSV output
 func LogPreDisplayCleanup(lastConfigLog *coreef.ConfigChangeLog) {
 	if lastConfigLog != nil {
 		lastConfigLog.ID = ""
-		lastConfigLog.Updated = 0
 	}
 }
 
@@ -352,6 +378,8 @@ func LogResponse(contextMap map[string]string, convertedContext *coreef.Converte
 		if contextMap[common.FIRMWARE_VERSION] != "" {
 			log.Trace("Logging last config request.")
 			lastConfigLog := coreef.NewConfigChangeLog(convertedContext, explanation, evaluationResult.FirmwareConfig, evaluationResult.AppliedFilters, evaluationResult.MatchedRule, true)
+			// record when the device was told, the last log is overwritten on every request
+			lastConfigLog.Updated = util.GetTimestamp()
 			err := coreef.SetLastConfigLog(mac, lastConfigLog)
 			if err != nil {
 				log.Error(fmt.Sprintf("Can't save last log config request: %+v", err))
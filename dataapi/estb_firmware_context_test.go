@@ -1,6 +1,7 @@
 package dataapi
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/rdkcentral/xconfwebconfig/common"
 	"github.com/rdkcentral/xconfwebconfig/dataapi/estbfirmware"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
 	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
 	"github.com/rdkcentral/xconfwebconfig/shared/firmware"
 	log "github.com/sirupsen/logrus"
@@ -139,6 +141,30 @@ func TestGetTimeInLocalTimezone(t *testing.T) {
 	}
 }
 
+func TestNormalizeEstbFirmwareContextSchedule(t *testing.T) {
+	// a window around the current time of the clock shifted by hours
+	window := func(hours int) string {
+		now := time.Now().UTC().Add(time.Duration(hours) * time.Hour)
+		return fmt.Sprintf(`time SCHEDULE "Daily %s-%s"`, now.Add(-time.Hour).Format("15:04"), now.Add(time.Hour).Format("15:04"))
+	}
+	processor := re.NewRuleProcessor()
+	for offset, hours := range map[string]int{"-05:00": -5, "+05:00": 5, "05:00": 5} {
+		contextMap := map[string]string{
+			common.ESTB_MAC:         "AA:BB:CC:DD:EE:FF",
+			common.TIME_ZONE_OFFSET: offset,
+		}
+		NormalizeEstbFirmwareContext(nil, nil, contextMap, false, false, log.Fields{})
+		properties := coreef.GetContextConverted(contextMap).GetProperties()
+
+		local, err := re.ParseRule(window(hours))
+		assert.NoError(t, err)
+		assert.True(t, processor.Evaluate(local, properties, log.Fields{}), offset)
+		utc, err := re.ParseRule(window(0))
+		assert.NoError(t, err)
+		assert.False(t, processor.Evaluate(utc, properties, log.Fields{}), offset)
+	}
+}
+
 func TestIsAllowedRequest(t *testing.T) {
 	// Setup
 	originalXc := Xc
@@ -302,7 +328,7 @@ func TestLogPreDisplayCleanup(t *testing.T) {
 				Updated: 1234567890,
 			},
 			expectedID:     "",
-			expectedUpdate: 0,
+			expectedUpdate: 1234567890,
 		},
 		{
 			name:           "Nil log does nothing",
@@ -562,6 +588,53 @@ func TestGetExplanation(t *testing.T) {
 			},
 			shouldContain: []string{"matched", "Test Rule", "received config"},
 		},
+		{
+			name: "Matched rule with upgrade path",
+			contextMap: map[string]string{
+				common.ESTB_MAC: "AA:BB:CC:DD:EE:FF",
+			},
+			evaluationResult: &estbfirmware.EvaluationResult{
+				MatchedRule: &firmware.FirmwareRule{
+					ID:   "rule-123",
+					Name: "Test Rule",
+					Type: firmware.ENV_MODEL_RULE,
+				},
+				FirmwareConfig: &coreef.FirmwareConfigFacade{
+					Properties: map[string]interface{}{
+						common.FIRMWARE_VERSION: "2.0.0",
+					},
+				},
+				UpgradePath: &coreef.UpgradePath{
+					Versions:  []string{"1.0.0", "2.0.0", "3.0.0"},
+					ConfigIds: []string{"config2", "config3"},
+				},
+			},
+			shouldContain: []string{"received config", "next hop of upgrade path 1.0.0 -> 2.0.0 -> 3.0.0"},
+		},
+		{
+			name: "Matched rule with unhealthy download location",
+			contextMap: map[string]string{
+				common.ESTB_MAC: "AA:BB:CC:DD:EE:FF",
+			},
+			evaluationResult: &estbfirmware.EvaluationResult{
+				MatchedRule: &firmware.FirmwareRule{
+					ID:   "rule-123",
+					Name: "Test Rule",
+					Type: firmware.ENV_MODEL_RULE,
+				},
+				FirmwareConfig: &coreef.FirmwareConfigFacade{
+					Properties: map[string]interface{}{
+						common.FIRMWARE_VERSION: "2.0.0",
+					},
+				},
+				LocationDecisions: []*estbfirmware.LocationDecision{{
+					Locations:     estbfirmware.LOCATIONS_IPV4,
+					Unhealthy:     []*coreef.LocationHealth{{ID: "10.0.0.1", Source: coreef.LocationHealthSourceProbe, Reason: "timeout"}},
+					Redistributed: []coreef.Location{{LocationIp: "10.0.0.2", Percentage: 100}},
+				}},
+			},
+			shouldContain: []string{"received config", "download location ipv4 locations without 10.0.0.1 unhealthy (probe: timeout), redistributed to 10.0.0.2 100.00%"},
+		},
 		{
 			name: "Blocked by distribution percent",
 			contextMap: map[string]string{
@@ -579,6 +652,82 @@ func TestGetExplanation(t *testing.T) {
 			},
 			shouldContain: []string{"matched", "blocked by Distribution percent"},
 		},
+		{
+			name: "Blocked by rollout halt",
+			contextMap: map[string]string{
+				common.ESTB_MAC: "AA:BB:CC:DD:EE:FF",
+			},
+			evaluationResult: &estbfirmware.EvaluationResult{
+				MatchedRule: &firmware.FirmwareRule{
+					ID:   "rule-123",
+					Name: "Model Rule",
+					Type: firmware.ENV_MODEL_RULE,
+				},
+				Blocked: true,
+				RolloutHalt: &estbfirmware.RolloutHalt{
+					Model:             "X1",
+					PartnerId:         "COMCAST",
+					FirmwareVersion:   "2.0.0",
+					Reports:           10,
+					Failures:          5,
+					FailurePercentage: 50,
+					Threshold:         20,
+					Action:            estbfirmware.ROLLOUT_HALT_FREEZE,
+				},
+			},
+			shouldContain: []string{"blocked because the rollout is halted: firmware 2.0.0 frozen for model X1 partner COMCAST: 5 of 10 devices failed"},
+		},
+		{
+			name: "Matched rule with delta image",
+			contextMap: map[string]string{
+				common.ESTB_MAC: "AA:BB:CC:DD:EE:FF",
+			},
+			evaluationResult: &estbfirmware.EvaluationResult{
+				MatchedRule: &firmware.FirmwareRule{
+					ID:   "rule-123",
+					Name: "Test Rule",
+					Type: firmware.ENV_MODEL_RULE,
+				},
+				FirmwareConfig: &coreef.FirmwareConfigFacade{
+					Properties: map[string]interface{}{
+						common.FIRMWARE_VERSION:        "2.0.0",
+						common.DELTA_FIRMWARE_FILENAME: "delta.bin",
+					},
+				},
+				AppliedVersionInfo: map[string]string{
+					estbfirmware.DELTA_FROM_VERSION: "1.0.0",
+				},
+			},
+			shouldContain: []string{"with the delta image from 1.0.0 and the full image as the fallback"},
+		},
+		{
+			name: "Reboot deferred by maintenance window",
+			contextMap: map[string]string{
+				common.ESTB_MAC: "AA:BB:CC:DD:EE:FF",
+			},
+			evaluationResult: &estbfirmware.EvaluationResult{
+				MatchedRule: &firmware.FirmwareRule{
+					ID:   "rule-123",
+					Name: "Test Rule",
+					Type: firmware.ENV_MODEL_RULE,
+				},
+				FirmwareConfig: &coreef.FirmwareConfigFacade{
+					Properties: map[string]interface{}{
+						common.FIRMWARE_VERSION:   "2.0.0",
+						common.REBOOT_IMMEDIATELY: false,
+					},
+				},
+				AppliedFilters: []interface{}{
+					&estbfirmware.MaintenanceWindowDecision{
+						PolicyId:    "night",
+						Windows:     []string{"Daily 01:00-05:00"},
+						LocalTime:   "2026-10-16T20:30:00-05:00",
+						WindowStart: "2026-10-17T01:00:00-05:00",
+					},
+				},
+			},
+			shouldContain: []string{"was blocked/modified by filter MaintenanceWindowPolicy night: device local time 2026-10-16T20:30:00-05:00 is outside Daily 01:00-05:00"},
+		},
 		{
 			name: "NO OP rule",
 			contextMap: map[string]string{
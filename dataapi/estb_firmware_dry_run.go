@@ -0,0 +1,164 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package dataapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	dataef "github.com/rdkcentral/xconfwebconfig/dataapi/estbfirmware"
+	xhttp "github.com/rdkcentral/xconfwebconfig/http"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
+	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	corefw "github.com/rdkcentral/xconfwebconfig/shared/firmware"
+	"github.com/rdkcentral/xconfwebconfig/util"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// firmwareDryRunResponse is what /xconf/swu would return for a context, Status is the status the device would get
+type firmwareDryRunResponse struct {
+	Status             int                                   `json:"status"`
+	Context            map[string]string                     `json:"context"`
+	MatchedRule        *corefw.FirmwareRule                  `json:"matchedRule,omitempty"`
+	AppliedFilters     []interface{}                         `json:"appliedFilters,omitempty"`
+	BypassedFilters    []string                              `json:"bypassedFilters,omitempty"`
+	Blocked            bool                                  `json:"blocked"`
+	BlockingFilter     interface{}                           `json:"blockingFilter,omitempty"`
+	FirmwareConfig     sharedef.FirmwareConfigFacadeResponse `json:"firmwareConfig,omitempty"`
+	AppliedVersionInfo map[string]string                     `json:"appliedVersionInfo,omitempty"`
+	UpgradePath        *sharedef.UpgradePath                 `json:"upgradePath,omitempty"`
+	LocationDecisions  []*dataef.LocationDecision            `json:"locationDecisions,omitempty"`
+	RolloutHalt        *dataef.RolloutHalt                   `json:"rolloutHalt,omitempty"`
+	Description        string                                `json:"description,omitempty"`
+	Explanation        string                                `json:"explanation"`
+	EvaluationTrace    *re.RuleTracer                        `json:"evaluationTrace,omitempty"`
+}
+
+// GetEstbFirmwareDryRunHandler evaluates the firmware rules for an arbitrary context like /xconf/swu does,
+// without writing the config change logs, penetration metrics or splunk logs and without adding canaries.
+// It is enabled with dry_run_enabled and requires the dry_run_token as a bearer token
+func GetEstbFirmwareDryRunHandler(w http.ResponseWriter, r *http.Request) {
+	xw, ok := w.(*xhttp.XResponseWriter)
+	if !ok {
+		xhttp.Error(w, http.StatusInternalServerError, common.NotOK)
+		return
+	}
+	fields := xw.Audit()
+	if Xc == nil || !Xc.DryRunEnabled {
+		xhttp.WriteXconfResponseAsText(w, http.StatusNotFound, []byte("\"<h2>404 NOT FOUND</h2><div>dry run is disabled</div>\""))
+		return
+	}
+	if !isBearerTokenAuthorized(r, Xc.DryRunToken) {
+		xhttp.WriteXconfResponseAsText(w, http.StatusUnauthorized, []byte("\"<h2>401 Unauthorized</h2>\""))
+		return
+	}
+
+	contextMap, err := newDryRunContextMap(r, xw.Body())
+	if err != nil {
+		xhttp.WriteXconfResponseAsText(w, http.StatusBadRequest, []byte(fmt.Sprintf("\"<h2>400 Bad Request</h2><div>%s</div>\"", err.Error())))
+		return
+	}
+	if contextMap[common.ESTB_MAC] == "" {
+		xhttp.WriteXconfResponseAsText(w, http.StatusBadRequest, []byte("\"eStbMac should be specified\""))
+		return
+	}
+	if _, err := util.MACAddressValidator(contextMap[common.ESTB_MAC]); err != nil {
+		xhttp.WriteXconfResponseAsText(w, http.StatusBadRequest, []byte(fmt.Sprintf("\"<h2>400 Bad Request</h2><div>invalid mac address: %s</div>\"", contextMap[common.ESTB_MAC])))
+		return
+	}
+
+	// the ip address of the request is the caller's, the device's comes from the context
+	AddEstbFirmwareContextNoStats(Ws, r, contextMap, true, false, fields)
+	estbFirmwareRuleBase := dataef.NewEstbFirmwareRuleBaseDefault()
+	// the dry run token also authorizes the evaluation trace
+	estbFirmwareRuleBase.SetTracer(newRuleTracerForAuthorizedRequest(r))
+	estbFirmwareRuleBase.SetNoStats(true)
+	if canaryCohortDao != nil {
+		estbFirmwareRuleBase.SetCanaryScheduler(dataef.NewCanaryScheduler(dataef.GetCanarySettings(), sharedef.NewReadOnlyCanaryCohortDao(canaryCohortDao)))
+	}
+	convertedContext := sharedef.GetContextConverted(contextMap)
+	evaluationResult, _ := estbFirmwareRuleBase.Eval(contextMap, convertedContext, contextMap[common.APPLICATION_TYPE], fields)
+	explanation := GetExplanation(contextMap, evaluationResult)
+	log.WithFields(fields).Infof("Firmware dry run for %s: %s", contextMap[common.ESTB_MAC], evaluationResult.Description)
+
+	response, _ := util.JSONMarshal(newFirmwareDryRunResponse(contextMap, convertedContext, evaluationResult, explanation))
+	xhttp.WriteXconfResponse(w, http.StatusOK, response)
+}
+
+// newDryRunContextMap reads the context from the query params and the body, either a JSON object of
+// strings or form encoded. Unlike /xconf/swu the client protocol and cert expiry can be given in the context
+func newDryRunContextMap(r *http.Request, body string) (map[string]string, error) {
+	contextMap := map[string]string{}
+	for k, v := range r.URL.Query() {
+		contextMap[k] = strings.Join(v, ",")
+	}
+	if body = strings.TrimSpace(body); strings.HasPrefix(body, "{") {
+		bodyMap := map[string]string{}
+		if err := json.Unmarshal([]byte(body), &bodyMap); err != nil {
+			return nil, fmt.Errorf("context must be a JSON object of strings: %v", err)
+		}
+		for k, v := range bodyMap {
+			contextMap[k] = v
+		}
+	} else if body != "" {
+		parseProcBody(body, contextMap)
+	}
+	contextMap[common.APPLICATION_TYPE] = mux.Vars(r)[common.APPLICATION_TYPE]
+	if contextMap[common.CLIENT_PROTOCOL] == "" {
+		AddClientProtocolToContextMap(contextMap, GetClientProtocolHeaderValue(r))
+	}
+	GetFirstElementsInContextMap(contextMap)
+	return contextMap, nil
+}
+
+func newFirmwareDryRunResponse(contextMap map[string]string, convertedContext *sharedef.ConvertedContext, evaluationResult *dataef.EvaluationResult, explanation string) *firmwareDryRunResponse {
+	response := &firmwareDryRunResponse{
+		Status:             http.StatusOK,
+		Context:            contextMap,
+		MatchedRule:        evaluationResult.MatchedRule,
+		AppliedFilters:     evaluationResult.AppliedFilters,
+		Blocked:            evaluationResult.Blocked,
+		AppliedVersionInfo: evaluationResult.AppliedVersionInfo,
+		UpgradePath:        evaluationResult.UpgradePath,
+		LocationDecisions:  evaluationResult.LocationDecisions,
+		RolloutHalt:        evaluationResult.RolloutHalt,
+		Description:        evaluationResult.Description,
+		Explanation:        explanation,
+		EvaluationTrace:    evaluationResult.Trace,
+	}
+	for filter := range convertedContext.GetBypassFiltersConverted() {
+		response.BypassedFilters = append(response.BypassedFilters, filter)
+	}
+	sort.Strings(response.BypassedFilters)
+	if evaluationResult.Blocked && len(evaluationResult.AppliedFilters) > 0 {
+		response.BlockingFilter = evaluationResult.AppliedFilters[len(evaluationResult.AppliedFilters)-1]
+	}
+	if evaluationResult.FirmwareConfig != nil && evaluationResult.FirmwareConfig.Properties != nil {
+		response.FirmwareConfig = sharedef.CreateFirmwareConfigFacadeResponse(*evaluationResult.FirmwareConfig)
+	}
+	if evaluationResult.Blocked || response.FirmwareConfig == nil {
+		response.Status = http.StatusNotFound
+	}
+	return response
+}
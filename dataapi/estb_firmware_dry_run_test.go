@@ -0,0 +1,129 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package dataapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rdkcentral/xconfwebconfig/common"
+	dataef "github.com/rdkcentral/xconfwebconfig/dataapi/estbfirmware"
+	xhttp "github.com/rdkcentral/xconfwebconfig/http"
+	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	corefw "github.com/rdkcentral/xconfwebconfig/shared/firmware"
+	"github.com/stretchr/testify/assert"
+)
+
+func dryRunRequest(method string, target string, body string, token string) (*httptest.ResponseRecorder, *xhttp.XResponseWriter, *http.Request) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{common.APPLICATION_TYPE: "stb"})
+	if token != "" {
+		req.Header.Set(common.HeaderAuthorization, "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	xw := xhttp.NewXResponseWriter(recorder)
+	xw.SetBody(body)
+	return recorder, xw, req
+}
+
+func TestGetEstbFirmwareDryRunHandler_Gated(t *testing.T) {
+	originalXc := Xc
+	defer func() { Xc = originalXc }()
+
+	tests := []struct {
+		name   string
+		xc     *XconfConfigs
+		token  string
+		status int
+	}{
+		{"disabled", &XconfConfigs{DryRunToken: "secret"}, "secret", http.StatusNotFound},
+		{"no token configured", &XconfConfigs{DryRunEnabled: true}, "secret", http.StatusUnauthorized},
+		{"missing token", &XconfConfigs{DryRunEnabled: true, DryRunToken: "secret"}, "", http.StatusUnauthorized},
+		{"wrong token", &XconfConfigs{DryRunEnabled: true, DryRunToken: "secret"}, "other", http.StatusUnauthorized},
+		{"authorized", &XconfConfigs{DryRunEnabled: true, DryRunToken: "secret"}, "secret", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		Xc = tt.xc
+		recorder, xw, req := dryRunRequest(http.MethodGet, "/xconf/stb/dryRun?model=X1", "", tt.token)
+		GetEstbFirmwareDryRunHandler(xw, req)
+		assert.Equal(t, tt.status, recorder.Code, tt.name)
+	}
+}
+
+func TestGetEstbFirmwareDryRunHandler_InvalidContext(t *testing.T) {
+	originalXc := Xc
+	defer func() { Xc = originalXc }()
+	Xc = &XconfConfigs{DryRunEnabled: true, DryRunToken: "secret"}
+
+	recorder, xw, req := dryRunRequest(http.MethodPost, "/xconf/stb/dryRun", `{"eStbMac": 1}`, "secret")
+	GetEstbFirmwareDryRunHandler(xw, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "JSON object")
+
+	recorder, xw, req = dryRunRequest(http.MethodPost, "/xconf/stb/dryRun", `{"eStbMac": "not-a-mac"}`, "secret")
+	GetEstbFirmwareDryRunHandler(xw, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid mac address")
+}
+
+func TestNewDryRunContextMap(t *testing.T) {
+	_, _, req := dryRunRequest(http.MethodPost, "/xconf/stb/dryRun?model=X1&env=QA", "", "")
+	contextMap, err := newDryRunContextMap(req, `{"eStbMac": "AA:BB:CC:DD:EE:FF", "env": "PROD", "clientProtocol": "mtls"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "AA:BB:CC:DD:EE:FF", contextMap[common.ESTB_MAC])
+	assert.Equal(t, "X1", contextMap[common.MODEL])
+	assert.Equal(t, "PROD", contextMap[common.ENV], "the body overrides the query")
+	assert.Equal(t, "mtls", contextMap[common.CLIENT_PROTOCOL])
+	assert.Equal(t, "stb", contextMap[common.APPLICATION_TYPE])
+
+	contextMap, err = newDryRunContextMap(req, "eStbMac=AA:BB:CC:DD:EE:FF&firmwareVersion=1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", contextMap[common.FIRMWARE_VERSION])
+	assert.Equal(t, common.HTTP_CLIENT_PROTOCOL, contextMap[common.CLIENT_PROTOCOL])
+}
+
+func TestNewFirmwareDryRunResponse(t *testing.T) {
+	contextMap := map[string]string{common.ESTB_MAC: "AA:BB:CC:DD:EE:FF", common.BYPASS_FILTERS: "TIME_FILTER"}
+	convertedContext := sharedef.GetContextConverted(contextMap)
+	convertedContext.AddBypassFiltersConverted(corefw.IP_FILTER)
+
+	result := dataef.NewEvaluationResult()
+	result.MatchedRule = &corefw.FirmwareRule{ID: "rule1", Type: corefw.ENV_MODEL_RULE}
+	result.FirmwareConfig = sharedef.NewFirmwareConfigFacadeEmptyProperties()
+	result.FirmwareConfig.SetStringValue(common.FIRMWARE_VERSION, "2.0")
+	response := newFirmwareDryRunResponse(contextMap, convertedContext, result, "explanation")
+	assert.Equal(t, http.StatusOK, response.Status)
+	assert.Equal(t, []string{corefw.IP_FILTER, corefw.TIME_FILTER}, response.BypassedFilters)
+	assert.Equal(t, "2.0", response.FirmwareConfig["firmwareVersion"])
+	assert.Nil(t, response.BlockingFilter)
+
+	blockingFilter := &corefw.FirmwareRule{ID: "filter1", Type: "ENV_MODEL_BLOCKING"}
+	result.AddAppliedFilters(blockingFilter)
+	result.Blocked = true
+	response = newFirmwareDryRunResponse(contextMap, convertedContext, result, "explanation")
+	assert.Equal(t, http.StatusNotFound, response.Status)
+	assert.Equal(t, blockingFilter, response.BlockingFilter)
+
+	response = newFirmwareDryRunResponse(contextMap, convertedContext, dataef.NewEvaluationResult(), "no match")
+	assert.Equal(t, http.StatusNotFound, response.Status)
+	assert.Nil(t, response.MatchedRule)
+	assert.Nil(t, response.FirmwareConfig)
+}
@@ -20,6 +20,7 @@ package dataapi
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/rdkcentral/xconfwebconfig/common"
@@ -97,27 +98,7 @@ func GetEstbFirmwareSwuHandler(w http.ResponseWriter, r *http.Request) {
 		// only invoke security manager code if flag is enabled
 		// also check if SecurityTokenOnlyForNewOfferedFw is enabled, make sure a new fw is offered
 		if Xc.SecurityTokenManagerEnabled && (!Ws.SecurityTokenConfig.SecurityTokenOnlyForNewOfferedFwEnabled || evaluationResult.FirmwareConfig.GetFirmwareVersion() != contextMap[common.FIRMWARE_VERSION]) {
-			filename := evaluationResult.FirmwareConfig.GetFirmwareFilename()
-			if additionalFwVerInfo, ok := evaluationResult.FirmwareConfig.Properties[common.ADDITIONAL_FW_VER_INFO]; ok {
-				filename = fmt.Sprintf("%s,%s", filename, additionalFwVerInfo)
-			}
-
-			deviceInfo := map[string]string{
-				xhttp.SECURITY_TOKEN_ESTB_MAC:        contextMap[common.ESTB_MAC],
-				xhttp.SECURITY_TOKEN_CLIENT_PROTOCOL: contextMap[common.CLIENT_PROTOCOL],
-				xhttp.SECURITY_TOKEN_ESTB_IP:         contextMap[common.IP_ADDRESS],
-				xhttp.SECURITY_TOKEN_FW_FILENAME:     filename,
-				xhttp.SECURITY_TOKEN_FW_VERSION:      evaluationResult.FirmwareConfig.GetFirmwareVersion(),
-			}
-			if !util.IsBlank(contextMap[common.PARTNER_ID]) {
-				deviceInfo[xhttp.SECURITY_TOKEN_PARTNER] = contextMap[common.PARTNER_ID]
-			}
-			if !util.IsBlank(contextMap[common.MODEL]) {
-				deviceInfo[xhttp.SECURITY_TOKEN_MODEL] = contextMap[common.MODEL]
-			}
-
-			locationWithToken := Ws.FirmwareSecurityTokenConfig.AddSecurityTokenToUrl(deviceInfo, evaluationResult.FirmwareConfig.GetFirmwareLocation(), fields)
-			evaluationResult.FirmwareConfig.SetFirmwareLocation(locationWithToken)
+			addFirmwareSecurityTokens(evaluationResult.FirmwareConfig, contextMap, fields)
 		}
 
 		if Xc.EnableFwDownloadLogs {
@@ -125,13 +106,57 @@ func GetEstbFirmwareSwuHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		firmwareConfigResponse := sharedef.CreateFirmwareConfigFacadeResponse(*evaluationResult.FirmwareConfig)
+		if evaluationResult.Trace != nil {
+			firmwareConfigResponse["evaluationTrace"] = evaluationResult.Trace
+		}
 		response, _ := util.JSONMarshal(firmwareConfigResponse)
-		xhttp.WriteXconfResponse(w, 200, response)
+		if err := xhttp.WriteSignedXconfResponse(w, Ws.ResponseSigner, 200, response); err != nil {
+			log.WithFields(fields).Errorf("Firmware response is not signed: %v", err)
+		}
+	} else if status == 404 && evaluationResult != nil && evaluationResult.Trace != nil {
+		traceResponse := util.Dict{
+			"description":     explanation,
+			"evaluationTrace": evaluationResult.Trace,
+		}
+		response, _ := util.JSONMarshal(traceResponse)
+		xhttp.WriteXconfResponse(w, status, response)
 	} else {
 		xhttp.WriteXconfResponseAsText(w, status, response)
 	}
 }
 
+// addFirmwareSecurityTokens adds the security token of the device to the firmware location, and to the delta
+// location with the delta filename
+func addFirmwareSecurityTokens(firmwareConfig *sharedef.FirmwareConfigFacade, contextMap map[string]string, fields log.Fields) {
+	filename := firmwareConfig.GetFirmwareFilename()
+	if additionalFwVerInfo, ok := firmwareConfig.Properties[common.ADDITIONAL_FW_VER_INFO]; ok {
+		filename = fmt.Sprintf("%s,%s", filename, additionalFwVerInfo)
+	}
+
+	deviceInfo := map[string]string{
+		xhttp.SECURITY_TOKEN_ESTB_MAC:        contextMap[common.ESTB_MAC],
+		xhttp.SECURITY_TOKEN_CLIENT_PROTOCOL: contextMap[common.CLIENT_PROTOCOL],
+		xhttp.SECURITY_TOKEN_ESTB_IP:         contextMap[common.IP_ADDRESS],
+		xhttp.SECURITY_TOKEN_FW_FILENAME:     filename,
+		xhttp.SECURITY_TOKEN_FW_VERSION:      firmwareConfig.GetFirmwareVersion(),
+	}
+	if !util.IsBlank(contextMap[common.PARTNER_ID]) {
+		deviceInfo[xhttp.SECURITY_TOKEN_PARTNER] = contextMap[common.PARTNER_ID]
+	}
+	if !util.IsBlank(contextMap[common.MODEL]) {
+		deviceInfo[xhttp.SECURITY_TOKEN_MODEL] = contextMap[common.MODEL]
+	}
+
+	locationWithToken := Ws.FirmwareSecurityTokenConfig.AddSecurityTokenToUrl(deviceInfo, firmwareConfig.GetFirmwareLocation(), fields)
+	firmwareConfig.SetFirmwareLocation(locationWithToken)
+
+	if deltaLocation := firmwareConfig.GetStringValue(common.DELTA_FIRMWARE_LOCATION); deltaLocation != "" {
+		deviceInfo[xhttp.SECURITY_TOKEN_FW_FILENAME] = firmwareConfig.GetStringValue(common.DELTA_FIRMWARE_FILENAME)
+		deltaLocationWithToken := Ws.FirmwareSecurityTokenConfig.AddSecurityTokenToUrl(deviceInfo, deltaLocation, fields)
+		firmwareConfig.SetStringValue(common.DELTA_FIRMWARE_LOCATION, deltaLocationWithToken)
+	}
+}
+
 func GetFirmwareResponse(w http.ResponseWriter, r *http.Request, xw *xhttp.XResponseWriter, fields log.Fields) (int, []byte, *dataef.EvaluationResult, *sharedef.ConvertedContext, string, map[string]string) {
 	queryParams := r.URL.Query()
 	clientProtocolHeader := GetClientProtocolHeaderValue(r)
@@ -174,6 +199,10 @@ func GetFirmwareResponse(w http.ResponseWriter, r *http.Request, xw *xhttp.XResp
 	AddEstbFirmwareContext(Ws, r, contextMap, true, true, fields)
 	log.Debugf("GetEstbFirmwareSwuHandler call AddEstbFirmwareContext  ... end contextMap %v", contextMap)
 	estbFirmwareRuleBase := dataef.NewEstbFirmwareRuleBaseDefault()
+	estbFirmwareRuleBase.SetTracer(NewRuleTracerForRequest(r))
+	if canaryCohortDao != nil {
+		estbFirmwareRuleBase.SetCanaryScheduler(dataef.NewCanaryScheduler(dataef.GetCanarySettings(), canaryCohortDao))
+	}
 	convertedContext := sharedef.GetContextConverted(contextMap)
 	evaluationResult, _ := estbFirmwareRuleBase.Eval(contextMap, convertedContext, contextMap[common.APPLICATION_TYPE], fields)
 	explanation := GetExplanation(contextMap, evaluationResult)
@@ -282,41 +311,133 @@ func GetEstbFirmwareVersionInfoPath(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetEstbLastlogPath returns the last firmware config a device was told, as JSON or CSV
 func GetEstbLastlogPath(w http.ResponseWriter, r *http.Request) {
 	isValid, mac, errStr := IsMacPresentAndValid(r.URL.Query())
 	if !isValid {
 		xhttp.WriteXconfResponseAsText(w, 400, []byte(errStr))
+		return
+	}
+	format, err := getChangeLogFormat(r)
+	if err != nil {
+		xhttp.WriteXconfResponseAsText(w, 400, []byte(err.Error()))
+		return
+	}
+	mac = util.NormalizeMacAddress(mac)
+	lastConfigLog := sharedef.GetLastConfigLog(mac)
+	if lastConfigLog != nil {
+		LogPreDisplayCleanup(lastConfigLog)
 	} else {
-		mac := util.NormalizeMacAddress(mac)
-		lastConfigLog := sharedef.GetLastConfigLog(mac)
+		log.Debugf("Last log is not found for mac %s", mac)
+	}
+	if format == ChangeLogFormatCsv {
+		changeLogs := []*sharedef.ConfigChangeLog{}
 		if lastConfigLog != nil {
-			LogPreDisplayCleanup(lastConfigLog)
-			response, _ := util.JSONMarshal(*lastConfigLog)
-			xhttp.WriteXconfResponse(w, 200, response)
-		} else {
-			log.Debugf("Last log is not found for mac %s", mac)
-			xhttp.WriteXconfResponse(w, 200, []byte(""))
+			changeLogs = append(changeLogs, lastConfigLog)
 		}
+		writeConfigChangeLogsCsv(w, map[string]string{}, changeLogs)
+		return
+	}
+	if lastConfigLog == nil {
+		xhttp.WriteXconfResponse(w, 200, []byte(""))
+		return
 	}
+	response, _ := util.JSONMarshal(*lastConfigLog)
+	xhttp.WriteXconfResponse(w, 200, response)
 }
 
+// GetEstbChangelogsPath returns the firmware config changes of a device, newest first, as JSON or CSV.
+// They can be filtered by time range and rule, and paged, see NewConfigChangeLogQuery.
 func GetEstbChangelogsPath(w http.ResponseWriter, r *http.Request) {
 	isValid, mac, errStr := IsMacPresentAndValid(r.URL.Query())
 	if !isValid {
 		xhttp.WriteXconfResponseAsText(w, 400, []byte(errStr))
-	} else {
-		mac := util.NormalizeMacAddress(mac)
-		configChangeLogs := sharedef.GetConfigChangeLogsOnly(mac)
-		if len(configChangeLogs) > 0 {
-			for _, log := range configChangeLogs {
-				LogPreDisplayCleanup(log)
-			}
-		} else {
-			log.Debugf("Last log is not found for mac %s", mac)
+		return
+	}
+	query, err := NewConfigChangeLogQuery(r.URL.Query())
+	if err != nil {
+		xhttp.WriteXconfResponseAsText(w, 400, []byte(err.Error()))
+		return
+	}
+	format, err := getChangeLogFormat(r)
+	if err != nil {
+		xhttp.WriteXconfResponseAsText(w, 400, []byte(err.Error()))
+		return
+	}
+	mac = util.NormalizeMacAddress(mac)
+	configChangeLogs, total := query.Apply(sharedef.GetConfigChangeLogsOnly(mac))
+	if total == 0 {
+		log.Debugf("Change logs are not found for mac %s", mac)
+	}
+	for _, changeLog := range configChangeLogs {
+		LogPreDisplayCleanup(changeLog)
+	}
+	headers := map[string]string{NumberOfItemsHeader: strconv.Itoa(total)}
+	if format == ChangeLogFormatCsv {
+		writeConfigChangeLogsCsv(w, headers, configChangeLogs)
+		return
+	}
+	response, _ := util.JSONMarshal(configChangeLogs)
+	xhttp.WriteXconfResponseWithHeaders(w, headers, 200, response)
+}
+
+// canaryCohortDao selects canary devices when canary_selection_enabled is set
+var canaryCohortDao sharedef.CanaryCohortDao
+
+type canaryCohortResponse struct {
+	CohortId string                   `json:"cohortId"`
+	MaxSize  int                      `json:"maxSize"`
+	Size     int                      `json:"size"`
+	Devices  []*sharedef.CanaryDevice `json:"devices"`
+}
+
+// GetEstbCanaryPath returns the canary cohort of a target firmware config, model and partner.
+// With a mac it returns the device when it is in the cohort, 404 otherwise. It requires the canary_token
+func GetEstbCanaryPath(w http.ResponseWriter, r *http.Request) {
+	if Xc == nil || !Xc.CanarySelectionEnabled {
+		xhttp.WriteXconfResponseAsText(w, http.StatusNotFound, []byte("\"<h2>404 NOT FOUND</h2><div>canary selection is disabled</div>\""))
+		return
+	}
+	if !isBearerTokenAuthorized(r, Xc.CanaryToken) {
+		xhttp.WriteXconfResponseAsText(w, http.StatusUnauthorized, []byte("\"<h2>401 Unauthorized</h2>\""))
+		return
+	}
+	queryParams := r.URL.Query()
+	configId := queryParams.Get("configId")
+	model := queryParams.Get(common.MODEL)
+	if configId == "" || model == "" {
+		xhttp.WriteXconfResponseAsText(w, 400, []byte(fmt.Sprintf("Required String parameters 'configId' and '%s' are not present", common.MODEL)))
+		return
+	}
+	cohortId := sharedef.CanaryCohortId(configId, model, queryParams.Get(common.PARTNER_ID))
+	dao := sharedef.GetCanaryCohortDao()
+	if queryParams.Has(common.MAC) {
+		isValid, mac, errStr := IsMacPresentAndValid(queryParams)
+		if !isValid {
+			xhttp.WriteXconfResponseAsText(w, 400, []byte(errStr))
+			return
+		}
+		device, err := dao.GetCanaryDevice(cohortId, util.NormalizeMacAddress(mac))
+		if err != nil || device == nil {
+			xhttp.WriteXconfResponseAsText(w, 404, []byte(fmt.Sprintf("%s is not in canary cohort %s", mac, cohortId)))
+			return
 		}
-		response, _ := util.JSONMarshal(configChangeLogs)
+		response, _ := util.JSONMarshal(device)
 		xhttp.WriteXconfResponse(w, 200, response)
+		return
+	}
+	devices, err := dao.GetCanaryCohort(cohortId)
+	if err != nil {
+		log.Debugf("Canary cohort %s is not found: %v", cohortId, err)
+		devices = []*sharedef.CanaryDevice{}
 	}
+	response, _ := util.JSONMarshal(canaryCohortResponse{
+		CohortId: cohortId,
+		MaxSize:  dataef.GetCanarySettings().MaxSize,
+		Size:     len(devices),
+		Devices:  devices,
+	})
+	xhttp.WriteXconfResponse(w, 200, response)
 }
 
 func parseProcBody(body string, contextMap map[string]string) string {
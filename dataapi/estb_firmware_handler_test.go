@@ -11,6 +11,8 @@ import (
 	xhttp "github.com/rdkcentral/xconfwebconfig/http"
 	"github.com/rdkcentral/xconfwebconfig/shared"
 	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/util"
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -320,5 +322,68 @@ func TestLogPreDisplayCleanup_ValidLog(t *testing.T) {
 	LogPreDisplayCleanup(log)
 
 	assert.Equal(t, "", log.ID)
-	assert.Equal(t, int64(0), log.Updated)
+	assert.Equal(t, int64(1234567890), log.Updated)
+}
+
+func TestGetEstbCanaryPath_Gated(t *testing.T) {
+	originalXc := Xc
+	defer func() { Xc = originalXc }()
+
+	tests := []struct {
+		name   string
+		xc     *XconfConfigs
+		token  string
+		query  string
+		status int
+		body   string
+	}{
+		{"disabled", &XconfConfigs{CanaryToken: "secret"}, "secret", "configId=target&model=X1", http.StatusNotFound, "disabled"},
+		{"no token configured", &XconfConfigs{CanarySelectionEnabled: true}, "", "configId=target&model=X1", http.StatusUnauthorized, "Unauthorized"},
+		{"wrong token", &XconfConfigs{CanarySelectionEnabled: true, CanaryToken: "secret"}, "other", "configId=target&model=X1", http.StatusUnauthorized, "Unauthorized"},
+		{"missing params", &XconfConfigs{CanarySelectionEnabled: true, CanaryToken: "secret"}, "secret", "model=X1", http.StatusBadRequest, "configId"},
+		{"invalid mac", &XconfConfigs{CanarySelectionEnabled: true, CanaryToken: "secret"}, "secret", "configId=target&model=X1&mac=invalid-mac", http.StatusBadRequest, "Mac is invalid"},
+	}
+	for _, tt := range tests {
+		Xc = tt.xc
+		req := httptest.NewRequest(http.MethodGet, "/estbfirmware/canary?"+tt.query, nil)
+		req.Header.Set(common.HeaderAuthorization, "Bearer "+tt.token)
+		recorder := httptest.NewRecorder()
+
+		GetEstbCanaryPath(recorder, req)
+
+		assert.Equal(t, tt.status, recorder.Code, tt.name)
+		assert.Contains(t, recorder.Body.String(), tt.body, tt.name)
+	}
+}
+
+func TestAddFirmwareSecurityTokens_DeltaImage(t *testing.T) {
+	originalWs, originalHttpWs := Ws, xhttp.Ws
+	defer func() { Ws, xhttp.Ws = originalWs, originalHttpWs }()
+	Ws = &xhttp.XconfServer{
+		SecurityTokenConfig:         &xhttp.SecurityTokenConfig{SkipSecurityTokenClientProtocolSet: util.NewSet()},
+		FirmwareSecurityTokenConfig: &xhttp.SecurityTokenPathConfig{UrlPathMap: map[string]bool{"/images": true}},
+	}
+	xhttp.Ws = Ws
+	contextMap := map[string]string{common.ESTB_MAC: "AA:BB:CC:DD:EE:FF", common.MODEL: "X1"}
+
+	tests := []struct {
+		name          string
+		delta         *sharedef.DeltaImage
+		deltaLocation string
+	}{
+		{"full image location", &sharedef.DeltaImage{Filename: "X1_1.0-2.0.delta"}, "http://ssr.example.com/xds/AABBCCDDEEFF/images"},
+		{"own location", &sharedef.DeltaImage{Filename: "X1_1.0-2.0.delta", Location: "http://delta.example.com/images/delta"}, "http://delta.example.com/xds/AABBCCDDEEFF/images/delta"},
+	}
+	for _, tt := range tests {
+		firmwareConfig := sharedef.NewFirmwareConfigFacadeEmptyProperties()
+		firmwareConfig.SetStringValue(common.FIRMWARE_FILENAME, "X1_2.0.bin")
+		firmwareConfig.SetStringValue(common.FIRMWARE_VERSION, "X1_2.0")
+		firmwareConfig.SetFirmwareLocation("http://ssr.example.com/images")
+		firmwareConfig.SetDeltaImage("X1_1.0", tt.delta)
+
+		addFirmwareSecurityTokens(firmwareConfig, contextMap, log.Fields{})
+
+		assert.Equal(t, "http://ssr.example.com/xds/AABBCCDDEEFF/images", firmwareConfig.GetFirmwareLocation(), tt.name)
+		assert.Equal(t, tt.deltaLocation, firmwareConfig.GetStringValue(common.DELTA_FIRMWARE_LOCATION), tt.name)
+	}
 }
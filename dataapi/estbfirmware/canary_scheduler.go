@@ -0,0 +1,162 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
+	"github.com/rdkcentral/xconfwebconfig/shared"
+	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	corefw "github.com/rdkcentral/xconfwebconfig/shared/firmware"
+	"github.com/rdkcentral/xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CANARY_COHORT is the AppliedVersionInfo key of the cohort a canary device is served from
+const CANARY_COHORT = "canaryCohort"
+
+// CanarySettings are read from the Canary app settings. The upgrade window is in seconds after
+// the device's local midnight, a window that ends before it starts spans midnight
+type CanarySettings struct {
+	MaxSize                int     `json:"maxSize"`
+	DistributionPercentage float64 `json:"distributionPercentage"`
+	StartTime              int     `json:"fwUpgradeStartTime"`
+	EndTime                int     `json:"fwUpgradeEndTime"`
+}
+
+// GetCanarySettings reads the canary app settings, canaries are disabled when they are not set
+func GetCanarySettings() *CanarySettings {
+	return &CanarySettings{
+		MaxSize:                shared.GetIntAppSetting(common.PROP_CANARY_MAXSIZE, 0),
+		DistributionPercentage: shared.GetFloat64AppSetting(common.PROP_CANARY_DISTRIBUTION_PERCENTAGE, 0),
+		StartTime:              shared.GetIntAppSetting(common.PROP_CANARY_FW_UPGRADE_STARTTIME, -1),
+		EndTime:                shared.GetIntAppSetting(common.PROP_CANARY_FW_UPGRADE_ENDTIME, -1),
+	}
+}
+
+func (s *CanarySettings) IsEnabled() bool {
+	return s != nil && s.MaxSize > 0 && s.DistributionPercentage > 0
+}
+
+// InWindow returns true when the time of day of t is in the upgrade window, or no window is set
+func (s *CanarySettings) InWindow(t time.Time) bool {
+	if s.StartTime < 0 || s.EndTime < 0 || s.StartTime == s.EndTime {
+		return true
+	}
+	seconds := t.Hour()*3600 + t.Minute()*60 + t.Second()
+	if s.StartTime < s.EndTime {
+		return seconds >= s.StartTime && seconds < s.EndTime
+	}
+	return seconds >= s.StartTime || seconds < s.EndTime
+}
+
+// GetCanaryTarget returns the config entry being rolled out by an active rule action,
+// the last one that is neither paused nor excluded from canaries
+func GetCanaryTarget(ruleAction *corefw.ApplicableAction) *corefw.ConfigEntry {
+	if ruleAction == nil || !ruleAction.Active {
+		return nil
+	}
+	for i := len(ruleAction.ConfigEntries) - 1; i >= 0; i-- {
+		entry := ruleAction.ConfigEntries[i]
+		if entry.IsPaused || entry.IsCanaryDisabled || entry.ConfigId == "" || entry.ConfigId == ruleAction.ConfigId {
+			continue
+		}
+		return &entry
+	}
+	return nil
+}
+
+// CanaryScheduler selects up to MaxSize devices of a model and partner, within the distribution
+// percentage and during the upgrade window, to get the target firmware of an ENV_MODEL_RULE before
+// its rollout reaches them. A selected device stays in its cohort until the target changes.
+// The cohort size is checked before adding a device, concurrent requests can exceed it slightly
+type CanaryScheduler struct {
+	settings *CanarySettings
+	dao      coreef.CanaryCohortDao
+}
+
+func NewCanaryScheduler(settings *CanarySettings, dao coreef.CanaryCohortDao) *CanaryScheduler {
+	return &CanaryScheduler{
+		settings: settings,
+		dao:      dao,
+	}
+}
+
+// Select returns the canary device for the context, nil when the device is not a canary of the rule, and
+// whether the device is new to its cohort. A new device only joins the cohort with Join, once its response
+// is known not to be blocked. Only devices that would get the last known good config of the rule are selected
+func (s *CanaryScheduler) Select(context *coreef.ConvertedContext, firmwareRule *corefw.FirmwareRule, boundConfigId string, appliedVersionInfo map[string]string, fields log.Fields) (*coreef.CanaryDevice, bool) {
+	if !s.settings.IsEnabled() || firmwareRule.GetTemplateId() != corefw.ENV_MODEL_RULE {
+		return nil, false
+	}
+	ruleAction := firmwareRule.ApplicableAction
+	target := GetCanaryTarget(ruleAction)
+	if target == nil || boundConfigId == "" || boundConfigId != ruleAction.ConfigId || strings.Contains(appliedVersionInfo[FIRMWARE_SOURCE], "doesntMeetMinCheck") {
+		return nil, false
+	}
+	mac := util.NormalizeMacAddress(context.GetEstbMacConverted())
+	if mac == "" {
+		return nil, false
+	}
+
+	cohortId := coreef.CanaryCohortId(target.ConfigId, context.GetModelConverted(), context.GetPartnerId())
+	if device, err := s.dao.GetCanaryDevice(cohortId, mac); err == nil && device != nil {
+		return device, false
+	}
+
+	now := time.Now()
+	if t := context.GetTimeConverted(); t != nil {
+		now = *t
+	}
+	if !s.settings.InWindow(now) || !re.FitsSaltedPercent(mac, s.settings.DistributionPercentage, cohortId) {
+		return nil, false
+	}
+	cohort, err := s.dao.GetCanaryCohort(cohortId)
+	if err != nil {
+		log.WithFields(fields).Warnf("CanaryScheduler failed to get cohort %s: %v", cohortId, err)
+		return nil, false
+	}
+	if len(cohort) >= s.settings.MaxSize {
+		return nil, false
+	}
+
+	device := &coreef.CanaryDevice{
+		ID:              mac,
+		CohortId:        cohortId,
+		RuleId:          firmwareRule.ID,
+		ConfigId:        target.ConfigId,
+		Model:           strings.ToUpper(context.GetModelConverted()),
+		PartnerId:       strings.ToUpper(context.GetPartnerId()),
+		FirmwareVersion: context.GetFirmwareVersionConverted(),
+		Updated:         util.GetTimestamp(time.Now()),
+	}
+	return device, true
+}
+
+// Join adds a device returned as new by Select to its cohort
+func (s *CanaryScheduler) Join(device *coreef.CanaryDevice, fields log.Fields) {
+	if err := s.dao.SetCanaryDevice(device); err != nil {
+		log.WithFields(fields).Warnf("CanaryScheduler failed to add %s to cohort %s: %v", device.ID, device.CohortId, err)
+		return
+	}
+	log.WithFields(fields).Infof("CanaryScheduler added %s to cohort %s, at most %d devices", device.ID, device.CohortId, s.settings.MaxSize)
+}
@@ -0,0 +1,222 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	corefw "github.com/rdkcentral/xconfwebconfig/shared/firmware"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryCanaryCohortDao struct {
+	cohorts map[string][]*coreef.CanaryDevice
+}
+
+func newMemoryCanaryCohortDao() *memoryCanaryCohortDao {
+	return &memoryCanaryCohortDao{cohorts: map[string][]*coreef.CanaryDevice{}}
+}
+
+func (d *memoryCanaryCohortDao) GetCanaryDevice(cohortId string, mac string) (*coreef.CanaryDevice, error) {
+	for _, device := range d.cohorts[cohortId] {
+		if device.ID == mac {
+			return device, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found", mac)
+}
+
+func (d *memoryCanaryCohortDao) GetCanaryCohort(cohortId string) ([]*coreef.CanaryDevice, error) {
+	return d.cohorts[cohortId], nil
+}
+
+func (d *memoryCanaryCohortDao) SetCanaryDevice(device *coreef.CanaryDevice) error {
+	d.cohorts[device.CohortId] = append(d.cohorts[device.CohortId], device)
+	return nil
+}
+
+func canaryTestRule() *corefw.FirmwareRule {
+	return &corefw.FirmwareRule{
+		ID:   "envModelRule",
+		Type: corefw.ENV_MODEL_RULE,
+		ApplicableAction: &corefw.ApplicableAction{
+			Active:   true,
+			ConfigId: "lkg",
+			ConfigEntries: []corefw.ConfigEntry{
+				{ConfigId: "previous", Percentage: 50, IsCanaryDisabled: true},
+				{ConfigId: "target", Percentage: 10},
+				{ConfigId: "paused", Percentage: 10, IsPaused: true},
+			},
+		},
+	}
+}
+
+func canaryTestContext(mac string, hour int) *coreef.ConvertedContext {
+	context := coreef.GetContextConverted(map[string]string{
+		common.ESTB_MAC:   mac,
+		common.MODEL:      "X1",
+		common.PARTNER_ID: "comcast",
+	})
+	t := time.Date(2024, 1, 1, hour, 30, 0, 0, time.UTC)
+	context.SetTimeConverted(&t)
+	return context
+}
+
+// selectCanary selects the device and adds it to its cohort as an unblocked response does
+func selectCanary(scheduler *CanaryScheduler, context *coreef.ConvertedContext, rule *corefw.FirmwareRule, boundConfigId string, appliedVersionInfo map[string]string) *coreef.CanaryDevice {
+	device, isNew := scheduler.Select(context, rule, boundConfigId, appliedVersionInfo, log.Fields{})
+	if isNew {
+		scheduler.Join(device, log.Fields{})
+	}
+	return device
+}
+
+func TestCanarySettingsInWindow(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC) }
+
+	settings := &CanarySettings{StartTime: 3600, EndTime: 4 * 3600}
+	assert.False(t, settings.InWindow(at(0)))
+	assert.True(t, settings.InWindow(at(1)))
+	assert.True(t, settings.InWindow(at(3)))
+	assert.False(t, settings.InWindow(at(4)))
+
+	overnight := &CanarySettings{StartTime: 22 * 3600, EndTime: 2 * 3600}
+	assert.True(t, overnight.InWindow(at(23)))
+	assert.True(t, overnight.InWindow(at(1)))
+	assert.False(t, overnight.InWindow(at(12)))
+
+	unset := &CanarySettings{StartTime: -1, EndTime: -1}
+	assert.True(t, unset.InWindow(at(12)))
+}
+
+func TestGetCanaryTarget(t *testing.T) {
+	rule := canaryTestRule()
+	assert.Equal(t, "target", GetCanaryTarget(rule.ApplicableAction).ConfigId)
+
+	rule.ApplicableAction.Active = false
+	assert.Nil(t, GetCanaryTarget(rule.ApplicableAction))
+
+	rule = canaryTestRule()
+	rule.ApplicableAction.ConfigEntries[1].IsCanaryDisabled = true
+	assert.Nil(t, GetCanaryTarget(rule.ApplicableAction))
+	assert.Nil(t, GetCanaryTarget(nil))
+}
+
+func TestCanarySchedulerSelect(t *testing.T) {
+	dao := newMemoryCanaryCohortDao()
+	scheduler := NewCanaryScheduler(&CanarySettings{MaxSize: 3, DistributionPercentage: 100, StartTime: 3600, EndTime: 4 * 3600}, dao)
+	rule := canaryTestRule()
+	cohortId := coreef.CanaryCohortId("target", "X1", "comcast")
+
+	// outside the window nobody is selected
+	assert.Nil(t, selectCanary(scheduler, canaryTestContext("AA:BB:CC:DD:EE:01", 12), rule, "lkg", map[string]string{}))
+
+	for i := 1; i <= 5; i++ {
+		device := selectCanary(scheduler, canaryTestContext(fmt.Sprintf("AA:BB:CC:DD:EE:0%d", i), 2), rule, "lkg", map[string]string{})
+		if i <= 3 {
+			assert.NotNil(t, device)
+			assert.Equal(t, "target", device.ConfigId)
+			assert.Equal(t, cohortId, device.CohortId)
+			assert.Equal(t, "COMCAST", device.PartnerId)
+		} else {
+			assert.Nil(t, device, "cohort is full")
+		}
+	}
+	assert.Equal(t, 3, len(dao.cohorts[cohortId]))
+
+	// members stay canaries outside the window and are not added twice
+	device := selectCanary(scheduler, canaryTestContext("aa:bb:cc:dd:ee:02", 12), rule, "lkg", map[string]string{})
+	assert.NotNil(t, device)
+	assert.Equal(t, "AA:BB:CC:DD:EE:02", device.ID)
+	assert.Equal(t, 3, len(dao.cohorts[cohortId]))
+
+	// other partners have their own cohort
+	context := canaryTestContext("AA:BB:CC:DD:EE:06", 2)
+	context.SetPartnerId("cox")
+	assert.NotNil(t, selectCanary(scheduler, context, rule, "lkg", map[string]string{}))
+}
+
+func TestCanarySchedulerSkipsDevicesInRollout(t *testing.T) {
+	dao := newMemoryCanaryCohortDao()
+	scheduler := NewCanaryScheduler(&CanarySettings{MaxSize: 10, DistributionPercentage: 100, StartTime: -1, EndTime: -1}, dao)
+	rule := canaryTestRule()
+	context := canaryTestContext("AA:BB:CC:DD:EE:01", 2)
+
+	assert.Nil(t, selectCanary(scheduler, context, rule, "previous", map[string]string{}))
+	assert.Nil(t, selectCanary(scheduler, context, rule, "", map[string]string{}))
+	assert.Nil(t, selectCanary(scheduler, context, rule, "lkg", map[string]string{FIRMWARE_SOURCE: "LKG,doesntMeetMinCheck"}))
+
+	rule.Type = corefw.MAC_RULE
+	assert.Nil(t, selectCanary(scheduler, context, rule, "lkg", map[string]string{}))
+
+	disabled := NewCanaryScheduler(&CanarySettings{MaxSize: 0, DistributionPercentage: 100}, dao)
+	assert.Nil(t, selectCanary(disabled, context, canaryTestRule(), "lkg", map[string]string{}))
+	assert.Equal(t, 0, len(dao.cohorts))
+}
+
+func TestCanarySchedulerDistributionPercentage(t *testing.T) {
+	dao := newMemoryCanaryCohortDao()
+	scheduler := NewCanaryScheduler(&CanarySettings{MaxSize: 1000, DistributionPercentage: 20, StartTime: -1, EndTime: -1}, dao)
+	rule := canaryTestRule()
+	selected := 0
+	for i := 0; i < 500; i++ {
+		mac := fmt.Sprintf("AA:BB:CC:DD:%02X:%02X", i/256, i%256)
+		if selectCanary(scheduler, canaryTestContext(mac, 2), rule, "lkg", map[string]string{}) != nil {
+			selected++
+		}
+	}
+	assert.InDelta(t, 100, selected, 40)
+}
+
+func TestCanarySchedulerJoinsOnlyOnJoin(t *testing.T) {
+	dao := newMemoryCanaryCohortDao()
+	scheduler := NewCanaryScheduler(&CanarySettings{MaxSize: 1, DistributionPercentage: 100, StartTime: -1, EndTime: -1}, dao)
+	rule := canaryTestRule()
+	cohortId := coreef.CanaryCohortId("target", "X1", "comcast")
+
+	// a device whose response is blocked after the selection does not take a slot
+	device, isNew := scheduler.Select(canaryTestContext("AA:BB:CC:DD:EE:01", 2), rule, "lkg", map[string]string{}, log.Fields{})
+	assert.NotNil(t, device)
+	assert.True(t, isNew)
+	assert.Equal(t, 0, len(dao.cohorts[cohortId]))
+
+	device, isNew = scheduler.Select(canaryTestContext("AA:BB:CC:DD:EE:02", 2), rule, "lkg", map[string]string{}, log.Fields{})
+	assert.True(t, isNew)
+	scheduler.Join(device, log.Fields{})
+	assert.Equal(t, 1, len(dao.cohorts[cohortId]))
+
+	// a member is not new
+	device, isNew = scheduler.Select(canaryTestContext("AA:BB:CC:DD:EE:02", 2), rule, "lkg", map[string]string{}, log.Fields{})
+	assert.NotNil(t, device)
+	assert.False(t, isNew)
+	device, _ = scheduler.Select(canaryTestContext("AA:BB:CC:DD:EE:01", 2), rule, "lkg", map[string]string{}, log.Fields{})
+	assert.Nil(t, device, "cohort is full")
+}
+
+func TestCanarySchedulerReadOnlyDao(t *testing.T) {
+	dao := newMemoryCanaryCohortDao()
+	scheduler := NewCanaryScheduler(&CanarySettings{MaxSize: 10, DistributionPercentage: 100, StartTime: -1, EndTime: -1}, coreef.NewReadOnlyCanaryCohortDao(dao))
+	device := selectCanary(scheduler, canaryTestContext("AA:BB:CC:DD:EE:01", 2), canaryTestRule(), "lkg", map[string]string{})
+	assert.NotNil(t, device, "the device would be selected")
+	assert.Equal(t, 0, len(dao.cohorts))
+}
@@ -20,6 +20,7 @@ package estbfirmware
 const (
 	PERCENT_FILTER_NAME = "PercentFilter"
 	FIRMWARE_SOURCE     = "firmwareVersionSource"
+	DELTA_FROM_VERSION  = "deltaFromVersion"
 )
 
 type DefaultValue string
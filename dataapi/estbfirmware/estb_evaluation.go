@@ -23,6 +23,7 @@ import (
 	"strconv"
 	"strings"
 
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
 	"github.com/rdkcentral/xconfwebconfig/shared"
 	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
 	"github.com/rdkcentral/xconfwebconfig/shared/firmware"
@@ -36,6 +37,10 @@ type EvaluationResult struct {
 	Description        string                       `json:"description,omitempty"`
 	Blocked            bool                         `json:"blocked,omitempty"`
 	AppliedVersionInfo map[string]string            `json:"appliedVersionInfo,omitempty"`
+	UpgradePath        *coreef.UpgradePath          `json:"upgradePath,omitempty"`
+	LocationDecisions  []*LocationDecision          `json:"locationDecisions,omitempty"`
+	RolloutHalt        *RolloutHalt                 `json:"rolloutHalt,omitempty"`
+	Trace              *re.RuleTracer               `json:"-"`
 }
 
 func NewEvaluationResult() *EvaluationResult {
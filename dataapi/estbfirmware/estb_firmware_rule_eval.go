@@ -47,6 +47,13 @@ type EstbFirmwareRuleBase struct {
 	ruleProcessorFactory *re.RuleProcessorFactory
 	driAlwaysReply       bool
 	driStateIdentifiers  string
+	tracer               *re.RuleTracer
+	ruleIndex            *re.RuleIndex
+	canaryScheduler      *CanaryScheduler
+	locationHealth       coreef.LocationHealthChecker
+	rolloutGuard         *RolloutGuard
+	maintenanceWindow    *MaintenanceWindowEnforcer
+	noStats              bool
 }
 
 func (e *EstbFirmwareRuleBase) SetruleProcessorFactory(ruleProcessorFactory *re.RuleProcessorFactory) {
@@ -61,6 +68,36 @@ func (e *EstbFirmwareRuleBase) SetdriStateIdentifiers(driStateIdentifiers string
 	e.driStateIdentifiers = driStateIdentifiers
 }
 
+// SetTracer turns on evaluation tracing, the collected traces are returned in EvaluationResult.Trace
+func (e *EstbFirmwareRuleBase) SetTracer(tracer *re.RuleTracer) {
+	e.tracer = tracer
+}
+
+// SetCanaryScheduler turns on canary selection, a selected device gets the canary target of the matched rule
+func (e *EstbFirmwareRuleBase) SetCanaryScheduler(canaryScheduler *CanaryScheduler) {
+	e.canaryScheduler = canaryScheduler
+}
+
+// SetLocationHealthChecker removes the unhealthy locations from the download location choice
+func (e *EstbFirmwareRuleBase) SetLocationHealthChecker(checker coreef.LocationHealthChecker) {
+	e.locationHealth = checker
+}
+
+// SetRolloutGuard halts the offers of firmware versions that too many devices failed to download or flash
+func (e *EstbFirmwareRuleBase) SetRolloutGuard(guard *RolloutGuard) {
+	e.rolloutGuard = guard
+}
+
+// SetMaintenanceWindowEnforcer keeps devices from rebooting immediately outside their maintenance window
+func (e *EstbFirmwareRuleBase) SetMaintenanceWindowEnforcer(enforcer *MaintenanceWindowEnforcer) {
+	e.maintenanceWindow = enforcer
+}
+
+// SetNoStats keeps the evaluations out of the rule and condition stats, for dry runs
+func (e *EstbFirmwareRuleBase) SetNoStats(noStats bool) {
+	e.noStats = noStats
+}
+
 // NewEstbFirmwareRuleBaseDefault ...
 func NewEstbFirmwareRuleBaseDefault() *EstbFirmwareRuleBase {
 	return NewEstbFirmwareRuleBase(true, "P-DRI,B-DRI")
@@ -72,6 +109,9 @@ func NewEstbFirmwareRuleBase(driAlwaysReply bool, driStateIdentifiers string) *E
 		ruleProcessorFactory: re.NewRuleProcessorFactory(),
 		driAlwaysReply:       driAlwaysReply,
 		driStateIdentifiers:  driStateIdentifiers,
+		locationHealth:       defaultLocationHealthChecker,
+		rolloutGuard:         defaultRolloutGuard,
+		maintenanceWindow:    defaultMaintenanceWindow,
 	}
 }
 
@@ -82,6 +122,7 @@ func (e *EstbFirmwareRuleBase) Eval(ctx map[string]string, convertedContext *cor
 	_ = start
 	// log.WithFields(fields).Debugf("EstbFirmwareRuleBase.Eval Start ... : context %v and applicationType %s", ctx, applicationType)
 	result := NewEvaluationResult()
+	result.Trace = e.tracer
 	// rulereflst, err := corefw.GetFirmwareSortedRuleAllAsListDB()
 	// var rules map[string][]*corefw.FirmwareRule
 	// if err == nil {
@@ -98,6 +139,12 @@ func (e *EstbFirmwareRuleBase) Eval(ctx map[string]string, convertedContext *cor
 	}
 	// log.WithFields(fields).Debugf("EstbFirmwareRuleBase.Eval ... corefw.GetFirmwareRuleAllAsListByApplicationType: finish in %v", time.Since(funcStartTime))
 
+	// tracing records every rule, so it always takes the linear path, as do the evaluations
+	// without stats since the compiled conditions of the index count their evaluations
+	if e.tracer == nil && !e.noStats {
+		e.ruleIndex = corefw.GetFirmwareRuleIndexByApplicationType(applicationType, e.ruleProcessorFactory.RuleProcessor())
+	}
+
 	bypassFilters := convertedContext.GetBypassFiltersConverted()
 
 	funcStartTime = time.Now()
@@ -113,9 +160,19 @@ func (e *EstbFirmwareRuleBase) Eval(ctx map[string]string, convertedContext *cor
 
 	result.MatchedRule = matchedRule
 	var firmwareConfig *coreef.FirmwareConfigFacade = nil
+	var deltaImage *coreef.DeltaImage
+	var servedConfigId string
 
 	funcStartTime = time.Now()
 	boundConfigId := e.GetBoundConfigId(ctx, convertedContext, matchedRule, result.AppliedVersionInfo)
+	var canary *coreef.CanaryDevice
+	var newCanary bool
+	if e.canaryScheduler != nil {
+		if canary, newCanary = e.canaryScheduler.Select(convertedContext, matchedRule, boundConfigId, result.AppliedVersionInfo, fields); canary != nil {
+			boundConfigId = canary.ConfigId
+			result.AppliedVersionInfo[CANARY_COHORT] = canary.CohortId
+		}
+	}
 	// log.WithFields(fields).Debugf("EstbFirmwareRuleBase.Eval ... e.GetBoundConfigId End: finish in %v", time.Since(funcStartTime))
 
 	if boundConfigId != "" && len(boundConfigId) != 0 { // check for no-op rules
@@ -131,7 +188,26 @@ func (e *EstbFirmwareRuleBase) Eval(ctx map[string]string, convertedContext *cor
 			// log.WithFields(fields).Debugf("EstbFirmwareRuleBase.Eval ... End %s: context %v and applicationType %s, finish in %v", result.Description, ctx, applicationType, time.Since(start))
 			return result, nil
 		} else {
+			hop := e.NextUpgradeHop(convertedContext, config, result, fields)
+			if hop == nil {
+				result.Blocked = true
+				result.Description = fmt.Sprintf("no upgrade path from %s to %s", convertedContext.GetFirmwareVersionConverted(), config.FirmwareVersion)
+				return result, nil
+			}
+			config = e.HaltRollout(convertedContext, matchedRule, hop, result, fields)
+			if config == nil {
+				result.Blocked = true
+				result.Description = fmt.Sprintf("rollout is halted: %s", result.RolloutHalt)
+				return result, nil
+			}
+			servedConfigId = config.ID
 			firmwareConfig = coreef.NewFirmwareConfigFacade(config)
+			if !convertedContext.IsSupportsFirmwareIntegrity() {
+				firmwareConfig.RemoveIntegrityProperties()
+			}
+			if convertedContext.IsSupportsDeltaFirmware() {
+				deltaImage = config.GetDeltaImage(convertedContext.GetFirmwareVersionConverted())
+			}
 			result.AppliedVersionInfo[FIRMWARE_SOURCE] = matchedRule.Type
 		}
 	} else if !matchedRule.IsNoop() {
@@ -159,9 +235,30 @@ func (e *EstbFirmwareRuleBase) Eval(ctx map[string]string, convertedContext *cor
 		// log.WithFields(fields).Debugf("EstbFirmwareRuleBase.Eval ... e.checkForDRIState End: finish in %v", time.Since(funcStartTime))
 	}
 
+	// the delta goes to the location chosen for the full image unless it has its own
+	if !blocked && deltaImage != nil {
+		firmwareConfig.SetDeltaImage(convertedContext.GetFirmwareVersionConverted(), deltaImage)
+		result.AppliedVersionInfo[DELTA_FROM_VERSION] = convertedContext.GetFirmwareVersionConverted()
+	}
+
+	// devices in a DRI state reboot immediately, they have no working firmware
+	if !blocked && e.maintenanceWindow != nil && !e.isDriState(ctx) {
+		if decision := e.maintenanceWindow.Apply(ctx, convertedContext, firmwareConfig, fields); decision != nil {
+			result.AddAppliedFilters(decision)
+		}
+	}
+
 	result.Blocked = blocked
 	if blocked {
 		result.Description = "output is blocked by filter"
+	} else {
+		// only a config the device gets counts the rule as applied
+		e.recordRuleApplied(matchedRule)
+		// the upgrade graph or a halted rollout may serve a config other than the canary target,
+		// a device joins its canary cohort only when it gets the target
+		if newCanary && servedConfigId == canary.ConfigId {
+			e.canaryScheduler.Join(canary, fields)
+		}
 	}
 	// log.WithFields(fields).Debugf("EstbFirmwareRuleBase.Eval ... End Succesful : context %v and applicationType %s, finish in %v", ctx, applicationType, time.Since(start))
 	return result, nil
@@ -228,7 +325,14 @@ func (e *EstbFirmwareRuleBase) FindMatchedRules(rules map[string][]*corefw.Firmw
 					fields["firmware_name"] = firmwareRule.Name
 				}
 				t0 := time.Now()
-				isEvaluate := e.ruleProcessorFactory.RuleProcessor().Evaluate(&firmwareRule.Rule, contextMap, fields)
+				var isEvaluate bool
+				if e.ruleIndex != nil {
+					isEvaluate = e.ruleIndex.Evaluate(firmwareRule, contextMap, fields)
+				} else if e.noStats {
+					isEvaluate = e.tracer.EvaluateWithoutStats(e.ruleProcessorFactory.RuleProcessor(), firmwareRule, contextMap, fields)
+				} else {
+					isEvaluate = e.tracer.Evaluate(e.ruleProcessorFactory.RuleProcessor(), firmwareRule, contextMap, fields)
+				}
 				diff1 := time.Now().Sub(t0).Milliseconds()
 				if diff1 > 10 {
 					fields["eval_duration"] = diff1
@@ -394,7 +498,7 @@ func (e *EstbFirmwareRuleBase) ExtractConfigFromAction(context *coreef.Converted
 			startPercentRange := entry.StartPercentRange
 			endPercentRange := entry.EndPercentRange
 			if startPercentRange >= 0 && endPercentRange >= 0 {
-				if !re.FitsPercent(source, startPercentRange) && re.FitsPercent(source, endPercentRange) {
+				if re.FitsSaltedPercentRange(source, startPercentRange, endPercentRange, ruleAction.PercentSalt) {
 					if entry.IsPaused {
 						appliedVersionInfo[FIRMWARE_SOURCE] = "MultipleVersionDistribution,isPaused"
 						return ""
@@ -404,7 +508,7 @@ func (e *EstbFirmwareRuleBase) ExtractConfigFromAction(context *coreef.Converted
 				}
 			} else if percentage > 0 {
 				currentPercent += percentage
-				if re.FitsPercent(source, currentPercent) {
+				if re.FitsSaltedPercent(source, currentPercent, ruleAction.PercentSalt) {
 					appliedVersionInfo[FIRMWARE_SOURCE] = "MultipleVersionDistribution,isPaused"
 					if entry.IsPaused {
 						return ""
@@ -464,6 +568,7 @@ func (e *EstbFirmwareRuleBase) ApplyMatchedFilters(
 				e.ApplyDefinePropertiesFilter(template, firmwareRule, action, mapinst, evaluationResult)
 			} else {
 				mapinst[coreef.REBOOT_IMMEDIATELY] = true
+				e.recordRuleApplied(firmwareRule)
 			}
 		} else if firmware.ACTIVATION_VERSION != template.ID {
 			e.ApplyDefinePropertiesFilter(template, firmwareRule, action, mapinst, evaluationResult)
@@ -491,6 +596,14 @@ func (e *EstbFirmwareRuleBase) ApplyDefinePropertiesFilter(
 	}
 
 	evaluationResult.AddAppliedFilters(firmwareRule)
+	e.recordRuleApplied(firmwareRule)
+}
+
+// recordRuleApplied counts a rule the response is built from, unless the rule base keeps no stats
+func (e *EstbFirmwareRuleBase) recordRuleApplied(xrule re.XRule) {
+	if !e.noStats {
+		re.RecordRuleApplied(xrule)
+	}
 }
 
 func (e *EstbFirmwareRuleBase) MatchFirmwareVersionRegEx(regExs []string, firmwareVersion string) bool {
@@ -554,6 +667,12 @@ func (e *EstbFirmwareRuleBase) DoFilters(ctx map[string]string, convertedContext
 	if err != nil {
 		log.WithFields(fields).Error("Failed to get download filter values")
 	} else {
+		if e.locationHealth != nil {
+			downloadLocationRoundRobinFilterValue, evaluationResult.LocationDecisions = ApplyLocationHealth(downloadLocationRoundRobinFilterValue, e.locationHealth, time.Now())
+			for _, decision := range evaluationResult.LocationDecisions {
+				log.WithFields(fields).Infof("Location health: %s", decision)
+			}
+		}
 		if DownloadLocationRoundRobinFilterFilter(firmwareConfig, downloadLocationRoundRobinFilterValue, convertedContext) {
 			evaluationResult.AddAppliedFilters(downloadLocationRoundRobinFilterValue)
 		}
@@ -591,6 +710,7 @@ func (e *EstbFirmwareRuleBase) DoFilters(ctx map[string]string, convertedContext
 	blockingFilter := e.FindMatchedRule(rules, corefw.BLOCKING_FILTER_TEMPLATE, contextProperties, bypassFilters, fields)
 	if blockingFilter != nil {
 		evaluationResult.AddAppliedFilters(blockingFilter)
+		e.recordRuleApplied(blockingFilter)
 		return true
 	}
 
@@ -598,22 +718,25 @@ func (e *EstbFirmwareRuleBase) DoFilters(ctx map[string]string, convertedContext
 }
 
 func (e *EstbFirmwareRuleBase) CheckForDRIState(ctx map[string]string, config *coreef.FirmwareConfigFacade, blocked bool) bool {
-	if len(e.driStateIdentifiers) == 0 || len(ctx[common.FIRMWARE_VERSION]) == 0 {
-		return blocked
+	if e.isDriState(ctx) {
+		blocked = false
+		if config != nil {
+			config.SetRebootImmediately(true)
+		}
 	}
+	return blocked
+}
 
-	identifiers := strings.Split(e.driStateIdentifiers, ",")
-
-	for _, identifier := range identifiers {
+func (e *EstbFirmwareRuleBase) isDriState(ctx map[string]string) bool {
+	if len(e.driStateIdentifiers) == 0 || len(ctx[common.FIRMWARE_VERSION]) == 0 {
+		return false
+	}
+	for _, identifier := range strings.Split(e.driStateIdentifiers, ",") {
 		if strings.Contains(strings.ToUpper(ctx[common.FIRMWARE_VERSION]), strings.ToUpper(identifier)) {
-			blocked = false
-			if config != nil {
-				config.SetRebootImmediately(true)
-			}
-			return blocked
+			return true
 		}
 	}
-	return blocked
+	return false
 }
 
 func (e *EstbFirmwareRuleBase) isPercentFilter(firmwareRule *corefw.FirmwareRule) bool {
@@ -38,6 +38,23 @@ func TestNewEstbFirmwareRuleBaseDefault(t *testing.T) {
 	assert.NotNil(t, ruleBase.ruleProcessorFactory)
 }
 
+func TestEstbFirmwareRuleBaseNoStats(t *testing.T) {
+	re.ResetRuleStats()
+	defer re.ResetRuleStats()
+	rule := &corefw.FirmwareRule{ID: "noStatsRule", Type: corefw.ENV_MODEL_RULE}
+
+	ruleBase := NewEstbFirmwareRuleBaseDefault()
+	ruleBase.SetNoStats(true)
+	ruleBase.recordRuleApplied(rule)
+	assert.Empty(t, re.GetRuleStats())
+
+	ruleBase.SetNoStats(false)
+	ruleBase.recordRuleApplied(rule)
+	stats := re.GetRuleStats()
+	assert.Equal(t, 1, len(stats))
+	assert.Equal(t, int64(1), stats[0].Applied)
+}
+
 // Test NewEstbFirmwareRuleBase
 func TestNewEstbFirmwareRuleBase(t *testing.T) {
 	ruleBase := NewEstbFirmwareRuleBase(false, "TEST-DRI")
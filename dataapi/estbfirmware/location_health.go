@@ -0,0 +1,176 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/util"
+)
+
+const (
+	LOCATIONS_IPV4 = "ipv4"
+	LOCATIONS_IPV6 = "ipv6"
+	LOCATIONS_HTTP = "http"
+)
+
+var defaultLocationHealthChecker coreef.LocationHealthChecker
+
+// SetDefaultLocationHealthChecker sets the LocationHealthChecker of new rule bases, nil turns location health off
+func SetDefaultLocationHealthChecker(checker coreef.LocationHealthChecker) {
+	defaultLocationHealthChecker = checker
+}
+
+// LocationDecision records how location health changed the download locations of a response
+type LocationDecision struct {
+	Locations     string                   `json:"locations"` // ipv4, ipv6 or http
+	Unhealthy     []*coreef.LocationHealth `json:"unhealthy"`
+	Redistributed []coreef.Location        `json:"redistributed,omitempty"`
+	FailOpen      bool                     `json:"failOpen,omitempty"` // every location was unhealthy, so none was removed
+}
+
+func (d *LocationDecision) String() string {
+	unhealthy := make([]string, len(d.Unhealthy))
+	for i, health := range d.Unhealthy {
+		unhealthy[i] = health.String()
+	}
+	switch {
+	case d.Locations == LOCATIONS_HTTP:
+		return fmt.Sprintf("http location %s has no alternative and is still used", strings.Join(unhealthy, ", "))
+	case d.FailOpen:
+		return fmt.Sprintf("all %s locations are unhealthy and are still used: %s", d.Locations, strings.Join(unhealthy, ", "))
+	}
+	redistributed := make([]string, len(d.Redistributed))
+	for i, location := range d.Redistributed {
+		redistributed[i] = fmt.Sprintf("%s %.2f%%", location.LocationIp, location.Percentage)
+	}
+	return fmt.Sprintf("%s locations without %s, redistributed to %s", d.Locations, strings.Join(unhealthy, ", "), strings.Join(redistributed, ", "))
+}
+
+type locationHealthCheckers []coreef.LocationHealthChecker
+
+// NewLocationHealthChecker returns the health from the first checker that has an unexpired health for a location
+func NewLocationHealthChecker(checkers ...coreef.LocationHealthChecker) coreef.LocationHealthChecker {
+	return locationHealthCheckers(checkers)
+}
+
+func (c locationHealthCheckers) GetLocationHealth(location string) *coreef.LocationHealth {
+	now := time.Now()
+	for _, checker := range c {
+		if health := checker.GetLocationHealth(location); health != nil && !health.IsExpired(now) {
+			return health
+		}
+	}
+	return nil
+}
+
+// ApplyLocationHealth returns a copy of the filter value without its unhealthy ipv4 and ipv6 locations, their
+// percentage redistributed among the healthy ones in proportion, and the decisions made. Locations of unknown
+// health count as healthy and nothing is removed when all locations are unhealthy. An unhealthy http location
+// is only reported, the filter has no other one
+func ApplyLocationHealth(filterValue *coreef.DownloadLocationRoundRobinFilterValue, checker coreef.LocationHealthChecker, now time.Time) (*coreef.DownloadLocationRoundRobinFilterValue, []*LocationDecision) {
+	if filterValue == nil || checker == nil {
+		return filterValue, nil
+	}
+	decisions := []*LocationDecision{}
+	healthyValue := *filterValue
+
+	var decision *LocationDecision
+	if healthyValue.Locations, decision = redistributeLocations(LOCATIONS_IPV4, filterValue.Locations, checker, now); decision != nil {
+		decisions = append(decisions, decision)
+	}
+	if healthyValue.Ipv6locations, decision = redistributeLocations(LOCATIONS_IPV6, filterValue.Ipv6locations, checker, now); decision != nil {
+		decisions = append(decisions, decision)
+	}
+	if len(filterValue.HttpLocation) != 0 && len(filterValue.HttpFullUrlLocation) != 0 {
+		decision = &LocationDecision{Locations: LOCATIONS_HTTP}
+		for _, host := range GetHttpLocationHosts(filterValue) {
+			if health := getUnhealthyLocation(checker, host, now); health != nil {
+				decision.Unhealthy = append(decision.Unhealthy, health)
+			}
+		}
+		if len(decision.Unhealthy) > 0 {
+			decisions = append(decisions, decision)
+		}
+	}
+
+	if len(decisions) == 0 {
+		return filterValue, nil
+	}
+	return &healthyValue, decisions
+}
+
+func redistributeLocations(locationType string, locations []coreef.Location, checker coreef.LocationHealthChecker, now time.Time) ([]coreef.Location, *LocationDecision) {
+	decision := &LocationDecision{Locations: locationType}
+	healthy := []coreef.Location{}
+	total := 0.0
+	for _, location := range locations {
+		if health := getUnhealthyLocation(checker, location.LocationIp, now); health != nil {
+			decision.Unhealthy = append(decision.Unhealthy, health)
+			continue
+		}
+		healthy = append(healthy, location)
+		total += location.Percentage
+	}
+	if len(decision.Unhealthy) == 0 {
+		return locations, nil
+	}
+	if total <= 0 {
+		decision.FailOpen = true
+		return locations, decision
+	}
+	for i := range healthy {
+		healthy[i].Percentage = healthy[i].Percentage * 100 / total
+	}
+	decision.Redistributed = healthy
+	return healthy, decision
+}
+
+func getUnhealthyLocation(checker coreef.LocationHealthChecker, location string, now time.Time) *coreef.LocationHealth {
+	health := checker.GetLocationHealth(location)
+	if health == nil || health.Healthy || health.IsExpired(now) {
+		return nil
+	}
+	return health
+}
+
+// GetHttpLocationHosts returns the hosts of the http locations of a filter value, their location health id
+func GetHttpLocationHosts(filterValue *coreef.DownloadLocationRoundRobinFilterValue) []string {
+	hosts := []string{}
+	for _, location := range []string{filterValue.HttpFullUrlLocation, filterValue.HttpLocation} {
+		if host := getHttpLocationHost(location); host != "" && !util.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+func getHttpLocationHost(location string) string {
+	if !strings.Contains(location, "://") {
+		location = "http://" + location
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
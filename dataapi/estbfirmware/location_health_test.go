@@ -0,0 +1,212 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func locationHealthTestFilterValue() *coreef.DownloadLocationRoundRobinFilterValue {
+	return &coreef.DownloadLocationRoundRobinFilterValue{
+		ID: coreef.ROUND_ROBIN_FILTER_SINGLETON_ID,
+		Locations: []coreef.Location{
+			{LocationIp: "10.0.0.1", Percentage: 50},
+			{LocationIp: "10.0.0.2", Percentage: 30},
+			{LocationIp: "10.0.0.3", Percentage: 20},
+		},
+		Ipv6locations: []coreef.Location{
+			{LocationIp: "::1", Percentage: 100},
+		},
+	}
+}
+
+func unhealthy(id string, reason string) *coreef.LocationHealth {
+	return &coreef.LocationHealth{ID: id, Healthy: false, Reason: reason, Source: coreef.LocationHealthSourceReport}
+}
+
+func TestApplyLocationHealthRedistributes(t *testing.T) {
+	filterValue := locationHealthTestFilterValue()
+	checker := coreef.LocationHealthMap{
+		"10.0.0.1": unhealthy("10.0.0.1", "origin down"),
+		"10.0.0.2": {ID: "10.0.0.2", Healthy: true},
+	}
+
+	healthyValue, decisions := ApplyLocationHealth(filterValue, checker, time.Now())
+	assert.Equal(t, []coreef.Location{{LocationIp: "10.0.0.2", Percentage: 60}, {LocationIp: "10.0.0.3", Percentage: 40}}, healthyValue.Locations)
+	assert.Equal(t, filterValue.Ipv6locations, healthyValue.Ipv6locations)
+	assert.Equal(t, 3, len(filterValue.Locations), "the filter value is not modified")
+	assert.Equal(t, 1, len(decisions))
+	assert.Equal(t, "ipv4 locations without 10.0.0.1 unhealthy (report: origin down), redistributed to 10.0.0.2 60.00%, 10.0.0.3 40.00%", decisions[0].String())
+}
+
+func TestApplyLocationHealthFailOpen(t *testing.T) {
+	filterValue := locationHealthTestFilterValue()
+	checker := coreef.LocationHealthMap{"::1": unhealthy("::1", "timeout")}
+
+	healthyValue, decisions := ApplyLocationHealth(filterValue, checker, time.Now())
+	assert.Equal(t, filterValue.Ipv6locations, healthyValue.Ipv6locations)
+	assert.Equal(t, 1, len(decisions))
+	assert.True(t, decisions[0].FailOpen)
+	assert.Equal(t, "all ipv6 locations are unhealthy and are still used: ::1 unhealthy (report: timeout)", decisions[0].String())
+}
+
+func TestApplyLocationHealthUnchanged(t *testing.T) {
+	filterValue := locationHealthTestFilterValue()
+	expired := unhealthy("10.0.0.1", "origin down")
+	expired.Expires = util.GetTimestamp(time.Now().Add(-time.Minute))
+
+	healthyValue, decisions := ApplyLocationHealth(filterValue, coreef.LocationHealthMap{"10.0.0.1": expired}, time.Now())
+	assert.True(t, healthyValue == filterValue)
+	assert.Nil(t, decisions)
+
+	healthyValue, decisions = ApplyLocationHealth(filterValue, nil, time.Now())
+	assert.True(t, healthyValue == filterValue)
+	assert.Nil(t, decisions)
+
+	healthyValue, _ = ApplyLocationHealth(nil, coreef.LocationHealthMap{}, time.Now())
+	assert.Nil(t, healthyValue)
+}
+
+func TestApplyLocationHealthHttp(t *testing.T) {
+	filterValue := locationHealthTestFilterValue()
+	filterValue.HttpLocation = "cdn.example.com"
+	filterValue.HttpFullUrlLocation = "https://cdn.example.com/firmware"
+	checker := coreef.LocationHealthMap{"cdn.example.com": unhealthy("cdn.example.com", "status 503")}
+
+	healthyValue, decisions := ApplyLocationHealth(filterValue, checker, time.Now())
+	assert.Equal(t, filterValue.HttpFullUrlLocation, healthyValue.HttpFullUrlLocation)
+	assert.Equal(t, 1, len(decisions))
+	assert.Equal(t, LOCATIONS_HTTP, decisions[0].Locations)
+	assert.Equal(t, "http location cdn.example.com unhealthy (report: status 503) has no alternative and is still used", decisions[0].String())
+}
+
+func TestNewLocationHealthChecker(t *testing.T) {
+	expired := unhealthy("10.0.0.1", "old report")
+	expired.Expires = util.GetTimestamp(time.Now().Add(-time.Minute))
+	reports := coreef.LocationHealthMap{"10.0.0.1": expired, "10.0.0.2": unhealthy("10.0.0.2", "report")}
+	probes := coreef.LocationHealthMap{
+		"10.0.0.1": {ID: "10.0.0.1", Healthy: true, Source: coreef.LocationHealthSourceProbe},
+		"10.0.0.2": {ID: "10.0.0.2", Healthy: true, Source: coreef.LocationHealthSourceProbe},
+	}
+	checker := NewLocationHealthChecker(reports, probes)
+
+	assert.Equal(t, coreef.LocationHealthSourceProbe, checker.GetLocationHealth("10.0.0.1").Source)
+	assert.Equal(t, coreef.LocationHealthSourceReport, checker.GetLocationHealth("10.0.0.2").Source)
+	assert.Nil(t, checker.GetLocationHealth("10.0.0.3"))
+}
+
+func TestGetLocationProbeTargets(t *testing.T) {
+	stb := locationHealthTestFilterValue()
+	stb.HttpLocation = "cdn.example.com"
+	stb.HttpFullUrlLocation = "https://cdn.example.com/firmware"
+	xhome := &coreef.DownloadLocationRoundRobinFilterValue{
+		Locations:    []coreef.Location{{LocationIp: "10.0.0.1", Percentage: 100}},
+		HttpLocation: "xhome.example.com",
+	}
+
+	targets := GetLocationProbeTargets([]*coreef.DownloadLocationRoundRobinFilterValue{stb, xhome})
+	assert.Equal(t, []LocationProbeTarget{
+		{ID: "10.0.0.1", Protocol: "tftp", Address: "10.0.0.1"},
+		{ID: "10.0.0.2", Protocol: "tftp", Address: "10.0.0.2"},
+		{ID: "10.0.0.3", Protocol: "tftp", Address: "10.0.0.3"},
+		{ID: "::1", Protocol: "tftp", Address: "::1"},
+		{ID: "cdn.example.com", Protocol: "http", Address: "https://cdn.example.com/firmware"},
+		{ID: "xhome.example.com", Protocol: "http", Address: "http://xhome.example.com"},
+	}, targets)
+}
+
+func TestLocationProberProbeAll(t *testing.T) {
+	prober := NewLocationProber(time.Minute, time.Second, "probe")
+	down := map[string]bool{"10.0.0.2": true}
+	prober.probe = func(target LocationProbeTarget) error {
+		if down[target.ID] {
+			return errors.New("timeout")
+		}
+		return nil
+	}
+	targets := GetLocationProbeTargets([]*coreef.DownloadLocationRoundRobinFilterValue{locationHealthTestFilterValue()})
+
+	prober.ProbeAll(targets)
+	assert.True(t, prober.GetLocationHealth("10.0.0.1").Healthy)
+	health := prober.GetLocationHealth("10.0.0.2")
+	assert.False(t, health.Healthy)
+	assert.Equal(t, "timeout", health.Reason)
+	assert.Equal(t, coreef.LocationHealthSourceProbe, health.Source)
+	assert.False(t, health.IsExpired(time.Now()))
+	assert.True(t, health.IsExpired(time.Now().Add(3*time.Minute)))
+	assert.Equal(t, 4, len(prober.GetAll()))
+
+	delete(down, "10.0.0.2")
+	prober.ProbeAll(targets)
+	assert.True(t, prober.GetLocationHealth("10.0.0.2").Healthy)
+}
+
+func TestProbeHttpLocation(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	assert.NoError(t, ProbeHttpLocation(server.URL, time.Second))
+	status = http.StatusNotFound
+	assert.NoError(t, ProbeHttpLocation(server.URL, time.Second))
+	status = http.StatusServiceUnavailable
+	assert.EqualError(t, ProbeHttpLocation(server.URL, time.Second), "status 503")
+}
+
+func TestProbeTftpLocation(t *testing.T) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	defer server.Close()
+	go func() {
+		request := make([]byte, tftpMaxPacketSize)
+		n, client, err := server.ReadFromUDP(request)
+		if err != nil {
+			return
+		}
+		assert.Equal(t, "\x00\x01probe\x00octet\x00", string(request[:n]))
+		// like a tftp server, answer from another port
+		transfer, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			return
+		}
+		defer transfer.Close()
+		transfer.WriteToUDP([]byte("\x00\x05\x00\x01File not found\x00"), client)
+	}()
+
+	assert.NoError(t, ProbeTftpLocation(server.LocalAddr().String(), "probe", time.Second))
+
+	silent, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	defer silent.Close()
+	err = ProbeTftpLocation(silent.LocalAddr().String(), "probe", 100*time.Millisecond)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "timeout"), err.Error())
+}
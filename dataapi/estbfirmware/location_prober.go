@@ -0,0 +1,257 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	TFTP_PORT         = "69"
+	tftpOpcodeRead    = 1
+	tftpOpcodeData    = 3
+	tftpOpcodeError   = 5
+	tftpMaxPacketSize = 516
+)
+
+// LocationProbeTarget is a download location to probe, Address is the ip of a tftp location or the url of an http one
+type LocationProbeTarget struct {
+	ID       string
+	Protocol string
+	Address  string
+}
+
+// GetLocationProbeTargets returns the locations of the filter values, each location once
+func GetLocationProbeTargets(filterValues []*coreef.DownloadLocationRoundRobinFilterValue) []LocationProbeTarget {
+	targets := []LocationProbeTarget{}
+	seen := util.Set{}
+	add := func(target LocationProbeTarget) {
+		if target.ID != "" && !seen.Contains(target.ID) {
+			seen.Add(target.ID)
+			targets = append(targets, target)
+		}
+	}
+	for _, filterValue := range filterValues {
+		for _, location := range append(append([]coreef.Location{}, filterValue.Locations...), filterValue.Ipv6locations...) {
+			add(LocationProbeTarget{ID: location.LocationIp, Protocol: "tftp", Address: location.LocationIp})
+		}
+		for _, location := range []string{filterValue.HttpFullUrlLocation, filterValue.HttpLocation} {
+			if location == "" {
+				continue
+			}
+			if !strings.Contains(location, "://") {
+				location = "http://" + location
+			}
+			add(LocationProbeTarget{ID: getHttpLocationHost(location), Protocol: "http", Address: location})
+		}
+	}
+	return targets
+}
+
+// LocationProber probes the locations of the round robin filters every interval. A location is healthy when
+// its http location answers with a status below 500, or its tftp server answers a read request of the probe
+// file with data or an error. Results expire after three intervals, so they are not used when probing stops
+type LocationProber struct {
+	interval      time.Duration
+	timeout       time.Duration
+	tftpProbeFile string
+	probe         func(target LocationProbeTarget) error
+	mutex         sync.RWMutex
+	health        map[string]*coreef.LocationHealth
+	stopped       chan bool
+}
+
+func NewLocationProber(interval time.Duration, timeout time.Duration, tftpProbeFile string) *LocationProber {
+	p := &LocationProber{
+		interval:      interval,
+		timeout:       timeout,
+		tftpProbeFile: tftpProbeFile,
+		health:        map[string]*coreef.LocationHealth{},
+		stopped:       make(chan bool),
+	}
+	p.probe = p.probeLocation
+	return p
+}
+
+func (p *LocationProber) GetLocationHealth(location string) *coreef.LocationHealth {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.health[location]
+}
+
+// GetAll returns the last probe of each location sorted by location
+func (p *LocationProber) GetAll() []*coreef.LocationHealth {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	healths := make([]*coreef.LocationHealth, 0, len(p.health))
+	for _, health := range p.health {
+		healths = append(healths, health)
+	}
+	sort.Slice(healths, func(i, j int) bool {
+		return healths[i].ID < healths[j].ID
+	})
+	return healths
+}
+
+// ProbeAll probes the targets concurrently and keeps their health
+func (p *LocationProber) ProbeAll(targets []LocationProbeTarget) {
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target LocationProbeTarget) {
+			defer wg.Done()
+			p.setHealth(target, p.probe(target))
+		}(target)
+	}
+	wg.Wait()
+}
+
+func (p *LocationProber) setHealth(target LocationProbeTarget, err error) {
+	now := time.Now()
+	health := &coreef.LocationHealth{
+		ID:      target.ID,
+		Healthy: err == nil,
+		Source:  coreef.LocationHealthSourceProbe,
+		Updated: util.GetTimestamp(now),
+		Expires: util.GetTimestamp(now.Add(3 * p.interval)),
+	}
+	if err != nil {
+		health.Reason = err.Error()
+	}
+
+	p.mutex.Lock()
+	previous := p.health[target.ID]
+	p.health[target.ID] = health
+	p.mutex.Unlock()
+
+	if !health.Healthy && (previous == nil || previous.Healthy) {
+		log.Warnf("LocationProber %s location %s is unhealthy: %s", target.Protocol, target.Address, health.Reason)
+	} else if health.Healthy && previous != nil && !previous.Healthy {
+		log.Infof("LocationProber %s location %s is healthy again", target.Protocol, target.Address)
+	}
+}
+
+func (p *LocationProber) probeLocation(target LocationProbeTarget) error {
+	if target.Protocol == "http" {
+		return ProbeHttpLocation(target.Address, p.timeout)
+	}
+	return ProbeTftpLocation(target.Address, p.tftpProbeFile, p.timeout)
+}
+
+// Run probes the locations of the round robin filters every interval until Stop is called
+func (p *LocationProber) Run() {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			filterValues, err := coreef.GetDownloadLocationRoundRobinFilterValAllDB()
+			if err != nil {
+				log.Errorf("LocationProber failed to get the round robin filters: %v", err)
+			} else {
+				p.ProbeAll(GetLocationProbeTargets(filterValues))
+			}
+			select {
+			case <-p.stopped:
+				log.Debug("stopping location prober")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (p *LocationProber) Stop() {
+	p.stopped <- true
+}
+
+// ProbeHttpLocation returns an error when the location does not answer a HEAD request or answers with a server error
+func ProbeHttpLocation(location string, timeout time.Duration) error {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Head(location)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ProbeTftpLocation sends a read request of file to the tftp server at address, an ip with an optional port, and
+// returns an error when it does not answer. The server answers from another port, so the reply is read from any
+// port of the server's ip
+func ProbeTftpLocation(address string, file string, timeout time.Duration) error {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, TFTP_PORT)
+	}
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	var request bytes.Buffer
+	request.Write([]byte{0, tftpOpcodeRead})
+	request.WriteString(file)
+	request.WriteByte(0)
+	request.WriteString("octet")
+	request.WriteByte(0)
+	if _, err := conn.WriteToUDP(request.Bytes(), addr); err != nil {
+		return err
+	}
+
+	reply := make([]byte, tftpMaxPacketSize)
+	for {
+		n, from, err := conn.ReadFromUDP(reply)
+		if err != nil {
+			return err
+		}
+		if !from.IP.Equal(addr.IP) || n < 4 {
+			continue
+		}
+		switch reply[1] {
+		case tftpOpcodeData:
+			// end the transfer, the probe only needs the answer
+			conn.WriteToUDP([]byte{0, tftpOpcodeError, 0, 0, 0}, from)
+			return nil
+		case tftpOpcodeError:
+			return nil
+		}
+		return fmt.Errorf("unexpected tftp opcode %d", reply[1])
+	}
+}
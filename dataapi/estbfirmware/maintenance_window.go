@@ -0,0 +1,137 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
+	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/shared/firmware"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MAINTENANCE_WINDOW_FILTER is the bypass filter name that turns the maintenance window off for a request
+const MAINTENANCE_WINDOW_FILTER = "MAINTENANCE_WINDOW_FILTER"
+
+var defaultMaintenanceWindow *MaintenanceWindowEnforcer
+
+// SetDefaultMaintenanceWindowEnforcer sets the MaintenanceWindowEnforcer of new rule bases, nil turns it off
+func SetDefaultMaintenanceWindowEnforcer(enforcer *MaintenanceWindowEnforcer) {
+	defaultMaintenanceWindow = enforcer
+}
+
+// MaintenanceWindowDecision records that the reboot of a device was moved to the maintenance window of its policy.
+// The times are in the local time of the device
+type MaintenanceWindowDecision struct {
+	PolicyId     string   `json:"policyId"`
+	PolicyName   string   `json:"policyName,omitempty"`
+	Windows      []string `json:"windows"`
+	LocalTime    string   `json:"localTime"`
+	WindowStart  string   `json:"windowStart,omitempty"`
+	UpgradeDelay int64    `json:"upgradeDelay,omitempty"` // seconds
+}
+
+func (d *MaintenanceWindowDecision) String() string {
+	s := fmt.Sprintf("MaintenanceWindowPolicy %s: device local time %s is outside %s, rebootImmediately is false", d.PolicyId, d.LocalTime, strings.Join(d.Windows, "; "))
+	if d.WindowStart != "" {
+		s += fmt.Sprintf(", next window starts %s", d.WindowStart)
+	}
+	if d.UpgradeDelay > 0 {
+		s += fmt.Sprintf(", upgradeDelay %ds", d.UpgradeDelay)
+	}
+	return s
+}
+
+// MaintenanceWindowEnforcer keeps devices from rebooting immediately outside the maintenance window of their
+// model and partner policy
+type MaintenanceWindowEnforcer struct {
+	getPolicies func() ([]*coreef.MaintenanceWindowPolicy, error)
+	now         func() time.Time
+}
+
+func NewMaintenanceWindowEnforcer(getPolicies func() ([]*coreef.MaintenanceWindowPolicy, error)) *MaintenanceWindowEnforcer {
+	return &MaintenanceWindowEnforcer{
+		getPolicies: getPolicies,
+		now:         time.Now,
+	}
+}
+
+// Apply sets rebootImmediately to false when the device is outside the windows of its policy, adds the start of the
+// next window and, for the delay action, an upgradeDelay up to it. Forced reboots and responses that do not reboot
+// immediately are left alone. It returns the decision, nil when the config was not changed
+func (m *MaintenanceWindowEnforcer) Apply(ctx map[string]string, context *coreef.ConvertedContext, config *coreef.FirmwareConfigFacade, fields log.Fields) *MaintenanceWindowDecision {
+	if config == nil || !config.GetRebootImmediately() {
+		return nil
+	}
+	if _, ok := context.GetForceFiltersConverted()[firmware.REBOOT_IMMEDIATELY_FILTER]; ok {
+		return nil
+	}
+	if _, ok := context.GetBypassFiltersConverted()[MAINTENANCE_WINDOW_FILTER]; ok {
+		return nil
+	}
+	policies, err := m.getPolicies()
+	if err != nil {
+		log.WithFields(fields).Errorf("Failed to get the maintenance window policies: %v", err)
+		return nil
+	}
+	policy := coreef.FindMaintenanceWindowPolicy(policies, context.GetModelConverted(), ctx[common.PARTNER_ID])
+	if policy == nil {
+		return nil
+	}
+	now := m.now().In(maintenanceWindowLocation(ctx))
+	if re.InSchedules(policy.Windows, now) {
+		return nil
+	}
+
+	config.SetRebootImmediately(false)
+	decision := &MaintenanceWindowDecision{
+		PolicyId:   policy.ID,
+		PolicyName: policy.Name,
+		Windows:    policy.Windows,
+		LocalTime:  now.Format(time.RFC3339),
+	}
+	if start, ok := re.NextScheduleStart(policy.Windows, now); ok {
+		decision.WindowStart = start.Format(time.RFC3339)
+		config.SetStringValue(common.MAINTENANCE_WINDOW_START, decision.WindowStart)
+		if policy.IsDelay() {
+			decision.UpgradeDelay = int64(math.Ceil(start.Sub(now).Seconds()))
+			// a longer delay of the config already keeps the device out of the window
+			if delay, ok := config.GetValue(common.UPGRADE_DELAY).(int64); ok && delay > decision.UpgradeDelay {
+				decision.UpgradeDelay = delay
+			}
+			config.Properties[common.UPGRADE_DELAY] = decision.UpgradeDelay
+		}
+	}
+	log.WithFields(fields).Infof("Maintenance window: %s", decision)
+	return decision
+}
+
+// maintenanceWindowLocation prefers the timeZoneOffset, read with the parser of the context time so an unsigned
+// offset is east of UTC here too. The context conversion replaces the timezone with a zone of its own
+func maintenanceWindowLocation(ctx map[string]string) *time.Location {
+	if location, ok := re.ParseTimeZoneOffset(ctx[common.TIME_ZONE_OFFSET]); ok {
+		return location
+	}
+	return re.DeviceLocation(ctx)
+}
@@ -0,0 +1,173 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/shared/firmware"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMaintenanceWindowTestEnforcer(policies ...*coreef.MaintenanceWindowPolicy) *MaintenanceWindowEnforcer {
+	enforcer := NewMaintenanceWindowEnforcer(func() ([]*coreef.MaintenanceWindowPolicy, error) {
+		return policies, nil
+	})
+	// 20:30 in the evening at UTC-05:00
+	enforcer.now = func() time.Time {
+		return time.Date(2026, 10, 17, 1, 30, 0, 0, time.UTC)
+	}
+	return enforcer
+}
+
+func newMaintenanceWindowTestRequest(rebootImmediately bool) (map[string]string, *coreef.ConvertedContext, *coreef.FirmwareConfigFacade) {
+	ctx := map[string]string{
+		common.ESTB_MAC:         "AA:BB:CC:DD:EE:FF",
+		common.MODEL:            "X1",
+		common.PARTNER_ID:       "comcast",
+		common.TIME_ZONE_OFFSET: "-05:00",
+	}
+	config := coreef.NewFirmwareConfigFacadeEmptyProperties()
+	config.SetRebootImmediately(rebootImmediately)
+	return ctx, coreef.GetContextConverted(ctx), config
+}
+
+func TestMaintenanceWindowEnforcerDelay(t *testing.T) {
+	enforcer := newMaintenanceWindowTestEnforcer(&coreef.MaintenanceWindowPolicy{ID: "night", Model: "X1", Windows: []string{"Daily 01:00-05:00"}})
+	ctx, context, config := newMaintenanceWindowTestRequest(true)
+
+	decision := enforcer.Apply(ctx, context, config, log.Fields{})
+	assert.NotNil(t, decision)
+	assert.False(t, config.GetRebootImmediately())
+	assert.Equal(t, "2026-10-16T20:30:00-05:00", decision.LocalTime)
+	assert.Equal(t, "2026-10-17T01:00:00-05:00", decision.WindowStart)
+	assert.Equal(t, decision.WindowStart, config.GetStringValue(common.MAINTENANCE_WINDOW_START))
+	assert.Equal(t, int64(4*3600+30*60), decision.UpgradeDelay)
+	assert.Equal(t, int64(4*3600+30*60), config.GetValue(common.UPGRADE_DELAY))
+	assert.Equal(t, "MaintenanceWindowPolicy night: device local time 2026-10-16T20:30:00-05:00 is outside Daily 01:00-05:00, rebootImmediately is false, next window starts 2026-10-17T01:00:00-05:00, upgradeDelay 16200s", decision.String())
+
+	// a longer delay of the config is kept
+	ctx, context, config = newMaintenanceWindowTestRequest(true)
+	config.Properties[common.UPGRADE_DELAY] = int64(86400)
+	decision = enforcer.Apply(ctx, context, config, log.Fields{})
+	assert.Equal(t, int64(86400), decision.UpgradeDelay)
+	assert.Equal(t, int64(86400), config.GetValue(common.UPGRADE_DELAY))
+}
+
+func TestMaintenanceWindowEnforcerUnsignedOffset(t *testing.T) {
+	enforcer := newMaintenanceWindowTestEnforcer(&coreef.MaintenanceWindowPolicy{ID: "night", Model: "X1", Windows: []string{"Daily 01:00-05:00"}})
+
+	// an unsigned offset is east of UTC, as in the local time of the firmware context
+	for _, offset := range []string{"05:00", "+05:00"} {
+		ctx, context, config := newMaintenanceWindowTestRequest(true)
+		ctx[common.TIME_ZONE_OFFSET] = offset
+		decision := enforcer.Apply(ctx, context, config, log.Fields{})
+		assert.NotNil(t, decision, offset)
+		assert.Equal(t, "2026-10-17T06:30:00+05:00", decision.LocalTime, offset)
+		assert.Equal(t, "2026-10-18T01:00:00+05:00", decision.WindowStart, offset)
+		assert.Equal(t, int64(18*3600+30*60), decision.UpgradeDelay, offset)
+	}
+}
+
+func TestMaintenanceWindowEnforcerConfigChangeLog(t *testing.T) {
+	enforcer := newMaintenanceWindowTestEnforcer(&coreef.MaintenanceWindowPolicy{ID: "night", Model: "X1", Windows: []string{"Daily 01:00-05:00"}})
+	ctx, context, config := newMaintenanceWindowTestRequest(true)
+	config.Properties[common.FIRMWARE_VERSION] = "2.0"
+	config.Properties[common.FIRMWARE_LOCATION] = "http://fw.example.com"
+	config.SetDeltaImage("1.0", &coreef.DeltaImage{Filename: "delta.bin", Sha256: "abc", Size: 2048})
+	assert.NotNil(t, enforcer.Apply(ctx, context, config, log.Fields{}))
+
+	// the config is written to the change logs and read back from them
+	data, err := json.Marshal(&coreef.ConfigChangeLog{ID: "0", FirmwareConfig: config})
+	assert.Nil(t, err)
+	var changeLog coreef.ConfigChangeLog
+	assert.Nil(t, json.Unmarshal(data, &changeLog))
+
+	properties := changeLog.FirmwareConfig.Properties
+	assert.Equal(t, int64(4*3600+30*60), properties[common.UPGRADE_DELAY])
+	assert.Equal(t, "2026-10-17T01:00:00-05:00", properties[common.MAINTENANCE_WINDOW_START])
+	assert.Equal(t, "1.0", properties[common.DELTA_FROM_VERSION])
+	assert.Equal(t, "delta.bin", properties[common.DELTA_FIRMWARE_FILENAME])
+	assert.Equal(t, "http://fw.example.com", properties[common.DELTA_FIRMWARE_LOCATION])
+	assert.Equal(t, "abc", properties[common.DELTA_FIRMWARE_SHA256])
+	assert.Equal(t, int64(2048), properties[common.DELTA_FIRMWARE_SIZE])
+	assert.Equal(t, false, properties[common.REBOOT_IMMEDIATELY])
+}
+
+func TestMaintenanceWindowEnforcerSuggest(t *testing.T) {
+	enforcer := newMaintenanceWindowTestEnforcer(&coreef.MaintenanceWindowPolicy{ID: "weekend", PartnerId: "COMCAST", Windows: []string{"Sun 02:00-04:00"}, Action: coreef.MaintenanceWindowActionSuggest})
+	ctx, context, config := newMaintenanceWindowTestRequest(true)
+
+	decision := enforcer.Apply(ctx, context, config, log.Fields{})
+	assert.NotNil(t, decision)
+	assert.False(t, config.GetRebootImmediately())
+	assert.Equal(t, "2026-10-18T02:00:00-05:00", decision.WindowStart)
+	assert.Equal(t, int64(0), decision.UpgradeDelay)
+	assert.Nil(t, config.GetValue(common.UPGRADE_DELAY))
+}
+
+func TestMaintenanceWindowEnforcerKeepsReboot(t *testing.T) {
+	policy := &coreef.MaintenanceWindowPolicy{ID: "evening", Model: "X1", Windows: []string{"Daily 20:00-22:00"}}
+	enforcer := newMaintenanceWindowTestEnforcer(policy)
+
+	// inside the window
+	ctx, context, config := newMaintenanceWindowTestRequest(true)
+	assert.Nil(t, enforcer.Apply(ctx, context, config, log.Fields{}))
+	assert.True(t, config.GetRebootImmediately())
+
+	policy.Windows = []string{"Daily 01:00-05:00"}
+
+	// no immediate reboot to defer
+	ctx, context, config = newMaintenanceWindowTestRequest(false)
+	assert.Nil(t, enforcer.Apply(ctx, context, config, log.Fields{}))
+	assert.Nil(t, config.GetValue(common.MAINTENANCE_WINDOW_START))
+
+	// forced reboots and bypassed windows
+	ctx, context, config = newMaintenanceWindowTestRequest(true)
+	context.AddForceFiltersConverted(firmware.REBOOT_IMMEDIATELY_FILTER)
+	assert.Nil(t, enforcer.Apply(ctx, context, config, log.Fields{}))
+	ctx, context, config = newMaintenanceWindowTestRequest(true)
+	context.AddBypassFiltersConverted(MAINTENANCE_WINDOW_FILTER)
+	assert.Nil(t, enforcer.Apply(ctx, context, config, log.Fields{}))
+	assert.True(t, config.GetRebootImmediately())
+
+	// no policy for the device, or no policies
+	ctx, context, config = newMaintenanceWindowTestRequest(true)
+	ctx[common.MODEL] = "X2"
+	context = coreef.GetContextConverted(ctx)
+	assert.Nil(t, enforcer.Apply(ctx, context, config, log.Fields{}))
+	enforcer.getPolicies = func() ([]*coreef.MaintenanceWindowPolicy, error) {
+		return nil, errors.New("no policies")
+	}
+	ctx, context, config = newMaintenanceWindowTestRequest(true)
+	assert.Nil(t, enforcer.Apply(ctx, context, config, log.Fields{}))
+	assert.True(t, config.GetRebootImmediately())
+}
+
+func TestEstbFirmwareRuleBaseIsDriState(t *testing.T) {
+	ruleBase := NewEstbFirmwareRuleBaseDefault()
+	assert.True(t, ruleBase.isDriState(map[string]string{common.FIRMWARE_VERSION: "X1_4.0p1s1_PROD_p-dri"}))
+	assert.False(t, ruleBase.isDriState(map[string]string{common.FIRMWARE_VERSION: "X1_4.0p1s1_PROD_sey"}))
+	assert.False(t, ruleBase.isDriState(map[string]string{}))
+}
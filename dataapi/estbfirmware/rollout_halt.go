@@ -0,0 +1,251 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	corefw "github.com/rdkcentral/xconfwebconfig/shared/firmware"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ROLLOUT_HALT is the AppliedVersionInfo key of the reason a firmware version was not offered
+const ROLLOUT_HALT = "rolloutHalt"
+
+const (
+	ROLLOUT_HALT_FREEZE   = "freeze"
+	ROLLOUT_HALT_ROLLBACK = "rollback"
+)
+
+var defaultRolloutGuard *RolloutGuard
+
+// SetDefaultRolloutGuard sets the RolloutGuard of new rule bases, nil turns rollout halts off
+func SetDefaultRolloutGuard(guard *RolloutGuard) {
+	defaultRolloutGuard = guard
+}
+
+// RolloutHaltSettings halt the offers of a firmware version to the devices of a model and partner once
+// MinReports of them reported an outcome and FailurePercentage of those failed. Action freeze stops
+// the offers, rollback offers the last known good config of the rule instead. Only a percentage rollout
+// has a last known good config apart from the config it offers, the rules with one config are frozen
+type RolloutHaltSettings struct {
+	FailurePercentage float64
+	MinReports        int
+	Action            string
+	CacheTtl          time.Duration
+}
+
+func (s *RolloutHaltSettings) IsEnabled() bool {
+	return s != nil && s.FailurePercentage > 0 && s.MinReports > 0
+}
+
+// RolloutHalt records why a firmware version is not offered to a model and partner
+type RolloutHalt struct {
+	Model             string  `json:"model"`
+	PartnerId         string  `json:"partnerId,omitempty"`
+	FirmwareVersion   string  `json:"firmwareVersion"`
+	ConfigId          string  `json:"configId,omitempty"`
+	Reports           int     `json:"reports"`
+	Failures          int     `json:"failures"`
+	FailurePercentage float64 `json:"failurePercentage"`
+	Threshold         float64 `json:"threshold"`
+	Action            string  `json:"action"`
+	RollbackConfigId  string  `json:"rollbackConfigId,omitempty"`
+	RollbackRejected  string  `json:"rollbackRejected,omitempty"`
+}
+
+func (h *RolloutHalt) String() string {
+	partner := h.PartnerId
+	if partner == "" {
+		partner = "none"
+	}
+	action := "frozen"
+	if h.RollbackConfigId != "" {
+		action = fmt.Sprintf("rolled back to config %s", h.RollbackConfigId)
+	} else if h.RollbackRejected != "" {
+		action = fmt.Sprintf("frozen (no rollback, %s)", h.RollbackRejected)
+	}
+	return fmt.Sprintf("firmware %s %s for model %s partner %s: %d of %d devices failed (%.2f%% >= %.2f%%)",
+		h.FirmwareVersion, action, h.Model, partner, h.Failures, h.Reports, h.FailurePercentage, h.Threshold)
+}
+
+// GetRolloutHalt returns the halt of the summary's version for a partner, nil when it may still be offered
+func (s *RolloutHaltSettings) GetRolloutHalt(summary *coreef.FirmwareOutcomeSummary, partnerId string) *RolloutHalt {
+	if !s.IsEnabled() || summary == nil {
+		return nil
+	}
+	count := summary.GetPartnerCount(partnerId)
+	if count.Reports < s.MinReports || count.FailurePercentage() < s.FailurePercentage {
+		return nil
+	}
+	return &RolloutHalt{
+		Model:             summary.Model,
+		PartnerId:         strings.ToUpper(partnerId),
+		FirmwareVersion:   summary.FirmwareVersion,
+		Reports:           count.Reports,
+		Failures:          count.Failures,
+		FailurePercentage: count.FailurePercentage(),
+		Threshold:         s.FailurePercentage,
+		Action:            s.Action,
+	}
+}
+
+// GetRolloutHalts returns the halts of every partner of the summary sorted by partner
+func (s *RolloutHaltSettings) GetRolloutHalts(summary *coreef.FirmwareOutcomeSummary) []*RolloutHalt {
+	halts := []*RolloutHalt{}
+	for partnerId := range summary.Partners {
+		if halt := s.GetRolloutHalt(summary, partnerId); halt != nil {
+			halts = append(halts, halt)
+		}
+	}
+	sort.Slice(halts, func(i, j int) bool {
+		return halts[i].PartnerId < halts[j].PartnerId
+	})
+	return halts
+}
+
+type cachedOutcomeSummary struct {
+	summary    *coreef.FirmwareOutcomeSummary
+	expires    time.Time
+	refreshing bool
+}
+
+// RolloutGuard checks the reported outcomes of the firmware versions being offered. The outcome summaries
+// are read in the background and kept for CacheTtl, so a version is halted about CacheTtl after its
+// failures cross the threshold and the requests never wait for the outcomes to be read
+type RolloutGuard struct {
+	settings   *RolloutHaltSettings
+	getSummary func(model string, firmwareVersion string) (*coreef.FirmwareOutcomeSummary, error)
+	refresh    func(read func())
+	mutex      sync.Mutex
+	summaries  map[string]*cachedOutcomeSummary
+}
+
+func NewRolloutGuard(settings *RolloutHaltSettings) *RolloutGuard {
+	return &RolloutGuard{
+		settings:   settings,
+		getSummary: coreef.GetFirmwareOutcomeSummaryDB,
+		refresh: func(read func()) {
+			go read()
+		},
+		summaries: map[string]*cachedOutcomeSummary{},
+	}
+}
+
+func (g *RolloutGuard) GetSettings() *RolloutHaltSettings {
+	return g.settings
+}
+
+// Check returns the halt of a firmware version for a model and partner, nil when it may be offered.
+// Versions whose outcomes cannot be read are not halted
+func (g *RolloutGuard) Check(model string, partnerId string, firmwareVersion string) *RolloutHalt {
+	if !g.settings.IsEnabled() || model == "" || firmwareVersion == "" {
+		return nil
+	}
+	return g.settings.GetRolloutHalt(g.getCachedSummary(strings.ToUpper(model), firmwareVersion), partnerId)
+}
+
+// getCachedSummary returns the cached summary of a version and starts reading it again once it expired.
+// Until the first read is done the version is not halted
+func (g *RolloutGuard) getCachedSummary(model string, firmwareVersion string) *coreef.FirmwareOutcomeSummary {
+	key := model + "|" + firmwareVersion
+	g.mutex.Lock()
+	cached, ok := g.summaries[key]
+	if !ok {
+		cached = &cachedOutcomeSummary{}
+		g.summaries[key] = cached
+	}
+	expired := !cached.refreshing && !time.Now().Before(cached.expires)
+	cached.refreshing = cached.refreshing || expired
+	g.mutex.Unlock()
+
+	if expired {
+		g.refresh(func() {
+			g.readSummary(cached, model, firmwareVersion)
+		})
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return cached.summary
+}
+
+func (g *RolloutGuard) readSummary(cached *cachedOutcomeSummary, model string, firmwareVersion string) {
+	summary, err := g.getSummary(model, firmwareVersion)
+	if err != nil {
+		log.Debugf("RolloutGuard has no outcomes of firmware %s for model %s: %v", firmwareVersion, model, err)
+		summary = nil
+	}
+	g.mutex.Lock()
+	cached.summary = summary
+	cached.expires = time.Now().Add(g.settings.CacheTtl)
+	cached.refreshing = false
+	g.mutex.Unlock()
+}
+
+// HaltRollout returns the config to offer instead of a config whose version is halted for the device's model
+// and partner, nil when the version is frozen. With the rollback action the last known good config of the
+// rule is offered, also to the devices already running the halted version. The version is frozen when the
+// rule has no other config to roll back to or it is halted too, RollbackRejected says why
+func (e *EstbFirmwareRuleBase) HaltRollout(context *coreef.ConvertedContext, firmwareRule *corefw.FirmwareRule, config *coreef.FirmwareConfig, result *EvaluationResult, fields log.Fields) *coreef.FirmwareConfig {
+	if e.rolloutGuard == nil || config == nil {
+		return config
+	}
+	model, partnerId := context.GetModelConverted(), context.GetPartnerId()
+	halt := e.rolloutGuard.Check(model, partnerId, config.FirmwareVersion)
+	if halt == nil {
+		return config
+	}
+	halt.ConfigId = config.ID
+
+	var rollbackConfig *coreef.FirmwareConfig
+	if halt.Action == ROLLOUT_HALT_ROLLBACK {
+		rollbackConfig = getRollbackConfig(firmwareRule, config)
+		if rollbackConfig == nil {
+			halt.RollbackRejected = fmt.Sprintf("rule %s has no last known good config other than %s", firmwareRule.ID, config.ID)
+		} else if e.rolloutGuard.Check(model, partnerId, rollbackConfig.FirmwareVersion) != nil {
+			halt.RollbackRejected = fmt.Sprintf("last known good config %s is halted too", rollbackConfig.ID)
+			rollbackConfig = nil
+		} else {
+			halt.RollbackConfigId = rollbackConfig.ID
+		}
+	}
+	result.RolloutHalt = halt
+	result.AppliedVersionInfo[ROLLOUT_HALT] = halt.String()
+	log.WithFields(fields).Infof("EstbFirmwareRuleBase %s", halt)
+	return rollbackConfig
+}
+
+// getRollbackConfig returns the last known good config of a percentage rollout, the config of the rule action
+// when it is not the halted config and has another version. A rule with one config keeps no previous one
+func getRollbackConfig(firmwareRule *corefw.FirmwareRule, config *coreef.FirmwareConfig) *coreef.FirmwareConfig {
+	ruleAction := firmwareRule.ApplicableAction
+	if ruleAction == nil || ruleAction.ConfigId == "" || ruleAction.ConfigId == config.ID {
+		return nil
+	}
+	rollbackConfig, err := coreef.GetFirmwareConfigOneDB(ruleAction.ConfigId)
+	if err != nil || !strings.EqualFold(rollbackConfig.ApplicationType, config.ApplicationType) || strings.EqualFold(rollbackConfig.FirmwareVersion, config.FirmwareVersion) {
+		return nil
+	}
+	return rollbackConfig
+}
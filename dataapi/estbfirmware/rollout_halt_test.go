@@ -0,0 +1,158 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	corefw "github.com/rdkcentral/xconfwebconfig/shared/firmware"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func rolloutHaltTestSummary(model string, firmwareVersion string) *coreef.FirmwareOutcomeSummary {
+	return &coreef.FirmwareOutcomeSummary{
+		Model:           model,
+		FirmwareVersion: firmwareVersion,
+		Partners: map[string]*coreef.FirmwareOutcomeCount{
+			"COMCAST": {Reports: 10, Failures: 3},
+			"COX":     {Reports: 10, Failures: 1},
+			"SKY":     {Reports: 2, Failures: 2},
+		},
+	}
+}
+
+func newRolloutHaltTestGuard(action string) (*RolloutGuard, *int) {
+	calls := 0
+	guard := NewRolloutGuard(&RolloutHaltSettings{FailurePercentage: 20, MinReports: 5, Action: action, CacheTtl: time.Minute})
+	guard.getSummary = func(model string, firmwareVersion string) (*coreef.FirmwareOutcomeSummary, error) {
+		calls++
+		if firmwareVersion != "2.0" {
+			return nil, errors.New("no outcomes")
+		}
+		return rolloutHaltTestSummary(model, firmwareVersion), nil
+	}
+	// the summaries are read before the check returns
+	guard.refresh = func(read func()) {
+		read()
+	}
+	return guard, &calls
+}
+
+func TestRolloutHaltSettingsGetRolloutHalt(t *testing.T) {
+	settings := &RolloutHaltSettings{FailurePercentage: 20, MinReports: 5, Action: ROLLOUT_HALT_FREEZE}
+	summary := rolloutHaltTestSummary("X1", "2.0")
+
+	halt := settings.GetRolloutHalt(summary, "comcast")
+	assert.NotNil(t, halt)
+	assert.Equal(t, "COMCAST", halt.PartnerId)
+	assert.Equal(t, 3, halt.Failures)
+	assert.Equal(t, 30.0, halt.FailurePercentage)
+	assert.Equal(t, "firmware 2.0 frozen for model X1 partner COMCAST: 3 of 10 devices failed (30.00% >= 20.00%)", halt.String())
+
+	// below the threshold, or too few reports
+	assert.Nil(t, settings.GetRolloutHalt(summary, "cox"))
+	assert.Nil(t, settings.GetRolloutHalt(summary, "sky"))
+	assert.Nil(t, settings.GetRolloutHalt(summary, "charter"))
+	assert.Nil(t, (&RolloutHaltSettings{}).GetRolloutHalt(summary, "comcast"))
+
+	halts := settings.GetRolloutHalts(summary)
+	assert.Equal(t, 1, len(halts))
+	assert.Equal(t, "COMCAST", halts[0].PartnerId)
+}
+
+func TestRolloutGuardCachesSummaries(t *testing.T) {
+	guard, calls := newRolloutHaltTestGuard(ROLLOUT_HALT_FREEZE)
+
+	assert.NotNil(t, guard.Check("x1", "comcast", "2.0"))
+	assert.Nil(t, guard.Check("X1", "cox", "2.0"))
+	assert.Equal(t, 1, *calls)
+
+	// versions without outcomes are not halted, and not read again until the cache expires
+	assert.Nil(t, guard.Check("X1", "comcast", "3.0"))
+	assert.Nil(t, guard.Check("X1", "comcast", "3.0"))
+	assert.Equal(t, 2, *calls)
+
+	// without a ttl every check reads the outcomes
+	guard.settings.CacheTtl = 0
+	assert.Nil(t, guard.Check("X1", "comcast", "4.0"))
+	assert.Nil(t, guard.Check("X1", "comcast", "4.0"))
+	assert.Equal(t, 4, *calls)
+}
+
+func TestRolloutGuardReadsSummariesInTheBackground(t *testing.T) {
+	read := make(chan bool)
+	guard := NewRolloutGuard(&RolloutHaltSettings{FailurePercentage: 20, MinReports: 5, Action: ROLLOUT_HALT_FREEZE, CacheTtl: time.Minute})
+	guard.getSummary = func(model string, firmwareVersion string) (*coreef.FirmwareOutcomeSummary, error) {
+		<-read
+		return rolloutHaltTestSummary(model, firmwareVersion), nil
+	}
+
+	// the check does not wait for the summary, and reads it once
+	assert.Nil(t, guard.Check("X1", "comcast", "2.0"))
+	assert.Nil(t, guard.Check("X1", "comcast", "2.0"))
+	read <- true
+	assert.Eventually(t, func() bool {
+		return guard.Check("X1", "comcast", "2.0") != nil
+	}, time.Second, time.Millisecond)
+}
+
+func TestHaltRollout(t *testing.T) {
+	context := coreef.GetContextConverted(map[string]string{
+		common.ESTB_MAC:   "AA:BB:CC:DD:EE:FF",
+		common.MODEL:      "X1",
+		common.PARTNER_ID: "comcast",
+	})
+	rule := &corefw.FirmwareRule{
+		ID:               "envModelRule",
+		Type:             corefw.ENV_MODEL_RULE,
+		ApplicableAction: &corefw.ApplicableAction{ConfigId: "config2"},
+	}
+	config := &coreef.FirmwareConfig{ID: "config2", FirmwareVersion: "2.0", ApplicationType: "stb"}
+
+	ruleBase := NewEstbFirmwareRuleBaseDefault()
+	result := NewEvaluationResult()
+	assert.Equal(t, config, ruleBase.HaltRollout(context, rule, config, result, log.Fields{}))
+	assert.Nil(t, result.RolloutHalt)
+
+	guard, _ := newRolloutHaltTestGuard(ROLLOUT_HALT_FREEZE)
+	ruleBase.SetRolloutGuard(guard)
+	assert.Nil(t, ruleBase.HaltRollout(context, rule, config, result, log.Fields{}))
+	assert.Equal(t, "config2", result.RolloutHalt.ConfigId)
+	assert.Equal(t, result.RolloutHalt.String(), result.AppliedVersionInfo[ROLLOUT_HALT])
+
+	// the halted config is the last known good one, there is nothing to roll back to
+	guard, _ = newRolloutHaltTestGuard(ROLLOUT_HALT_ROLLBACK)
+	ruleBase.SetRolloutGuard(guard)
+	result = NewEvaluationResult()
+	assert.Nil(t, ruleBase.HaltRollout(context, rule, config, result, log.Fields{}))
+	assert.Equal(t, ROLLOUT_HALT_ROLLBACK, result.RolloutHalt.Action)
+	assert.Equal(t, "", result.RolloutHalt.RollbackConfigId)
+	assert.Equal(t, "rule envModelRule has no last known good config other than config2", result.RolloutHalt.RollbackRejected)
+	assert.Equal(t, "firmware 2.0 frozen (no rollback, rule envModelRule has no last known good config other than config2) for model X1 partner COMCAST: 3 of 10 devices failed (30.00% >= 20.00%)", result.AppliedVersionInfo[ROLLOUT_HALT])
+
+	// other partners still get the version
+	context.SetPartnerId("cox")
+	result = NewEvaluationResult()
+	assert.Equal(t, config, ruleBase.HaltRollout(context, rule, config, result, log.Fields{}))
+	assert.Nil(t, result.RolloutHalt)
+}
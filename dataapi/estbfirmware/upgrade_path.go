@@ -0,0 +1,66 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"fmt"
+	"strings"
+
+	coreef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// UPGRADE_PATH is the AppliedVersionInfo key of the planned upgrade path
+const UPGRADE_PATH = "upgradePath"
+
+// NextUpgradeHop returns the config of the next hop from the device's firmware version toward config,
+// along the upgrade graph of the device's model. It returns config when the graph does not apply and
+// nil when the graph has no path, the planned path is kept in result.UpgradePath
+func (e *EstbFirmwareRuleBase) NextUpgradeHop(context *coreef.ConvertedContext, config *coreef.FirmwareConfig, result *EvaluationResult, fields log.Fields) *coreef.FirmwareConfig {
+	graph, err := coreef.GetFirmwareUpgradeGraphOneDB(context.GetModelConverted())
+	if err != nil || graph == nil {
+		return config
+	}
+	configs := map[string]*coreef.FirmwareConfig{config.ID: config}
+	getVersion := func(configId string) (string, error) {
+		hop, ok := configs[configId]
+		if !ok {
+			hop, err = coreef.GetFirmwareConfigOneDB(configId)
+			if err != nil {
+				return "", err
+			}
+			configs[configId] = hop
+		}
+		if !strings.EqualFold(hop.ApplicationType, config.ApplicationType) {
+			return "", fmt.Errorf("config %s is not %s", configId, config.ApplicationType)
+		}
+		return hop.FirmwareVersion, nil
+	}
+	path, err := graph.PlanPath(context.GetFirmwareVersionConverted(), config.FirmwareVersion, getVersion)
+	if err != nil {
+		log.WithFields(fields).Warnf("EstbFirmwareRuleBase does not serve %s: %v", config.FirmwareVersion, err)
+		return nil
+	}
+	if path == nil {
+		return config
+	}
+	result.UpgradePath = path
+	result.AppliedVersionInfo[UPGRADE_PATH] = path.String()
+	return configs[path.NextConfigId()]
+}
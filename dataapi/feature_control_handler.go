@@ -63,6 +63,9 @@ func GetFeatureControlSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		skipPenetrationLogging = true
 	}
 
+	// precook data and 304s are skipped when tracing so the rules are always evaluated
+	tracer := NewRuleTracerForRequest(r)
+
 	configSetHash := r.Header.Get(common.CONFIG_SET_HASH)
 	fields["configsetHashDevice"] = configSetHash
 	contextMap := make(map[string]string)
@@ -93,7 +96,9 @@ func GetFeatureControlSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	tfields := common.FilterLogFields(fields)
 	ruleEvalReasons := []string{}
 	// only check values of precook flags if not in precook lockdown mode and mac is not in exclusion
-	if isPrecookLockdownMode {
+	if tracer != nil {
+		ruleEvalReasons = append(ruleEvalReasons, "trace")
+	} else if isPrecookLockdownMode {
 		log.WithFields(tfields).Debug("Currently in pre-cook lockdown mode, setting pre-cook flags to false.")
 		ruleEvalReasons = append(ruleEvalReasons, "precook-off")
 	} else {
@@ -186,6 +191,7 @@ func GetFeatureControlSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	featureControlRuleBase := featurecontrol.NewFeatureControlRuleBase()
+	featureControlRuleBase.Tracer = tracer
 
 	if isRfcPrecook304Enabled {
 		// if configsetHash from device matches precook, return 304 without running rules engine
@@ -288,7 +294,7 @@ func GetFeatureControlSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		common.CONFIG_SET_HASH: calculatedConfigSetHash,
 	}
 	// if device configsethash matches the one we calculate, return 304 with no body
-	if configSetHash != "" && calculatedConfigSetHash == configSetHash {
+	if configSetHash != "" && calculatedConfigSetHash == configSetHash && tracer == nil {
 		xhttp.IncreaseReturn304RulesEngineCounter(contextMap[common.PARTNER_ID], contextMap[common.MODEL])
 		xhttp.WriteXconfResponseWithHeaders(w, headers, http.StatusNotModified, []byte(""))
 		return
@@ -308,10 +314,18 @@ func GetFeatureControlSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.IncreaseReturnPostProcessOnTheFlyCounter(contextMap[common.PARTNER_ID], contextMap[common.MODEL])
 	}
 
-	featureControlMap := &map[string]rfc.FeatureControl{
-		"featureControl": *featureControl,
+	var response []byte
+	if tracer != nil {
+		response, _ = util.XConfJSONMarshal(util.Dict{
+			"featureControl":  *featureControl,
+			"evaluationTrace": tracer,
+		}, true)
+	} else {
+		featureControlMap := &map[string]rfc.FeatureControl{
+			"featureControl": *featureControl,
+		}
+		response, _ = util.XConfJSONMarshal(featureControlMap, true)
 	}
-	response, _ := util.XConfJSONMarshal(featureControlMap, true)
 	xhttp.WriteXconfResponseWithHeaders(w, headers, http.StatusOK, []byte(response))
 }
 
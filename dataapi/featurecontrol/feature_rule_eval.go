@@ -38,6 +38,7 @@ import (
 type FeatureControlRuleBase struct {
 	FeatureDAO           db.CachedSimpleDao
 	RuleProcessorFactory re.RuleProcessorFactory
+	Tracer               *re.RuleTracer // optional, records the evaluation of every feature rule
 }
 
 func NewFeatureControlRuleBase() *FeatureControlRuleBase {
@@ -53,6 +54,7 @@ func (f *FeatureControlRuleBase) Eval(context map[string]string, applicationType
 	if len(appliedFeatureRules) > 0 {
 		for _, featureRule := range appliedFeatureRules {
 			f.AddFeaturesToResult(featureMap, featureRule.FeatureIds)
+			re.RecordRuleApplied(featureRule)
 		}
 	}
 	featureResponseList := make([]rfc.FeatureResponse, 0)
@@ -92,10 +94,27 @@ func (f *FeatureControlRuleBase) AddFeaturesToResult(featureMap map[string]*rfc.
 }
 
 func (f *FeatureControlRuleBase) ProcessFeatureRules(context map[string]string, applicationType string) []*rfc.FeatureRule {
+	// tracing records every rule, so it always takes the linear path
+	if f.Tracer == nil {
+		if index := rfc.GetFeatureRuleIndex(f.RuleProcessorFactory.RuleProcessor()); index != nil {
+			return f.processIndexedFeatureRules(index, context, applicationType)
+		}
+	}
 	featureRules := rfc.GetSortedFeatureRules()
 	var filteredfeatureRules []*rfc.FeatureRule
 	for _, featureRule := range featureRules {
-		if applicationType == featureRule.ApplicationType && f.RuleProcessorFactory.RuleProcessor().Evaluate(featureRule.Rule, context, log.Fields{}) {
+		if applicationType == featureRule.ApplicationType && f.Tracer.Evaluate(f.RuleProcessorFactory.RuleProcessor(), featureRule, context, log.Fields{}) {
+			filteredfeatureRules = append(filteredfeatureRules, featureRule)
+		}
+	}
+	return filteredfeatureRules
+}
+
+func (f *FeatureControlRuleBase) processIndexedFeatureRules(index *re.RuleIndex, context map[string]string, applicationType string) []*rfc.FeatureRule {
+	var filteredfeatureRules []*rfc.FeatureRule
+	for _, xrule := range index.Candidates(context) {
+		featureRule := xrule.(*rfc.FeatureRule)
+		if applicationType == featureRule.ApplicationType && index.Evaluate(featureRule, context, log.Fields{}) {
 			filteredfeatureRules = append(filteredfeatureRules, featureRule)
 		}
 	}
@@ -20,6 +20,7 @@ package featurecontrol
 import (
 	"testing"
 
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
 	"github.com/rdkcentral/xconfwebconfig/shared"
 	"github.com/rdkcentral/xconfwebconfig/shared/rfc"
 
@@ -112,6 +113,28 @@ func TestGetSortedFeatureRules(t *testing.T) {
 
 }
 
+func TestGetSortedFeatureRulesSkipsInvalidPatterns(t *testing.T) {
+	getFeatureListFunc := rfc.GetFeatureListFunc
+	defer func() { rfc.GetFeatureListFunc = getFeatureListFunc }()
+
+	newFeatureRule := func(id string, priority int, text string) *rfc.FeatureRule {
+		rule, err := re.ParseRule(text)
+		assert.NilError(t, err)
+		return &rfc.FeatureRule{Id: id, Name: id, Rule: rule, Priority: priority, ApplicationType: "stb"}
+	}
+	rfc.GetFeatureListFunc = func() []*rfc.FeatureRule {
+		return []*rfc.FeatureRule{
+			newFeatureRule("regex", 3, `serialNum REGEX_IGNORE_CASE "^m1[0-9]+$"`),
+			newFeatureRule("invalid", 1, `partnerId REGEX "comcast(("`),
+			newFeatureRule("prefix", 2, `partnerId STARTS_WITH "comcast"`),
+		}
+	}
+	sortedRules := rfc.GetSortedFeatureRules()
+	assert.Equal(t, len(sortedRules), 2)
+	assert.Equal(t, sortedRules[0].Id, "prefix")
+	assert.Equal(t, sortedRules[1].Id, "regex")
+}
+
 func TestAddFeaturesToResult(t *testing.T) {
 	GetGenericNamedListOneByTypeFunc = func(string, string) (*shared.GenericNamespacedList, error) {
 		genericNamespacedList := shared.GenericNamespacedList{
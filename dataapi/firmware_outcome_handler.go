@@ -0,0 +1,158 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package dataapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	dataef "github.com/rdkcentral/xconfwebconfig/dataapi/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/db"
+	xhttp "github.com/rdkcentral/xconfwebconfig/http"
+	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// rolloutGuard halts the firmware rollouts when rollout_halt_enabled is set
+var rolloutGuard *dataef.RolloutGuard
+
+// firmwareOutcomeReport is the download or flash outcome of a firmware version reported by a device or a pipeline.
+// The model and partner default to the ones of the device's penetration metrics
+type firmwareOutcomeReport struct {
+	EstbMac         string `json:"estbMac"`
+	Model           string `json:"model,omitempty"`
+	PartnerId       string `json:"partnerId,omitempty"`
+	FirmwareVersion string `json:"firmwareVersion"`
+	Stage           string `json:"stage"`
+	Success         *bool  `json:"success"`
+	Reason          string `json:"reason,omitempty"`
+}
+
+type firmwareOutcomeResponse struct {
+	*sharedef.FirmwareOutcomeSummary
+	Halts []*dataef.RolloutHalt `json:"halts"`
+}
+
+func (r *firmwareOutcomeReport) toFirmwareOutcome(now time.Time) (*db.FirmwareOutcome, error) {
+	if _, err := util.MACAddressValidator(r.EstbMac); err != nil {
+		return nil, fmt.Errorf("invalid estbMac: %s", r.EstbMac)
+	}
+	if strings.TrimSpace(r.FirmwareVersion) == "" {
+		return nil, errors.New("firmwareVersion is required")
+	}
+	if r.Stage != sharedef.FirmwareOutcomeStageDownload && r.Stage != sharedef.FirmwareOutcomeStageFlash {
+		return nil, fmt.Errorf("stage must be %s or %s: %s", sharedef.FirmwareOutcomeStageDownload, sharedef.FirmwareOutcomeStageFlash, r.Stage)
+	}
+	if r.Success == nil {
+		return nil, errors.New("success is required")
+	}
+	outcome := &db.FirmwareOutcome{
+		EstbMac:   util.NormalizeMacAddress(r.EstbMac),
+		Model:     strings.TrimSpace(r.Model),
+		Partner:   strings.TrimSpace(r.PartnerId),
+		FwVersion: strings.TrimSpace(r.FirmwareVersion),
+		Stage:     r.Stage,
+		Success:   *r.Success,
+		Reason:    r.Reason,
+		Ts:        util.GetTimestamp(now),
+	}
+	if outcome.Model == "" || outcome.Partner == "" {
+		if metrics, err := db.GetDatabaseClient().GetFwPenetrationMetrics(outcome.EstbMac); err == nil {
+			if outcome.Model == "" {
+				outcome.Model = metrics.Model
+			}
+			if outcome.Partner == "" {
+				outcome.Partner = metrics.Partner
+			}
+		}
+	}
+	if outcome.Model == "" {
+		return nil, fmt.Errorf("model is required, it is not known for %s", outcome.EstbMac)
+	}
+	return outcome, nil
+}
+
+// GetEstbFirmwareOutcomeHandler returns the outcomes reported for the model and firmwareVersion query params,
+// by partner, and the partners the version is halted for
+func GetEstbFirmwareOutcomeHandler(w http.ResponseWriter, r *http.Request) {
+	if Xc == nil || !Xc.FirmwareOutcomeEnabled {
+		xhttp.WriteXconfResponseAsText(w, http.StatusNotFound, []byte("\"<h2>404 NOT FOUND</h2><div>firmware outcomes are disabled</div>\""))
+		return
+	}
+	model, firmwareVersion := r.URL.Query().Get(common.MODEL), r.URL.Query().Get(common.FIRMWARE_VERSION)
+	if model == "" || firmwareVersion == "" {
+		xhttp.WriteXconfResponseAsText(w, http.StatusBadRequest, []byte("\"model and firmwareVersion should be specified\""))
+		return
+	}
+	summary, err := sharedef.GetFirmwareOutcomeSummaryDB(model, firmwareVersion)
+	if err != nil {
+		xhttp.WriteXconfResponseAsText(w, http.StatusInternalServerError, []byte(fmt.Sprintf("\"<h2>500 Internal Server Error</h2><div>%s</div>\"", err.Error())))
+		return
+	}
+	response := firmwareOutcomeResponse{FirmwareOutcomeSummary: summary, Halts: []*dataef.RolloutHalt{}}
+	if rolloutGuard != nil {
+		response.Halts = rolloutGuard.GetSettings().GetRolloutHalts(summary)
+	}
+	body, _ := util.JSONMarshal(response)
+	xhttp.WriteXconfResponse(w, http.StatusOK, body)
+}
+
+// PostEstbFirmwareOutcomeHandler stores the download or flash outcome of a firmware version for a device, replacing
+// its previous outcome for the version. The firmware_outcome_token is required as a bearer token, the outcomes
+// halt rollouts, so without a token no outcome is accepted
+func PostEstbFirmwareOutcomeHandler(w http.ResponseWriter, r *http.Request) {
+	xw, ok := w.(*xhttp.XResponseWriter)
+	if !ok {
+		xhttp.Error(w, http.StatusInternalServerError, common.NotOK)
+		return
+	}
+	if Xc == nil || !Xc.FirmwareOutcomeEnabled {
+		xhttp.WriteXconfResponseAsText(w, http.StatusNotFound, []byte("\"<h2>404 NOT FOUND</h2><div>firmware outcomes are disabled</div>\""))
+		return
+	}
+	if !isBearerTokenAuthorized(r, Xc.FirmwareOutcomeToken) {
+		xhttp.WriteXconfResponseAsText(w, http.StatusUnauthorized, []byte("\"<h2>401 Unauthorized</h2>\""))
+		return
+	}
+	report := firmwareOutcomeReport{}
+	if err := json.Unmarshal([]byte(xw.Body()), &report); err != nil {
+		xhttp.WriteXconfResponseAsText(w, http.StatusBadRequest, []byte(fmt.Sprintf("\"<h2>400 Bad Request</h2><div>%s</div>\"", err.Error())))
+		return
+	}
+	outcome, err := report.toFirmwareOutcome(time.Now())
+	if err != nil {
+		xhttp.WriteXconfResponseAsText(w, http.StatusBadRequest, []byte(fmt.Sprintf("\"<h2>400 Bad Request</h2><div>%s</div>\"", err.Error())))
+		return
+	}
+	if err := sharedef.SetFirmwareOutcomeDB(outcome, int(Xc.FirmwareOutcomeTtl.Seconds())); err != nil {
+		xhttp.WriteXconfResponseAsText(w, http.StatusInternalServerError, []byte(fmt.Sprintf("\"<h2>500 Internal Server Error</h2><div>%s</div>\"", err.Error())))
+		return
+	}
+	fields := xw.Audit()
+	fields["estbMac"] = outcome.EstbMac
+	log.WithFields(fields).Infof("Firmware %s %s outcome of model %s: success=%t %s", outcome.FwVersion, outcome.Stage, outcome.Model, outcome.Success, outcome.Reason)
+	response, _ := util.JSONMarshal(outcome)
+	xhttp.WriteXconfResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,111 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package dataapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	xhttp "github.com/rdkcentral/xconfwebconfig/http"
+	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirmwareOutcomeReport(t *testing.T) {
+	now := time.Now()
+	success := false
+	report := firmwareOutcomeReport{
+		EstbMac:         "aa-bb-cc-dd-ee-ff",
+		Model:           " X1 ",
+		PartnerId:       "comcast",
+		FirmwareVersion: "2.0",
+		Stage:           sharedef.FirmwareOutcomeStageFlash,
+		Success:         &success,
+		Reason:          "checksum mismatch",
+	}
+	outcome, err := report.toFirmwareOutcome(now)
+	assert.NoError(t, err)
+	assert.Equal(t, "AA:BB:CC:DD:EE:FF", outcome.EstbMac)
+	assert.Equal(t, "X1", outcome.Model)
+	assert.Equal(t, "comcast", outcome.Partner)
+	assert.False(t, outcome.Success)
+	assert.Equal(t, util.GetTimestamp(now), outcome.Ts)
+
+	for _, invalid := range []firmwareOutcomeReport{
+		{EstbMac: "invalid", Model: "X1", PartnerId: "comcast", FirmwareVersion: "2.0", Stage: sharedef.FirmwareOutcomeStageFlash, Success: &success},
+		{EstbMac: "AA:BB:CC:DD:EE:FF", Model: "X1", PartnerId: "comcast", Stage: sharedef.FirmwareOutcomeStageFlash, Success: &success},
+		{EstbMac: "AA:BB:CC:DD:EE:FF", Model: "X1", PartnerId: "comcast", FirmwareVersion: "2.0", Stage: "install", Success: &success},
+		{EstbMac: "AA:BB:CC:DD:EE:FF", Model: "X1", PartnerId: "comcast", FirmwareVersion: "2.0", Stage: sharedef.FirmwareOutcomeStageDownload},
+	} {
+		_, err = invalid.toFirmwareOutcome(now)
+		assert.Error(t, err)
+	}
+}
+
+func TestPostEstbFirmwareOutcomeHandler_Gated(t *testing.T) {
+	originalXc := Xc
+	defer func() { Xc = originalXc }()
+
+	body := `{"estbMac": "AA:BB:CC:DD:EE:FF", "model": "X1", "firmwareVersion": "2.0", "stage": "flash", "success": false}`
+	tests := []struct {
+		name   string
+		xc     *XconfConfigs
+		token  string
+		body   string
+		status int
+	}{
+		{"disabled", &XconfConfigs{}, "", body, http.StatusNotFound},
+		{"wrong token", &XconfConfigs{FirmwareOutcomeEnabled: true, FirmwareOutcomeToken: "secret"}, "other", body, http.StatusUnauthorized},
+		{"no token configured", &XconfConfigs{FirmwareOutcomeEnabled: true}, "", body, http.StatusUnauthorized},
+		{"invalid json", &XconfConfigs{FirmwareOutcomeEnabled: true, FirmwareOutcomeToken: "secret"}, "secret", `{"estbMac": 1}`, http.StatusBadRequest},
+		{"missing success", &XconfConfigs{FirmwareOutcomeEnabled: true, FirmwareOutcomeToken: "secret"}, "secret", `{"estbMac": "AA:BB:CC:DD:EE:FF", "model": "X1", "firmwareVersion": "2.0", "stage": "flash"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		Xc = tt.xc
+		req := httptest.NewRequest(http.MethodPost, "/estbfirmware/firmwareOutcome", strings.NewReader(tt.body))
+		req.Header.Set(common.HeaderAuthorization, "Bearer "+tt.token)
+		recorder := httptest.NewRecorder()
+		xw := xhttp.NewXResponseWriter(recorder)
+		xw.SetBody(tt.body)
+
+		PostEstbFirmwareOutcomeHandler(xw, req)
+		assert.Equal(t, tt.status, recorder.Code, tt.name)
+	}
+}
+
+func TestGetEstbFirmwareOutcomeHandler(t *testing.T) {
+	originalXc := Xc
+	defer func() { Xc = originalXc }()
+
+	Xc = &XconfConfigs{}
+	req := httptest.NewRequest(http.MethodGet, "/estbfirmware/firmwareOutcome?model=X1&firmwareVersion=2.0", nil)
+	recorder := httptest.NewRecorder()
+	GetEstbFirmwareOutcomeHandler(recorder, req)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	Xc = &XconfConfigs{FirmwareOutcomeEnabled: true}
+	req = httptest.NewRequest(http.MethodGet, "/estbfirmware/firmwareOutcome?model=X1", nil)
+	recorder = httptest.NewRecorder()
+	GetEstbFirmwareOutcomeHandler(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
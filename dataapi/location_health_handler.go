@@ -0,0 +1,134 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package dataapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	dataef "github.com/rdkcentral/xconfwebconfig/dataapi/estbfirmware"
+	xhttp "github.com/rdkcentral/xconfwebconfig/http"
+	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// locationProber probes the download locations when location_probe_interval_in_secs is set
+var locationProber *dataef.LocationProber
+
+// locationHealthReport is a health report pushed for a download location, without a ttl it stays until replaced
+type locationHealthReport struct {
+	Location   string `json:"location"`
+	Healthy    *bool  `json:"healthy"`
+	Reason     string `json:"reason,omitempty"`
+	TtlSeconds int64  `json:"ttlSeconds,omitempty"`
+}
+
+type locationHealthResponse struct {
+	Reports []*sharedef.LocationHealth `json:"reports"`
+	Probes  []*sharedef.LocationHealth `json:"probes"`
+}
+
+func (r *locationHealthReport) toLocationHealth(now time.Time) (*sharedef.LocationHealth, error) {
+	location := strings.TrimSpace(r.Location)
+	if location == "" {
+		return nil, errors.New("location is required")
+	}
+	if r.Healthy == nil {
+		return nil, errors.New("healthy is required")
+	}
+	if r.TtlSeconds < 0 {
+		return nil, fmt.Errorf("ttlSeconds %d is negative", r.TtlSeconds)
+	}
+	health := &sharedef.LocationHealth{
+		ID:      location,
+		Healthy: *r.Healthy,
+		Reason:  r.Reason,
+		Source:  sharedef.LocationHealthSourceReport,
+		Updated: util.GetTimestamp(now),
+	}
+	if r.TtlSeconds > 0 {
+		health.Expires = util.GetTimestamp(now.Add(time.Duration(r.TtlSeconds) * time.Second))
+	}
+	return health, nil
+}
+
+// GetEstbLocationHealthHandler returns the reported health of the download locations and, when they are probed,
+// the last probe of each location by this instance
+func GetEstbLocationHealthHandler(w http.ResponseWriter, r *http.Request) {
+	if Xc == nil || !Xc.LocationHealthEnabled {
+		xhttp.WriteXconfResponseAsText(w, http.StatusNotFound, []byte("\"<h2>404 NOT FOUND</h2><div>location health is disabled</div>\""))
+		return
+	}
+	reports, err := sharedef.GetLocationHealthAllDB()
+	if err != nil {
+		log.Debugf("No location health reports: %v", err)
+		reports = []*sharedef.LocationHealth{}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].ID < reports[j].ID
+	})
+	probes := []*sharedef.LocationHealth{}
+	if locationProber != nil {
+		probes = locationProber.GetAll()
+	}
+	response, _ := util.JSONMarshal(locationHealthResponse{Reports: reports, Probes: probes})
+	xhttp.WriteXconfResponse(w, http.StatusOK, response)
+}
+
+// PostEstbLocationHealthHandler stores a health report of a download location, the location is its ip in the
+// round robin filter or the host of its http location. It requires the location_health_token as a bearer token
+func PostEstbLocationHealthHandler(w http.ResponseWriter, r *http.Request) {
+	xw, ok := w.(*xhttp.XResponseWriter)
+	if !ok {
+		xhttp.Error(w, http.StatusInternalServerError, common.NotOK)
+		return
+	}
+	if Xc == nil || !Xc.LocationHealthEnabled {
+		xhttp.WriteXconfResponseAsText(w, http.StatusNotFound, []byte("\"<h2>404 NOT FOUND</h2><div>location health is disabled</div>\""))
+		return
+	}
+	if !isBearerTokenAuthorized(r, Xc.LocationHealthToken) {
+		xhttp.WriteXconfResponseAsText(w, http.StatusUnauthorized, []byte("\"<h2>401 Unauthorized</h2>\""))
+		return
+	}
+	report := locationHealthReport{}
+	if err := json.Unmarshal([]byte(xw.Body()), &report); err != nil {
+		xhttp.WriteXconfResponseAsText(w, http.StatusBadRequest, []byte(fmt.Sprintf("\"<h2>400 Bad Request</h2><div>%s</div>\"", err.Error())))
+		return
+	}
+	health, err := report.toLocationHealth(time.Now())
+	if err != nil {
+		xhttp.WriteXconfResponseAsText(w, http.StatusBadRequest, []byte(fmt.Sprintf("\"<h2>400 Bad Request</h2><div>%s</div>\"", err.Error())))
+		return
+	}
+	if err := sharedef.SetLocationHealthOneDB(health); err != nil {
+		xhttp.WriteXconfResponseAsText(w, http.StatusInternalServerError, []byte(fmt.Sprintf("\"<h2>500 Internal Server Error</h2><div>%s</div>\"", err.Error())))
+		return
+	}
+	log.WithFields(xw.Audit()).Infof("Location health reported: %s", health)
+	response, _ := util.JSONMarshal(health)
+	xhttp.WriteXconfResponse(w, http.StatusOK, response)
+}
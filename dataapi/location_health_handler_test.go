@@ -0,0 +1,98 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package dataapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	xhttp "github.com/rdkcentral/xconfwebconfig/http"
+	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocationHealthReport(t *testing.T) {
+	now := time.Now()
+	healthy := false
+	report := locationHealthReport{Location: " 10.0.0.1 ", Healthy: &healthy, Reason: "origin down", TtlSeconds: 60}
+	health, err := report.toLocationHealth(now)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", health.ID)
+	assert.False(t, health.Healthy)
+	assert.Equal(t, sharedef.LocationHealthSourceReport, health.Source)
+	assert.Equal(t, util.GetTimestamp(now.Add(time.Minute)), health.Expires)
+
+	report.TtlSeconds = 0
+	health, err = report.toLocationHealth(now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), health.Expires)
+
+	for _, invalid := range []locationHealthReport{
+		{Healthy: &healthy},
+		{Location: "10.0.0.1"},
+		{Location: "10.0.0.1", Healthy: &healthy, TtlSeconds: -1},
+	} {
+		_, err = invalid.toLocationHealth(now)
+		assert.Error(t, err)
+	}
+}
+
+func TestPostEstbLocationHealthHandler_Gated(t *testing.T) {
+	originalXc := Xc
+	defer func() { Xc = originalXc }()
+
+	tests := []struct {
+		name   string
+		xc     *XconfConfigs
+		token  string
+		body   string
+		status int
+	}{
+		{"disabled", &XconfConfigs{LocationHealthToken: "secret"}, "secret", `{"location": "10.0.0.1", "healthy": false}`, http.StatusNotFound},
+		{"wrong token", &XconfConfigs{LocationHealthEnabled: true, LocationHealthToken: "secret"}, "other", `{"location": "10.0.0.1", "healthy": false}`, http.StatusUnauthorized},
+		{"invalid json", &XconfConfigs{LocationHealthEnabled: true, LocationHealthToken: "secret"}, "secret", `{"location": 1}`, http.StatusBadRequest},
+		{"missing healthy", &XconfConfigs{LocationHealthEnabled: true, LocationHealthToken: "secret"}, "secret", `{"location": "10.0.0.1"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		Xc = tt.xc
+		req := httptest.NewRequest(http.MethodPost, "/estbfirmware/locationHealth", strings.NewReader(tt.body))
+		req.Header.Set(common.HeaderAuthorization, "Bearer "+tt.token)
+		recorder := httptest.NewRecorder()
+		xw := xhttp.NewXResponseWriter(recorder)
+		xw.SetBody(tt.body)
+
+		PostEstbLocationHealthHandler(xw, req)
+		assert.Equal(t, tt.status, recorder.Code, tt.name)
+	}
+}
+
+func TestGetEstbLocationHealthHandler_Disabled(t *testing.T) {
+	originalXc := Xc
+	defer func() { Xc = originalXc }()
+	Xc = &XconfConfigs{}
+
+	req := httptest.NewRequest(http.MethodGet, "/estbfirmware/locationHealth", nil)
+	recorder := httptest.NewRecorder()
+	GetEstbLocationHealthHandler(recorder, req)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
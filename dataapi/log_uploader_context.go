@@ -29,6 +29,7 @@ import (
 	loguploader "github.com/rdkcentral/xconfwebconfig/dataapi/dcm/logupload"
 	"github.com/rdkcentral/xconfwebconfig/dataapi/dcm/telemetry"
 	xhttp "github.com/rdkcentral/xconfwebconfig/http"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
 	"github.com/rdkcentral/xconfwebconfig/shared"
 	"github.com/rdkcentral/xconfwebconfig/shared/logupload"
 
@@ -218,8 +219,10 @@ type TelemetryEvaluationResult struct {
 	ProfilesData []util.Dict
 }
 
-func GetTelemetryTwoProfileResponeDicts(contextMap map[string]string, fields log.Fields) (*TelemetryEvaluationResult, error) {
+// GetTelemetryTwoProfileResponeDicts evaluates the telemetry 2.0 rules, tracer may be nil
+func GetTelemetryTwoProfileResponeDicts(contextMap map[string]string, tracer *re.RuleTracer, fields log.Fields) (*TelemetryEvaluationResult, error) {
 	telemetryProfileService := telemetry.NewTelemetryProfileService()
+	telemetryProfileService.Tracer = tracer
 	matchedRules := telemetryProfileService.ProcessTelemetryTwoRules(contextMap)
 	matchedProfiles := telemetryProfileService.GetTelemetryTwoProfileByTelemetryRules(matchedRules, fields)
 	dicts := []util.Dict{}
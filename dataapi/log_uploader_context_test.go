@@ -491,7 +491,7 @@ func TestGetTelemetryTwoProfileResponeDicts(t *testing.T) {
 		fields := log.Fields{}
 
 		// This will return empty results since no rules/profiles are configured
-		result, err := GetTelemetryTwoProfileResponeDicts(contextMap, fields)
+		result, err := GetTelemetryTwoProfileResponeDicts(contextMap, nil, fields)
 
 		// Should handle gracefully even with no configured rules
 		assert.NoError(t, err)
@@ -504,7 +504,7 @@ func TestGetTelemetryTwoProfileResponeDicts(t *testing.T) {
 		contextMap := map[string]string{}
 		fields := log.Fields{}
 
-		result, err := GetTelemetryTwoProfileResponeDicts(contextMap, fields)
+		result, err := GetTelemetryTwoProfileResponeDicts(contextMap, nil, fields)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
@@ -58,18 +58,22 @@ func GetLogUploaderTelemetryProfilesHandler(w http.ResponseWriter, r *http.Reque
 	coastTags, _ := AddLogUploaderContext(Ws, r, contextMap, false, fields)
 	xconfTags := AddGroupServiceFTContext(Ws, common.ESTB_MAC_ADDRESS, contextMap, true, fields)
 	CompareTaggingSources(contextMap, coastTags, xconfTags, fields)
-	evaluationResult, err := GetTelemetryTwoProfileResponeDicts(contextMap, fields)
+	tracer := NewRuleTracerForRequest(r)
+	evaluationResult, err := GetTelemetryTwoProfileResponeDicts(contextMap, tracer, fields)
 	if err != nil {
 		xhttp.Error(w, http.StatusInternalServerError, err)
 		return
 	}
-	if evaluationResult != nil && evaluationResult.RulesMatched == false {
+	if evaluationResult != nil && evaluationResult.RulesMatched == false && tracer == nil {
 		xhttp.WriteXconfResponseAsText(w, 404, []byte("\"<h2>404 NOT FOUND</h2>profiles not found\""))
 	} else {
 		log.WithFields(fields).Debug("LogUploaderService TelemetryTwo AppliedRules")
 		resp := util.Dict{
 			"profiles": evaluationResult.ProfilesData,
 		}
+		if tracer != nil {
+			resp["evaluationTrace"] = tracer
+		}
 		rbytes, err := util.JSONMarshal(resp)
 		if err != nil {
 			xhttp.Error(w, http.StatusInternalServerError, err)
@@ -137,11 +141,14 @@ func GetLogUploaderSettings(w http.ResponseWriter, r *http.Request, isTelemetry2
 	} else {
 		clientProtocol := GetClientProtocolHeaderValue(r)
 		AddClientProtocolToContextMap(contextMap, clientProtocol)
+		tracer := NewRuleTracerForRequest(r)
 		logUploadRuleBase := dcmlogupload.NewLogUploadRuleBase()
+		logUploadRuleBase.Tracer = tracer
 		result := logUploadRuleBase.Eval(contextMap, fields)
 		var telemetryRule *logupload.TelemetryRule
 		if result != nil {
 			telemetryProfileService := telemetry.NewTelemetryProfileService()
+			telemetryProfileService.Tracer = tracer
 			telemetryRule = telemetryProfileService.GetTelemetryRuleForContext(contextMap)
 			permanentTelemetryProfile := telemetryProfileService.GetPermanentProfileByTelemetryRule(telemetryRule)
 			if permanentTelemetryProfile != nil {
@@ -186,7 +193,13 @@ func GetLogUploaderSettings(w http.ResponseWriter, r *http.Request, isTelemetry2
 			}
 		}
 
-		if result == nil {
+		if result == nil && tracer != nil {
+			response, _ := util.JSONMarshal(util.Dict{
+				"description":     "settings not found",
+				"evaluationTrace": tracer,
+			})
+			xhttp.WriteXconfResponse(w, 404, response)
+		} else if result == nil {
 			xhttp.WriteXconfResponseAsText(w, 404, []byte("\"<h2>404 NOT FOUND</h2><div>settings not found</div>\""))
 		} else {
 			if Xc.SecurityTokenManagerEnabled {
@@ -210,6 +223,7 @@ func GetLogUploaderSettings(w http.ResponseWriter, r *http.Request, isTelemetry2
 			}
 			LogResultSettings(result, telemetryRule, settingRules, fields)
 			settingsResponse := logupload.CreateSettingsResponseObject(result)
+			settingsResponse.EvaluationTrace = tracer
 			response, _ := util.JSONMarshal(settingsResponse)
 			xhttp.WriteXconfResponse(w, 200, response)
 		}
@@ -21,10 +21,12 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	dataef "github.com/rdkcentral/xconfwebconfig/dataapi/estbfirmware"
 	"github.com/rdkcentral/xconfwebconfig/db"
 	xhttp "github.com/rdkcentral/xconfwebconfig/http"
 	"github.com/rdkcentral/xconfwebconfig/rulesengine"
@@ -90,6 +92,30 @@ type XconfConfigs struct {
 	ValidPartnerIdRegex          *regexp.Regexp
 	SecurityTokenManagerEnabled  bool
 	EnableTaggingComparison      bool
+	EvaluationTraceEnabled       bool
+	EvaluationTraceToken         string
+	DryRunEnabled                bool
+	DryRunToken                  string
+	LocationHealthEnabled        bool
+	LocationHealthToken          string
+	LocationProbeInterval        time.Duration
+	LocationProbeTimeout         time.Duration
+	LocationTftpProbeFile        string
+	FirmwareOutcomeEnabled       bool
+	FirmwareOutcomeToken         string
+	FirmwareOutcomeTtl           time.Duration
+	RolloutHaltEnabled           bool
+	RolloutHaltFailurePercentage float64
+	RolloutHaltMinReports        int
+	RolloutHaltAction            string
+	RolloutHaltCacheTtl          time.Duration
+	MaintenanceWindowEnabled     bool
+	CanarySelectionEnabled       bool
+	CanaryCohortCacheTtl         time.Duration
+	CanaryToken                  string
+	VersionSchemes               []rulesengine.VersionScheme
+	CustomEvaluators             []rulesengine.IConditionEvaluator
+	RuleStatsMaxRules            int
 }
 
 // Function to register the table name and the corresponding model/struct constructor
@@ -106,6 +132,9 @@ func RegisterTables() {
 		db.RegisterTableConfigSimple(db.TABLE_FIRMWARE_RULE, fw.NewFirmwareRuleInf)
 		db.RegisterTableConfigSimple(db.TABLE_FIRMWARE_RULE_TEMPLATE, fw.NewFirmwareRuleTemplateInf)
 		db.RegisterTableConfigSimple(db.TABLE_SINGLETON_FILTER_VALUE, sharedef.NewSingletonFilterValueInf)
+		db.RegisterTableConfigSimple(db.TABLE_FIRMWARE_UPGRADE_GRAPH, sharedef.NewFirmwareUpgradeGraphInf)
+		db.RegisterTableConfigSimple(db.TABLE_LOCATION_HEALTH, sharedef.NewLocationHealthInf)
+		db.RegisterTableConfigSimple(db.TABLE_MAINTENANCE_WINDOW, sharedef.NewMaintenanceWindowPolicyInf)
 		db.RegisterTableConfigSimple(db.TABLE_UPLOAD_REPOSITORY, logupload.NewUploadRepositoryInf)
 		db.RegisterTableConfigSimple(db.TABLE_LOG_FILE, logupload.NewLogFileInf)
 		db.RegisterTableConfigSimple(db.TABLE_LOG_FILE_LIST, logupload.NewLogFileListInf)
@@ -157,6 +186,12 @@ func RegisterTables() {
 			Key2FieldName:   db.DefaultKey2FieldName,
 		})
 
+		db.RegisterTableConfig(&db.TableInfo{
+			TableName:       db.TABLE_CANARY_COHORT,
+			ConstructorFunc: sharedef.NewCanaryDeviceInf,
+			TTL:             90 * 24 * 60 * 60,
+		})
+
 		db.RegisterTableConfig(&db.TableInfo{
 			TableName:       db.TABLE_NS_LIST,
 			ConstructorFunc: shared.NewNamespacedListInf,
@@ -293,6 +328,18 @@ func GetXconfConfigs(conf *conf.Config) *XconfConfigs {
 		validPartnerIdRegex = regexp.MustCompile(defaultValidPartnerIdRegex)
 	}
 
+	versionSchemes, err := rulesengine.ParseVersionSchemes(conf.GetString("xconfwebconfig.xconf.version_schemes"))
+	if err != nil {
+		log.Errorf("Error loading version schemes: %v", err)
+		panic(err)
+	}
+
+	customEvaluators, err := GetCustomEvaluators(conf)
+	if err != nil {
+		log.Errorf("Error loading custom evaluators: %v", err)
+		panic(err)
+	}
+
 	xc := &XconfConfigs{
 		DeriveAppTypeFromPartnerId:   conf.GetBoolean("xconfwebconfig.xconf.derive_application_type_from_partner_id"),
 		PartnerApplicationTypes:      appTypes,
@@ -339,19 +386,76 @@ func GetXconfConfigs(conf *conf.Config) *XconfConfigs {
 		PartnerIdValidationEnabled:   partnerIdValidationEnabled,
 		SecurityTokenManagerEnabled:  conf.GetBoolean("xconfwebconfig.xconf.security_token_manager_enabled"),
 		EnableTaggingComparison:      conf.GetBoolean("xconfwebconfig.xconf.enable_tagging_comparison"),
+		EvaluationTraceEnabled:       conf.GetBoolean("xconfwebconfig.xconf.evaluation_trace_enabled", false),
+		EvaluationTraceToken:         conf.GetString("xconfwebconfig.xconf.evaluation_trace_token"),
+		DryRunEnabled:                conf.GetBoolean("xconfwebconfig.xconf.dry_run_enabled", false),
+		DryRunToken:                  conf.GetString("xconfwebconfig.xconf.dry_run_token"),
+		LocationHealthEnabled:        conf.GetBoolean("xconfwebconfig.xconf.location_health_enabled", false),
+		LocationHealthToken:          conf.GetString("xconfwebconfig.xconf.location_health_token"),
+		LocationProbeInterval:        time.Duration(conf.GetInt32("xconfwebconfig.xconf.location_probe_interval_in_secs", 0)) * time.Second,
+		LocationProbeTimeout:         time.Duration(conf.GetInt32("xconfwebconfig.xconf.location_probe_timeout_in_secs", 5)) * time.Second,
+		LocationTftpProbeFile:        conf.GetString("xconfwebconfig.xconf.location_tftp_probe_file", "xconf-location-probe"),
+		FirmwareOutcomeEnabled:       conf.GetBoolean("xconfwebconfig.xconf.firmware_outcome_enabled", false),
+		FirmwareOutcomeToken:         conf.GetString("xconfwebconfig.xconf.firmware_outcome_token"),
+		FirmwareOutcomeTtl:           time.Duration(conf.GetInt32("xconfwebconfig.xconf.firmware_outcome_ttl_in_secs", 2592000)) * time.Second,
+		RolloutHaltEnabled:           conf.GetBoolean("xconfwebconfig.xconf.rollout_halt_enabled", false),
+		RolloutHaltFailurePercentage: conf.GetFloat64("xconfwebconfig.xconf.rollout_halt_failure_percentage", 20),
+		RolloutHaltMinReports:        int(conf.GetInt32("xconfwebconfig.xconf.rollout_halt_min_reports", 100)),
+		RolloutHaltAction:            conf.GetString("xconfwebconfig.xconf.rollout_halt_action", dataef.ROLLOUT_HALT_FREEZE),
+		RolloutHaltCacheTtl:          time.Duration(conf.GetInt32("xconfwebconfig.xconf.rollout_halt_cache_ttl_in_secs", 60)) * time.Second,
+		MaintenanceWindowEnabled:     conf.GetBoolean("xconfwebconfig.xconf.maintenance_window_enabled", false),
+		CanarySelectionEnabled:       conf.GetBoolean("xconfwebconfig.xconf.canary_selection_enabled", false),
+		CanaryCohortCacheTtl:         time.Duration(conf.GetInt32("xconfwebconfig.xconf.canary_cohort_cache_ttl_in_secs", 60)) * time.Second,
+		CanaryToken:                  conf.GetString("xconfwebconfig.xconf.canary_token"),
+		VersionSchemes:               versionSchemes,
+		CustomEvaluators:             customEvaluators,
+		RuleStatsMaxRules:            int(conf.GetInt32("xconfwebconfig.xconf.rule_stats_max_rules", rulesengine.DefaultMaxRuleStats)),
 	}
 	return xc
 }
 
+// GetCustomEvaluators builds the declarative evaluators configured under xconfwebconfig.xconf.custom_evaluators
+func GetCustomEvaluators(config *conf.Config) ([]rulesengine.IConditionEvaluator, error) {
+	path := "xconfwebconfig.xconf.custom_evaluators"
+	evaluators := []rulesengine.IConditionEvaluator{}
+	node := config.GetNode(path)
+	if node == nil || !node.IsObject() {
+		return evaluators, nil
+	}
+	names := node.GetObject().GetKeys()
+	sort.Strings(names)
+	for _, name := range names {
+		spec := rulesengine.DeclarativeEvaluatorSpec{
+			FreeArgType: config.GetString(path+"."+name+".free_arg_type", rulesengine.StandardFreeArgTypeString),
+			Operation:   config.GetString(path + "." + name + ".operation"),
+			Match:       config.GetString(path + "." + name + ".match"),
+			IgnoreCase:  config.GetBoolean(path+"."+name+".ignore_case", false),
+		}
+		evaluator, err := rulesengine.NewDeclarativeEvaluator(spec)
+		if err != nil {
+			return nil, fmt.Errorf("custom evaluator '%v': %v", name, err)
+		}
+		evaluators = append(evaluators, evaluator)
+	}
+	return evaluators, nil
+}
+
 // Xconf setup
 func XconfSetup(server *xhttp.XconfServer, r *mux.Router) {
 	xc := GetXconfConfigs(server.ServerConfig.Config)
 	WebServerInjection(server, xc)
+	if err := setupRulesEngine(xc); err != nil {
+		panic(err)
+	}
 	db.ConfigInjection(server.ServerConfig.Config)
 	db.SetGrpCacheLoadFunc(LoadGroupServiceFeatureTags)
 	RegisterTables()
 	db.GetCacheManager() // Initialize cache manager
 
+	setupLocationHealth(xc)
+	setupRolloutHalt(xc)
+	setupMaintenanceWindow(xc)
+	setupCanarySelection(xc)
 	RouteXconfDataserviceApis(r, server)
 
 	if xc.DiagnosticAPIsEnabled {
@@ -359,6 +463,68 @@ func XconfSetup(server *xhttp.XconfServer, r *mux.Router) {
 	}
 }
 
+// setupLocationHealth removes unhealthy locations from the download location choice, using the reported
+// health and, with a probe interval, the health probed by this instance
+func setupLocationHealth(xc *XconfConfigs) {
+	if !xc.LocationHealthEnabled {
+		return
+	}
+	checkers := []sharedef.LocationHealthChecker{sharedef.GetReportedLocationHealthChecker()}
+	if xc.LocationProbeInterval > 0 {
+		locationProber = dataef.NewLocationProber(xc.LocationProbeInterval, xc.LocationProbeTimeout, xc.LocationTftpProbeFile)
+		locationProber.Run()
+		checkers = append(checkers, locationProber)
+	}
+	dataef.SetDefaultLocationHealthChecker(dataef.NewLocationHealthChecker(checkers...))
+}
+
+// setupRolloutHalt halts the offers of the firmware versions whose reported outcomes fail too often
+func setupRolloutHalt(xc *XconfConfigs) {
+	if !xc.RolloutHaltEnabled {
+		return
+	}
+	if xc.RolloutHaltAction != dataef.ROLLOUT_HALT_FREEZE && xc.RolloutHaltAction != dataef.ROLLOUT_HALT_ROLLBACK {
+		panic(fmt.Errorf("rollout_halt_action must be %s or %s: %s", dataef.ROLLOUT_HALT_FREEZE, dataef.ROLLOUT_HALT_ROLLBACK, xc.RolloutHaltAction))
+	}
+	if xc.FirmwareOutcomeToken == "" {
+		panic(fmt.Errorf("rollout_halt_enabled requires a firmware_outcome_token for the outcome reports"))
+	}
+	rolloutGuard = dataef.NewRolloutGuard(&dataef.RolloutHaltSettings{
+		FailurePercentage: xc.RolloutHaltFailurePercentage,
+		MinReports:        xc.RolloutHaltMinReports,
+		Action:            xc.RolloutHaltAction,
+		CacheTtl:          xc.RolloutHaltCacheTtl,
+	})
+	dataef.SetDefaultRolloutGuard(rolloutGuard)
+}
+
+// setupMaintenanceWindow keeps devices from rebooting immediately outside the maintenance window of their policy
+func setupMaintenanceWindow(xc *XconfConfigs) {
+	if !xc.MaintenanceWindowEnabled {
+		return
+	}
+	dataef.SetDefaultMaintenanceWindowEnforcer(dataef.NewMaintenanceWindowEnforcer(sharedef.GetMaintenanceWindowPolicyAllDB))
+}
+
+// setupCanarySelection selects canary devices for the firmware requests, the cohorts are cached by each instance
+func setupCanarySelection(xc *XconfConfigs) {
+	if !xc.CanarySelectionEnabled {
+		return
+	}
+	canaryCohortDao = sharedef.NewCachedCanaryCohortDao(sharedef.GetCanaryCohortDao(), xc.CanaryCohortCacheTtl)
+}
+
+func setupRulesEngine(xc *XconfConfigs) error {
+	rulesengine.SetVersionSchemes(xc.VersionSchemes)
+	rulesengine.SetMaxRuleStats(xc.RuleStatsMaxRules)
+	for _, evaluator := range xc.CustomEvaluators {
+		if err := rulesengine.RegisterEvaluator(evaluator); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func RouteXconfDataserviceApis(r *mux.Router, s *xhttp.XconfServer) {
 	paths := []*mux.Router{}
 
@@ -412,6 +578,34 @@ func RouteXconfDataserviceApis(r *mux.Router, s *xhttp.XconfServer) {
 	getCheckMinFirmwarePath.HandleFunc("", GetCheckMinFirmwareHandler)
 	paths = append(paths, getCheckMinFirmwarePath)
 
+	getEstbLastlogPath := r.Path("/estbfirmware/lastlog").Subrouter()
+	getEstbLastlogPath.HandleFunc("", GetEstbLastlogPath).Methods("GET")
+	paths = append(paths, getEstbLastlogPath)
+
+	getEstbChangelogsPath := r.Path("/estbfirmware/changelogs").Subrouter()
+	getEstbChangelogsPath.HandleFunc("", GetEstbChangelogsPath).Methods("GET")
+	paths = append(paths, getEstbChangelogsPath)
+
+	if Xc != nil && Xc.CanarySelectionEnabled {
+		getEstbCanaryPath := r.Path("/estbfirmware/canary").Subrouter()
+		getEstbCanaryPath.HandleFunc("", GetEstbCanaryPath).Methods("GET")
+		paths = append(paths, getEstbCanaryPath)
+	}
+
+	getEstbFirmwareDryRunPath := r.Path("/xconf/{applicationType}/dryRun").Subrouter()
+	getEstbFirmwareDryRunPath.HandleFunc("", GetEstbFirmwareDryRunHandler).Methods("GET", "POST")
+	paths = append(paths, getEstbFirmwareDryRunPath)
+
+	estbLocationHealthPath := r.Path("/estbfirmware/locationHealth").Subrouter()
+	estbLocationHealthPath.HandleFunc("", GetEstbLocationHealthHandler).Methods("GET")
+	estbLocationHealthPath.HandleFunc("", PostEstbLocationHealthHandler).Methods("POST")
+	paths = append(paths, estbLocationHealthPath)
+
+	estbFirmwareOutcomePath := r.Path("/estbfirmware/firmwareOutcome").Subrouter()
+	estbFirmwareOutcomePath.HandleFunc("", GetEstbFirmwareOutcomeHandler).Methods("GET")
+	estbFirmwareOutcomePath.HandleFunc("", PostEstbFirmwareOutcomeHandler).Methods("POST")
+	paths = append(paths, estbFirmwareOutcomePath)
+
 	getEstbFirmwareVersionInfoPath := r.Path("/xconf/{applicationType}/runningFirmwareVersion/info").Subrouter()
 	getEstbFirmwareVersionInfoPath.HandleFunc("", GetEstbFirmwareVersionInfoPath)
 	paths = append(paths, getEstbFirmwareVersionInfoPath)
@@ -441,6 +635,23 @@ func RouteDiagnosticApis(r *mux.Router, s *xhttp.XconfServer) {
 	getInfoStatisticsPath := r.Path("/info/statistics").Subrouter()
 	getInfoStatisticsPath.HandleFunc("", GetInfoStatistics).Methods("GET")
 	paths = append(paths, getInfoStatisticsPath)
+
+	getInfoRuleAnalysisPath := r.Path("/info/ruleAnalysis").Subrouter()
+	getInfoRuleAnalysisPath.HandleFunc("", GetInfoRuleAnalysis).Methods("GET")
+	paths = append(paths, getInfoRuleAnalysisPath)
+
+	getInfoPercentBucketPath := r.Path("/info/percentBucket").Subrouter()
+	getInfoPercentBucketPath.HandleFunc("", GetInfoPercentBucket).Methods("GET")
+	paths = append(paths, getInfoPercentBucketPath)
+
+	getInfoConditionStatsPath := r.Path("/info/conditionStats").Subrouter()
+	getInfoConditionStatsPath.HandleFunc("", GetInfoConditionStats).Methods("GET")
+	paths = append(paths, getInfoConditionStatsPath)
+
+	getInfoRuleStatsPath := r.Path("/info/ruleStats").Subrouter()
+	getInfoRuleStatsPath.HandleFunc("", GetInfoRuleStats).Methods("GET")
+	getInfoRuleStatsPath.HandleFunc("", DeleteInfoRuleStats).Methods("DELETE")
+	paths = append(paths, getInfoRuleStatsPath)
 }
 
 // PathNotFoundHandler - invalid URL should return 404 with message
@@ -0,0 +1,58 @@
+package dataapi
+
+import (
+	"testing"
+
+	"github.com/go-akka/configuration"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCustomEvaluators(t *testing.T) {
+	conf := configuration.ParseString(`
+		xconfwebconfig {
+			xconf {
+				custom_evaluators {
+					partner_prefix {
+						operation = "PREFIX_IN"
+						match = "prefix"
+						ignore_case = true
+					}
+					mac_glob {
+						free_arg_type = "MAC_ADDRESS"
+						operation = "GLOB"
+						match = "glob"
+					}
+				}
+			}
+		}`)
+	evaluators, err := GetCustomEvaluators(conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(evaluators))
+	assert.Equal(t, re.AuxFreeArgTypeMacAddress, evaluators[0].FreeArgType())
+	assert.Equal(t, "GLOB", evaluators[0].Operation())
+	assert.Equal(t, re.StandardFreeArgTypeString, evaluators[1].FreeArgType())
+	assert.Equal(t, "PREFIX_IN", evaluators[1].Operation())
+
+	condition := re.NewCondition(re.NewFreeArg(re.StandardFreeArgTypeString, "partnerId"), "PREFIX_IN", re.NewFixedArg([]string{"comcast"}))
+	assert.True(t, evaluators[1].Evaluate(condition, map[string]string{"partnerId": "COMCAST-1"}))
+}
+
+func TestGetCustomEvaluatorsInvalid(t *testing.T) {
+	conf := configuration.ParseString(`xconfwebconfig.xconf.custom_evaluators.bad { operation = "X", match = "soundex" }`)
+	_, err := GetCustomEvaluators(conf)
+	assert.ErrorContains(t, err, "custom evaluator 'bad'")
+
+	evaluators, err := GetCustomEvaluators(configuration.ParseString(`xconfwebconfig.xconf.version_schemes = ""`))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(evaluators))
+}
+
+func TestGetXconfConfigsInvalidVersionSchemes(t *testing.T) {
+	schemes := `xconfwebconfig.xconf.version_schemes = "^X1=[0-9"`
+	_, err := re.ParseVersionSchemes("^X1=[0-9")
+	assert.Error(t, err)
+	assert.PanicsWithError(t, err.Error(), func() {
+		GetXconfConfigs(configuration.ParseString(schemes))
+	})
+}
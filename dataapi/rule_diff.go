@@ -0,0 +1,80 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package dataapi
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/db"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
+	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DiffRuleExports compares the rules of a table in two exports. The device contexts of the optional
+// corpus are normalized like the requests of the API that evaluates the rules of the table.
+func DiffRuleExports(tableName string, oldExport db.CacheExport, newExport db.CacheExport, corpus []map[string]string) (*re.RuleSetDiff, error) {
+	oldRules, err := exportedXRules(oldExport, tableName)
+	if err != nil {
+		return nil, err
+	}
+	newRules, err := exportedXRules(newExport, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &re.RuleDiffOptions{}
+	now := time.Now()
+	for _, context := range corpus {
+		contextMap := copyContext(context)
+		if tableName == db.TABLE_FIRMWARE_RULE {
+			normalizeFirmwareSimulationContext(contextMap, now, log.Fields{})
+			contextMap = sharedef.GetContextConverted(contextMap).GetProperties()
+		} else {
+			normalizeSimulationContext(contextMap)
+		}
+		options.Contexts = append(options.Contexts, contextMap)
+	}
+	return re.DiffRuleSets(oldRules, newRules, options)
+}
+
+// exportedXRules returns the rules of a table in the export, sorted by row key
+func exportedXRules(export db.CacheExport, tableName string) ([]re.XRule, error) {
+	entities, err := export.Entities(tableName)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(entities))
+	for key := range entities {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	xrules := make([]re.XRule, 0, len(keys))
+	for _, key := range keys {
+		xrule, ok := entities[key].(re.XRule)
+		if !ok {
+			return nil, fmt.Errorf("table '%v' does not hold rules", tableName)
+		}
+		xrules = append(xrules, xrule)
+	}
+	return xrules, nil
+}
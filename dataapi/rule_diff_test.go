@@ -0,0 +1,55 @@
+package dataapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	"github.com/rdkcentral/xconfwebconfig/db"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
+	"github.com/rdkcentral/xconfwebconfig/shared/firmware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRuleExports(t *testing.T) {
+	sc, err := common.NewServerConfig(GetTestConfig())
+	assert.NoError(t, err)
+	originalXc, originalWs := Xc, Ws
+	defer func() { Xc, Ws = originalXc, originalWs }()
+	if db.GetDatabaseClient() != nil {
+		t.Skip("the diff runs without a database")
+	}
+	oldExport := simulationExport(t)
+	assert.NoError(t, SetupRuleSimulation(sc.Config, oldExport))
+
+	newExport := simulationExport(t)
+	rule := &firmware.FirmwareRule{}
+	assert.NoError(t, json.Unmarshal(newExport[db.TABLE_FIRMWARE_RULE]["rule1"], rule))
+	newRule, err := re.ParseRule(`model IS "X1" AND env IN ["QA", "DEV"]`)
+	assert.NoError(t, err)
+	rule.Rule = *newRule
+	rule.ApplicableAction.ConfigId = "config2"
+	newExport[db.TABLE_FIRMWARE_RULE]["rule1"], err = json.Marshal(rule)
+	assert.NoError(t, err)
+
+	corpus := []map[string]string{
+		{"eStbMac": "AA:BB:CC:DD:EE:01", "model": "x1", "env": "qa"},
+		{"eStbMac": "AA:BB:CC:DD:EE:02", "model": "X1", "env": "dev"},
+		{"eStbMac": "AA:BB:CC:DD:EE:03", "model": "X2", "env": "QA"},
+	}
+	diff, err := DiffRuleExports(db.TABLE_FIRMWARE_RULE, oldExport, newExport, corpus)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(diff.Added))
+	assert.Equal(t, 0, len(diff.Removed))
+	assert.Equal(t, 1, len(diff.Modified))
+
+	change := diff.Modified[0]
+	assert.Equal(t, "X1 QA", change.Name)
+	assert.Equal(t, &re.ConditionDiff{Removed: []string{`env IS "QA"`}, Added: []string{`env IN ["DEV", "QA"]`}}, change.Conditions)
+	assert.Equal(t, []re.FieldChange{{Field: "applicableAction.configId", Old: "config1", New: "config2"}}, change.Fields)
+	assert.Equal(t, &re.RuleImpact{Devices: 2, Segments: map[string]int{"model=X1 env=QA": 1, "model=X1 env=DEV": 1}}, diff.Impact)
+
+	diff, err = DiffRuleExports(db.TABLE_FIRMWARE_CONFIG, oldExport, newExport, nil)
+	assert.Nil(t, diff)
+	assert.ErrorContains(t, err, "does not hold rules")
+}
@@ -0,0 +1,299 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package dataapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	dcmlogupload "github.com/rdkcentral/xconfwebconfig/dataapi/dcm/logupload"
+	dataef "github.com/rdkcentral/xconfwebconfig/dataapi/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/dataapi/featurecontrol"
+	"github.com/rdkcentral/xconfwebconfig/db"
+	"github.com/rdkcentral/xconfwebconfig/shared"
+	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+
+	conf "github.com/go-akka/configuration"
+	log "github.com/sirupsen/logrus"
+)
+
+// SimulationNone is the result of a device that gets no features or DCM settings
+const SimulationNone = "none"
+
+// SimulationCount is the number of devices of a corpus that get one result, and the rules that produced it
+type SimulationCount struct {
+	Result  string         `json:"result"`
+	Devices int            `json:"devices"`
+	Rules   map[string]int `json:"rules,omitempty"`
+}
+
+// SimulationReport counts the devices of a corpus by the firmware config, feature set and DCM settings they get,
+// each list is sorted by the number of devices
+type SimulationReport struct {
+	Devices  int               `json:"devices"`
+	Firmware []SimulationCount `json:"firmware"`
+	Features []SimulationCount `json:"features"`
+	Dcm      []SimulationCount `json:"dcm"`
+}
+
+// RuleSimulator evaluates the firmware, feature control and DCM rules of the cache against device contexts
+// without calling any external service, and aggregates the results. It is not safe for concurrent use.
+type RuleSimulator struct {
+	// Now is the time of the firmware contexts without a time, the current time when zero
+	Now time.Time
+
+	firmwareRuleBase *dataef.EstbFirmwareRuleBase
+	featureRuleBase  *featurecontrol.FeatureControlRuleBase
+	dcmRuleBase      *dcmlogupload.LogUploadRuleBase
+
+	devices  int
+	firmware map[string]*SimulationCount
+	features map[string]*SimulationCount
+	dcm      map[string]*SimulationCount
+}
+
+func NewRuleSimulator() *RuleSimulator {
+	return &RuleSimulator{
+		firmwareRuleBase: dataef.NewEstbFirmwareRuleBaseDefault(),
+		featureRuleBase:  featurecontrol.NewFeatureControlRuleBase(),
+		dcmRuleBase:      dcmlogupload.NewLogUploadRuleBase(),
+		firmware:         map[string]*SimulationCount{},
+		features:         map[string]*SimulationCount{},
+		dcm:              map[string]*SimulationCount{},
+	}
+}
+
+// SetupRuleSimulation prepares a process without a database for the simulation, the cache is filled from export
+func SetupRuleSimulation(config *conf.Config, export db.CacheExport) error {
+	xc := GetXconfConfigs(config)
+	WebServerInjection(nil, xc)
+	if err := setupRulesEngine(xc); err != nil {
+		return err
+	}
+	db.ConfigInjection(config)
+	RegisterTables()
+	return db.GetCacheManager().LoadCacheExport(export)
+}
+
+// SimulateCorpus simulates every device of an NDJSON corpus, each line is a JSON object of the query params of a request
+func (s *RuleSimulator) SimulateCorpus(r io.Reader) error {
+	return forEachCorpusContext(r, s.Simulate)
+}
+
+// ReadCorpus returns the device contexts of an NDJSON corpus
+func ReadCorpus(r io.Reader) ([]map[string]string, error) {
+	contexts := []map[string]string{}
+	err := forEachCorpusContext(r, func(context map[string]string) {
+		contexts = append(contexts, context)
+	})
+	return contexts, err
+}
+
+func forEachCorpusContext(r io.Reader, fn func(map[string]string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		context, err := parseCorpusContext([]byte(line))
+		if err != nil {
+			return fmt.Errorf("invalid device context at line %v: %v", lineNumber, err)
+		}
+		fn(context)
+	}
+	return scanner.Err()
+}
+
+// parseCorpusContext converts the query params of a corpus line to a context, lists are joined with a comma like repeated query params
+func parseCorpusContext(line []byte) (map[string]string, error) {
+	params := map[string]interface{}{}
+	if err := json.Unmarshal(line, &params); err != nil {
+		return nil, err
+	}
+	context := make(map[string]string, len(params))
+	for key, value := range params {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			context[key] = v
+		case []interface{}:
+			values := make([]string, len(v))
+			for i, item := range v {
+				values[i] = fmt.Sprint(item)
+			}
+			context[key] = strings.Join(values, ",")
+		default:
+			context[key] = fmt.Sprint(v)
+		}
+	}
+	return context, nil
+}
+
+// Simulate evaluates the firmware, feature control and DCM rules for one device context, context is not changed
+func (s *RuleSimulator) Simulate(context map[string]string) {
+	s.devices++
+	s.simulateFirmware(copyContext(context))
+	s.simulateFeatures(copyContext(context))
+	s.simulateDcm(copyContext(context))
+}
+
+func (s *RuleSimulator) simulateFirmware(contextMap map[string]string) {
+	fields := log.Fields{}
+	now := s.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	normalizeFirmwareSimulationContext(contextMap, now, fields)
+	convertedContext := sharedef.GetContextConverted(contextMap)
+	result, _ := s.firmwareRuleBase.Eval(contextMap, convertedContext, contextMap[common.APPLICATION_TYPE], fields)
+
+	var ruleNames []string
+	if result.MatchedRule != nil {
+		ruleNames = append(ruleNames, result.MatchedRule.Name)
+	}
+	switch {
+	case result.Blocked:
+		countSimulationResult(s.firmware, "blocked: "+result.Description, ruleNames)
+	case result.FirmwareConfig == nil:
+		countSimulationResult(s.firmware, SimulationNone+": "+result.Description, ruleNames)
+	default:
+		config := result.FirmwareConfig
+		countSimulationResult(s.firmware, fmt.Sprintf("%s %s", config.GetFirmwareVersion(), config.GetFirmwareFilename()), ruleNames)
+	}
+}
+
+func (s *RuleSimulator) simulateFeatures(contextMap map[string]string) {
+	normalizeSimulationContext(contextMap)
+	featureControl, appliedRules := s.featureRuleBase.Eval(contextMap, contextMap[common.APPLICATION_TYPE], log.Fields{})
+
+	features := []string{}
+	for _, feature := range featureControl.FeatureResponses {
+		features = append(features, fmt.Sprint(feature["name"]))
+	}
+	sort.Strings(features)
+	result := SimulationNone
+	if len(features) > 0 {
+		result = strings.Join(features, ", ")
+	}
+	ruleNames := []string{}
+	for _, rule := range appliedRules {
+		ruleNames = append(ruleNames, rule.Name)
+	}
+	countSimulationResult(s.features, result, ruleNames)
+}
+
+func (s *RuleSimulator) simulateDcm(contextMap map[string]string) {
+	normalizeSimulationContext(contextMap)
+	settings := s.dcmRuleBase.Eval(contextMap, log.Fields{})
+	if settings == nil {
+		countSimulationResult(s.dcm, SimulationNone, nil)
+		return
+	}
+	ruleNames := []string{}
+	for _, name := range settings.RuleIDs {
+		ruleNames = append(ruleNames, name)
+	}
+	result := fmt.Sprintf("deviceSettings=%s logUploadSettings=%s vodSettings=%s", settings.GroupName, settings.LusName, settings.VodSettingsName)
+	countSimulationResult(s.dcm, result, ruleNames)
+}
+
+// normalizeFirmwareSimulationContext applies the normalization of the firmware handler, without the context of external services
+func normalizeFirmwareSimulationContext(contextMap map[string]string, now time.Time, fields log.Fields) {
+	if contextMap[common.APPLICATION_TYPE] == "" {
+		contextMap[common.APPLICATION_TYPE] = shared.STB
+	}
+	GetFirstElementsInContextMap(contextMap)
+	if contextMap[common.TIME] == "" {
+		GetTimeInLocalTimezone(now.UTC(), contextMap)
+	}
+	NormalizeEstbFirmwareContext(Ws, nil, contextMap, true, false, fields)
+}
+
+// normalizeSimulationContext applies the normalization of the feature control and DCM handlers
+func normalizeSimulationContext(contextMap map[string]string) {
+	if contextMap[common.APPLICATION_TYPE] == "" {
+		contextMap[common.APPLICATION_TYPE] = shared.STB
+	}
+	NormalizeCommonContext(contextMap, common.ESTB_MAC_ADDRESS, common.ECM_MAC_ADDRESS)
+	if contextMap[common.APPLICATION_TYPE] == shared.STB {
+		if appType := GetApplicationTypeFromPartnerId(contextMap[common.PARTNER_ID]); appType != "" {
+			contextMap[common.APPLICATION_TYPE] = appType
+		}
+	}
+}
+
+func countSimulationResult(counts map[string]*SimulationCount, result string, ruleNames []string) {
+	count, ok := counts[result]
+	if !ok {
+		count = &SimulationCount{Result: result, Rules: map[string]int{}}
+		counts[result] = count
+	}
+	count.Devices++
+	for _, name := range ruleNames {
+		count.Rules[name]++
+	}
+}
+
+// Report returns the aggregated results of the devices simulated so far
+func (s *RuleSimulator) Report() *SimulationReport {
+	return &SimulationReport{
+		Devices:  s.devices,
+		Firmware: sortedSimulationCounts(s.firmware),
+		Features: sortedSimulationCounts(s.features),
+		Dcm:      sortedSimulationCounts(s.dcm),
+	}
+}
+
+func sortedSimulationCounts(counts map[string]*SimulationCount) []SimulationCount {
+	result := make([]SimulationCount, 0, len(counts))
+	for _, count := range counts {
+		c := SimulationCount{Result: count.Result, Devices: count.Devices}
+		if len(count.Rules) > 0 {
+			c.Rules = make(map[string]int, len(count.Rules))
+			for name, devices := range count.Rules {
+				c.Rules[name] = devices
+			}
+		}
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Devices != result[j].Devices {
+			return result[i].Devices > result[j].Devices
+		}
+		return result[i].Result < result[j].Result
+	})
+	return result
+}
+
+func copyContext(context map[string]string) map[string]string {
+	result := make(map[string]string, len(context))
+	for k, v := range context {
+		result[k] = v
+	}
+	return result
+}
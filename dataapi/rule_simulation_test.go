@@ -0,0 +1,284 @@
+package dataapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+	dataef "github.com/rdkcentral/xconfwebconfig/dataapi/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/db"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
+	"github.com/rdkcentral/xconfwebconfig/shared"
+	sharedef "github.com/rdkcentral/xconfwebconfig/shared/estbfirmware"
+	"github.com/rdkcentral/xconfwebconfig/shared/firmware"
+	"github.com/rdkcentral/xconfwebconfig/shared/rfc"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func simulationExport(t *testing.T) db.CacheExport {
+	parse := func(text string) *re.Rule {
+		rule, err := re.ParseRule(text)
+		assert.NoError(t, err)
+		return rule
+	}
+	entries := map[string]map[string]interface{}{
+		db.TABLE_FIRMWARE_RULE_TEMPLATE: {
+			firmware.ENV_MODEL_RULE: &firmware.FirmwareRuleTemplate{
+				ID:               firmware.ENV_MODEL_RULE,
+				Rule:             *parse(`model IS "" AND env IS ""`),
+				ApplicableAction: &firmware.TemplateApplicableAction{Type: ".RuleAction", ActionType: firmware.RULE_TEMPLATE},
+				Priority:         1,
+			},
+		},
+		db.TABLE_FIRMWARE_CONFIG: {
+			"config1": &sharedef.FirmwareConfig{
+				ID:                "config1",
+				Description:       "config1",
+				SupportedModelIds: []string{"X1"},
+				FirmwareFilename:  "X1_2.0.bin",
+				FirmwareVersion:   "X1_2.0",
+				ApplicationType:   shared.STB,
+			},
+		},
+		db.TABLE_FIRMWARE_RULE: {
+			"rule1": &firmware.FirmwareRule{
+				ID:               "rule1",
+				Name:             "X1 QA",
+				Type:             firmware.ENV_MODEL_RULE,
+				Rule:             *parse(`model IS "X1" AND env IS "QA"`),
+				ApplicableAction: &firmware.ApplicableAction{Type: ".RuleAction", ActionType: firmware.RULE, ConfigId: "config1"},
+				Active:           true,
+				ApplicationType:  shared.STB,
+			},
+		},
+		db.TABLE_XCONF_FEATURE: {
+			"feature1": &rfc.Feature{ID: "feature1", Name: "featureA", FeatureName: "featureA", Enable: true, ApplicationType: shared.STB},
+		},
+		db.TABLE_FEATURE_CONTROL_RULE: {
+			"featureRule1": &rfc.FeatureRule{
+				Id:              "featureRule1",
+				Name:            "X1 features",
+				Rule:            parse(`model IS "X1"`),
+				FeatureIds:      []string{"feature1"},
+				ApplicationType: shared.STB,
+			},
+		},
+	}
+
+	return newCacheExport(t, entries)
+}
+
+func newCacheExport(t *testing.T, entries map[string]map[string]interface{}) db.CacheExport {
+	export := db.CacheExport{}
+	for tableName, values := range entries {
+		export[tableName] = map[string]json.RawMessage{}
+		for key, value := range values {
+			data, err := json.Marshal(value)
+			assert.NoError(t, err)
+			export[tableName][key] = data
+		}
+	}
+	return export
+}
+
+func TestRuleSimulation(t *testing.T) {
+	sc, err := common.NewServerConfig(GetTestConfig())
+	assert.NoError(t, err)
+	originalXc, originalWs := Xc, Ws
+	defer func() { Xc, Ws = originalXc, originalWs }()
+	if db.GetDatabaseClient() != nil {
+		t.Skip("the simulation runs without a database")
+	}
+	assert.NoError(t, SetupRuleSimulation(sc.Config, simulationExport(t)))
+
+	corpus := strings.Join([]string{
+		`{"eStbMac": "AA:BB:CC:DD:EE:01", "estbMacAddress": "AA:BB:CC:DD:EE:01", "model": "x1", "env": "qa"}`,
+		``,
+		`{"eStbMac": "AA:BB:CC:DD:EE:02", "estbMacAddress": "AA:BB:CC:DD:EE:02", "model": "X1", "env": ["QA", "DEV"]}`,
+		`{"eStbMac": "AA:BB:CC:DD:EE:03", "estbMacAddress": "AA:BB:CC:DD:EE:03", "model": "X1", "env": "DEV"}`,
+		`{"eStbMac": "AA:BB:CC:DD:EE:04", "estbMacAddress": "AA:BB:CC:DD:EE:04", "model": "X2", "env": "QA"}`,
+	}, "\n")
+	simulator := NewRuleSimulator()
+	assert.NoError(t, simulator.SimulateCorpus(strings.NewReader(corpus)))
+	report := simulator.Report()
+
+	assert.Equal(t, 4, report.Devices)
+	assert.Equal(t, 2, len(report.Firmware))
+	assert.Equal(t, SimulationCount{Result: "X1_2.0 X1_2.0.bin", Devices: 2, Rules: map[string]int{"X1 QA": 2}}, report.Firmware[0])
+	assert.Equal(t, 2, report.Firmware[1].Devices)
+	assert.True(t, strings.HasPrefix(report.Firmware[1].Result, SimulationNone))
+
+	assert.Equal(t, []SimulationCount{
+		{Result: "featureA", Devices: 3, Rules: map[string]int{"X1 features": 3}},
+		{Result: SimulationNone, Devices: 1},
+	}, report.Features)
+	assert.Equal(t, []SimulationCount{{Result: SimulationNone, Devices: 4}}, report.Dcm)
+
+	export, err := db.GetCacheManager().ExportCache(db.TABLE_FIRMWARE_CONFIG)
+	assert.NoError(t, err)
+	assert.Contains(t, string(export[db.TABLE_FIRMWARE_CONFIG]["config1"]), `"firmwareVersion":"X1_2.0"`)
+}
+
+type memoryCanaryCohortDao struct {
+	devices []*sharedef.CanaryDevice
+}
+
+func (d *memoryCanaryCohortDao) GetCanaryDevice(cohortId string, mac string) (*sharedef.CanaryDevice, error) {
+	for _, device := range d.devices {
+		if device.CohortId == cohortId && device.ID == mac {
+			return device, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found", mac)
+}
+
+func (d *memoryCanaryCohortDao) GetCanaryCohort(cohortId string) ([]*sharedef.CanaryDevice, error) {
+	cohort := []*sharedef.CanaryDevice{}
+	for _, device := range d.devices {
+		if device.CohortId == cohortId {
+			cohort = append(cohort, device)
+		}
+	}
+	return cohort, nil
+}
+
+func (d *memoryCanaryCohortDao) SetCanaryDevice(device *sharedef.CanaryDevice) error {
+	d.devices = append(d.devices, device)
+	return nil
+}
+
+// upgradeGraphExport has an ENV_MODEL_RULE of X1 QA devices that serves lkg, the last known good config, with
+// a target config as canary target, and the upgrade graph X1_1.0 -> X1_2.0 -> X1_3.0 of X1
+func upgradeGraphExport(t *testing.T) db.CacheExport {
+	parse := func(text string) *re.Rule {
+		rule, err := re.ParseRule(text)
+		assert.NoError(t, err)
+		return rule
+	}
+	config := func(id string, version string) *sharedef.FirmwareConfig {
+		return &sharedef.FirmwareConfig{
+			ID:                id,
+			Description:       id,
+			SupportedModelIds: []string{"X1"},
+			FirmwareFilename:  version + ".bin",
+			FirmwareVersion:   version,
+			ApplicationType:   shared.STB,
+		}
+	}
+	return newCacheExport(t, map[string]map[string]interface{}{
+		db.TABLE_FIRMWARE_RULE_TEMPLATE: {
+			firmware.ENV_MODEL_RULE: &firmware.FirmwareRuleTemplate{
+				ID:               firmware.ENV_MODEL_RULE,
+				Rule:             *parse(`model IS "" AND env IS ""`),
+				ApplicableAction: &firmware.TemplateApplicableAction{Type: ".RuleAction", ActionType: firmware.RULE_TEMPLATE},
+				Priority:         1,
+			},
+		},
+		db.TABLE_FIRMWARE_CONFIG: {
+			"lkg":    config("lkg", "X1_1.0"),
+			"hop":    config("hop", "X1_2.0"),
+			"target": config("target", "X1_3.0"),
+		},
+		db.TABLE_FIRMWARE_RULE: {
+			"rule1": &firmware.FirmwareRule{
+				ID:   "rule1",
+				Name: "X1 QA",
+				Type: firmware.ENV_MODEL_RULE,
+				Rule: *parse(`model IS "X1" AND env IS "QA"`),
+				ApplicableAction: &firmware.ApplicableAction{
+					Type:          ".RuleAction",
+					ActionType:    firmware.RULE,
+					ConfigId:      "lkg",
+					Active:        true,
+					ConfigEntries: []firmware.ConfigEntry{{ConfigId: "target", StartPercentRange: -1, EndPercentRange: -1}},
+				},
+				Active:          true,
+				ApplicationType: shared.STB,
+			},
+		},
+		db.TABLE_FIRMWARE_UPGRADE_GRAPH: {
+			"X1": &sharedef.FirmwareUpgradeGraph{ID: "X1", Edges: []sharedef.UpgradeEdge{
+				{FromVersion: "X1_1.0", ConfigId: "hop"},
+				{FromVersion: "X1_2.0", ConfigId: "target"},
+			}},
+		},
+	})
+}
+
+func evalUpgradeGraphContext(t *testing.T, ruleBase *dataef.EstbFirmwareRuleBase, mac string, version string) *dataef.EvaluationResult {
+	contextMap := map[string]string{
+		common.ESTB_MAC:         mac,
+		common.MODEL:            "X1",
+		common.ENV:              "QA",
+		common.FIRMWARE_VERSION: version,
+		common.APPLICATION_TYPE: shared.STB,
+	}
+	result, err := ruleBase.Eval(contextMap, sharedef.GetContextConverted(contextMap), shared.STB, log.Fields{})
+	assert.NoError(t, err)
+	return result
+}
+
+func TestCanaryJoinsOnlyWithTheCanaryTarget(t *testing.T) {
+	sc, err := common.NewServerConfig(GetTestConfig())
+	assert.NoError(t, err)
+	originalXc, originalWs := Xc, Ws
+	defer func() { Xc, Ws = originalXc, originalWs }()
+	if db.GetDatabaseClient() != nil {
+		t.Skip("the evaluation runs without a database")
+	}
+	assert.NoError(t, SetupRuleSimulation(sc.Config, upgradeGraphExport(t)))
+
+	dao := &memoryCanaryCohortDao{}
+	ruleBase := dataef.NewEstbFirmwareRuleBaseDefault()
+	ruleBase.SetCanaryScheduler(dataef.NewCanaryScheduler(&dataef.CanarySettings{MaxSize: 10, DistributionPercentage: 100, StartTime: -1, EndTime: -1}, dao))
+	// the upgrade graph serves the hop to the canary target, the device does not join the cohort
+	result := evalUpgradeGraphContext(t, ruleBase, "AA:BB:CC:DD:EE:01", "X1_1.0")
+	assert.False(t, result.Blocked)
+	assert.Equal(t, "X1_2.0", result.FirmwareConfig.GetFirmwareVersion())
+	assert.Empty(t, dao.devices)
+
+	// the last hop is the canary target
+	result = evalUpgradeGraphContext(t, ruleBase, "AA:BB:CC:DD:EE:02", "X1_2.0")
+	assert.False(t, result.Blocked)
+	assert.Equal(t, "X1_3.0", result.FirmwareConfig.GetFirmwareVersion())
+	assert.Equal(t, 1, len(dao.devices))
+	assert.Equal(t, "AA:BB:CC:DD:EE:02", dao.devices[0].ID)
+}
+
+func TestUpgradeGraphBlocksWithoutPath(t *testing.T) {
+	sc, err := common.NewServerConfig(GetTestConfig())
+	assert.NoError(t, err)
+	originalXc, originalWs := Xc, Ws
+	defer func() { Xc, Ws = originalXc, originalWs }()
+	if db.GetDatabaseClient() != nil {
+		t.Skip("the evaluation runs without a database")
+	}
+	assert.NoError(t, SetupRuleSimulation(sc.Config, upgradeGraphExport(t)))
+	ruleBase := dataef.NewEstbFirmwareRuleBaseDefault()
+
+	// the graph has no edge back to lkg
+	result := evalUpgradeGraphContext(t, ruleBase, "AA:BB:CC:DD:EE:01", "X1_2.0")
+	assert.True(t, result.Blocked)
+	assert.Nil(t, result.FirmwareConfig)
+	assert.Equal(t, "no upgrade path from X1_2.0 to X1_1.0", result.Description)
+
+	// devices running a version the graph does not have are not limited by it
+	result = evalUpgradeGraphContext(t, ruleBase, "AA:BB:CC:DD:EE:02", "X1_0.9")
+	assert.False(t, result.Blocked)
+	assert.Equal(t, "X1_1.0", result.FirmwareConfig.GetFirmwareVersion())
+}
+
+func TestRuleSimulationWithInvalidCorpus(t *testing.T) {
+	simulator := NewRuleSimulator()
+	err := simulator.SimulateCorpus(strings.NewReader("{\"model\": \"X1\"\n"))
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestParseCorpusContext(t *testing.T) {
+	context, err := parseCorpusContext([]byte(`{"model": "X1", "env": ["QA", "DEV"], "timeZoneOffset": null, "capabilities": 2}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"model": "X1", "env": "QA,DEV", "capabilities": "2"}, context)
+}
@@ -0,0 +1,107 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CacheExport is a snapshot of cached tables as the JSON of each entry, by table name and row key
+type CacheExport map[string]map[string]json.RawMessage
+
+// ExportCache returns the cached entries of the given tables, or of all cached tables when none is given
+func (cm CacheManager) ExportCache(tableNames ...string) (CacheExport, error) {
+	if len(tableNames) == 0 {
+		for tableName := range cm.cacheMap {
+			tableNames = append(tableNames, tableName)
+		}
+		sort.Strings(tableNames)
+	}
+
+	export := CacheExport{}
+	for _, tableName := range tableNames {
+		cache, err := cm.getCache(tableName)
+		if err != nil {
+			return nil, err
+		}
+		entries := map[string]json.RawMessage{}
+		for key, value := range cache.GetAll() {
+			jsonData, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to export '%v' of table '%v': %v", key, tableName, err)
+			}
+			entries[fmt.Sprint(key)] = jsonData
+		}
+		export[tableName] = entries
+	}
+	return export, nil
+}
+
+// LoadCacheExport replaces the cached entries of the tables in the export, the database is not changed.
+// It lets tools evaluate rules against an export without a database, it must not run concurrently
+// with other cache operations.
+func (cm CacheManager) LoadCacheExport(export CacheExport) error {
+	for tableName := range export {
+		tableInfo, err := GetTableInfo(tableName)
+		if err != nil {
+			return err
+		}
+		if !tableInfo.CacheData {
+			return fmt.Errorf("table '%v' is not cached", tableName)
+		}
+		values, err := export.Entities(tableName)
+		if err != nil {
+			return err
+		}
+
+		// a new cache, the table may have been registered after the cache manager was created and the
+		// invalidation of the entries of a cache is asynchronous, it would drop entries put again
+		cache := cm.newLoadingCache(tableName)
+		if cacheInfo, ok := cm.cacheMap[tableName]; ok {
+			cacheInfo.cache.Close()
+		}
+		cm.cacheMap[tableName] = CacheInfo{cache: cache, Stats: &CacheStats{}}
+		for key, obj := range values {
+			cache.Put(key, obj)
+		}
+		cm.ApplicationCacheDeleteAll(tableName)
+		log.Debugf("'%v' loaded %v entries from export", tableName, len(values))
+	}
+	return nil
+}
+
+// Entities returns the entries of a table in the export as the models of the table, by row key
+func (e CacheExport) Entities(tableName string) (map[string]interface{}, error) {
+	tableInfo, err := GetTableInfo(tableName)
+	if err != nil {
+		return nil, err
+	}
+	entities := make(map[string]interface{}, len(e[tableName]))
+	for key, jsonData := range e[tableName] {
+		obj := tableInfo.ConstructorFunc()
+		if err := json.Unmarshal(jsonData, obj); err != nil {
+			return nil, fmt.Errorf("failed to load '%v' of table '%v': %v", key, tableName, err)
+		}
+		entities[key] = obj
+	}
+	return entities, nil
+}
@@ -245,24 +245,7 @@ func GetCacheManager() *CacheManager {
 				continue // No caching for this table
 			}
 
-			// Generate a load function for the table
-			loadFn := generateLoadFunction(tableName)
-
-			// Create a LoadingCache for each table
-			var loadingCache cache.LoadingCache
-			if cacheManager.settings.reloadCacheEntries {
-				freshAfterWrite := getDuration(
-					cacheManager.settings.reloadCacheEntriesTimeout, cacheManager.settings.reloadCacheEntriesTimeUnit)
-				loadingCache = cache.NewLoadingCache(loadFn,
-					cache.WithMaximumSize(0),                     // Unlimited number of entries in the cache.
-					cache.WithRefreshAfterWrite(freshAfterWrite), // Expire entries after specified duration since last created.
-				)
-			} else {
-				loadingCache = cache.NewLoadingCache(loadFn,
-					cache.WithMaximumSize(0), // Unlimited number of entries in the cache.
-				)
-			}
-			cacheManager.cacheMap[tableName] = CacheInfo{cache: loadingCache, Stats: &CacheStats{}}
+			cacheManager.cacheMap[tableName] = CacheInfo{cache: cacheManager.newLoadingCache(tableName), Stats: &CacheStats{}}
 		}
 
 		// Initialize group service feature tags cache
@@ -296,6 +279,24 @@ func GetCacheManager() *CacheManager {
 	return &cacheManager
 }
 
+// newLoadingCache creates the LoadingCache of a table
+func (cm CacheManager) newLoadingCache(tableName string) cache.LoadingCache {
+	// Generate a load function for the table
+	loadFn := generateLoadFunction(tableName)
+
+	if cm.settings.reloadCacheEntries {
+		freshAfterWrite := getDuration(
+			cm.settings.reloadCacheEntriesTimeout, cm.settings.reloadCacheEntriesTimeUnit)
+		return cache.NewLoadingCache(loadFn,
+			cache.WithMaximumSize(0),                     // Unlimited number of entries in the cache.
+			cache.WithRefreshAfterWrite(freshAfterWrite), // Expire entries after specified duration since last created.
+		)
+	}
+	return cache.NewLoadingCache(loadFn,
+		cache.WithMaximumSize(0), // Unlimited number of entries in the cache.
+	)
+}
+
 // SetCacheChangeNotifier sets a notifier to be called on cache changed events
 func (cm *CacheManager) SetCacheChangeNotifier(notifier CacheChangeNotifier) {
 	if notifier == nil {
@@ -733,6 +734,11 @@ func generateLoadFunction(tableName string) func(k cache.Key) (cache.Value, erro
 			return nil, err
 		}
 
+		// Caches filled by LoadCacheExport have no database behind them
+		if GetDatabaseClient() == nil {
+			return nil, fmt.Errorf("no database to load '%v' of table '%v' from", k, name)
+		}
+
 		// Use the appropriate DAO based on compression policy
 		if tableInfo.IsCompressAndSplit() {
 			// return GetCompressingDataDao().GetOne(name, k.(string))
@@ -809,6 +815,12 @@ func refreshTables(tableNames []string) {
 	}
 }
 
+// IsApplicationCacheEnabled returns true when values derived from cached tables can be kept
+// in the application cache until the table changes
+func (cm CacheManager) IsApplicationCacheEnabled() bool {
+	return cm.applicationCacheEnabled
+}
+
 // ApplicationCacheGet value for the specified table and key
 func (cm CacheManager) ApplicationCacheGet(tableName string, key string) interface{} {
 	if !cm.applicationCacheEnabled {
@@ -128,6 +128,8 @@ type DatabaseClient interface {
 	SetRfcPenetrationMetrics(pMetrics *RfcPenetrationMetrics, is304FromPrecook bool) error
 	GetRfcPenetrationMetrics(string) (*RfcPenetrationMetrics, error)
 	UpdateFwPenetrationMetrics(map[string]string) error
+	SetFirmwareOutcome(outcome *FirmwareOutcome, ttl int) error
+	GetFirmwareOutcomes(model string, fwVersion string) ([]*FirmwareOutcome, error)
 	GetEstbIp(string) (string, error)
 	GetSecurityTokenFields(string) (*SecurityTokenDeviceInfo, error)
 
@@ -43,6 +43,11 @@ const (
 	RfcEstbIpColumnValue               = "rfc_estb_ip"
 	RfcTsColumnValue                   = "rfc_ts"
 	RfcPostProcColumnValue             = "rfc_post_proc"
+	FirmwareOutcomesTable              = "FirmwareOutcomes"
+	StageColumnValue                   = "stage"
+	SuccessColumnValue                 = "success"
+	ReasonColumnValue                  = "reason"
+	TsColumnValue                      = "ts"
 )
 
 // PenetrationMetrics struct
@@ -60,6 +65,19 @@ type FwPenetrationMetrics struct {
 	RecoveryCertExpiry      string
 }
 
+// FirmwareOutcome is a reported download or flash outcome of a firmware version, the FirmwareOutcomes
+// table keeps the last outcome of each device for a model and version
+type FirmwareOutcome struct {
+	EstbMac   string `json:"estbMac"`
+	Model     string `json:"model"`
+	Partner   string `json:"partnerId,omitempty"`
+	FwVersion string `json:"firmwareVersion"`
+	Stage     string `json:"stage"`
+	Success   bool   `json:"success"`
+	Reason    string `json:"reason,omitempty"`
+	Ts        int64  `json:"ts"`
+}
+
 type SecurityTokenDeviceInfo struct {
 	Partner                 string
 	Model                   string
@@ -490,3 +508,66 @@ func (c *CassandraClient) GetEstbIp(estbMac string) (string, error) {
 
 	return estbIp, nil
 }
+
+// SetFirmwareOutcome stores the outcome of a device, replacing its previous outcome for the same model and version.
+// The outcome expires after ttl seconds, 0 keeps it
+func (c *CassandraClient) SetFirmwareOutcome(outcome *FirmwareOutcome, ttl int) error {
+	columns := []string{
+		ModelColumnValue,
+		FwVersionColumnValue,
+		EstbMacColumnValue,
+		PartnerColumnValue,
+		StageColumnValue,
+		SuccessColumnValue,
+		ReasonColumnValue,
+		TsColumnValue,
+	}
+	values := []interface{}{
+		outcome.Model,
+		outcome.FwVersion,
+		outcome.EstbMac,
+		outcome.Partner,
+		outcome.Stage,
+		outcome.Success,
+		outcome.Reason,
+		outcome.Ts,
+	}
+	stmt := fmt.Sprintf(`INSERT INTO "%s"(%v) VALUES(%v)`, FirmwareOutcomesTable, GetColumnsStr(columns), GetValuesStr(len(columns)))
+	if ttl > 0 {
+		stmt = fmt.Sprintf(`%s USING TTL %d`, stmt, ttl)
+	}
+
+	c.ConcurrentQueries <- true
+	defer func() { <-c.ConcurrentQueries }()
+	return c.Query(stmt, values...).Exec()
+}
+
+// GetFirmwareOutcomes returns the last outcome of each device reported for a model and version
+func (c *CassandraClient) GetFirmwareOutcomes(model string, fwVersion string) ([]*FirmwareOutcome, error) {
+	c.ConcurrentQueries <- true
+	defer func() { <-c.ConcurrentQueries }()
+
+	stmt := fmt.Sprintf(`SELECT * FROM "%s" WHERE %s=? AND %s=?`, FirmwareOutcomesTable, ModelColumnValue, FwVersionColumnValue)
+	iter := c.Query(stmt, model, fwVersion).Iter()
+	outcomes := []*FirmwareOutcome{}
+	for {
+		row := util.Dict{}
+		if !iter.MapScan(row) {
+			break
+		}
+		outcome := &FirmwareOutcome{Model: model, FwVersion: fwVersion}
+		outcome.EstbMac, _ = row[EstbMacColumnValue].(string)
+		outcome.Partner, _ = row[PartnerColumnValue].(string)
+		outcome.Stage, _ = row[StageColumnValue].(string)
+		outcome.Success, _ = row[SuccessColumnValue].(bool)
+		outcome.Reason, _ = row[ReasonColumnValue].(string)
+		if ts, ok := row[TsColumnValue].(time.Time); ok {
+			outcome.Ts = util.GetTimestamp(ts)
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
@@ -49,6 +49,9 @@ const (
 	TABLE_FIRMWARE_RULE_TEMPLATE = "FirmwareRuleTemplate"
 	TABLE_FIRMWARE_CONFIG        = "FirmwareConfig"
 	TABLE_SINGLETON_FILTER_VALUE = "SingletonFilterValue"
+	TABLE_FIRMWARE_UPGRADE_GRAPH = "FirmwareUpgradeGraph"
+	TABLE_LOCATION_HEALTH        = "LocationHealth"
+	TABLE_MAINTENANCE_WINDOW     = "MaintenanceWindowPolicy"
 
 	// RFC
 	TABLE_FEATURE_CONTROL_RULE = "FeatureControlRule2"
@@ -71,6 +74,7 @@ const (
 	TABLE_APP_SETTINGS       = "AppSettings"
 	TABLE_TAG                = "Tag"
 	TABLE_LOCKS              = "Locks"
+	TABLE_CANARY_COHORT      = "CanaryCohort"
 )
 
 var AllTables = []string{
@@ -94,6 +98,9 @@ var AllTables = []string{
 	TABLE_FIRMWARE_RULE_TEMPLATE,
 	TABLE_FIRMWARE_CONFIG,
 	TABLE_SINGLETON_FILTER_VALUE,
+	TABLE_FIRMWARE_UPGRADE_GRAPH,
+	TABLE_LOCATION_HEALTH,
+	TABLE_MAINTENANCE_WINDOW,
 	TABLE_FEATURE_CONTROL_RULE,
 	TABLE_XCONF_FEATURE,
 	TABLE_XCONF_CHANGE,
@@ -108,6 +115,7 @@ var AllTables = []string{
 	TABLE_LOGS,
 	TABLE_XCONF_CHANGED_KEYS,
 	TABLE_TAG,
+	TABLE_CANARY_COHORT,
 }
 
 // Two possible values for Key2FieldName
@@ -215,4 +223,7 @@ var xconfDaoIdMap = map[string]int32{
 	TABLE_NS_LIST:                1409490260,
 	TABLE_APP_SETTINGS:           1,
 	TABLE_TAG:                    1698455800,
+	TABLE_FIRMWARE_UPGRADE_GRAPH: 1136474610,
+	TABLE_LOCATION_HEALTH:        -1902417735,
+	TABLE_MAINTENANCE_WINDOW:     1462391858,
 }
@@ -366,6 +366,7 @@ func NewMetrics() *AppMetrics {
 		metrics.modelChangedCounter, metrics.partnerChangedCounter, metrics.fwVersionChangedCounter, metrics.fwVersionMismatchCounter, metrics.offeredFwVersionMatchedCounter, metrics.experienceChangedCounter, metrics.accountIdChangedCounter, metrics.ipAddressNotInSameNetworkCounter,
 		metrics.modelChangedIn200Counter, metrics.partnerChangedIn200Counter, metrics.fwVersionChangedIn200Counter, metrics.experienceChangedIn200Counter, metrics.accountIdChangedIn200Counter, metrics.ipAddressNotInSameNetworkIn200Counter,
 		metrics.modelRequestsCounter, metrics.accountServiceEmptyResponseCounter, metrics.grpServiceAccountDataFetchCounter, metrics.unknownIdReceivedCounter, metrics.accountServiceFetchedDataCounter, metrics.grpServiceNotFoundResponseCounter,
+		newRuleStatsCollector(),
 	)
 	return metrics
 }
@@ -0,0 +1,155 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package http
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+
+	"github.com/go-akka/configuration"
+)
+
+// XCONF_SIGNATURE_HEADER has the detached JWS of a signed response body
+const XCONF_SIGNATURE_HEADER = "X-Xconf-Signature"
+
+// ResponseSigner signs response bodies with a detached JWS (RFC 7515, appendix F), the compact serialization
+// without its payload. RSA keys sign with RS256, P-256 keys with ES256 and Ed25519 keys with EdDSA
+type ResponseSigner struct {
+	algorithm       string
+	key             crypto.Signer
+	protectedHeader string
+}
+
+// NewResponseSigner reads the PEM private key of xconfwebconfig.xconf.response_signing_key_file, nil when it is not set
+func NewResponseSigner(conf *configuration.Config) (*ResponseSigner, error) {
+	keyFile := conf.GetString("xconfwebconfig.xconf.response_signing_key_file")
+	if keyFile == "" {
+		return nil, nil
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response signing key file %s: %w", keyFile, err)
+	}
+	return NewResponseSignerFromPEM(keyPEM, conf.GetString("xconfwebconfig.xconf.response_signing_key_id"))
+}
+
+// NewResponseSignerFromPEM returns a signer of a PKCS #8, PKCS #1 or SEC 1 private key, keyId is the kid of the JWS header
+func NewResponseSignerFromPEM(keyPEM []byte, keyId string) (*ResponseSigner, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM private key found")
+	}
+	var key interface{}
+	var err error
+	if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+				return nil, errors.New("unsupported private key, it must be PKCS #8, PKCS #1 or SEC 1")
+			}
+		}
+	}
+
+	signer := &ResponseSigner{}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signer.algorithm, signer.key = "RS256", k
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported elliptic curve %s, it must be P-256", k.Curve.Params().Name)
+		}
+		signer.algorithm, signer.key = "ES256", k
+	case ed25519.PrivateKey:
+		signer.algorithm, signer.key = "EdDSA", k
+	default:
+		return nil, fmt.Errorf("unsupported private key %T", key)
+	}
+
+	header := map[string]string{"alg": signer.algorithm}
+	if keyId != "" {
+		header["kid"] = keyId
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	signer.protectedHeader = base64.RawURLEncoding.EncodeToString(headerJSON)
+	return signer, nil
+}
+
+func (s *ResponseSigner) Algorithm() string {
+	return s.algorithm
+}
+
+func (s *ResponseSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// Sign returns the detached JWS of body, "<protected header>..<signature>"
+func (s *ResponseSigner) Sign(body []byte) (string, error) {
+	signingInput := s.protectedHeader + "." + base64.RawURLEncoding.EncodeToString(body)
+	var signature []byte
+	var err error
+	switch key := s.key.(type) {
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(signingInput))
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		var r, sig *big.Int
+		if r, sig, err = ecdsa.Sign(rand.Reader, key, digest[:]); err == nil {
+			// JWS uses the 64 byte concatenation of r and s instead of ASN.1
+			signature = make([]byte, 64)
+			r.FillBytes(signature[:32])
+			sig.FillBytes(signature[32:])
+		}
+	default:
+		digest := sha256.Sum256([]byte(signingInput))
+		signature, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return "", err
+	}
+	return s.protectedHeader + ".." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// WriteSignedXconfResponse writes a JSON response like WriteXconfResponse, with the detached JWS of data in
+// the X-Xconf-Signature header when signer is not nil. A response that cannot be signed is sent unsigned
+func WriteSignedXconfResponse(w http.ResponseWriter, signer *ResponseSigner, status int, data []byte) error {
+	var err error
+	if signer != nil {
+		var signature string
+		if signature, err = signer.Sign(data); err == nil {
+			w.Header().Set(XCONF_SIGNATURE_HEADER, signature)
+		}
+	}
+	WriteXconfResponse(w, status, data)
+	return err
+}
@@ -0,0 +1,121 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package http
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func responseSignerTestPEM(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// verifyDetachedJWS checks a detached JWS of body against the public key
+func verifyDetachedJWS(t *testing.T, jws string, body []byte, public crypto.PublicKey) bool {
+	parts := strings.Split(jws, ".")
+	assert.Equal(t, 3, len(parts))
+	assert.Equal(t, "", parts[1])
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(t, err)
+	signingInput := []byte(parts[0] + "." + base64.RawURLEncoding.EncodeToString(body))
+	digest := sha256.Sum256(signingInput)
+
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		return len(signature) == 64 && ecdsa.Verify(key, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, signingInput, signature)
+	}
+	return false
+}
+
+func TestResponseSignerSign(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	body := []byte(`{"firmwareVersion":"2.0","firmwareSha256":"abc"}`)
+	for algorithm, keyPEM := range map[string][]byte{
+		"RS256": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+		"ES256": responseSignerTestPEM(t, ecKey),
+		"EdDSA": responseSignerTestPEM(t, edKey),
+	} {
+		signer, err := NewResponseSignerFromPEM(keyPEM, "xconf-1")
+		assert.NoError(t, err, algorithm)
+		assert.Equal(t, algorithm, signer.Algorithm())
+
+		jws, err := signer.Sign(body)
+		assert.NoError(t, err)
+		header, err := base64.RawURLEncoding.DecodeString(strings.Split(jws, ".")[0])
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"alg":"`+algorithm+`","kid":"xconf-1"}`, string(header))
+		assert.True(t, verifyDetachedJWS(t, jws, body, signer.Public()), algorithm)
+		assert.False(t, verifyDetachedJWS(t, jws, []byte(`{"firmwareVersion":"3.0"}`), signer.Public()), algorithm)
+	}
+}
+
+func TestNewResponseSignerFromPEM_Invalid(t *testing.T) {
+	_, err := NewResponseSignerFromPEM([]byte("not a key"), "")
+	assert.Error(t, err)
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	_, err = NewResponseSignerFromPEM(responseSignerTestPEM(t, p384Key), "")
+	assert.Error(t, err)
+}
+
+func TestWriteSignedXconfResponse(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	signer, err := NewResponseSignerFromPEM(responseSignerTestPEM(t, edKey), "")
+	assert.NoError(t, err)
+
+	body := []byte(`{"firmwareVersion":"2.0"}`)
+	recorder := httptest.NewRecorder()
+	assert.NoError(t, WriteSignedXconfResponse(recorder, signer, http.StatusOK, body))
+	assert.Equal(t, body, recorder.Body.Bytes())
+	assert.True(t, verifyDetachedJWS(t, recorder.Header().Get(XCONF_SIGNATURE_HEADER), body, signer.Public()))
+
+	recorder = httptest.NewRecorder()
+	assert.NoError(t, WriteSignedXconfResponse(recorder, nil, http.StatusOK, body))
+	assert.Equal(t, "", recorder.Header().Get(XCONF_SIGNATURE_HEADER))
+}
@@ -0,0 +1,56 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package http
+
+import (
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ruleStatsCollector exports the rule counters of the rulesengine when scraped. The number of
+// rules with their own rule_id label is limited by rulesengine.SetMaxRuleStats.
+type ruleStatsCollector struct {
+	evaluated *prometheus.Desc
+	matched   *prometheus.Desc
+	applied   *prometheus.Desc
+}
+
+func newRuleStatsCollector() *ruleStatsCollector {
+	labels := []string{"app", "rule_type", "rule_id"}
+	return &ruleStatsCollector{
+		evaluated: prometheus.NewDesc("rule_evaluations_total", "A counter for the evaluations of a rule.", labels, nil),
+		matched:   prometheus.NewDesc("rule_matches_total", "A counter for the evaluations of a rule that matched.", labels, nil),
+		applied:   prometheus.NewDesc("rule_applied_total", "A counter for the responses built from a rule.", labels, nil),
+	}
+}
+
+func (c *ruleStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.evaluated
+	ch <- c.matched
+	ch <- c.applied
+}
+
+func (c *ruleStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	app := AppName()
+	for _, stat := range re.GetRuleStats() {
+		ch <- prometheus.MustNewConstMetric(c.evaluated, prometheus.CounterValue, float64(stat.Evaluated), app, stat.RuleType, stat.Id)
+		ch <- prometheus.MustNewConstMetric(c.matched, prometheus.CounterValue, float64(stat.Matched), app, stat.RuleType, stat.Id)
+		ch <- prometheus.MustNewConstMetric(c.applied, prometheus.CounterValue, float64(stat.Applied), app, stat.RuleType, stat.Id)
+	}
+}
@@ -71,6 +71,7 @@ type XconfServer struct {
 	SecurityTokenConfig          *SecurityTokenConfig
 	LogUploadSecurityTokenConfig *SecurityTokenPathConfig
 	FirmwareSecurityTokenConfig  *SecurityTokenPathConfig
+	ResponseSigner               *ResponseSigner
 	AppMetrics                   IAppMetrics
 	tlsConfig                    *tls.Config
 	notLoggedHeaders             []string
@@ -175,6 +176,11 @@ func NewXconfServer(sc *common.ServerConfig, testOnly bool, ec *ExternalConnecto
 		panic(err)
 	}
 
+	responseSigner, err := NewResponseSigner(conf)
+	if err != nil && !testOnly {
+		panic(err)
+	}
+
 	var serviceHostname string
 	if conf.GetBoolean("xconfwebconfig.server.localhost_only") {
 		serviceHostname = "localhost"
@@ -207,6 +213,7 @@ func NewXconfServer(sc *common.ServerConfig, testOnly bool, ec *ExternalConnecto
 		SecurityTokenConfig:          securityTokenConfig,
 		LogUploadSecurityTokenConfig: loguploadSecurityTokenConfig,
 		FirmwareSecurityTokenConfig:  firmwareSecurityTokenConfig,
+		ResponseSigner:               responseSigner,
 		SatServiceConnector:          NewSatServiceConnector(conf, tlsConfig, ec.SatServiceConnector),
 		AccountServiceConnector:      NewAccountServiceConnector(conf, tlsConfig, ec.AccountServiceConnector),
 		DeviceServiceConnector:       NewDeviceServiceConnector(conf, tlsConfig, ec.DeviceServiceConnector),
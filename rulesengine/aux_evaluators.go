@@ -19,7 +19,9 @@ package rulesengine
 
 import (
 	"regexp"
+	"strconv"
 
+	"github.com/rdkcentral/xconfwebconfig/shared"
 	"github.com/rdkcentral/xconfwebconfig/util"
 )
 
@@ -30,20 +32,24 @@ func GetAuxEvaluators() []IConditionEvaluator {
 		AuxFreeArgTypeIpAddress,
 		StandardOperationIs,
 		func(freeArgValue string, fixedArgValue interface{}) bool {
-			return freeArgValue == fixedArgValue
+			if value, ok := fixedArgValue.(string); ok {
+				return ipAddressMatches(value, freeArgValue)
+			}
+			return false
 		},
 	)
 	evaluators = append(evaluators, ev2)
 
-	// TODO for now, i will use the []string style to eval
-	//      if it causes errors, I can come back and fix the problem
-	//      ASSUME the fixedArgValue is a []string and all normalized
 	ev3 := NewBaseEvaluator(
 		AuxFreeArgTypeIpAddress,
 		StandardOperationIn,
 		func(freeArgValue string, fixedArgValueItf interface{}) bool {
 			if values, ok := fixedArgValueItf.([]string); ok {
-				return util.Contains(values, freeArgValue)
+				for _, value := range values {
+					if ipAddressMatches(value, freeArgValue) {
+						return true
+					}
+				}
 			}
 			return false
 		},
@@ -57,6 +63,12 @@ func GetAuxEvaluators() []IConditionEvaluator {
 			if percent, ok := fixedArgValueItf.(float64); ok {
 				return FitsPercent(freeArgValue, percent)
 			}
+			if percentString, ok := fixedArgValueItf.(string); ok {
+				percentString, salt := SplitPercentSalt(percentString)
+				if percent, err := strconv.ParseFloat(percentString, 64); err == nil {
+					return FitsSaltedPercent(freeArgValue, percent, salt)
+				}
+			}
 			return false
 		},
 	)
@@ -114,3 +126,12 @@ func GetAuxEvaluators() []IConditionEvaluator {
 
 	return evaluators
 }
+
+// ipAddressMatches reports whether ipAddress is the IPv4 or IPv6 address or in the CIDR block fixedArgValue.
+// A fixed arg that is not an IP address is compared as a string.
+func ipAddressMatches(fixedArgValue string, ipAddress string) bool {
+	if fixedIpAddress := shared.NewIpAddress(fixedArgValue); fixedIpAddress != nil {
+		return fixedIpAddress.IsInRange(ipAddress)
+	}
+	return fixedArgValue == ipAddress
+}
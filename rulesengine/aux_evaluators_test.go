@@ -427,3 +427,26 @@ func TestAuxEvaluator_EvaluatorProperties(t *testing.T) {
 }
 
 // Helper functions for creating test fixtures are now replaced with the existing NewFixedArg function
+
+func TestAuxEvaluator_IpAddress_IPv6AndCIDR(t *testing.T) {
+	processor := NewRuleProcessor()
+	testCases := []struct {
+		rule     string
+		ip       string
+		expected bool
+	}{
+		{`estbIP:IP_ADDRESS IS "2001:db8::1"`, "2001:DB8:0::1", true},
+		{`estbIP:IP_ADDRESS IS "1.2.3.4"`, "::ffff:1.2.3.4", true},
+		{`estbIP:IP_ADDRESS IS "10.0.0.0/8"`, "10.1.2.3", true},
+		{`estbIP:IP_ADDRESS IS "10.0.0.0/8"`, "11.1.2.3", false},
+		{`estbIP:IP_ADDRESS IN ["192.168.0.0/16", "2001:558:6027:180::/57"]`, "2001:558:6027:180::1234", true},
+		{`estbIP:IP_ADDRESS IN ["192.168.0.0/16", "2001:558:6027:180::/57"]`, "192.168.7.7", true},
+		{`estbIP:IP_ADDRESS IN ["192.168.0.0/16", "2001:558:6027:180::/57"]`, "2001:558:4321:180::2345", false},
+		{`estbIP:IP_ADDRESS IN ["not-an-ip"]`, "not-an-ip", true},
+	}
+	for _, tc := range testCases {
+		rule, err := ParseRule(tc.rule)
+		assert.NilError(t, err)
+		assert.Equal(t, tc.expected, processor.Evaluate(rule, map[string]string{"estbIP": tc.ip}, nil), "%v %v", tc.rule, tc.ip)
+	}
+}
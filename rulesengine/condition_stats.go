@@ -0,0 +1,197 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultConditionCost is the cost of an operation missing from OperationCosts
+const DefaultConditionCost = 5.0
+
+// OperationCosts are the relative costs of evaluating a condition, an IS costs 1.
+// Evaluators implementing CostEstimator override them.
+var OperationCosts = map[string]float64{
+	StandardOperationIs:                   1,
+	StandardOperationExists:               1,
+	StandardOperationGt:                   2,
+	StandardOperationGte:                  2,
+	StandardOperationLt:                   2,
+	StandardOperationLte:                  2,
+	StandardOperationStartsWith:           1,
+	StandardOperationStartsWithIgnoreCase: 2,
+	StandardOperationEndsWith:             1,
+	StandardOperationEndsWithIgnoreCase:   2,
+	StandardOperationPercent:              3,
+	StandardOperationRange:                3,
+	StandardOperationRegex:                4,
+	StandardOperationRegexIgnoreCase:      4,
+	StandardOperationSchedule:             4,
+	StandardOperationLike:                 8,
+	StandardOperationMatch:                8,
+	StandardOperationInList:               10,
+}
+
+// CostEstimator is implemented by evaluators that know the relative cost of a condition
+type CostEstimator interface {
+	EstimateCost(condition *Condition) float64
+}
+
+// ConditionCounter counts the evaluations of a condition by compiled rules and how many of them matched
+type ConditionCounter struct {
+	evaluations int64
+	matches     int64
+}
+
+func (c *ConditionCounter) record(matched bool) {
+	atomic.AddInt64(&c.evaluations, 1)
+	if matched {
+		atomic.AddInt64(&c.matches, 1)
+	}
+}
+
+func (c *ConditionCounter) Evaluations() int64 {
+	return atomic.LoadInt64(&c.evaluations)
+}
+
+func (c *ConditionCounter) Matches() int64 {
+	return atomic.LoadInt64(&c.matches)
+}
+
+// MatchRate estimates the probability that the condition matches, 0.5 before it was evaluated
+func (c *ConditionCounter) MatchRate() float64 {
+	return (float64(c.Matches()) + 1) / (float64(c.Evaluations()) + 2)
+}
+
+// ConditionStat is the snapshot of the counter of a condition
+type ConditionStat struct {
+	Condition   string  `json:"condition"`
+	Cost        float64 `json:"cost"`
+	Evaluations int64   `json:"evaluations"`
+	Matches     int64   `json:"matches"`
+	MatchRate   float64 `json:"matchRate"`
+}
+
+// conditionStats holds a *conditionStatsEntry per condition text, so identical conditions of
+// different rules share their counter. The entries no cached index uses are dropped when the
+// cached indexes are rebuilt, see NewCachedRuleIndex
+var (
+	conditionStatsMutex  sync.Mutex
+	conditionStats       = map[string]*conditionStatsEntry{}
+	conditionStatsOwners = map[string]map[string]*conditionStatsEntry{}
+)
+
+type conditionStatsEntry struct {
+	counter *ConditionCounter
+	cost    float64
+	owners  int // the number of cached indexes using the entry
+}
+
+func getConditionStatsEntry(condition *Condition, cost float64) (string, *conditionStatsEntry) {
+	key := conditionKey(condition, false)
+	conditionStatsMutex.Lock()
+	defer conditionStatsMutex.Unlock()
+	entry, ok := conditionStats[key]
+	if !ok {
+		entry = &conditionStatsEntry{counter: &ConditionCounter{}, cost: cost}
+		conditionStats[key] = entry
+	}
+	return key, entry
+}
+
+// setConditionStatsOwner makes owner use entries instead of the entries it used before, the
+// entries no owner uses any more are dropped
+func setConditionStatsOwner(owner string, entries map[string]*conditionStatsEntry) {
+	conditionStatsMutex.Lock()
+	defer conditionStatsMutex.Unlock()
+	for key, entry := range entries {
+		entry.owners++
+		// dropped by a rebuild of another owner since the index was compiled
+		if _, ok := conditionStats[key]; !ok {
+			conditionStats[key] = entry
+		}
+	}
+	for key, entry := range conditionStatsOwners[owner] {
+		entry.owners--
+		if entry.owners <= 0 && conditionStats[key] == entry {
+			delete(conditionStats, key)
+		}
+	}
+	conditionStatsOwners[owner] = entries
+}
+
+// GetConditionStats returns the counters of the conditions evaluated by compiled rules, most evaluated first
+func GetConditionStats() []ConditionStat {
+	stats := []ConditionStat{}
+	conditionStatsMutex.Lock()
+	for key, entry := range conditionStats {
+		stats = append(stats, ConditionStat{
+			Condition:   key,
+			Cost:        entry.cost,
+			Evaluations: entry.counter.Evaluations(),
+			Matches:     entry.counter.Matches(),
+			MatchRate:   entry.counter.MatchRate(),
+		})
+	}
+	conditionStatsMutex.Unlock()
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Evaluations != stats[j].Evaluations {
+			return stats[i].Evaluations > stats[j].Evaluations
+		}
+		return stats[i].Condition < stats[j].Condition
+	})
+	return stats
+}
+
+// ResetConditionStats clears the counters, compiled rules keep counting into the counters they hold
+func ResetConditionStats() {
+	conditionStatsMutex.Lock()
+	defer conditionStatsMutex.Unlock()
+	for _, entry := range conditionStats {
+		atomic.StoreInt64(&entry.counter.evaluations, 0)
+		atomic.StoreInt64(&entry.counter.matches, 0)
+	}
+}
+
+// EstimateConditionCost returns the cost from the evaluator when it is a CostEstimator, or else from
+// OperationCosts. The cost of IN and ANY_MATCHED grows with the number of values.
+func EstimateConditionCost(evaluator IConditionEvaluator, condition *Condition) float64 {
+	if estimator, ok := evaluator.(CostEstimator); ok {
+		return estimator.EstimateCost(condition)
+	}
+	switch operation := condition.GetOperation(); operation {
+	case StandardOperationIn:
+		return 1 + float64(collectionSize(condition))/16
+	case StandardOperationAnyMatched:
+		return 8 * float64(collectionSize(condition))
+	default:
+		if cost, ok := OperationCosts[operation]; ok {
+			return cost
+		}
+	}
+	return DefaultConditionCost
+}
+
+func collectionSize(condition *Condition) int {
+	if fixedArg := condition.GetFixedArg(); fixedArg != nil && fixedArg.Collection != nil {
+		return len(fixedArg.Collection.Value)
+	}
+	return 1
+}
@@ -37,9 +37,18 @@ const (
 	StandardOperationMatch      = "MATCH"
 	StandardOperationRange      = "RANGE"
 
+	StandardOperationRegex                = "REGEX"
+	StandardOperationRegexIgnoreCase      = "REGEX_IGNORE_CASE"
+	StandardOperationStartsWith           = "STARTS_WITH"
+	StandardOperationStartsWithIgnoreCase = "STARTS_WITH_IGNORE_CASE"
+	StandardOperationEndsWith             = "ENDS_WITH"
+	StandardOperationEndsWithIgnoreCase   = "ENDS_WITH_IGNORE_CASE"
+	StandardOperationSchedule             = "SCHEDULE"
+
 	AuxFreeArgTypeTime       = "TIME"
 	AuxFreeArgTypeIpAddress  = "IP_ADDRESS"
 	AuxFreeArgTypeMacAddress = "MAC_ADDRESS"
+	AuxFreeArgTypeVersion    = "VERSION"
 
 	RelationOr  = "OR"
 	RelationAnd = "AND"
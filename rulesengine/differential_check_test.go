@@ -0,0 +1,178 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxEvaluationMismatches is the number of mismatches after which CheckEvaluator stops
+const MaxEvaluationMismatches = 10
+
+// DefaultGeneratorFields are the context values of the RuleGenerator, few per field so that the
+// generated conditions match often
+var DefaultGeneratorFields = map[string][]string{
+	"model":           {"X1", "X2", "X3", "XG1"},
+	"env":             {"QA", "DEV", "PROD"},
+	"partnerId":       {"comcast", "cox", "sky"},
+	"firmwareVersion": {"1.0", "1.5", "2.0"},
+	"estbMacAddress":  {"AA:BB:CC:DD:EE:01", "AA:BB:CC:DD:EE:02", "AA:BB:CC:DD:EE:03"},
+}
+
+// Evaluator evaluates a rule against a context, like RuleProcessor.Evaluate
+type Evaluator func(r *Rule, context map[string]string) bool
+
+// EvaluationMismatch is a rule and context for which an evaluator disagrees with RuleProcessor.Evaluate
+type EvaluationMismatch struct {
+	Rule     string            `json:"rule"`
+	Context  map[string]string `json:"context"`
+	Expected bool              `json:"expected"`
+	Actual   bool              `json:"actual"`
+}
+
+func (m EvaluationMismatch) String() string {
+	return fmt.Sprintf("%v with %v: expected %v, got %v", m.Rule, m.Context, m.Expected, m.Actual)
+}
+
+// RuleGenerator generates random rules and contexts over the values of Fields. Rules are nested
+// up to MaxDepth and every compound has up to MaxParts parts. It is not safe for concurrent use.
+type RuleGenerator struct {
+	Fields   map[string][]string
+	MaxDepth int
+	MaxParts int
+
+	rnd *rand.Rand
+}
+
+func NewRuleGenerator(seed int64) *RuleGenerator {
+	return &RuleGenerator{
+		Fields:   DefaultGeneratorFields,
+		MaxDepth: 2,
+		MaxParts: 3,
+		rnd:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+// fieldNames returns the names of Fields sorted, so a seed always generates the same rules
+func (g *RuleGenerator) fieldNames() []string {
+	names := make([]string, 0, len(g.Fields))
+	for name := range g.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g *RuleGenerator) pick(values []string) string {
+	return values[g.rnd.Intn(len(values))]
+}
+
+// Condition returns a random condition on one of Fields
+func (g *RuleGenerator) Condition() *Condition {
+	name := g.pick(g.fieldNames())
+	values := g.Fields[name]
+	freeArg := NewFreeArg(StandardFreeArgTypeString, name)
+	value := g.pick(values)
+	switch g.rnd.Intn(10) {
+	case 0, 1, 2:
+		return NewCondition(freeArg, StandardOperationIs, NewFixedArg(value))
+	case 3, 4:
+		return NewCondition(freeArg, StandardOperationIn, NewFixedArg([]string{value, g.pick(values)}))
+	case 5:
+		return NewCondition(freeArg, StandardOperationLike, NewFixedArg("^"+value[:1]))
+	case 6:
+		return NewCondition(NewFreeArg(StandardFreeArgTypeAny, name), StandardOperationExists, nil)
+	case 7:
+		return NewCondition(freeArg, StandardOperationStartsWith, NewFixedArg(value[:1]))
+	case 8:
+		return NewCondition(freeArg, StandardOperationEndsWithIgnoreCase, NewFixedArg(strings.ToLower(value[len(value)-1:])))
+	default:
+		return NewCondition(freeArg, StandardOperationPercent, NewFixedArg(float64(g.rnd.Intn(11)*10)))
+	}
+}
+
+// Rule returns a random rule, a single condition or a compound of conditions and nested compounds
+func (g *RuleGenerator) Rule() *Rule {
+	return g.rule(g.MaxDepth)
+}
+
+func (g *RuleGenerator) rule(depth int) *Rule {
+	n := 1
+	if g.MaxParts > 1 {
+		n += g.rnd.Intn(g.MaxParts)
+	}
+	if n == 1 && (depth == 0 || g.rnd.Intn(2) == 0) {
+		return &Rule{Condition: g.Condition(), Negated: g.rnd.Intn(6) == 0}
+	}
+
+	r := NewEmptyRule()
+	r.SetNegated(g.rnd.Intn(6) == 0)
+	for i := 0; i < n; i++ {
+		var part *Rule
+		if depth > 0 && g.rnd.Intn(4) == 0 {
+			part = g.rule(depth - 1)
+		} else {
+			part = &Rule{Condition: g.Condition(), Negated: g.rnd.Intn(6) == 0}
+		}
+		if i > 0 {
+			part.SetRelation(RelationAnd)
+			if g.rnd.Intn(4) == 0 {
+				part.SetRelation(RelationOr)
+			}
+		}
+		r.AddCompoundPart(*part)
+	}
+	return r
+}
+
+// Context returns a random context, most fields are set to one of their values and some are missing
+func (g *RuleGenerator) Context() map[string]string {
+	context := map[string]string{}
+	for _, name := range g.fieldNames() {
+		if g.rnd.Intn(8) > 0 {
+			context[name] = g.pick(g.Fields[name])
+		}
+	}
+	return context
+}
+
+// CheckEvaluator compares an optimized evaluator with the linear RuleProcessor.Evaluate on generated
+// rules, each evaluated against contextsPerRule generated contexts. It returns the mismatches found,
+// at most MaxEvaluationMismatches.
+func CheckEvaluator(processor *RuleProcessor, candidate Evaluator, generator *RuleGenerator, rules int, contextsPerRule int) []EvaluationMismatch {
+	mismatches := []EvaluationMismatch{}
+	for i := 0; i < rules; i++ {
+		r := generator.Rule()
+		for j := 0; j < contextsPerRule; j++ {
+			context := generator.Context()
+			expected := processor.Evaluate(r, context, log.Fields{})
+			if actual := candidate(r, context); actual != expected {
+				mismatches = append(mismatches, EvaluationMismatch{Rule: r.String(), Context: context, Expected: expected, Actual: actual})
+				if len(mismatches) >= MaxEvaluationMismatches {
+					return mismatches
+				}
+			}
+		}
+	}
+	return mismatches
+}
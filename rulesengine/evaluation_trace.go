@@ -0,0 +1,199 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EvaluationTrace mirrors the Rule tree and records how each part was evaluated
+type EvaluationTrace struct {
+	Relation      string             `json:"relation,omitempty"`
+	Negated       bool               `json:"negated,omitempty"`
+	Condition     *ConditionTrace    `json:"condition,omitempty"`
+	CompoundParts []*EvaluationTrace `json:"compoundParts,omitempty"`
+	Result        bool               `json:"result"`
+	// Skipped is set when short-circuiting meant the part was never evaluated
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// ConditionTrace records the inputs and the evaluator of a single Condition
+type ConditionTrace struct {
+	FreeArg      *FreeArg    `json:"freeArg,omitempty"`
+	FreeArgValue string      `json:"freeArgValue"`
+	FreeArgFound bool        `json:"freeArgFound"`
+	Operation    string      `json:"operation"`
+	FixedArg     interface{} `json:"fixedArg,omitempty"`
+	Evaluator    string      `json:"evaluator,omitempty"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// EvaluateWithTrace returns the same result as Evaluate together with a trace of the evaluation
+func (p *RuleProcessor) EvaluateWithTrace(r *Rule, context map[string]string) (bool, *EvaluationTrace) {
+	trace := p.traceRule(r, context)
+	return trace.Result, trace
+}
+
+func (p *RuleProcessor) traceRule(r *Rule, context map[string]string) *EvaluationTrace {
+	trace := &EvaluationTrace{
+		Relation: r.GetRelation(),
+		Negated:  r.IsNegated(),
+	}
+	if !r.IsCompound() {
+		trace.Condition, trace.Result = p.traceCondition(r.GetCondition(), r.IsNegated(), context)
+		return trace
+	}
+
+	// the short-circuit rules below must stay in sync with Evaluate()
+	var result bool
+	stopped := false
+	for i := range r.CompoundParts {
+		cp := &r.CompoundParts[i]
+		if i > 0 {
+			relation := cp.GetRelation()
+			if !result && relation == RelationAnd {
+				stopped = true
+			}
+			if stopped || (result && relation == RelationOr) {
+				trace.CompoundParts = append(trace.CompoundParts, p.traceSkipped(cp, context))
+				continue
+			}
+		}
+		partTrace := p.traceRule(cp, context)
+		trace.CompoundParts = append(trace.CompoundParts, partTrace)
+		result = partTrace.Result
+	}
+	if r.IsNegated() {
+		result = !result
+	}
+	trace.Result = result
+	return trace
+}
+
+func (p *RuleProcessor) traceSkipped(r *Rule, context map[string]string) *EvaluationTrace {
+	trace := &EvaluationTrace{
+		Relation: r.GetRelation(),
+		Negated:  r.IsNegated(),
+		Skipped:  true,
+	}
+	if !r.IsCompound() {
+		trace.Condition = newConditionTrace(r.GetCondition(), context)
+		return trace
+	}
+	for i := range r.CompoundParts {
+		trace.CompoundParts = append(trace.CompoundParts, p.traceSkipped(&r.CompoundParts[i], context))
+	}
+	return trace
+}
+
+func (p *RuleProcessor) traceCondition(condition *Condition, negation bool, context map[string]string) (*ConditionTrace, bool) {
+	trace := newConditionTrace(condition, context)
+	if condition.GetFreeArg() == nil {
+		trace.Error = "condition has no free arg"
+		return trace, false
+	}
+	evaluator := p.getEvaluator(condition.GetFreeArg().GetType(), condition.GetOperation())
+	if evaluator == nil {
+		trace.Error = fmt.Sprintf("no evaluator for type=%v, operation=%v", condition.GetFreeArg().GetType(), condition.GetOperation())
+		return trace, false
+	}
+	trace.Evaluator = strings.TrimPrefix(fmt.Sprintf("%T", evaluator), "*rulesengine.")
+
+	result := evaluator.Evaluate(condition, context)
+	if negation {
+		result = !result
+	}
+	return trace, result
+}
+
+func newConditionTrace(condition *Condition, context map[string]string) *ConditionTrace {
+	trace := &ConditionTrace{
+		FreeArg:   condition.GetFreeArg(),
+		Operation: condition.GetOperation(),
+		FixedArg:  condition.GetFixedArg().GetValue(),
+	}
+	if freeArg := condition.GetFreeArg(); freeArg != nil {
+		trace.FreeArgValue, trace.FreeArgFound = context[freeArg.GetName()]
+	}
+	return trace
+}
+
+// RuleTrace is the evaluation trace of one rule-backed entity such as a FirmwareRule or FeatureRule
+type RuleTrace struct {
+	Id         string           `json:"id"`
+	Name       string           `json:"name,omitempty"`
+	RuleType   string           `json:"ruleType,omitempty"`
+	TemplateId string           `json:"templateId,omitempty"`
+	Rule       string           `json:"rule"`
+	Matched    bool             `json:"matched"`
+	Trace      *EvaluationTrace `json:"trace"`
+}
+
+// RuleTracer collects the traces of all rules evaluated while serving one request.
+// A nil *RuleTracer is valid and simply evaluates without tracing.
+// It is not safe for concurrent use.
+type RuleTracer struct {
+	Rules []*RuleTrace
+}
+
+func NewRuleTracer() *RuleTracer {
+	return &RuleTracer{
+		Rules: []*RuleTrace{},
+	}
+}
+
+// Evaluate evaluates the rule of xrule, counts it in the rule stats and records its trace when tracing is on
+func (t *RuleTracer) Evaluate(p *RuleProcessor, xrule XRule, context map[string]string, fields log.Fields) bool {
+	matched := t.EvaluateWithoutStats(p, xrule, context, fields)
+	RecordRuleEvaluation(xrule, matched)
+	return matched
+}
+
+// EvaluateWithoutStats evaluates like Evaluate without counting the rule in the rule stats, for evaluations
+// such as dry runs that serve no device
+func (t *RuleTracer) EvaluateWithoutStats(p *RuleProcessor, xrule XRule, context map[string]string, fields log.Fields) bool {
+	if t == nil {
+		return p.Evaluate(xrule.GetRule(), context, fields)
+	}
+	rule := xrule.GetRule()
+	if rule == nil {
+		rule = NewEmptyRule()
+	}
+	matched, trace := p.EvaluateWithTrace(rule, context)
+	t.Rules = append(t.Rules, &RuleTrace{
+		Id:         xrule.GetId(),
+		Name:       xrule.GetName(),
+		RuleType:   xrule.GetRuleType(),
+		TemplateId: xrule.GetTemplateId(),
+		Rule:       rule.String(),
+		Matched:    matched,
+		Trace:      trace,
+	})
+	return matched
+}
+
+func (t *RuleTracer) MarshalJSON() ([]byte, error) {
+	if t.Rules == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(t.Rules)
+}
@@ -0,0 +1,148 @@
+package rulesengine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+type traceTestRule struct {
+	id   string
+	rule *Rule
+}
+
+func (r *traceTestRule) GetId() string         { return r.id }
+func (r *traceTestRule) GetRule() *Rule        { return r.rule }
+func (r *traceTestRule) GetName() string       { return "name-" + r.id }
+func (r *traceTestRule) GetTemplateId() string { return "" }
+func (r *traceTestRule) GetRuleType() string   { return "TestRule" }
+
+func TestEvaluateWithTraceMatchesEvaluate(t *testing.T) {
+	processor := NewRuleProcessor()
+	texts := []string{
+		`model IS "X1"`,
+		`NOT model IS "X1"`,
+		`model IS "X1" AND (env IN ["QA", "DEV"] OR NOT firmwareVersion IS "1.0")`,
+		`a IS "1" OR b IS "2" AND c IS "3"`,
+		`NOT (a IS "1" OR (b IS "2" AND NOT (c IS "3" OR d IS "4")))`,
+		`a IS "1" AND b IS "2" OR c IS "3"`,
+		`a:ANY EXISTS OR model IN ["X1", "X2"]`,
+	}
+	contexts := []map[string]string{
+		{},
+		{"model": "X1", "env": "QA", "firmwareVersion": "1.0"},
+		{"model": "X1", "env": "PROD", "firmwareVersion": "2.0"},
+		{"model": "X2", "a": "1", "b": "2", "c": "3", "d": "4"},
+		{"a": "0", "b": "2", "c": "0", "d": "4"},
+		{"a": "1", "b": "0", "c": "3"},
+	}
+	for _, text := range texts {
+		rule, err := ParseRule(text)
+		assert.NilError(t, err)
+		for _, context := range contexts {
+			expected := processor.Evaluate(rule, context, nil)
+			result, trace := processor.EvaluateWithTrace(rule, context)
+			assert.Equal(t, expected, result, "%v with %v", text, context)
+			assert.Equal(t, expected, trace.Result, "%v with %v", text, context)
+		}
+	}
+}
+
+func TestEvaluateWithTraceConditionDetails(t *testing.T) {
+	processor := NewRuleProcessor()
+	rule, err := ParseRule(`model IS "X1" AND env IN ["QA", "DEV"]`)
+	assert.NilError(t, err)
+
+	result, trace := processor.EvaluateWithTrace(rule, map[string]string{"model": "X1"})
+	assert.Assert(t, !result)
+	assert.Equal(t, 2, len(trace.CompoundParts))
+
+	model := trace.CompoundParts[0]
+	assert.Assert(t, model.Result)
+	assert.Equal(t, "model", model.Condition.FreeArg.GetName())
+	assert.Equal(t, "X1", model.Condition.FreeArgValue)
+	assert.Assert(t, model.Condition.FreeArgFound)
+	assert.Equal(t, StandardOperationIs, model.Condition.Operation)
+	assert.Equal(t, "X1", model.Condition.FixedArg)
+	assert.Assert(t, model.Condition.Evaluator != "")
+
+	env := trace.CompoundParts[1]
+	assert.Assert(t, !env.Result)
+	assert.Assert(t, !env.Skipped)
+	assert.Equal(t, RelationAnd, env.Relation)
+	assert.Assert(t, !env.Condition.FreeArgFound)
+	assert.DeepEqual(t, []string{"QA", "DEV"}, env.Condition.FixedArg)
+}
+
+func TestEvaluateWithTraceShortCircuit(t *testing.T) {
+	processor := NewRuleProcessor()
+
+	// a false AND stops the evaluation of all remaining parts
+	rule, err := ParseRule(`a IS "1" AND b IS "2" OR c IS "3"`)
+	assert.NilError(t, err)
+	result, trace := processor.EvaluateWithTrace(rule, map[string]string{"a": "0", "c": "3"})
+	assert.Assert(t, !result)
+	assert.Assert(t, !trace.CompoundParts[0].Skipped)
+	assert.Assert(t, trace.CompoundParts[1].Skipped)
+	assert.Assert(t, trace.CompoundParts[2].Skipped)
+	assert.Equal(t, "3", trace.CompoundParts[2].Condition.FreeArgValue)
+
+	// a true OR only skips the OR part
+	rule, err = ParseRule(`a IS "1" OR (b IS "2" AND c IS "3") AND d IS "4"`)
+	assert.NilError(t, err)
+	result, trace = processor.EvaluateWithTrace(rule, map[string]string{"a": "1", "d": "4"})
+	assert.Assert(t, result)
+	assert.Assert(t, trace.CompoundParts[1].Skipped)
+	assert.Assert(t, trace.CompoundParts[1].CompoundParts[0].Skipped)
+	assert.Assert(t, trace.CompoundParts[1].CompoundParts[1].Skipped)
+	assert.Assert(t, !trace.CompoundParts[2].Skipped)
+	assert.Assert(t, trace.CompoundParts[2].Result)
+}
+
+func TestEvaluateWithTraceMissingEvaluator(t *testing.T) {
+	processor := NewRuleProcessor()
+	rule := Rule{
+		Condition: NewCondition(NewFreeArg(StandardFreeArgTypeString, "model"), "NO_SUCH_OPERATION", NewFixedArg("X1")),
+	}
+	result, trace := processor.EvaluateWithTrace(&rule, map[string]string{"model": "X1"})
+	assert.Assert(t, !result)
+	assert.Assert(t, trace.Condition.Error != "")
+	assert.Equal(t, "", trace.Condition.Evaluator)
+}
+
+func TestRuleTracer(t *testing.T) {
+	processor := NewRuleProcessor()
+	rule1, err := ParseRule(`model IS "X1"`)
+	assert.NilError(t, err)
+	rule2, err := ParseRule(`model IS "X2"`)
+	assert.NilError(t, err)
+	context := map[string]string{"model": "X1"}
+
+	var nilTracer *RuleTracer
+	assert.Assert(t, nilTracer.Evaluate(processor, &traceTestRule{"1", rule1}, context, nil))
+	assert.Assert(t, !nilTracer.Evaluate(processor, &traceTestRule{"2", rule2}, context, nil))
+
+	tracer := NewRuleTracer()
+	assert.Assert(t, tracer.Evaluate(processor, &traceTestRule{"1", rule1}, context, nil))
+	assert.Assert(t, !tracer.Evaluate(processor, &traceTestRule{"2", rule2}, context, nil))
+	assert.Equal(t, 2, len(tracer.Rules))
+	assert.Equal(t, "1", tracer.Rules[0].Id)
+	assert.Equal(t, "name-1", tracer.Rules[0].Name)
+	assert.Equal(t, "TestRule", tracer.Rules[0].RuleType)
+	assert.Equal(t, `model IS "X1"`, tracer.Rules[0].Rule)
+	assert.Assert(t, tracer.Rules[0].Matched)
+	assert.Assert(t, !tracer.Rules[1].Matched)
+
+	bbytes, err := json.Marshal(tracer)
+	assert.NilError(t, err)
+	var decoded []map[string]interface{}
+	assert.NilError(t, json.Unmarshal(bbytes, &decoded))
+	assert.Equal(t, 2, len(decoded))
+	assert.Equal(t, "2", decoded[1]["id"])
+	assert.Equal(t, false, decoded[1]["matched"])
+
+	bbytes, err = json.Marshal(&RuleTracer{})
+	assert.NilError(t, err)
+	assert.Equal(t, "[]", string(bbytes))
+}
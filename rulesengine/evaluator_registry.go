@@ -0,0 +1,213 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DeclarativeMatchEquals   = "equals"
+	DeclarativeMatchPrefix   = "prefix"
+	DeclarativeMatchSuffix   = "suffix"
+	DeclarativeMatchContains = "contains"
+	DeclarativeMatchGlob     = "glob"
+)
+
+var (
+	registeredEvaluators []IConditionEvaluator
+	// defaultProcessor validates rules, it is rebuilt after the registry changes
+	defaultProcessor *RuleProcessor
+	registryVersion  int
+	registryMutex    sync.RWMutex
+)
+
+// RegisterEvaluator adds an evaluator to every RuleProcessorFactory created afterwards, replacing
+// the built-in or previously registered evaluator of the same free arg type and operation.
+// Evaluators should be registered at startup, before rules are loaded.
+func RegisterEvaluator(evaluator IConditionEvaluator) error {
+	if evaluator == nil {
+		return errors.New("evaluator is nil")
+	}
+	if len(evaluator.FreeArgType()) == 0 || len(evaluator.Operation()) == 0 {
+		return fmt.Errorf("evaluator %T has no free arg type or operation", evaluator)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	for i, registered := range registeredEvaluators {
+		if registered.FreeArgType() == evaluator.FreeArgType() && registered.Operation() == evaluator.Operation() {
+			registeredEvaluators[i] = evaluator
+			registryChanged()
+			return nil
+		}
+	}
+	registeredEvaluators = append(registeredEvaluators, evaluator)
+	registryChanged()
+	return nil
+}
+
+// GetRegisteredEvaluators returns the evaluators added with RegisterEvaluator
+func GetRegisteredEvaluators() []IConditionEvaluator {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	evaluators := make([]IConditionEvaluator, len(registeredEvaluators))
+	copy(evaluators, registeredEvaluators)
+	return evaluators
+}
+
+func registryChanged() {
+	defaultProcessor = nil
+	registryVersion++
+}
+
+func getDefaultProcessor() *RuleProcessor {
+	registryMutex.RLock()
+	processor := defaultProcessor
+	version := registryVersion
+	registryMutex.RUnlock()
+	if processor != nil {
+		return processor
+	}
+
+	processor = NewRuleProcessorFactory().RuleProcessor()
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	// only keep it when nothing was registered while it was built
+	if version == registryVersion {
+		defaultProcessor = processor
+	}
+	return processor
+}
+
+// UnknownEvaluatorError is returned when no evaluator exists for the free arg type and operation of a condition
+type UnknownEvaluatorError struct {
+	FreeArgType string
+	Operation   string
+}
+
+func (e *UnknownEvaluatorError) Error() string {
+	return fmt.Sprintf("no evaluator for type=%v, operation=%v", e.FreeArgType, e.Operation)
+}
+
+// Validate checks that every condition of the rule can be evaluated by the processor and that
+// evaluators implementing FixedArgValidator accept the fixed arg
+func (p *RuleProcessor) Validate(r *Rule) error {
+	if r == nil {
+		return nil
+	}
+	if !r.IsCompound() {
+		condition := r.GetCondition()
+		if condition.GetFreeArg() == nil {
+			return errors.New("condition has no free arg")
+		}
+		evaluator, ok := p.evaluatorMap[condition.GetFreeArg().GetType()+"_"+condition.GetOperation()]
+		if !ok {
+			return &UnknownEvaluatorError{
+				FreeArgType: condition.GetFreeArg().GetType(),
+				Operation:   condition.GetOperation(),
+			}
+		}
+		if validator, ok := evaluator.(FixedArgValidator); ok {
+			return validator.Validate(condition.GetFixedArg())
+		}
+		return nil
+	}
+	for i := range r.CompoundParts {
+		if err := p.Validate(&r.CompoundParts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateRule checks the rule against the evaluators of NewRuleProcessorFactory. Rule loaders
+// use it to reject rules that could only ever be evaluated with an error.
+func ValidateRule(r *Rule) error {
+	return getDefaultProcessor().Validate(r)
+}
+
+// IsEvaluable validates the rule of xrule with ValidateRule, logging why an invalid rule is skipped
+func IsEvaluable(xrule XRule) bool {
+	if err := ValidateRule(xrule.GetRule()); err != nil {
+		log.Errorf("%v %v '%v' is skipped: %v", xrule.GetRuleType(), xrule.GetId(), xrule.GetName(), err)
+		return false
+	}
+	return true
+}
+
+// DeclarativeEvaluatorSpec describes a STRING style evaluator defined in the configuration.
+// The condition holds when the free arg matches the fixed arg, or any element of a fixed arg collection.
+type DeclarativeEvaluatorSpec struct {
+	FreeArgType string
+	Operation   string
+	Match       string
+	IgnoreCase  bool
+}
+
+// NewDeclarativeEvaluator builds the evaluator described by spec
+func NewDeclarativeEvaluator(spec DeclarativeEvaluatorSpec) (IConditionEvaluator, error) {
+	if len(spec.FreeArgType) == 0 || len(spec.Operation) == 0 {
+		return nil, errors.New("declarative evaluator needs a free arg type and an operation")
+	}
+
+	var match func(string, string) bool
+	switch spec.Match {
+	case DeclarativeMatchEquals:
+		match = func(value string, pattern string) bool { return value == pattern }
+	case DeclarativeMatchPrefix:
+		match = strings.HasPrefix
+	case DeclarativeMatchSuffix:
+		match = strings.HasSuffix
+	case DeclarativeMatchContains:
+		match = strings.Contains
+	case DeclarativeMatchGlob:
+		match = func(value string, pattern string) bool {
+			matched, err := filepath.Match(pattern, value)
+			return err == nil && matched
+		}
+	default:
+		return nil, fmt.Errorf("declarative evaluator %v %v has an unknown match '%v'", spec.FreeArgType, spec.Operation, spec.Match)
+	}
+	if spec.IgnoreCase {
+		caseSensitive := match
+		match = func(value string, pattern string) bool {
+			return caseSensitive(strings.ToLower(value), strings.ToLower(pattern))
+		}
+	}
+
+	return NewBaseEvaluator(spec.FreeArgType, spec.Operation, func(freeArgValue string, fixedArgValue interface{}) bool {
+		switch fixedArg := fixedArgValue.(type) {
+		case string:
+			return match(freeArgValue, fixedArg)
+		case []string:
+			for _, pattern := range fixedArg {
+				if match(freeArgValue, pattern) {
+					return true
+				}
+			}
+		}
+		return false
+	}), nil
+}
@@ -0,0 +1,155 @@
+package rulesengine
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func resetEvaluatorRegistry(t *testing.T) {
+	registryMutex.Lock()
+	saved := registeredEvaluators
+	registeredEvaluators = nil
+	registryChanged()
+	registryMutex.Unlock()
+
+	t.Cleanup(func() {
+		registryMutex.Lock()
+		registeredEvaluators = saved
+		registryChanged()
+		registryMutex.Unlock()
+	})
+}
+
+func TestRegisterEvaluator(t *testing.T) {
+	resetEvaluatorRegistry(t)
+
+	rule, err := ParseRule(`model SAME_LENGTH "abc"`)
+	assert.NilError(t, err)
+	context := map[string]string{"model": "xyz"}
+	assert.Assert(t, !NewRuleProcessorFactory().RuleProcessor().Evaluate(rule, context, nil))
+	_, ok := ValidateRule(rule).(*UnknownEvaluatorError)
+	assert.Assert(t, ok)
+
+	sameLength := NewBaseEvaluator(StandardFreeArgTypeString, "SAME_LENGTH", func(freeArgValue string, fixedArgValue interface{}) bool {
+		fixedArg, ok := fixedArgValue.(string)
+		return ok && len(fixedArg) == len(freeArgValue)
+	})
+	assert.NilError(t, RegisterEvaluator(sameLength))
+	assert.Equal(t, 1, len(GetRegisteredEvaluators()))
+
+	assert.Assert(t, NewRuleProcessorFactory().RuleProcessor().Evaluate(rule, context, nil))
+	assert.NilError(t, ValidateRule(rule))
+
+	// registering the same pair again replaces the evaluator
+	assert.NilError(t, RegisterEvaluator(NewBaseEvaluator(StandardFreeArgTypeString, "SAME_LENGTH", func(string, interface{}) bool { return false })))
+	assert.Equal(t, 1, len(GetRegisteredEvaluators()))
+	assert.Assert(t, !NewRuleProcessorFactory().RuleProcessor().Evaluate(rule, context, nil))
+
+	assert.ErrorContains(t, RegisterEvaluator(nil), "nil")
+	assert.ErrorContains(t, RegisterEvaluator(NewBaseEvaluator(StandardFreeArgTypeString, "", nil)), "no free arg type or operation")
+}
+
+func TestRegisterEvaluatorReplacesBuiltIn(t *testing.T) {
+	resetEvaluatorRegistry(t)
+	defer func(useMap bool) { UseMap = useMap }(UseMap)
+
+	rule, err := ParseRule(`model IS "X1"`)
+	assert.NilError(t, err)
+	context := map[string]string{"model": "x1"}
+
+	isIgnoreCase, err := NewDeclarativeEvaluator(DeclarativeEvaluatorSpec{
+		FreeArgType: StandardFreeArgTypeString,
+		Operation:   StandardOperationIs,
+		Match:       DeclarativeMatchEquals,
+		IgnoreCase:  true,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, RegisterEvaluator(isIgnoreCase))
+
+	processor := NewRuleProcessorFactory().RuleProcessor()
+	for _, useMap := range []bool{true, false} {
+		UseMap = useMap
+		assert.Assert(t, processor.Evaluate(rule, context, nil), "UseMap=%v", useMap)
+	}
+}
+
+func TestAddEvaluatorsReplacesSamePair(t *testing.T) {
+	processor := NewRuleProcessor()
+	size := processor.Size()
+	processor.AddEvaluators([]IConditionEvaluator{
+		NewBaseEvaluator(StandardFreeArgTypeString, StandardOperationIs, func(string, interface{}) bool { return true }),
+	})
+	assert.Equal(t, size, processor.Size())
+	assert.Assert(t, processor.getEvaluator(StandardFreeArgTypeString, StandardOperationIs).(*BaseEvaluator).evaluateInternal("a", "b"))
+}
+
+func TestRuleProcessorValidate(t *testing.T) {
+	processor := NewRuleProcessor()
+	rule, err := ParseRule(`model IS "X1" AND (env IN ["QA"] OR NOT firmwareVersion:VERSION GTE "1.0")`)
+	assert.NilError(t, err)
+	assert.NilError(t, processor.Validate(rule))
+	assert.NilError(t, processor.Validate(nil))
+
+	rule, err = ParseRule(`model IS "X1" AND (env IN ["QA"] OR estbIP:IP_ADDRESS GT "10.0.0.1")`)
+	assert.NilError(t, err)
+	err = processor.Validate(rule)
+	unknown, ok := err.(*UnknownEvaluatorError)
+	assert.Assert(t, ok, "%v", err)
+	assert.Equal(t, AuxFreeArgTypeIpAddress, unknown.FreeArgType)
+	assert.Equal(t, StandardOperationGt, unknown.Operation)
+	assert.Equal(t, "no evaluator for type=IP_ADDRESS, operation=GT", err.Error())
+
+	assert.Assert(t, processor.Validate(&Rule{Condition: &Condition{Operation: StandardOperationIs}}) != nil)
+}
+
+func TestIsEvaluable(t *testing.T) {
+	resetEvaluatorRegistry(t)
+	valid, err := ParseRule(`estbMacAddress IN_LIST "macs" AND model MATCH "X*"`)
+	assert.NilError(t, err)
+	invalid, err := ParseRule(`model UNKNOWN_OP "X1"`)
+	assert.NilError(t, err)
+
+	assert.Assert(t, IsEvaluable(&traceTestRule{id: "valid", rule: valid}))
+	assert.Assert(t, !IsEvaluable(&traceTestRule{id: "invalid", rule: invalid}))
+	assert.Assert(t, IsEvaluable(&traceTestRule{id: "empty"}))
+}
+
+func TestDeclarativeEvaluator(t *testing.T) {
+	testCases := []struct {
+		match      string
+		ignoreCase bool
+		value      string
+		fixedArg   interface{}
+		expected   bool
+	}{
+		{DeclarativeMatchEquals, false, "abc", "abc", true},
+		{DeclarativeMatchEquals, false, "ABC", "abc", false},
+		{DeclarativeMatchEquals, true, "ABC", "abc", true},
+		{DeclarativeMatchPrefix, false, "comcast-x1", "comcast", true},
+		{DeclarativeMatchPrefix, false, "x1-comcast", "comcast", false},
+		{DeclarativeMatchPrefix, true, "COMCAST-x1", []string{"cox", "comcast"}, true},
+		{DeclarativeMatchSuffix, false, "model.bin", ".bin", true},
+		{DeclarativeMatchSuffix, false, "model.bin", []string{".tgz", ".img"}, false},
+		{DeclarativeMatchContains, false, "abc_PROD_1", "PROD", true},
+		{DeclarativeMatchContains, true, "abc_prod_1", "PROD", true},
+		{DeclarativeMatchGlob, false, "TG1682G", "TG16*", true},
+		{DeclarativeMatchGlob, false, "TG1682G", "[", false},
+	}
+	for _, tc := range testCases {
+		evaluator, err := NewDeclarativeEvaluator(DeclarativeEvaluatorSpec{
+			FreeArgType: StandardFreeArgTypeString,
+			Operation:   "CUSTOM",
+			Match:       tc.match,
+			IgnoreCase:  tc.ignoreCase,
+		})
+		assert.NilError(t, err)
+		condition := NewCondition(NewFreeArg(StandardFreeArgTypeString, "value"), "CUSTOM", NewFixedArg(tc.fixedArg))
+		assert.Equal(t, tc.expected, evaluator.Evaluate(condition, map[string]string{"value": tc.value}), "%+v", tc)
+	}
+
+	_, err := NewDeclarativeEvaluator(DeclarativeEvaluatorSpec{FreeArgType: StandardFreeArgTypeString, Operation: "CUSTOM", Match: "soundex"})
+	assert.ErrorContains(t, err, "unknown match 'soundex'")
+	_, err = NewDeclarativeEvaluator(DeclarativeEvaluatorSpec{Match: DeclarativeMatchEquals})
+	assert.Assert(t, err != nil)
+}
@@ -102,11 +102,17 @@ func (b *Bean) UnmarshalJSON(bbytes []byte) error {
 	if val, ok := dict["value"]; ok {
 		if innerItf, ok := val.(map[string]interface{}); ok {
 			if jsvItf, ok := innerItf["java.lang.String"]; ok {
-				var tmp = jsvItf.(string)
+				tmp, ok := jsvItf.(string)
+				if !ok {
+					return fmt.Errorf("java.lang.String value is not a string: %v", jsvItf)
+				}
 				b.Value.JLString = &tmp
 			}
 			if jdvItf, ok := innerItf["java.lang.Double"]; ok {
-				var tmp = jdvItf.(float64)
+				tmp, ok := jdvItf.(float64)
+				if !ok {
+					return fmt.Errorf("java.lang.Double value is not a number: %v", jdvItf)
+				}
 				b.Value.JLDouble = &tmp
 			}
 		}
@@ -218,8 +224,8 @@ func (a *FixedArg) Equals(x *FixedArg) bool {
 	if x.Collection != nil && a.Collection != nil && len(a.Collection.Value) > 0 && len(x.Collection.Value) > 0 {
 		// Two Collections can be equal when their contents are same. Order does not matter.
 		// Equality testing should not alter the objects being compared. So sort a copy of the objects
-		atmp := a.Collection.Value
-		xtmp := x.Collection.Value
+		atmp := append([]string{}, a.Collection.Value...)
+		xtmp := append([]string{}, x.Collection.Value...)
 
 		sort.Strings(atmp)
 		sort.Strings(xtmp)
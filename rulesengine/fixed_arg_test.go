@@ -408,3 +408,20 @@ func TestCollection_MarshalJSON_Extended(t *testing.T) {
 		assert.Equal(t, `{"value":["single"]}`, string(result))
 	})
 }
+
+func TestBeanUnmarshalJSONInvalidValue(t *testing.T) {
+	var f FixedArg
+	err := json.Unmarshal([]byte(`{"bean": {"value": {"java.lang.String": 40}}}`), &f)
+	assert.ErrorContains(t, err, "java.lang.String value is not a string")
+
+	err = json.Unmarshal([]byte(`{"bean": {"value": {"java.lang.Double": "40"}}}`), &f)
+	assert.ErrorContains(t, err, "java.lang.Double value is not a number")
+}
+
+func TestFixedArgEqualsKeepsTheOrderOfTheCollection(t *testing.T) {
+	f1 := FixedArg{Collection: &Collection{Value: []string{"C", "A", "B"}}}
+	f2 := FixedArg{Collection: &Collection{Value: []string{"B", "C", "A"}}}
+	assert.Assert(t, f1.Equals(&f2))
+	assert.DeepEqual(t, f1.Collection.Value, []string{"C", "A", "B"})
+	assert.DeepEqual(t, f2.Collection.Value, []string{"B", "C", "A"})
+}
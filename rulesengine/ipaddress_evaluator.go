@@ -99,17 +99,5 @@ func (e *IpAddressEvaluator) Evaluate(condition *Condition, context map[string]s
 		return false
 	}
 
-	for _, s := range nsList.Data {
-		ipAddr := shared.NewIpAddress(s)
-		if ipAddr != nil {
-			if ipAddr.IsInRange(freeArgValue) {
-				return true
-			}
-		} else {
-			if s == freeArgValue {
-				return true
-			}
-		}
-	}
-	return false
+	return nsList.IsInIpListData(freeArgValue)
 }
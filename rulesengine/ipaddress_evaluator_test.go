@@ -488,3 +488,33 @@ func TestIpAddressEvaluator_Interface_Compliance(t *testing.T) {
 	assert.Equal(t, StandardOperationIn, iface.Operation(), "Interface should return correct Operation")
 	assert.Assert(t, iface != nil, "Interface should not be nil")
 }
+
+func TestIpAddressEvaluator_Evaluate_IpList_MixedFamilies(t *testing.T) {
+	ipList := &shared.GenericNamespacedList{
+		ID:       "mixed-ip-list",
+		TypeName: shared.IpList,
+		Data:     []string{"192.168.1.0/24", "2001:558:6027:180::/57", "2001:db8::1"},
+	}
+	mockDao := &MockCachedSimpleDao{
+		data: map[string]interface{}{
+			"mixed-ip-list": ipList,
+		},
+	}
+	evaluator := NewIpAddressEvaluator(StandardFreeArgTypeString, StandardOperationIn, mockDao)
+	condition := NewCondition(
+		NewFreeArg(StandardFreeArgTypeString, "ipAddress"),
+		StandardOperationIn,
+		NewFixedArg("mixed-ip-list"),
+	)
+
+	for ip, expected := range map[string]bool{
+		"192.168.1.150":           true,
+		"::ffff:192.168.1.150":    true,
+		"2001:558:6027:180::1234": true,
+		"2001:DB8::1":             true,
+		"2001:558:4321:180::2345": false,
+		"172.16.0.1":              false,
+	} {
+		assert.Equal(t, expected, evaluator.Evaluate(condition, map[string]string{"ipAddress": ip}), ip)
+	}
+}
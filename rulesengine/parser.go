@@ -17,20 +17,488 @@
  */
 package rulesengine
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The text rule language maps one-to-one onto the Rule tree:
+//
+//	rule      := [ part { ( AND | OR ) part } ]
+//	part      := [ NOT ] ( "(" rule ")" | condition )
+//	condition := freeArg [ ":" TYPE ] OPERATION [ fixedArg ]
+//	freeArg   := identifier | string
+//	fixedArg  := string | number | "[" [ string { "," string } ] "]"
+//
+// e.g. model IS "X1" AND (env IN ["QA","DEV"] OR NOT estbMacAddress IN_LIST "lab-macs")
+//
+// A sequence of parts becomes the CompoundParts of one Rule, each part carrying
+// the relation that precedes it. RuleProcessor.Evaluate ends the sequence at a false
+// result before an AND and skips the part after an OR once the result is true, so
+// OR binds tighter than AND: a AND b OR c means a AND (b OR c), and a OR b AND c
+// means (a OR b) AND c. Use parentheses to group otherwise.
+// The free arg type defaults to STRING. Only EXISTS takes no fixed arg.
+
+const (
+	keywordAnd = RelationAnd
+	keywordOr  = RelationOr
+	keywordNot = "NOT"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenComma
+	tokenColon
+)
+
+var tokenKindNames = map[tokenKind]string{
+	tokenEOF:      "end of input",
+	tokenIdent:    "identifier",
+	tokenString:   "string",
+	tokenNumber:   "number",
+	tokenLParen:   "'('",
+	tokenRParen:   "')'",
+	tokenLBracket: "'['",
+	tokenRBracket: "']'",
+	tokenComma:    "','",
+	tokenColon:    "':'",
+}
+
+type token struct {
+	kind   tokenKind
+	text   string
+	line   int
+	column int
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokenIdent, tokenNumber:
+		return fmt.Sprintf("'%v'", t.text)
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+	return tokenKindNames[t.kind]
+}
+
+// ParseError reports where in the rule text parsing failed. Line and Column are 1-based.
+type ParseError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %v, column %v: %v", e.Line, e.Column, e.Message)
+}
+
 type Parser struct {
-	str  string
-	rule *Rule
-	// infixQueue   []Token
-	// postfixQueue []Token
+	str    string
+	rule   *Rule
+	err    error
+	tokens []token
+	pos    int
 }
 
 func NewParser(str string) *Parser {
 	return &Parser{
-		str:  str,
-		rule: parse(),
+		str: str,
+	}
+}
+
+// ParseRule parses the text form of a rule, see Rule.String()
+func ParseRule(str string) (*Rule, error) {
+	return NewParser(str).Parse()
+}
+
+// Parse returns the rule described by the parser input. An empty input yields an empty rule.
+func (p *Parser) Parse() (*Rule, error) {
+	if p.rule != nil || p.err != nil {
+		return p.rule, p.err
+	}
+	p.rule, p.err = p.parse()
+	return p.rule, p.err
+}
+
+func (p *Parser) parse() (*Rule, error) {
+	tokens, err := tokenize(p.str)
+	if err != nil {
+		return nil, err
+	}
+	p.tokens = tokens
+	p.pos = 0
+
+	if p.peek().kind == tokenEOF {
+		return NewEmptyRule(), nil
+	}
+	rule, err := p.parseRule()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorAt(tok, "unexpected %v, expected AND, OR or end of input", tok.describe())
+	}
+	return rule, nil
+}
+
+func (p *Parser) parseRule() (*Rule, error) {
+	first, err := p.parsePart()
+	if err != nil {
+		return nil, err
+	}
+	parts := []Rule{*first}
+	for {
+		tok := p.peek()
+		if !isKeyword(tok, keywordAnd) && !isKeyword(tok, keywordOr) {
+			break
+		}
+		p.next()
+		part, err := p.parsePart()
+		if err != nil {
+			return nil, err
+		}
+		part.SetRelation(tok.text)
+		parts = append(parts, *part)
+	}
+	if len(parts) == 1 {
+		return first, nil
+	}
+	rule := NewEmptyRule()
+	rule.SetCompoundParts(parts)
+	return rule, nil
+}
+
+func (p *Parser) parsePart() (*Rule, error) {
+	negated := false
+	if isKeyword(p.peek(), keywordNot) {
+		p.next()
+		negated = true
+	}
+
+	var rule *Rule
+	if tok := p.peek(); tok.kind == tokenLParen {
+		p.next()
+		inner, err := p.parseRule()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen); err != nil {
+			return nil, err
+		}
+		rule = inner
+	} else {
+		condition, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		rule = &Rule{Condition: condition}
+	}
+
+	if negated {
+		if rule.IsNegated() {
+			// keep "NOT (NOT x)" as written instead of silently cancelling it out
+			rule = &Rule{CompoundParts: []Rule{*rule}}
+		}
+		rule.SetNegated(true)
+	}
+	return rule, nil
+}
+
+func (p *Parser) parseCondition() (*Condition, error) {
+	tok := p.next()
+	if tok.kind != tokenIdent && tok.kind != tokenString {
+		return nil, p.errorAt(tok, "unexpected %v, expected a free arg", tok.describe())
+	}
+	if tok.kind == tokenIdent && isReservedWord(tok.text) {
+		return nil, p.errorAt(tok, "unexpected %v, expected a free arg", tok.describe())
+	}
+	freeArg := NewFreeArg(StandardFreeArgTypeString, tok.text)
+
+	if p.peek().kind == tokenColon {
+		p.next()
+		typeTok, err := p.expect(tokenIdent)
+		if err != nil {
+			return nil, err
+		}
+		freeArg.SetType(typeTok.text)
+	}
+
+	opTok := p.next()
+	if opTok.kind != tokenIdent || isReservedWord(opTok.text) {
+		return nil, p.errorAt(opTok, "unexpected %v, expected an operation", opTok.describe())
+	}
+	operation := opTok.text
+
+	var fixedArg *FixedArg
+	switch p.peek().kind {
+	case tokenString, tokenNumber, tokenLBracket:
+		if operation == StandardOperationExists {
+			return nil, p.errorAt(p.peek(), "operation %v does not take a fixed arg", operation)
+		}
+		var err error
+		if fixedArg, err = p.parseFixedArg(); err != nil {
+			return nil, err
+		}
+	default:
+		if operation != StandardOperationExists {
+			next := p.peek()
+			return nil, p.errorAt(next, "unexpected %v, expected a fixed arg for operation %v", next.describe(), operation)
+		}
 	}
+	return NewCondition(freeArg, operation, fixedArg), nil
 }
 
-func parse() *Rule {
-	return &Rule{}
+func (p *Parser) parseFixedArg() (*FixedArg, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return NewFixedArg(tok.text), nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorAt(tok, "invalid number %v", tok.describe())
+		}
+		return NewFixedArg(value), nil
+	}
+
+	// collection
+	values := []string{}
+	if p.peek().kind == tokenRBracket {
+		p.next()
+		return &FixedArg{Collection: &Collection{Value: values}}, nil
+	}
+	for {
+		item, err := p.expect(tokenString)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, item.text)
+		sep := p.next()
+		if sep.kind == tokenRBracket {
+			break
+		}
+		if sep.kind != tokenComma {
+			return nil, p.errorAt(sep, "unexpected %v, expected ',' or ']'", sep.describe())
+		}
+	}
+	return &FixedArg{Collection: &Collection{Value: values}}, nil
+}
+
+func (p *Parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *Parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *Parser) expect(kind tokenKind) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return tok, p.errorAt(tok, "unexpected %v, expected %v", tok.describe(), tokenKindNames[kind])
+	}
+	return tok, nil
+}
+
+func (p *Parser) errorAt(tok token, format string, args ...interface{}) error {
+	return &ParseError{
+		Line:    tok.line,
+		Column:  tok.column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func isKeyword(tok token, keyword string) bool {
+	return tok.kind == tokenIdent && tok.text == keyword
+}
+
+func isReservedWord(s string) bool {
+	return s == keywordAnd || s == keywordOr || s == keywordNot
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func tokenize(str string) ([]token, error) {
+	runes := []rune(str)
+	tokens := []token{}
+	line, column := 1, 1
+	i := 0
+
+	advance := func() {
+		if runes[i] == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+		i++
+	}
+
+	singles := map[rune]tokenKind{
+		'(': tokenLParen,
+		')': tokenRParen,
+		'[': tokenLBracket,
+		']': tokenRBracket,
+		',': tokenComma,
+		':': tokenColon,
+	}
+
+	for i < len(runes) {
+		r := runes[i]
+		if unicode.IsSpace(r) {
+			advance()
+			continue
+		}
+
+		tok := token{line: line, column: column}
+		start := i
+		switch {
+		case singles[r] != tokenEOF:
+			tok.kind = singles[r]
+			tok.text = string(r)
+			advance()
+		case r == '"':
+			advance()
+			closed := false
+			for i < len(runes) {
+				c := runes[i]
+				if c == '\\' && i+1 < len(runes) {
+					advance()
+				} else if c == '"' {
+					advance()
+					closed = true
+					break
+				} else if c == '\n' {
+					break
+				}
+				advance()
+			}
+			if !closed {
+				return nil, &ParseError{Line: tok.line, Column: tok.column, Message: "unterminated string"}
+			}
+			value, err := strconv.Unquote(string(runes[start:i]))
+			if err != nil {
+				return nil, &ParseError{Line: tok.line, Column: tok.column, Message: "invalid string " + string(runes[start:i])}
+			}
+			tok.kind = tokenString
+			tok.text = value
+		case r == '-' || unicode.IsDigit(r):
+			advance()
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || strings.ContainsRune(".eE+-", runes[i])) {
+				advance()
+			}
+			tok.kind = tokenNumber
+			tok.text = string(runes[start:i])
+			if _, err := strconv.ParseFloat(tok.text, 64); err != nil {
+				return nil, &ParseError{Line: tok.line, Column: tok.column, Message: fmt.Sprintf("invalid number '%v'", tok.text)}
+			}
+		case isIdentStart(r):
+			for i < len(runes) && isIdentPart(runes[i]) {
+				advance()
+			}
+			tok.kind = tokenIdent
+			tok.text = string(runes[start:i])
+		default:
+			return nil, &ParseError{Line: line, Column: column, Message: fmt.Sprintf("unexpected character '%c'", r)}
+		}
+		tokens = append(tokens, tok)
+	}
+	tokens = append(tokens, token{kind: tokenEOF, line: line, column: column})
+	return tokens, nil
+}
+
+// writeRule prints r in the text rule language, parenthesizing nested compound rules
+func writeRule(sb *strings.Builder, r *Rule, nested bool) {
+	if !r.IsCompound() {
+		if r.IsNegated() {
+			sb.WriteString(keywordNot + " ")
+		}
+		writeCondition(sb, r.GetCondition())
+		return
+	}
+
+	parens := nested || r.IsNegated()
+	if r.IsNegated() {
+		sb.WriteString(keywordNot + " ")
+	}
+	if parens {
+		sb.WriteString(LeftParan)
+	}
+	for i := range r.CompoundParts {
+		cp := &r.CompoundParts[i]
+		if i > 0 {
+			relation := cp.GetRelation()
+			if relation != RelationOr {
+				relation = RelationAnd
+			}
+			sb.WriteString(SpaceChar + relation + SpaceChar)
+		}
+		writeRule(sb, cp, true)
+	}
+	if parens {
+		sb.WriteString(RightParan)
+	}
+}
+
+func writeCondition(sb *strings.Builder, c *Condition) {
+	freeArg := c.GetFreeArg()
+	if freeArg != nil {
+		sb.WriteString(formatName(freeArg.GetName()))
+		if freeArg.GetType() != StandardFreeArgTypeString {
+			sb.WriteString(":" + freeArg.GetType())
+		}
+	} else {
+		sb.WriteString(`""`)
+	}
+	sb.WriteString(SpaceChar + c.GetOperation())
+
+	fixedArg := c.GetFixedArg()
+	if fixedArg == nil {
+		return
+	}
+	if fixedArg.Collection != nil {
+		values := make([]string, len(fixedArg.Collection.Value))
+		for i, v := range fixedArg.Collection.Value {
+			values[i] = strconv.Quote(v)
+		}
+		sb.WriteString(" [" + strings.Join(values, ", ") + "]")
+	} else if fixedArg.IsStringValue() {
+		sb.WriteString(SpaceChar + strconv.Quote(*fixedArg.Bean.Value.JLString))
+	} else if fixedArg.IsDoubleValue() {
+		sb.WriteString(SpaceChar + strconv.FormatFloat(*fixedArg.Bean.Value.JLDouble, 'g', -1, 64))
+	}
+}
+
+func formatName(name string) string {
+	runes := []rune(name)
+	if len(runes) == 0 || !isIdentStart(runes[0]) || isReservedWord(name) {
+		return strconv.Quote(name)
+	}
+	for _, r := range runes {
+		if !isIdentPart(r) {
+			return strconv.Quote(name)
+		}
+	}
+	return name
 }
@@ -1,48 +1,202 @@
 package rulesengine
 
 import (
+	"encoding/json"
 	"testing"
 
 	"gotest.tools/assert"
 )
 
 func TestNewParser(t *testing.T) {
-	// Test creating a new parser
 	t.Run("Create_parser_with_string", func(t *testing.T) {
-		parser := NewParser("test string")
+		parser := NewParser(`model IS "TG1682G"`)
 		assert.Assert(t, parser != nil, "Parser should not be nil")
-		assert.Equal(t, "test string", parser.str)
-		assert.Assert(t, parser.rule != nil, "Parser rule should not be nil")
+		assert.Equal(t, `model IS "TG1682G"`, parser.str)
+		assert.Assert(t, parser.rule == nil, "Parser should not parse until asked")
 	})
 
-	// Test with empty string
-	t.Run("Create_parser_with_empty_string", func(t *testing.T) {
-		parser := NewParser("")
-		assert.Assert(t, parser != nil, "Parser should not be nil")
-		assert.Equal(t, "", parser.str)
-		assert.Assert(t, parser.rule != nil, "Parser rule should not be nil")
+	t.Run("Parse_is_cached", func(t *testing.T) {
+		parser := NewParser(`model IS "TG1682G"`)
+		rule1, err := parser.Parse()
+		assert.NilError(t, err)
+		rule2, err := parser.Parse()
+		assert.NilError(t, err)
+		assert.Assert(t, rule1 == rule2)
 	})
 
-	// Test with complex string
-	t.Run("Create_parser_with_complex_string", func(t *testing.T) {
-		complexStr := "model IS TG1682G AND env IN (QA,PROD)"
-		parser := NewParser(complexStr)
-		assert.Assert(t, parser != nil, "Parser should not be nil")
-		assert.Equal(t, complexStr, parser.str)
-		assert.Assert(t, parser.rule != nil, "Parser rule should not be nil")
+	t.Run("Parse_empty_string", func(t *testing.T) {
+		rule, err := ParseRule("  \n ")
+		assert.NilError(t, err)
+		assert.Assert(t, rule.IsEmpty())
+		assert.Equal(t, "", rule.String())
 	})
 }
 
-func TestParse(t *testing.T) {
-	// Test parse function returns empty rule
-	t.Run("Parse_returns_empty_rule", func(t *testing.T) {
-		rule := parse()
-		assert.Assert(t, rule != nil, "Parse should return a rule")
-		
-		// Verify it's an empty rule
-		assert.Assert(t, rule.Condition == nil, "Condition should be nil")
-		assert.Assert(t, len(rule.CompoundParts) == 0, "CompoundParts should be empty")
-		assert.Equal(t, "", rule.Xxid)
-		assert.Equal(t, false, rule.Negated)
-	})
+func TestParseRuleSimpleCondition(t *testing.T) {
+	rule, err := ParseRule(`model IS "X1"`)
+	assert.NilError(t, err)
+	expected := Rule{
+		Condition: NewCondition(NewFreeArg(StandardFreeArgTypeString, "model"), StandardOperationIs, NewFixedArg("X1")),
+	}
+	assert.Assert(t, expected.Equals(rule))
+	assert.Assert(t, !rule.IsCompound())
+}
+
+func TestParseRuleTree(t *testing.T) {
+	rule, err := ParseRule(`model IS "X1" AND (env IN ["QA","DEV"] OR NOT estbMacAddress IN_LIST "lab-macs")`)
+	assert.NilError(t, err)
+
+	model := Rule{Condition: NewCondition(NewFreeArg(StandardFreeArgTypeString, "model"), StandardOperationIs, NewFixedArg("X1"))}
+	env := Rule{Condition: NewCondition(NewFreeArg(StandardFreeArgTypeString, "env"), StandardOperationIn, NewFixedArg([]string{"QA", "DEV"}))}
+	mac := Rule{Condition: NewCondition(NewFreeArg(StandardFreeArgTypeString, "estbMacAddress"), StandardOperationInList, NewFixedArg("lab-macs"))}
+	mac.SetNegated(true)
+	mac.SetRelation(RelationOr)
+	group := Rule{CompoundParts: []Rule{env, mac}}
+	group.SetRelation(RelationAnd)
+	expected := Rule{CompoundParts: []Rule{model, group}}
+
+	assert.Assert(t, expected.Equals(rule), rule.String())
+	assert.Equal(t, 2, len(rule.CompoundParts))
+	assert.Equal(t, "", rule.CompoundParts[0].Relation)
+	assert.Equal(t, RelationAnd, rule.CompoundParts[1].Relation)
+	assert.Equal(t, "", rule.CompoundParts[1].CompoundParts[0].Relation)
+	assert.Equal(t, RelationOr, rule.CompoundParts[1].CompoundParts[1].Relation)
+}
+
+func TestParseRuleFixedArgsAndTypes(t *testing.T) {
+	rule, err := ParseRule(`estbIP:IP_ADDRESS IN_LIST "office" AND eStbMac PERCENT 12.5 AND certExpiryDuration:LONG GT 30 AND rebootDecoupled:ANY EXISTS AND "odd name" IS ""`)
+	assert.NilError(t, err)
+	parts := rule.CompoundParts
+	assert.Equal(t, 5, len(parts))
+
+	assert.Equal(t, AuxFreeArgTypeIpAddress, parts[0].GetFreeArg().GetType())
+	assert.Equal(t, "office", parts[0].GetCondition().GetFixedArg().GetValue())
+
+	assert.Equal(t, StandardFreeArgTypeString, parts[1].GetFreeArg().GetType())
+	assert.Assert(t, parts[1].GetCondition().GetFixedArg().IsDoubleValue())
+	assert.Equal(t, 12.5, parts[1].GetCondition().GetFixedArg().GetValue())
+
+	assert.Equal(t, StandardFreeArgTypeLong, parts[2].GetFreeArg().GetType())
+	assert.Equal(t, float64(30), parts[2].GetCondition().GetFixedArg().GetValue())
+
+	assert.Equal(t, StandardFreeArgTypeAny, parts[3].GetFreeArg().GetType())
+	assert.Equal(t, StandardOperationExists, parts[3].GetCondition().GetOperation())
+	assert.Assert(t, parts[3].GetCondition().GetFixedArg() == nil)
+
+	assert.Equal(t, "odd name", parts[4].GetFreeArg().GetName())
+	assert.Equal(t, "", parts[4].GetCondition().GetFixedArg().GetValue())
+}
+
+func TestParseRuleNegation(t *testing.T) {
+	rule, err := ParseRule(`NOT (model IS "A" OR model IS "B")`)
+	assert.NilError(t, err)
+	assert.Assert(t, rule.IsCompound())
+	assert.Assert(t, rule.IsNegated())
+	assert.Equal(t, 2, len(rule.CompoundParts))
+
+	rule, err = ParseRule(`NOT (NOT model IS "A")`)
+	assert.NilError(t, err)
+	assert.Assert(t, rule.IsNegated())
+	assert.Equal(t, 1, len(rule.CompoundParts))
+	assert.Assert(t, rule.CompoundParts[0].IsNegated())
+	assert.Equal(t, `NOT (NOT model IS "A")`, rule.String())
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	testCases := []struct {
+		text   string
+		line   int
+		column int
+	}{
+		{`model IS`, 1, 9},
+		{`model IS "X1" AND`, 1, 18},
+		{`model IS "X1" model IS "X2"`, 1, 15},
+		{`(model IS "X1"`, 1, 15},
+		{`model IS "X1`, 1, 10},
+		{"model IS \"X1\" AND\n  env IN [\"QA\" \"DEV\"]", 2, 16},
+		{"model IS \"X1\"\nOR env IN [QA]", 2, 12},
+		{`model EXISTS "X1"`, 1, 14},
+		{`AND IS "X1"`, 1, 1},
+		{`model IS "X1" # comment`, 1, 15},
+		{`model: IS "X1"`, 1, 11},
+		{`model IS 1.2.3`, 1, 10},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			_, err := ParseRule(tc.text)
+			assert.Assert(t, err != nil)
+			perr, ok := err.(*ParseError)
+			assert.Assert(t, ok, "expected a ParseError, got %T", err)
+			assert.Equal(t, tc.line, perr.Line, perr.Error())
+			assert.Equal(t, tc.column, perr.Column, perr.Error())
+		})
+	}
+}
+
+func TestRuleStringRoundTrip(t *testing.T) {
+	texts := []string{
+		`model IS "X1"`,
+		`NOT env IS "PROD"`,
+		`model IS "X1" AND (env IN ["QA", "DEV"] OR NOT estbMacAddress IN_LIST "lab-macs")`,
+		`a IS "1" OR b IS "2" AND c IS "3"`,
+		`NOT (a IS "1" OR (b IS "2" AND NOT (c IS "3" OR d IS "4")))`,
+		`estbIP:IP_ADDRESS IN_LIST "office" AND eStbMac PERCENT 12.5`,
+		`time:TIME GTE "01:00:00" AND "tag with \"quotes\"" IS "line\nbreak"`,
+		`rebootDecoupled:ANY EXISTS AND env IN []`,
+		`"AND" IS "reserved"`,
+	}
+	for _, text := range texts {
+		t.Run(text, func(t *testing.T) {
+			rule, err := ParseRule(text)
+			assert.NilError(t, err)
+			assert.Equal(t, text, rule.String())
+
+			reparsed, err := ParseRule(rule.String())
+			assert.NilError(t, err)
+			assert.Assert(t, rule.Equals(reparsed))
+		})
+	}
+}
+
+func TestRuleStringRoundTripFromJson(t *testing.T) {
+	ruleJson := `{"negated":false,"compoundParts":[{"negated":false,"condition":{"freeArg":{"type":"STRING","name":"model"},"operation":"IS","fixedArg":{"bean":{"value":{"java.lang.String":"TG1682G"}}}}},{"negated":false,"relation":"AND","compoundParts":[{"negated":false,"condition":{"freeArg":{"type":"STRING","name":"env"},"operation":"IN","fixedArg":{"collection":{"value":["QA","DEV"]}}}},{"negated":true,"relation":"OR","condition":{"freeArg":{"type":"STRING","name":"eStbMac"},"operation":"PERCENT","fixedArg":{"bean":{"value":{"java.lang.Double":50.0}}}}}]}]}`
+	var rule Rule
+	assert.NilError(t, json.Unmarshal([]byte(ruleJson), &rule))
+
+	text := rule.String()
+	assert.Equal(t, `model IS "TG1682G" AND (env IN ["QA", "DEV"] OR NOT eStbMac PERCENT 50)`, text)
+
+	reparsed, err := ParseRule(text)
+	assert.NilError(t, err)
+	assert.Assert(t, rule.Equals(reparsed))
+}
+
+func TestParsedRuleEvaluation(t *testing.T) {
+	processor := NewRuleProcessor()
+	rule, err := ParseRule(`model IS "X1" AND (env IN ["QA","DEV"] OR NOT firmwareVersion IS "1.0")`)
+	assert.NilError(t, err)
+
+	assert.Assert(t, processor.Evaluate(rule, map[string]string{"model": "X1", "env": "QA", "firmwareVersion": "1.0"}, nil))
+	assert.Assert(t, processor.Evaluate(rule, map[string]string{"model": "X1", "env": "PROD", "firmwareVersion": "2.0"}, nil))
+	assert.Assert(t, !processor.Evaluate(rule, map[string]string{"model": "X1", "env": "PROD", "firmwareVersion": "1.0"}, nil))
+	assert.Assert(t, !processor.Evaluate(rule, map[string]string{"model": "X2", "env": "QA", "firmwareVersion": "1.0"}, nil))
+}
+
+func TestParsedRuleEvaluationMixedRelations(t *testing.T) {
+	processor := NewRuleProcessor()
+	context := func(a, b, c string) map[string]string {
+		return map[string]string{"a": a, "b": b, "c": c}
+	}
+
+	// a AND b OR c is a AND (b OR c), not (a AND b) OR c
+	rule, err := ParseRule(`a IS "1" AND b IS "1" OR c IS "1"`)
+	assert.NilError(t, err)
+	assert.Assert(t, !processor.Evaluate(rule, context("0", "0", "1"), nil))
+	assert.Assert(t, processor.Evaluate(rule, context("1", "0", "1"), nil))
+
+	// a OR b AND c is (a OR b) AND c, not a OR (b AND c)
+	rule, err = ParseRule(`a IS "1" OR b IS "1" AND c IS "1"`)
+	assert.NilError(t, err)
+	assert.Assert(t, !processor.Evaluate(rule, context("1", "0", "0"), nil))
+	assert.Assert(t, processor.Evaluate(rule, context("1", "0", "1"), nil))
 }
@@ -18,8 +18,8 @@
 package rulesengine
 
 import (
-	"bytes"
 	"fmt"
+	"strings"
 )
 
 const (
@@ -125,20 +125,13 @@ func (r *Rule) conditionString() string {
 	return r.Condition.String()
 }
 
+// String prints the rule in the text rule language accepted by ParseRule
 func (r *Rule) String() string {
-	// return fmt.Sprintf("Rule(Negated=%v, Relation='%v', Condition='%v', CompoundParts='%v')", r.Negated, r.Relation, r.Condition, r.CompoundParts)
-	if len(r.CompoundParts) > 0 {
-		start := fmt.Sprintf("Rule(%v%v%v", r.relationString(), r.negatedString(), r.conditionString())
-		buffer := bytes.NewBufferString(start)
-		buffer.WriteString("  CompoundParts:[\n")
-		for _, cp := range r.CompoundParts {
-			buffer.WriteString(fmt.Sprintf("    %v\n", cp.String()))
-		}
-		buffer.WriteString("  ]")
-		buffer.WriteString(")")
-		return buffer.String()
+	var sb strings.Builder
+	if !r.IsEmpty() {
+		writeRule(&sb, r, false)
 	}
-	return fmt.Sprintf("Rule(%v%v%v)", r.relationString(), r.negatedString(), r.conditionString())
+	return sb.String()
 }
 
 // NOTE: my understanding is the ordering of the CompoundParts matters
@@ -0,0 +1,343 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"fmt"
+
+	"github.com/rdkcentral/xconfwebconfig/util"
+)
+
+const (
+	RuleIssueContradiction       = "CONTRADICTION"
+	RuleIssueMissingList         = "MISSING_NAMESPACED_LIST"
+	RuleIssueShadowed            = "SHADOWED"
+	RuleIssueDuplicateCondition  = "DUPLICATE_CONDITION"
+	RuleIssueEmptyRule           = "EMPTY_RULE"
+	ruleIssueMessageContradicted = "rule can never match, %v contradicts %v"
+)
+
+// RuleIssue is a problem found by the RuleAnalyzer
+type RuleIssue struct {
+	Type            string `json:"type"`
+	RuleId          string `json:"ruleId"`
+	RuleName        string `json:"ruleName,omitempty"`
+	RuleType        string `json:"ruleType,omitempty"`
+	RelatedRuleId   string `json:"relatedRuleId,omitempty"`
+	RelatedRuleName string `json:"relatedRuleName,omitempty"`
+	Condition       string `json:"condition,omitempty"`
+	Message         string `json:"message"`
+}
+
+// RuleAnalyzer statically finds rules that can never match or that are shadowed by other rules.
+// The analysis is conservative, an issue is only reported when it holds for every context.
+type RuleAnalyzer struct {
+	// NamespacedListExists reports whether a namespaced list exists, a nil func skips the check
+	NamespacedListExists func(id string) bool
+}
+
+func NewRuleAnalyzer(namespacedListExists func(id string) bool) *RuleAnalyzer {
+	return &RuleAnalyzer{
+		NamespacedListExists: namespacedListExists,
+	}
+}
+
+// literal is a condition that must evaluate to !negated for the rule to match
+type literal struct {
+	condition *Condition
+	negated   bool
+}
+
+// Analyze checks each rule on its own, then reports rules shadowed by an earlier rule.
+// The rules must compete with each other and be in evaluation order, highest priority first.
+func (a *RuleAnalyzer) Analyze(rules []XRule) []RuleIssue {
+	return a.AnalyzeCovered(rules, nil)
+}
+
+// AnalyzeCovered is Analyze for rules that all apply when they match, like feature rules whose features
+// are merged. A rule is only shadowed by an earlier rule when covers reports that the earlier rule already
+// provides everything the rule provides. A nil covers means the first matching rule wins.
+func (a *RuleAnalyzer) AnalyzeCovered(rules []XRule, covers func(earlier XRule, rule XRule) bool) []RuleIssue {
+	issues := []RuleIssue{}
+	for _, xrule := range rules {
+		issues = append(issues, a.AnalyzeRule(xrule)...)
+	}
+
+	for j := 1; j < len(rules); j++ {
+		rule := rules[j].GetRule()
+		if rule.IsEmpty() {
+			continue
+		}
+		for i := 0; i < j; i++ {
+			earlier := rules[i].GetRule()
+			if earlier.IsEmpty() || !Implies(rule, earlier) {
+				continue
+			}
+			if covers != nil && !covers(rules[i], rules[j]) {
+				continue
+			}
+			issue := newRuleIssue(RuleIssueShadowed, rules[j], "")
+			issue.RelatedRuleId = rules[i].GetId()
+			issue.RelatedRuleName = rules[i].GetName()
+			if covers == nil {
+				issue.Message = fmt.Sprintf("rule is shadowed by %v, which matches every context this rule matches", describeRule(rules[i]))
+			} else {
+				issue.Message = fmt.Sprintf("rule is shadowed by %v, which matches every context this rule matches and provides all it provides", describeRule(rules[i]))
+			}
+			issues = append(issues, issue)
+			break
+		}
+	}
+	return issues
+}
+
+// AnalyzeRule checks a single rule for contradictions, missing namespaced lists and duplicate conditions
+func (a *RuleAnalyzer) AnalyzeRule(xrule XRule) []RuleIssue {
+	issues := []RuleIssue{}
+	rule := xrule.GetRule()
+	if rule.IsEmpty() {
+		issue := newRuleIssue(RuleIssueEmptyRule, xrule, "")
+		issue.Message = "rule has no conditions and can never match"
+		return append(issues, issue)
+	}
+
+	literals := requiredLiterals(rule, nil)
+	for i := range literals {
+		if literals[i].alwaysFalse() {
+			issue := newRuleIssue(RuleIssueContradiction, xrule, literals[i].String())
+			issue.Message = fmt.Sprintf("rule can never match, %v is never true", literals[i].String())
+			issues = append(issues, issue)
+			continue
+		}
+		for j := 0; j < i; j++ {
+			if contradicts(literals[j], literals[i]) {
+				issue := newRuleIssue(RuleIssueContradiction, xrule, literals[i].String())
+				issue.Message = fmt.Sprintf(ruleIssueMessageContradicted, literals[i].String(), literals[j].String())
+				issues = append(issues, issue)
+				break
+			}
+		}
+	}
+
+	if a.NamespacedListExists != nil {
+		for _, condition := range ToConditions(rule) {
+			if condition.GetOperation() != StandardOperationInList {
+				continue
+			}
+			listId, ok := condition.GetFixedArg().GetValue().(string)
+			if ok && a.NamespacedListExists(listId) {
+				continue
+			}
+			issue := newRuleIssue(RuleIssueMissingList, xrule, conditionText(condition))
+			issue.Message = fmt.Sprintf("namespaced list '%v' does not exist", condition.GetFixedArg().GetValue())
+			issues = append(issues, issue)
+		}
+	}
+
+	for _, condition := range GetDuplicateConditionsFromRule(*rule) {
+		issue := newRuleIssue(RuleIssueDuplicateCondition, xrule, conditionText(&condition))
+		issue.Message = "condition appears more than once"
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// Implies returns true when rule1 matching guarantees that rule2 matches. Only rule2 that are
+// conjunctions can be proven, any other rule2 returns false.
+func Implies(rule1 *Rule, rule2 *Rule) bool {
+	if rule1.IsEmpty() || rule2.IsEmpty() {
+		return false
+	}
+	if EqualComplexRules(rule1, rule2) {
+		return true
+	}
+	conjuncts, ok := conjunctionLiterals(rule2)
+	if !ok {
+		return false
+	}
+	literals := requiredLiterals(rule1, nil)
+	for _, target := range conjuncts {
+		implied := false
+		for _, l := range literals {
+			if impliesLiteral(l, target) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			return false
+		}
+	}
+	return true
+}
+
+func newRuleIssue(issueType string, xrule XRule, condition string) RuleIssue {
+	return RuleIssue{
+		Type:      issueType,
+		RuleId:    xrule.GetId(),
+		RuleName:  xrule.GetName(),
+		RuleType:  xrule.GetRuleType(),
+		Condition: condition,
+	}
+}
+
+func describeRule(xrule XRule) string {
+	if name := xrule.GetName(); name != "" {
+		return fmt.Sprintf("'%v' (%v)", name, xrule.GetId())
+	}
+	return xrule.GetId()
+}
+
+func conditionText(condition *Condition) string {
+	return (&Rule{Condition: condition}).String()
+}
+
+func (l literal) String() string {
+	rule := Rule{Condition: l.condition, Negated: l.negated}
+	return rule.String()
+}
+
+// requiredLiterals is like requiredConditions() but keeps negated conditions
+func requiredLiterals(r *Rule, literals []literal) []literal {
+	if !r.IsCompound() {
+		if r.GetCondition().GetFreeArg() == nil {
+			return literals
+		}
+		return append(literals, literal{condition: r.GetCondition(), negated: r.IsNegated()})
+	}
+	if r.IsNegated() {
+		return literals
+	}
+	for i := range r.CompoundParts {
+		if i > 0 && r.CompoundParts[i].GetRelation() != RelationAnd {
+			return literals
+		}
+	}
+	for i := range r.CompoundParts {
+		literals = requiredLiterals(&r.CompoundParts[i], literals)
+	}
+	return literals
+}
+
+// conjunctionLiterals returns the literals of a rule that is exactly their conjunction
+func conjunctionLiterals(r *Rule) ([]literal, bool) {
+	literals := requiredLiterals(r, nil)
+	if len(literals) != len(FlattenRule(*r)) {
+		return nil, false
+	}
+	return literals, true
+}
+
+// stringValues returns the values a STRING IS/IN condition accepts
+func (l literal) stringValues() ([]string, bool) {
+	if l.condition.GetFreeArg().GetType() != StandardFreeArgTypeString {
+		return nil, false
+	}
+	switch l.condition.GetOperation() {
+	case StandardOperationIs:
+		if value, ok := l.condition.GetFixedArg().GetValue().(string); ok {
+			return []string{value}, true
+		}
+	case StandardOperationIn:
+		if l.condition.GetFixedArg() != nil && l.condition.GetFixedArg().Collection != nil {
+			return l.condition.GetFixedArg().Collection.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (l literal) alwaysFalse() bool {
+	if l.negated {
+		return false
+	}
+	values, ok := l.stringValues()
+	if !ok {
+		return false
+	}
+	// the evaluators never match an empty free arg value
+	for _, value := range values {
+		if value != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func sameFreeArg(l1 literal, l2 literal) bool {
+	return l1.condition.GetFreeArg().Equals(l2.condition.GetFreeArg())
+}
+
+// contradicts returns true when both literals can never be true together
+func contradicts(l1 literal, l2 literal) bool {
+	if !sameFreeArg(l1, l2) {
+		return false
+	}
+	values1, ok1 := l1.stringValues()
+	values2, ok2 := l2.stringValues()
+	if !ok1 || !ok2 {
+		return l1.negated != l2.negated && equalConditions(l1.condition, l2.condition)
+	}
+	switch {
+	case !l1.negated && !l2.negated:
+		return !intersects(values1, values2)
+	case !l1.negated && l2.negated:
+		return subsetOf(values1, values2)
+	case l1.negated && !l2.negated:
+		return subsetOf(values2, values1)
+	}
+	return false
+}
+
+// impliesLiteral returns true when l1 being true guarantees that l2 is true
+func impliesLiteral(l1 literal, l2 literal) bool {
+	if !sameFreeArg(l1, l2) {
+		return false
+	}
+	values1, ok1 := l1.stringValues()
+	values2, ok2 := l2.stringValues()
+	if !ok1 || !ok2 {
+		return l1.negated == l2.negated && equalConditions(l1.condition, l2.condition)
+	}
+	switch {
+	case !l1.negated && !l2.negated:
+		return subsetOf(values1, values2)
+	case !l1.negated && l2.negated:
+		return !intersects(values1, values2)
+	case l1.negated && l2.negated:
+		return subsetOf(values2, values1)
+	}
+	return false
+}
+
+func intersects(values1 []string, values2 []string) bool {
+	for _, value := range values1 {
+		if util.Contains(values2, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func subsetOf(values1 []string, values2 []string) bool {
+	for _, value := range values1 {
+		if !util.Contains(values2, value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package rulesengine
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func analyzerTestRule(t *testing.T, id string, text string) XRule {
+	rule, err := ParseRule(text)
+	assert.NilError(t, err)
+	return &traceTestRule{id: id, rule: rule}
+}
+
+func issueTypes(issues []RuleIssue) []string {
+	types := []string{}
+	for _, issue := range issues {
+		types = append(types, issue.Type)
+	}
+	return types
+}
+
+func TestRuleAnalyzerContradictions(t *testing.T) {
+	analyzer := NewRuleAnalyzer(nil)
+	testCases := []struct {
+		text          string
+		contradiction bool
+	}{
+		{`model IS "A" AND model IS "B"`, true},
+		{`model IS "A" AND env IS "B"`, false},
+		{`model IS "A" AND model IN ["B", "C"]`, true},
+		{`model IS "A" AND model IN ["A", "C"]`, false},
+		{`model IN ["A", "B"] AND model IN ["C", "D"]`, true},
+		{`model IN ["A", "B"] AND model IN ["B", "D"]`, false},
+		{`model IS "A" AND NOT model IS "A"`, true},
+		{`model IS "A" AND NOT model IN ["A", "B"]`, true},
+		{`NOT model IN ["A", "B"] AND model IN ["A"]`, true},
+		{`NOT model IS "A" AND model IS "B"`, false},
+		{`model IN []`, true},
+		{`model IS ""`, true},
+		{`model IS "A" AND (env IS "QA" AND model IS "B")`, true},
+		{`model IS "A" OR model IS "B"`, false},
+		{`NOT (model IS "A" AND model IS "B")`, false},
+		{`time:TIME GTE "01:00:00" AND NOT time:TIME GTE "01:00:00"`, true},
+		{`eStbMac PERCENT 10 AND eStbMac PERCENT 20`, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			issues := analyzer.AnalyzeRule(analyzerTestRule(t, "r1", tc.text))
+			found := false
+			for _, issue := range issues {
+				if issue.Type == RuleIssueContradiction {
+					found = true
+					assert.Equal(t, "r1", issue.RuleId)
+					assert.Equal(t, "name-r1", issue.RuleName)
+					assert.Equal(t, "TestRule", issue.RuleType)
+				}
+			}
+			assert.Equal(t, tc.contradiction, found, "%v", issues)
+		})
+	}
+}
+
+func TestRuleAnalyzerMissingNamespacedList(t *testing.T) {
+	lists := map[string]bool{"macs": true}
+	analyzer := NewRuleAnalyzer(func(id string) bool { return lists[id] })
+
+	issues := analyzer.AnalyzeRule(analyzerTestRule(t, "r1", `estbMacAddress IN_LIST "macs" AND estbIP:IP_ADDRESS IN_LIST "missing"`))
+	assert.Equal(t, 1, len(issues), "%v", issues)
+	assert.Equal(t, RuleIssueMissingList, issues[0].Type)
+	assert.Equal(t, `estbIP:IP_ADDRESS IN_LIST "missing"`, issues[0].Condition)
+
+	// the check is skipped without a lookup
+	issues = NewRuleAnalyzer(nil).AnalyzeRule(analyzerTestRule(t, "r1", `estbMacAddress IN_LIST "missing"`))
+	assert.Equal(t, 0, len(issues))
+}
+
+func TestRuleAnalyzerDuplicateConditionsAndEmptyRule(t *testing.T) {
+	analyzer := NewRuleAnalyzer(nil)
+	issues := analyzer.AnalyzeRule(analyzerTestRule(t, "r1", `model IS "A" AND env IS "QA" AND model IS "A"`))
+	assert.DeepEqual(t, []string{RuleIssueDuplicateCondition}, issueTypes(issues))
+	assert.Equal(t, `model IS "A"`, issues[0].Condition)
+
+	issues = analyzer.AnalyzeRule(&traceTestRule{id: "r2"})
+	assert.DeepEqual(t, []string{RuleIssueEmptyRule}, issueTypes(issues))
+}
+
+func TestRuleAnalyzerShadowing(t *testing.T) {
+	analyzer := NewRuleAnalyzer(nil)
+	rules := []XRule{
+		analyzerTestRule(t, "broad", `model IN ["A", "B"] AND env IS "QA"`),
+		analyzerTestRule(t, "narrow", `model IS "A" AND env IS "QA" AND partnerId IS "p1"`),
+		analyzerTestRule(t, "other-env", `model IS "A" AND env IS "PROD"`),
+		analyzerTestRule(t, "reordered", `env IS "PROD" AND model IS "A"`),
+		analyzerTestRule(t, "or", `model IS "C" OR env IS "DEV"`),
+		analyzerTestRule(t, "or-reordered", `env IS "DEV" OR model IS "C"`),
+		analyzerTestRule(t, "negated", `NOT model IN ["A", "B", "C"] AND env IS "DEV"`),
+	}
+	issues := analyzer.Analyze(rules)
+
+	shadowed := map[string]string{}
+	for _, issue := range issues {
+		assert.Equal(t, RuleIssueShadowed, issue.Type, "%v", issue)
+		shadowed[issue.RuleId] = issue.RelatedRuleId
+	}
+	assert.DeepEqual(t, map[string]string{
+		"narrow":       "broad",
+		"reordered":    "other-env",
+		"or-reordered": "or",
+	}, shadowed)
+}
+
+func TestRuleAnalyzerShadowingCovered(t *testing.T) {
+	analyzer := NewRuleAnalyzer(nil)
+	rules := []XRule{
+		analyzerTestRule(t, "broad", `model IN ["A", "B"] AND env IS "QA"`),
+		analyzerTestRule(t, "narrow", `model IS "A" AND env IS "QA"`),
+		analyzerTestRule(t, "covered", `model IS "B" AND env IS "QA"`),
+	}
+	issues := analyzer.AnalyzeCovered(rules, func(earlier XRule, rule XRule) bool {
+		return rule.GetId() == "covered"
+	})
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, RuleIssueShadowed, issues[0].Type)
+	assert.Equal(t, "covered", issues[0].RuleId)
+	assert.Equal(t, "broad", issues[0].RelatedRuleId)
+}
+
+func TestImplies(t *testing.T) {
+	testCases := []struct {
+		rule1   string
+		rule2   string
+		implies bool
+	}{
+		{`model IS "A" AND env IS "QA"`, `model IS "A"`, true},
+		{`model IS "A"`, `model IS "A" AND env IS "QA"`, false},
+		{`model IS "A"`, `model IN ["A", "B"]`, true},
+		{`model IN ["A", "B"]`, `model IS "A"`, false},
+		{`model IS "A"`, `NOT model IS "B"`, true},
+		{`NOT model IN ["A", "B"]`, `NOT model IS "A"`, true},
+		{`NOT model IS "A"`, `NOT model IN ["A", "B"]`, false},
+		{`NOT model IS "A"`, `model IS "B"`, false},
+		{`model IS "A"`, `model IS "A" OR env IS "QA"`, false},
+		{`model IS "A" OR env IS "QA"`, `model IS "A"`, false},
+		{`eStbMac PERCENT 10 AND model IS "A"`, `eStbMac PERCENT 10`, true},
+		{`eStbMac PERCENT 10`, `eStbMac PERCENT 20`, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.rule1+" => "+tc.rule2, func(t *testing.T) {
+			rule1, err := ParseRule(tc.rule1)
+			assert.NilError(t, err)
+			rule2, err := ParseRule(tc.rule2)
+			assert.NilError(t, err)
+			assert.Equal(t, tc.implies, Implies(rule1, rule2))
+		})
+	}
+}
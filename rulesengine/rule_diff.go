@@ -0,0 +1,330 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RuleAdded    = "ADDED"
+	RuleRemoved  = "REMOVED"
+	RuleModified = "MODIFIED"
+)
+
+// DefaultSegmentBy are the context keys that make up a device segment
+var DefaultSegmentBy = []string{"model", "env"}
+
+// ruleDiffIgnoredFields are the JSON fields of a rule entity left out of the field changes, the rule
+// tree is compared by its conditions and the update time always changes
+var ruleDiffIgnoredFields = map[string]bool{
+	"rule":          true,
+	"compoundParts": true,
+	"condition":     true,
+	"negated":       true,
+	"relation":      true,
+	"xxid":          true,
+	"updated":       true,
+}
+
+// ConditionDiff lists the conditions of the normalized rules that are only in the old or only in the
+// new rule. The conditions are the parts joined by AND, so an OR group is one condition.
+type ConditionDiff struct {
+	Removed []string `json:"removed,omitempty"`
+	Added   []string `json:"added,omitempty"`
+}
+
+// FieldChange is a changed field of a rule entity, nested fields are joined with a dot, like applicableAction.configId
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// RuleImpact estimates the devices of a corpus whose evaluation of a rule changes
+type RuleImpact struct {
+	Devices  int            `json:"devices"`
+	Segments map[string]int `json:"segments,omitempty"`
+}
+
+// RuleChange is an added, removed or modified rule
+type RuleChange struct {
+	Id         string         `json:"id"`
+	Name       string         `json:"name"`
+	RuleType   string         `json:"ruleType,omitempty"`
+	Change     string         `json:"change"`
+	Conditions *ConditionDiff `json:"conditions,omitempty"`
+	Fields     []FieldChange  `json:"fields,omitempty"`
+	Impact     *RuleImpact    `json:"impact,omitempty"`
+}
+
+// RuleSetDiff is the difference between two sets of rules of the same table. The impact is only
+// estimated when a corpus of contexts is given, a device counts once however many of its rules change.
+type RuleSetDiff struct {
+	Added     []RuleChange `json:"added"`
+	Removed   []RuleChange `json:"removed"`
+	Modified  []RuleChange `json:"modified"`
+	Unchanged int          `json:"unchanged"`
+	Impact    *RuleImpact  `json:"impact,omitempty"`
+}
+
+// RuleDiffOptions are the optional corpus of contexts to estimate the impact of a change
+// and the context keys of a device segment, DefaultSegmentBy when empty
+type RuleDiffOptions struct {
+	Contexts  []map[string]string
+	SegmentBy []string
+}
+
+// DiffRuleSets compares the rules by their id
+func DiffRuleSets(oldRules []XRule, newRules []XRule, options *RuleDiffOptions) (*RuleSetDiff, error) {
+	if options == nil {
+		options = &RuleDiffOptions{}
+	}
+	oldById := map[string]XRule{}
+	for _, xrule := range oldRules {
+		oldById[xrule.GetId()] = xrule
+	}
+	newById := map[string]XRule{}
+	for _, xrule := range newRules {
+		newById[xrule.GetId()] = xrule
+	}
+
+	diff := &RuleSetDiff{Added: []RuleChange{}, Removed: []RuleChange{}, Modified: []RuleChange{}}
+	affected := make([]bool, len(options.Contexts))
+	for _, xrule := range newRules {
+		change, err := diffXRules(oldById[xrule.GetId()], xrule, options, affected)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case change == nil:
+			diff.Unchanged++
+		case change.Change == RuleAdded:
+			diff.Added = append(diff.Added, *change)
+		default:
+			diff.Modified = append(diff.Modified, *change)
+		}
+	}
+	for _, xrule := range oldRules {
+		if _, ok := newById[xrule.GetId()]; ok {
+			continue
+		}
+		change, err := diffXRules(xrule, nil, options, affected)
+		if err != nil {
+			return nil, err
+		}
+		diff.Removed = append(diff.Removed, *change)
+	}
+
+	if len(options.Contexts) > 0 {
+		diff.Impact = newRuleImpact(options, affected)
+	}
+	sortRuleChanges(diff.Added)
+	sortRuleChanges(diff.Removed)
+	sortRuleChanges(diff.Modified)
+	return diff, nil
+}
+
+// DiffXRules compares two versions of a rule, either may be nil for an added or removed rule.
+// It returns nil when nothing but the update time changed.
+func DiffXRules(oldRule XRule, newRule XRule, options *RuleDiffOptions) (*RuleChange, error) {
+	if options == nil {
+		options = &RuleDiffOptions{}
+	}
+	return diffXRules(oldRule, newRule, options, make([]bool, len(options.Contexts)))
+}
+
+// diffXRules marks the contexts whose evaluation changes in affected
+func diffXRules(oldRule XRule, newRule XRule, options *RuleDiffOptions, affected []bool) (*RuleChange, error) {
+	var change *RuleChange
+	switch {
+	case oldRule == nil && newRule == nil:
+		return nil, nil
+	case oldRule == nil:
+		change = newRuleChange(newRule, RuleAdded)
+	case newRule == nil:
+		change = newRuleChange(oldRule, RuleRemoved)
+	default:
+		fields, err := diffRuleFields(oldRule, newRule)
+		if err != nil {
+			return nil, err
+		}
+		conditions := DiffConditions(oldRule.GetRule(), newRule.GetRule())
+		if conditions == nil && len(fields) == 0 {
+			return nil, nil
+		}
+		change = newRuleChange(newRule, RuleModified)
+		change.Conditions = conditions
+		change.Fields = fields
+	}
+
+	if len(options.Contexts) > 0 {
+		changed := make([]bool, len(options.Contexts))
+		processor := NewRuleProcessor()
+		for i, context := range options.Contexts {
+			// changed fields change the result of every device the new rule matches
+			oldMatch := oldRule != nil && processor.Evaluate(oldRule.GetRule(), context, log.Fields{})
+			newMatch := newRule != nil && processor.Evaluate(newRule.GetRule(), context, log.Fields{})
+			if oldMatch != newMatch || (change.Change == RuleModified && len(change.Fields) > 0 && newMatch) {
+				changed[i] = true
+				affected[i] = true
+			}
+		}
+		change.Impact = newRuleImpact(options, changed)
+	}
+	return change, nil
+}
+
+func newRuleChange(xrule XRule, change string) *RuleChange {
+	return &RuleChange{Id: xrule.GetId(), Name: xrule.GetName(), RuleType: xrule.GetRuleType(), Change: change}
+}
+
+func newRuleImpact(options *RuleDiffOptions, changed []bool) *RuleImpact {
+	segmentBy := options.SegmentBy
+	if len(segmentBy) == 0 {
+		segmentBy = DefaultSegmentBy
+	}
+	impact := &RuleImpact{Segments: map[string]int{}}
+	for i, context := range options.Contexts {
+		if !changed[i] {
+			continue
+		}
+		impact.Devices++
+		values := make([]string, len(segmentBy))
+		for j, key := range segmentBy {
+			values[j] = key + "=" + context[key]
+		}
+		impact.Segments[strings.Join(values, " ")]++
+	}
+	return impact
+}
+
+// DiffConditions compares the normalized rules, it returns nil when they are equal
+func DiffConditions(oldRule *Rule, newRule *Rule) *ConditionDiff {
+	if NormalizedRuleKey(oldRule) == NormalizedRuleKey(newRule) {
+		return nil
+	}
+	oldConditions := normalizedConjuncts(oldRule)
+	newConditions := normalizedConjuncts(newRule)
+	diff := &ConditionDiff{}
+	for key, text := range oldConditions {
+		if _, ok := newConditions[key]; !ok {
+			diff.Removed = append(diff.Removed, text)
+		}
+	}
+	for key, text := range newConditions {
+		if _, ok := oldConditions[key]; !ok {
+			diff.Added = append(diff.Added, text)
+		}
+	}
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Added)
+	return diff
+}
+
+// normalizedConjuncts returns the text of the parts of the normalized rule joined by AND, by their key
+func normalizedConjuncts(r *Rule) map[string]string {
+	conjuncts := map[string]string{}
+	if r.IsEmpty() {
+		return conjuncts
+	}
+	expr, ok := newNormalExpr(r)
+	if !ok {
+		conjuncts[r.String()] = r.String()
+		return conjuncts
+	}
+	expr = simplifyExpr(expr)
+	parts := []*normalExpr{expr}
+	if !expr.isCondition() && expr.relation == RelationAnd {
+		parts = expr.parts
+	}
+	for _, part := range parts {
+		rule := part.toRule()
+		conjuncts[part.key] = rule.String()
+	}
+	return conjuncts
+}
+
+// diffRuleFields compares the JSON of the rule entities without the rule tree
+func diffRuleFields(oldRule XRule, newRule XRule) ([]FieldChange, error) {
+	oldFields, err := toJSONFields(oldRule)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := toJSONFields(newRule)
+	if err != nil {
+		return nil, err
+	}
+	for field := range ruleDiffIgnoredFields {
+		delete(oldFields, field)
+		delete(newFields, field)
+	}
+	changes := []FieldChange{}
+	diffJSONFields("", oldFields, newFields, &changes)
+	return changes, nil
+}
+
+func toJSONFields(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func diffJSONFields(prefix string, oldFields map[string]interface{}, newFields map[string]interface{}, changes *[]FieldChange) {
+	keys := []string{}
+	for key := range oldFields {
+		keys = append(keys, key)
+	}
+	for key := range newFields {
+		if _, ok := oldFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		oldValue, newValue := oldFields[key], newFields[key]
+		oldMap, oldIsMap := oldValue.(map[string]interface{})
+		newMap, newIsMap := newValue.(map[string]interface{})
+		if oldIsMap && newIsMap {
+			diffJSONFields(prefix+key+".", oldMap, newMap, changes)
+		} else if !reflect.DeepEqual(oldValue, newValue) {
+			*changes = append(*changes, FieldChange{Field: prefix + key, Old: oldValue, New: newValue})
+		}
+	}
+}
+
+func sortRuleChanges(changes []RuleChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Id < changes[j].Id
+	})
+}
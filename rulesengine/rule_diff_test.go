@@ -0,0 +1,128 @@
+package rulesengine
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+type diffTestRule struct {
+	Rule
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	Priority         int               `json:"priority"`
+	ApplicableAction map[string]string `json:"applicableAction,omitempty"`
+	Updated          int64             `json:"updated,omitempty"`
+}
+
+func (r *diffTestRule) GetId() string         { return r.ID }
+func (r *diffTestRule) GetRule() *Rule        { return &r.Rule }
+func (r *diffTestRule) GetName() string       { return r.Name }
+func (r *diffTestRule) GetTemplateId() string { return "" }
+func (r *diffTestRule) GetRuleType() string   { return "DiffTestRule" }
+
+func newDiffTestRule(t *testing.T, id string, text string, priority int, configId string) *diffTestRule {
+	rule, err := ParseRule(text)
+	assert.NilError(t, err, text)
+	return &diffTestRule{
+		Rule:             *rule,
+		ID:               id,
+		Name:             "name-" + id,
+		Priority:         priority,
+		ApplicableAction: map[string]string{"configId": configId},
+	}
+}
+
+func parseDiffTestRule(t *testing.T, text string) *Rule {
+	rule, err := ParseRule(text)
+	assert.NilError(t, err, text)
+	return rule
+}
+
+func TestDiffConditions(t *testing.T) {
+	oldRule := parseDiffTestRule(t, `model IS "X1" AND env IS "QA"`)
+	newRule := parseDiffTestRule(t, `env IN ["QA", "DEV"] AND model IS "X1"`)
+	diff := DiffConditions(oldRule, newRule)
+	assert.Assert(t, diff != nil)
+	assert.DeepEqual(t, []string{`env IS "QA"`}, diff.Removed)
+	assert.DeepEqual(t, []string{`env IN ["DEV", "QA"]`}, diff.Added)
+
+	// equivalent rules have no condition changes
+	equivalent := parseDiffTestRule(t, `(env IS "DEV" OR env IS "QA") AND model IS "X1"`)
+	assert.Assert(t, DiffConditions(newRule, equivalent) == nil)
+
+	diff = DiffConditions(oldRule, parseDiffTestRule(t, `model IS "X1"`))
+	assert.DeepEqual(t, []string{`env IS "QA"`}, diff.Removed)
+	assert.Equal(t, 0, len(diff.Added))
+}
+
+func TestDiffXRules(t *testing.T) {
+	oldRule := newDiffTestRule(t, "r1", `model IS "X1"`, 1, "config-1")
+	newRule := newDiffTestRule(t, "r1", `model IS "X1"`, 1, "config-1")
+	newRule.Updated = 1000
+	change, err := DiffXRules(oldRule, newRule, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, change == nil, "the update time is not a change")
+
+	newRule.Priority = 2
+	newRule.ApplicableAction["configId"] = "config-2"
+	change, err = DiffXRules(oldRule, newRule, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, RuleModified, change.Change)
+	assert.Assert(t, change.Conditions == nil)
+	assert.DeepEqual(t, []FieldChange{
+		{Field: "applicableAction.configId", Old: "config-1", New: "config-2"},
+		{Field: "priority", Old: float64(1), New: float64(2)},
+	}, change.Fields)
+}
+
+func TestDiffRuleSets(t *testing.T) {
+	oldRules := []XRule{
+		newDiffTestRule(t, "unchanged", `partnerId IS "P1"`, 1, "config-1"),
+		newDiffTestRule(t, "modified", `model IS "X1" AND env IS "QA"`, 2, "config-1"),
+		newDiffTestRule(t, "removed", `model IS "X3"`, 3, "config-1"),
+	}
+	newRules := []XRule{
+		newDiffTestRule(t, "unchanged", `partnerId IS "P1"`, 1, "config-1"),
+		newDiffTestRule(t, "modified", `model IS "X1" AND env IN ["QA", "DEV"]`, 2, "config-1"),
+		newDiffTestRule(t, "added", `model IS "X2"`, 4, "config-2"),
+	}
+	contexts := []map[string]string{
+		{"model": "X1", "env": "QA"},
+		{"model": "X1", "env": "DEV"},
+		{"model": "X1", "env": "DEV"},
+		{"model": "X2", "env": "QA"},
+		{"model": "X3", "env": "QA"},
+		{"model": "X4", "env": "QA", "partnerId": "P1"},
+	}
+	diff, err := DiffRuleSets(oldRules, newRules, &RuleDiffOptions{Contexts: contexts})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, diff.Unchanged)
+
+	assert.Equal(t, 1, len(diff.Added))
+	assert.Equal(t, "added", diff.Added[0].Id)
+	assert.Equal(t, RuleAdded, diff.Added[0].Change)
+	assert.DeepEqual(t, &RuleImpact{Devices: 1, Segments: map[string]int{"model=X2 env=QA": 1}}, diff.Added[0].Impact)
+
+	assert.Equal(t, 1, len(diff.Removed))
+	assert.Equal(t, "removed", diff.Removed[0].Id)
+	assert.DeepEqual(t, &RuleImpact{Devices: 1, Segments: map[string]int{"model=X3 env=QA": 1}}, diff.Removed[0].Impact)
+
+	assert.Equal(t, 1, len(diff.Modified))
+	modified := diff.Modified[0]
+	assert.Equal(t, "modified", modified.Id)
+	assert.Equal(t, 0, len(modified.Fields))
+	assert.DeepEqual(t, &ConditionDiff{Removed: []string{`env IS "QA"`}, Added: []string{`env IN ["DEV", "QA"]`}}, modified.Conditions)
+	assert.DeepEqual(t, &RuleImpact{Devices: 2, Segments: map[string]int{"model=X1 env=DEV": 2}}, modified.Impact)
+
+	assert.DeepEqual(t, &RuleImpact{Devices: 4, Segments: map[string]int{
+		"model=X1 env=DEV": 2,
+		"model=X2 env=QA":  1,
+		"model=X3 env=QA":  1,
+	}}, diff.Impact)
+
+	diff, err = DiffRuleSets(oldRules, newRules, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, diff.Impact == nil)
+	assert.Assert(t, diff.Modified[0].Impact == nil)
+}
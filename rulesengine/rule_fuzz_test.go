@@ -0,0 +1,207 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+// The seed corpus runs with go test, run go test -fuzz=FuzzEvaluate ./rulesengine/ to explore further
+
+func FuzzEvaluate(f *testing.F) {
+	for seed := int64(0); seed < 10; seed++ {
+		f.Add(seed)
+	}
+	processor := NewRuleProcessor()
+	f.Fuzz(func(t *testing.T, seed int64) {
+		generator := NewRuleGenerator(seed)
+		r := generator.Rule()
+		negated := Not(*r)
+		twice := Not(negated)
+		normalized := NormalizeRule(r)
+		for i := 0; i < 10; i++ {
+			context := generator.Context()
+			result := processor.Evaluate(r, context, nil)
+			if processor.Evaluate(&negated, context, nil) == result {
+				t.Fatalf("NOT does not invert %v with %v", r, context)
+			}
+			if processor.Evaluate(&twice, context, nil) != result {
+				t.Fatalf("NOT NOT changes %v with %v", r, context)
+			}
+			if processor.Evaluate(normalized, context, nil) != result {
+				t.Fatalf("normalized %v differs from %v with %v", normalized, r, context)
+			}
+			if traced, _ := processor.EvaluateWithTrace(r, context); traced != result {
+				t.Fatalf("trace differs for %v with %v", r, context)
+			}
+		}
+	})
+}
+
+func FuzzParseRule(f *testing.F) {
+	f.Add(`model IS "X1"`)
+	f.Add(`NOT (model IS "X1" OR env IN ["QA", "DEV"]) AND partnerId:ANY EXISTS`)
+	f.Add(`model LIKE "^X" OR estbMacAddress PERCENT 50 AND firmwareVersion STARTS_WITH "1."`)
+	f.Add(`"time zone" IS "UTC" AND NOT NOT env IS "QA"`)
+	processor := NewRuleProcessor()
+	f.Fuzz(func(t *testing.T, text string) {
+		r, err := ParseRule(text)
+		if err != nil {
+			return
+		}
+		parsed, err := ParseRule(r.String())
+		if err != nil {
+			t.Fatalf("%q prints as %q which does not parse: %v", text, r.String(), err)
+		}
+		if !processor.GetEvaluatorOK(r) {
+			// a condition without an evaluator is false, negated or not
+			return
+		}
+		negated := Not(*r)
+		generator := NewRuleGenerator(int64(len(text)))
+		for i := 0; i < 5; i++ {
+			context := generator.Context()
+			result := processor.Evaluate(r, context, nil)
+			if processor.Evaluate(&negated, context, nil) == result {
+				t.Fatalf("NOT does not invert %q with %v", text, context)
+			}
+			if processor.Evaluate(parsed, context, nil) != result {
+				t.Fatalf("%q and its printed form %q differ with %v", text, r.String(), context)
+			}
+		}
+	})
+}
+
+func FuzzRuleEquals(f *testing.F) {
+	for seed := int64(0); seed < 10; seed++ {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, seed int64) {
+		r := NewRuleGenerator(seed).Rule()
+		before, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		copied := Copy(*r)
+		if !r.Equals(r) || !r.Equals(&copied) || !copied.Equals(r) {
+			t.Fatalf("%v does not equal itself or its copy", r)
+		}
+		negated := Not(*r)
+		if r.Equals(&negated) || negated.Equals(r) {
+			t.Fatalf("%v equals its negation", r)
+		}
+
+		decoded := &Rule{}
+		if err := json.Unmarshal(before, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !r.Equals(decoded) || !decoded.Equals(r) {
+			t.Fatalf("%v does not equal its JSON %s", r, before)
+		}
+
+		after, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(before) != string(after) {
+			t.Fatalf("comparing changed %s to %s", before, after)
+		}
+	})
+}
+
+func FuzzFixedArgJSON(f *testing.F) {
+	f.Add([]byte(`{"bean":{"value":{"java.lang.String":"AA:AA:AA:AA:AA:AA"}}}`))
+	f.Add([]byte(`{"bean":{"value":{"java.lang.Double":40.0}}}`))
+	f.Add([]byte(`{"bean":{"value":"X1"}}`))
+	f.Add([]byte(`{"collection":{"value":["TG1234","TG1234","TG5678"]}}`))
+	f.Add([]byte(`{"collection":{"value":[]}}`))
+	f.Add([]byte(`{"bean":{"value":{"java.lang.String":1}}}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fixedArg := &FixedArg{}
+		if err := json.Unmarshal(data, fixedArg); err != nil {
+			return
+		}
+		encoded, err := json.Marshal(fixedArg)
+		if err != nil {
+			t.Fatalf("%s does not marshal: %v", data, err)
+		}
+		decoded := &FixedArg{}
+		if err := json.Unmarshal(encoded, decoded); err != nil {
+			t.Fatalf("%s does not unmarshal: %v", encoded, err)
+		}
+		if !reflect.DeepEqual(fixedArg.GetValue(), decoded.GetValue()) {
+			t.Fatalf("%s decodes to %v, its JSON %s to %v", data, fixedArg.GetValue(), encoded, decoded.GetValue())
+		}
+		if fixedArg.IsValid() != decoded.IsValid() {
+			t.Fatalf("%s and %s differ in validity", data, encoded)
+		}
+		if fixedArg.IsValid() && (!fixedArg.Equals(decoded) || !decoded.Equals(fixedArg)) {
+			t.Fatalf("%s does not equal its JSON %s", data, encoded)
+		}
+	})
+}
+
+func FuzzFreeArg(f *testing.F) {
+	f.Add(StandardFreeArgTypeString, "model")
+	f.Add(StandardFreeArgTypeAny, "partnerId")
+	f.Add(AuxFreeArgTypeIpAddress, "ipAddress")
+	f.Add(AuxFreeArgTypeTime, "time zone")
+	f.Add(StandardFreeArgTypeLong, "")
+	f.Fuzz(func(t *testing.T, ttype string, name string) {
+		if !utf8.ValidString(ttype) || !utf8.ValidString(name) {
+			// JSON replaces invalid UTF-8
+			return
+		}
+		freeArg := NewFreeArg(ttype, name)
+		if !freeArg.Equals(freeArg.Copy()) {
+			t.Fatalf("%v does not equal its copy", freeArg)
+		}
+		if freeArg.Equals(NewFreeArg(ttype, name+"x")) || freeArg.Equals(NewFreeArg(ttype+"x", name)) {
+			t.Fatalf("%v equals a different free arg", freeArg)
+		}
+
+		data, err := json.Marshal(freeArg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &FreeArg{}
+		if err := json.Unmarshal(data, decoded); err != nil {
+			t.Fatalf("%s does not unmarshal: %v", data, err)
+		}
+		if !freeArg.Equals(decoded) {
+			t.Fatalf("%v does not equal its JSON %s", freeArg, data)
+		}
+
+		// the type is printed after the name as is, so only identifiers parse back
+		if formatName(ttype) != ttype {
+			return
+		}
+		r := &Rule{Condition: NewCondition(freeArg, StandardOperationExists, nil)}
+		parsed, err := ParseRule(r.String())
+		if err != nil {
+			t.Fatalf("%v prints as %q which does not parse: %v", freeArg, r.String(), err)
+		}
+		if !parsed.GetCondition().GetFreeArg().Equals(freeArg) {
+			t.Fatalf("%v prints as %q which parses to %v", freeArg, r.String(), parsed.GetCondition().GetFreeArg())
+		}
+	})
+}
@@ -0,0 +1,452 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"math"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultIndexFields are the free args used to index rules, in order of preference
+var DefaultIndexFields = []string{"model", "env", "partnerId"}
+
+// RuleIndex is a compiled, read-only view of an ordered rule list.
+//
+// Every rule that is a pure conjunction containing a STRING IS/IN condition on one of the
+// index fields is bucketed under the values of that condition, unless the evaluator of that
+// operation was replaced with RegisterEvaluator. The condition must hold for the
+// rule to match, so for a given context only the rules in the matching buckets plus the
+// unindexed rules are candidates. Evaluators are resolved once when the index is built and the
+// parts of AND/OR groups are reordered so the cheapest and most selective conditions run first,
+// using the evaluator costs and the match rates observed so far.
+// Results are always identical to evaluating every rule with RuleProcessor.Evaluate.
+type RuleIndex struct {
+	processor *RuleProcessor
+	entries   []*ruleIndexEntry
+	entryById map[string]*ruleIndexEntry
+	buckets   map[string]map[string][]int
+	unindexed []int
+	// the condition stats entries of the compiled conditions
+	conditionStats map[string]*conditionStatsEntry
+}
+
+type ruleIndexEntry struct {
+	position int
+	xrule    XRule
+	rule     *Rule
+	compiled *compiledRule // nil when the rule cannot be compiled
+	field    string        // empty when the rule is not indexed
+	values   map[string]struct{}
+}
+
+type compiledRule struct {
+	negated   bool
+	relation  string
+	group     string // RelationAnd or RelationOr when the parts can be evaluated in any order
+	condition *Condition
+	evaluator IConditionEvaluator
+	counter   *ConditionCounter
+	parts     []*compiledRule
+	cost      float64 // expected cost of an evaluation
+	matchRate float64 // estimated probability of evaluating to true
+}
+
+// NewRuleIndex compiles rules, keeping their order, using DefaultIndexFields when no fields are given
+func NewRuleIndex(processor *RuleProcessor, rules []XRule, fields ...string) *RuleIndex {
+	if len(fields) == 0 {
+		fields = DefaultIndexFields
+	}
+	x := &RuleIndex{
+		processor: processor,
+		entries:   make([]*ruleIndexEntry, 0, len(rules)),
+		entryById: make(map[string]*ruleIndexEntry, len(rules)),
+		buckets:   make(map[string]map[string][]int, len(fields)),
+
+		conditionStats: map[string]*conditionStatsEntry{},
+	}
+	for _, field := range fields {
+		x.buckets[field] = map[string][]int{}
+	}
+	overridden := overriddenStringOperations()
+
+	for i, xrule := range rules {
+		entry := &ruleIndexEntry{
+			position: i,
+			xrule:    xrule,
+			rule:     xrule.GetRule(),
+		}
+		x.entries = append(x.entries, entry)
+		if id := xrule.GetId(); id != "" {
+			if _, ok := x.entryById[id]; ok {
+				// ambiguous, such rules are always evaluated without the index
+				x.entryById[id] = nil
+			} else {
+				x.entryById[id] = entry
+			}
+		}
+		if entry.rule == nil {
+			x.unindexed = append(x.unindexed, i)
+			continue
+		}
+		entry.compiled = x.compile(entry.rule)
+		if entry.compiled == nil {
+			x.unindexed = append(x.unindexed, i)
+			continue
+		}
+		entry.field, entry.values = findIndexKey(entry.rule, fields, overridden)
+		if entry.field == "" {
+			x.unindexed = append(x.unindexed, i)
+			continue
+		}
+		for value := range entry.values {
+			x.buckets[entry.field][value] = append(x.buckets[entry.field][value], i)
+		}
+	}
+	return x
+}
+
+// NewCachedRuleIndex is NewRuleIndex for an index cached under cacheKey, which replaces the index
+// previously built for the same cacheKey. The condition stats of the conditions that no cached
+// index uses any more are dropped.
+func NewCachedRuleIndex(cacheKey string, processor *RuleProcessor, rules []XRule, fields ...string) *RuleIndex {
+	x := NewRuleIndex(processor, rules, fields...)
+	setConditionStatsOwner(cacheKey, x.conditionStats)
+	return x
+}
+
+// Size returns the number of rules in the index
+func (x *RuleIndex) Size() int {
+	return len(x.entries)
+}
+
+// IndexedSize returns the number of rules that are bucketed by an index field
+func (x *RuleIndex) IndexedSize() int {
+	return len(x.entries) - len(x.unindexed)
+}
+
+// Candidates returns, in the original order, the rules that may match the context
+func (x *RuleIndex) Candidates(context map[string]string) []XRule {
+	positions := make([]int, 0, len(x.unindexed))
+	positions = append(positions, x.unindexed...)
+	for field, bucket := range x.buckets {
+		if value := context[field]; len(value) > 0 {
+			positions = append(positions, bucket[value]...)
+		}
+	}
+	sort.Ints(positions)
+
+	candidates := make([]XRule, 0, len(positions))
+	for _, pos := range positions {
+		candidates = append(candidates, x.entries[pos].xrule)
+	}
+	return candidates
+}
+
+// Filter returns, in the original order, the rules that match the context
+func (x *RuleIndex) Filter(context map[string]string) []XRule {
+	matched := []XRule{}
+	for _, xrule := range x.Candidates(context) {
+		if x.Evaluate(xrule, context, nil) {
+			matched = append(matched, xrule)
+		}
+	}
+	return matched
+}
+
+// Evaluate evaluates xrule against the context and counts it in the rule stats. Rules that were
+// not part of the index when it was built are evaluated with RuleProcessor.Evaluate.
+func (x *RuleIndex) Evaluate(xrule XRule, context map[string]string, fields log.Fields) bool {
+	matched := x.evaluate(xrule, context, fields)
+	RecordRuleEvaluation(xrule, matched)
+	return matched
+}
+
+func (x *RuleIndex) evaluate(xrule XRule, context map[string]string, fields log.Fields) bool {
+	entry := x.lookup(xrule)
+	if entry == nil || entry.compiled == nil {
+		return x.processor.Evaluate(xrule.GetRule(), context, fields)
+	}
+	if entry.field != "" {
+		if _, ok := entry.values[context[entry.field]]; !ok {
+			return false
+		}
+	}
+	return x.evaluateCompiled(entry.compiled, context)
+}
+
+func (x *RuleIndex) lookup(xrule XRule) *ruleIndexEntry {
+	entry := x.entryById[xrule.GetId()]
+	// the rule must be the very same instance that was compiled
+	if entry == nil || entry.rule != xrule.GetRule() {
+		return nil
+	}
+	return entry
+}
+
+func (x *RuleIndex) compile(r *Rule) *compiledRule {
+	c := &compiledRule{
+		negated:  r.IsNegated(),
+		relation: r.GetRelation(),
+	}
+	if !r.IsCompound() {
+		condition := r.GetCondition()
+		if condition.GetFreeArg() == nil {
+			return nil
+		}
+		c.condition = condition
+		c.evaluator = x.processor.getEvaluator(condition.GetFreeArg().GetType(), condition.GetOperation())
+		c.cost, c.matchRate = DefaultConditionCost, 0.5
+		if c.evaluator != nil {
+			key, entry := getConditionStatsEntry(condition, EstimateConditionCost(c.evaluator, condition))
+			x.conditionStats[key] = entry
+			c.counter = entry.counter
+			c.cost, c.matchRate = entry.cost, entry.counter.MatchRate()
+		}
+		if c.negated {
+			c.matchRate = 1 - c.matchRate
+		}
+		return c
+	}
+	if len(r.CompoundParts) == 0 {
+		// evaluated by the processor
+		return nil
+	}
+
+	parts := make([]*compiledRule, 0, len(r.CompoundParts))
+	grouped := true
+	for i := range r.CompoundParts {
+		part := x.compile(&r.CompoundParts[i])
+		if part == nil {
+			return nil
+		}
+		if i > 0 && part.relation != RelationAnd && part.relation != RelationOr {
+			grouped = false
+		}
+		parts = append(parts, part)
+	}
+	if !grouped {
+		// a part without a relation replaces the result so far, the order must be kept
+		c.parts = parts
+		c.cost, c.matchRate = 0, 0.5
+		for _, part := range parts {
+			c.cost += part.cost
+		}
+	} else {
+		// an AND with a false result ends the evaluation, so a AND b OR c is a AND (b OR c)
+		conjuncts := []*compiledRule{}
+		disjuncts := []*compiledRule{}
+		for i, part := range parts {
+			if i > 0 && part.relation == RelationAnd {
+				conjuncts = append(conjuncts, newCompiledGroup(RelationOr, disjuncts))
+				disjuncts = []*compiledRule{}
+			}
+			disjuncts = append(disjuncts, part)
+		}
+		if len(conjuncts) == 0 {
+			// the parts are all joined by OR
+			c.group, c.parts = RelationOr, disjuncts
+		} else {
+			c.group, c.parts = RelationAnd, append(conjuncts, newCompiledGroup(RelationOr, disjuncts))
+		}
+		c.order()
+	}
+	if c.negated {
+		c.matchRate = 1 - c.matchRate
+	}
+	return c
+}
+
+func newCompiledGroup(group string, parts []*compiledRule) *compiledRule {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	c := &compiledRule{
+		group: group,
+		parts: parts,
+	}
+	c.order()
+	return c
+}
+
+// order sorts the parts of a group by cost per chance of ending the evaluation, and estimates
+// the cost and the match rate of the group
+func (c *compiledRule) order() {
+	rank := func(part *compiledRule) float64 {
+		stopRate := part.matchRate
+		if c.group == RelationAnd {
+			stopRate = 1 - part.matchRate
+		}
+		return part.cost / math.Max(stopRate, 0.01)
+	}
+	sort.SliceStable(c.parts, func(i, j int) bool {
+		return rank(c.parts[i]) < rank(c.parts[j])
+	})
+
+	c.cost = 0
+	reached := 1.0
+	for _, part := range c.parts {
+		c.cost += reached * part.cost
+		if c.group == RelationAnd {
+			reached *= part.matchRate
+		} else {
+			reached *= 1 - part.matchRate
+		}
+	}
+	if c.group == RelationAnd {
+		c.matchRate = reached
+	} else {
+		c.matchRate = 1 - reached
+	}
+}
+
+// evaluateCompiled must give the same results as RuleProcessor.Evaluate()
+func (x *RuleIndex) evaluateCompiled(c *compiledRule, context map[string]string) bool {
+	if c.condition != nil {
+		if c.evaluator == nil {
+			log.Errorf("type=%v, operation=%v\n", c.condition.GetFreeArg().GetType(), c.condition.GetOperation())
+			return false
+		}
+		result := c.evaluator.Evaluate(c.condition, context)
+		c.counter.record(result)
+		if c.negated {
+			result = !result
+		}
+		return result
+	}
+
+	var result bool
+	switch c.group {
+	case RelationAnd:
+		result = true
+		for _, part := range c.parts {
+			if !x.evaluateCompiled(part, context) {
+				result = false
+				break
+			}
+		}
+	case RelationOr:
+		for _, part := range c.parts {
+			if x.evaluateCompiled(part, context) {
+				result = true
+				break
+			}
+		}
+	default:
+		for i, part := range c.parts {
+			if i > 0 {
+				if result && part.relation == RelationOr {
+					continue
+				}
+				if !result && part.relation == RelationAnd {
+					break
+				}
+			}
+			result = x.evaluateCompiled(part, context)
+		}
+	}
+	if c.negated {
+		result = !result
+	}
+	return result
+}
+
+// overriddenStringOperations returns the STRING operations with a registered evaluator, whose
+// values cannot be derived from the fixed arg like those of the built-in IS and IN
+func overriddenStringOperations() map[string]struct{} {
+	operations := map[string]struct{}{}
+	for _, evaluator := range GetRegisteredEvaluators() {
+		if evaluator.FreeArgType() == StandardFreeArgTypeString {
+			operations[evaluator.Operation()] = struct{}{}
+		}
+	}
+	return operations
+}
+
+// findIndexKey returns the first field, in order of fields, constrained by a required condition
+// together with the values that satisfy that condition. Conditions of the overridden operations
+// are not used.
+func findIndexKey(r *Rule, fields []string, overridden map[string]struct{}) (string, map[string]struct{}) {
+	conditions := requiredConditions(r, nil)
+	for _, field := range fields {
+		for _, condition := range conditions {
+			if condition.GetFreeArg().GetName() != field || condition.GetFreeArg().GetType() != StandardFreeArgTypeString {
+				continue
+			}
+			if _, ok := overridden[condition.GetOperation()]; ok {
+				continue
+			}
+			if values, ok := indexValues(condition); ok {
+				return field, values
+			}
+		}
+	}
+	return "", nil
+}
+
+// requiredConditions collects the conditions that must be true for the rule to evaluate to true.
+// Evaluate() walks parts left to right, so this only holds when every relation is AND.
+func requiredConditions(r *Rule, conditions []*Condition) []*Condition {
+	if r.IsNegated() {
+		return conditions
+	}
+	if !r.IsCompound() {
+		if r.GetCondition().GetFreeArg() == nil {
+			return conditions
+		}
+		return append(conditions, r.GetCondition())
+	}
+	for i := range r.CompoundParts {
+		if i > 0 && r.CompoundParts[i].GetRelation() != RelationAnd {
+			return conditions
+		}
+	}
+	for i := range r.CompoundParts {
+		conditions = requiredConditions(&r.CompoundParts[i], conditions)
+	}
+	return conditions
+}
+
+// indexValues returns the non-empty context values for which a STRING IS/IN condition holds
+func indexValues(condition *Condition) (map[string]struct{}, bool) {
+	values := map[string]struct{}{}
+	switch condition.GetOperation() {
+	case StandardOperationIs:
+		value, ok := condition.GetFixedArg().GetValue().(string)
+		if !ok {
+			return nil, false
+		}
+		if len(value) > 0 {
+			values[value] = struct{}{}
+		}
+	case StandardOperationIn:
+		collection, ok := condition.GetFixedArg().GetValue().([]string)
+		if !ok {
+			return nil, false
+		}
+		for _, value := range collection {
+			if len(value) > 0 {
+				values[value] = struct{}{}
+			}
+		}
+	default:
+		return nil, false
+	}
+	return values, true
+}
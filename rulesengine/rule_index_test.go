@@ -0,0 +1,370 @@
+package rulesengine
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func parseIndexTestRules(t testing.TB, texts ...string) []XRule {
+	rules := make([]XRule, 0, len(texts))
+	for i, text := range texts {
+		rule, err := ParseRule(text)
+		assert.NilError(t, err, text)
+		rules = append(rules, &traceTestRule{id: fmt.Sprintf("rule-%d", i), rule: rule})
+	}
+	return rules
+}
+
+func ruleIds(rules []XRule) []string {
+	ids := []string{}
+	for _, rule := range rules {
+		ids = append(ids, rule.GetId())
+	}
+	return ids
+}
+
+func linearFilter(processor *RuleProcessor, rules []XRule, context map[string]string) []XRule {
+	matched := []XRule{}
+	for _, rule := range rules {
+		if processor.Evaluate(rule.GetRule(), context, nil) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+func TestRuleIndexBuckets(t *testing.T) {
+	processor := NewRuleProcessor()
+	rules := parseIndexTestRules(t,
+		`model IS "X1"`,                          // model
+		`env IN ["QA", "DEV"] AND model IS "X2"`, // model, preferred over env
+		`env IS "QA" AND (firmwareVersion IS "1.0" AND partnerId IS "comcast")`, // env
+		`model IS "X1" OR env IS "QA"`,                                          // not a pure conjunction
+		`NOT model IS "X1"`,                                                     // negated
+		`partnerId:ANY EXISTS AND firmwareVersion IS "2.0"`,
+		`NOT (model IS "X1" OR model IS "X2") AND partnerId IS "cox"`, // partnerId
+		`model LIKE "X.*"`,
+	)
+	index := NewRuleIndex(processor, rules)
+	assert.Equal(t, 8, index.Size())
+	assert.Equal(t, 4, index.IndexedSize())
+
+	candidates := index.Candidates(map[string]string{"model": "X1", "env": "PROD"})
+	assert.DeepEqual(t, []string{"rule-0", "rule-3", "rule-4", "rule-5", "rule-7"}, ruleIds(candidates))
+
+	candidates = index.Candidates(map[string]string{"model": "X2", "env": "QA", "partnerId": "cox"})
+	assert.DeepEqual(t, []string{"rule-1", "rule-2", "rule-3", "rule-4", "rule-5", "rule-6", "rule-7"}, ruleIds(candidates))
+
+	candidates = index.Candidates(map[string]string{})
+	assert.DeepEqual(t, []string{"rule-3", "rule-4", "rule-5", "rule-7"}, ruleIds(candidates))
+}
+
+func TestRuleIndexCustomFields(t *testing.T) {
+	processor := NewRuleProcessor()
+	rules := parseIndexTestRules(t,
+		`model IS "X1" AND firmwareVersion IS "1.0"`,
+		`model IS "X1" AND firmwareVersion IS "2.0"`,
+	)
+	index := NewRuleIndex(processor, rules, "firmwareVersion")
+	candidates := index.Candidates(map[string]string{"model": "X1", "firmwareVersion": "2.0"})
+	assert.DeepEqual(t, []string{"rule-1"}, ruleIds(candidates))
+}
+
+func TestRuleIndexSkipsOverriddenOperations(t *testing.T) {
+	resetEvaluatorRegistry(t)
+
+	isIgnoreCase, err := NewDeclarativeEvaluator(DeclarativeEvaluatorSpec{
+		FreeArgType: StandardFreeArgTypeString,
+		Operation:   StandardOperationIs,
+		Match:       DeclarativeMatchEquals,
+		IgnoreCase:  true,
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, RegisterEvaluator(isIgnoreCase))
+
+	processor := NewRuleProcessorFactory().RuleProcessor()
+	rules := parseIndexTestRules(t,
+		`model IS "X1"`,
+		`env IN ["QA", "DEV"] AND model IS "X2"`,
+	)
+	index := NewRuleIndex(processor, rules)
+	assert.Equal(t, 1, index.IndexedSize())
+
+	context := map[string]string{"model": "x1", "env": "QA"}
+	assert.DeepEqual(t, ruleIds(linearFilter(processor, rules, context)), ruleIds(index.Filter(context)))
+	assert.DeepEqual(t, []string{"rule-0"}, ruleIds(index.Filter(context)))
+}
+
+func TestRuleIndexEvaluateUnknownRule(t *testing.T) {
+	processor := NewRuleProcessor()
+	rules := parseIndexTestRules(t, `model IS "X1"`)
+	index := NewRuleIndex(processor, rules)
+	context := map[string]string{"model": "X2"}
+
+	// same id but a different rule instance must not use the compiled rule
+	changed := parseIndexTestRules(t, `model IS "X2"`)[0]
+	assert.Equal(t, rules[0].GetId(), changed.GetId())
+	assert.Assert(t, !index.Evaluate(rules[0], context, nil))
+	assert.Assert(t, index.Evaluate(changed, context, nil))
+
+	other := &traceTestRule{id: "other", rule: changed.GetRule()}
+	assert.Assert(t, index.Evaluate(other, context, nil))
+}
+
+func TestRuleIndexMatchesLinearEvaluation(t *testing.T) {
+	processor := NewRuleProcessor()
+	rnd := rand.New(rand.NewSource(42))
+	rules := generateIndexTestRules(rnd, 500)
+	index := NewRuleIndex(processor, rules)
+	assert.Assert(t, index.IndexedSize() > 0)
+
+	for i := 0; i < 1000; i++ {
+		context := generateIndexTestContext(rnd)
+		expected := linearFilter(processor, rules, context)
+		assert.DeepEqual(t, ruleIds(expected), ruleIds(index.Filter(context)))
+		for _, rule := range rules {
+			assert.Equal(t, processor.Evaluate(rule.GetRule(), context, nil), index.Evaluate(rule, context, nil))
+		}
+	}
+}
+
+func findConditionStat(condition string) ConditionStat {
+	for _, stat := range GetConditionStats() {
+		if stat.Condition == condition {
+			return stat
+		}
+	}
+	return ConditionStat{}
+}
+
+func TestRuleIndexEvaluatesCheapConditionsFirst(t *testing.T) {
+	processor := NewRuleProcessor()
+	rules := parseIndexTestRules(t, `costModel LIKE "^X" AND costEnv IS "QA"`)
+	index := NewRuleIndex(processor, rules, "none")
+	compiled := index.entries[0].compiled
+	assert.Equal(t, RelationAnd, compiled.group)
+	assert.Equal(t, StandardOperationIs, compiled.parts[0].condition.GetOperation())
+
+	assert.Assert(t, !index.Evaluate(rules[0], map[string]string{"costModel": "X1", "costEnv": "DEV"}, nil))
+	assert.Assert(t, index.Evaluate(rules[0], map[string]string{"costModel": "X1", "costEnv": "QA"}, nil))
+
+	like := findConditionStat(`"costModel" LIKE "^X"`)
+	assert.Equal(t, int64(1), like.Evaluations)
+	assert.Equal(t, float64(8), like.Cost)
+	is := findConditionStat(`"costEnv" IS "QA"`)
+	assert.Equal(t, int64(2), is.Evaluations)
+	assert.Equal(t, int64(1), is.Matches)
+}
+
+func TestCachedRuleIndexDropsUnusedConditionStats(t *testing.T) {
+	processor := NewRuleProcessor()
+	hasStat := func(condition string) bool {
+		return findConditionStat(condition).Condition == condition
+	}
+	NewCachedRuleIndex("owner1", processor, parseIndexTestRules(t, `ownedModel IS "X1" AND ownedEnv IS "QA"`), "none")
+	NewCachedRuleIndex("owner2", processor, parseIndexTestRules(t, `ownedEnv IS "QA"`), "none")
+	assert.Assert(t, hasStat(`"ownedModel" IS "X1"`))
+
+	// the rebuilt index of owner1 no longer uses the condition on ownedModel
+	NewCachedRuleIndex("owner1", processor, parseIndexTestRules(t, `ownedModel IS "X2" AND ownedEnv IS "QA"`), "none")
+	assert.Assert(t, !hasStat(`"ownedModel" IS "X1"`))
+	assert.Assert(t, hasStat(`"ownedModel" IS "X2"`))
+
+	// owner2 still uses the condition on ownedEnv
+	NewCachedRuleIndex("owner1", processor, parseIndexTestRules(t, `ownedModel IS "X2"`), "none")
+	assert.Assert(t, hasStat(`"ownedEnv" IS "QA"`))
+	NewCachedRuleIndex("owner2", processor, nil, "none")
+	assert.Assert(t, !hasStat(`"ownedEnv" IS "QA"`))
+	assert.Assert(t, hasStat(`"ownedModel" IS "X2"`))
+}
+
+func TestRuleIndexUsesObservedMatchRates(t *testing.T) {
+	processor := NewRuleProcessor()
+	rules := parseIndexTestRules(t, `rateModel IS "X1" OR rateEnv IS "QA"`)
+	index := NewRuleIndex(processor, rules, "none")
+	assert.Equal(t, "rateModel", index.entries[0].compiled.parts[0].condition.GetFreeArg().GetName())
+
+	context := map[string]string{"rateModel": "X2", "rateEnv": "QA"}
+	for i := 0; i < 10; i++ {
+		assert.Assert(t, index.Evaluate(rules[0], context, nil))
+	}
+
+	// rateEnv matches more often, so it ends an OR sooner
+	index = NewRuleIndex(processor, rules, "none")
+	assert.Equal(t, "rateEnv", index.entries[0].compiled.parts[0].condition.GetFreeArg().GetName())
+	assert.Assert(t, index.Evaluate(rules[0], context, nil))
+	assert.Equal(t, int64(10), findConditionStat(`"rateModel" IS "X1"`).Evaluations)
+}
+
+func TestRuleIndexKeepsOrderWithoutRelation(t *testing.T) {
+	processor := NewRuleProcessor()
+	first := Rule{Condition: NewCondition(NewFreeArg(StandardFreeArgTypeString, "model"), StandardOperationLike, NewFixedArg("^X"))}
+	second := Rule{Condition: NewCondition(NewFreeArg(StandardFreeArgTypeString, "env"), StandardOperationIs, NewFixedArg("QA"))}
+	rule := &Rule{CompoundParts: []Rule{first, second}}
+	rules := []XRule{&traceTestRule{id: "rule", rule: rule}}
+	index := NewRuleIndex(processor, rules, "none")
+	assert.Equal(t, "", index.entries[0].compiled.group)
+
+	// the part without a relation replaces the result of the first part
+	for _, context := range []map[string]string{{"model": "X1", "env": "DEV"}, {"model": "Y", "env": "QA"}} {
+		assert.Equal(t, processor.Evaluate(rule, context, nil), index.Evaluate(rules[0], context, nil))
+	}
+}
+
+func TestResetConditionStats(t *testing.T) {
+	processor := NewRuleProcessor()
+	rules := parseIndexTestRules(t, `resetModel IS "X1"`)
+	index := NewRuleIndex(processor, rules, "none")
+	index.Evaluate(rules[0], map[string]string{"resetModel": "X1"}, nil)
+	assert.Equal(t, int64(1), findConditionStat(`"resetModel" IS "X1"`).Matches)
+
+	ResetConditionStats()
+	stat := findConditionStat(`"resetModel" IS "X1"`)
+	assert.Equal(t, int64(0), stat.Evaluations)
+	assert.Equal(t, 0.5, stat.MatchRate)
+}
+
+var (
+	indexTestModels   = []string{"X1", "X2", "X3", "TG1682G", "PX051AEI", "SR150BW", "", "x1"}
+	indexTestEnvs     = []string{"QA", "DEV", "PROD", "VBN", ""}
+	indexTestPartners = []string{"comcast", "cox", "shaw", "sky", ""}
+	indexTestVersions = []string{"1.0", "2.0", "3.0"}
+)
+
+func randomIndexTestCondition(rnd *rand.Rand) string {
+	pick := func(values []string) string {
+		return values[rnd.Intn(len(values))]
+	}
+	switch rnd.Intn(9) {
+	case 0, 1:
+		return fmt.Sprintf(`model IS "%s"`, pick(indexTestModels))
+	case 2:
+		return fmt.Sprintf(`model IN ["%s", "%s"]`, pick(indexTestModels), pick(indexTestModels))
+	case 3:
+		return fmt.Sprintf(`env IS "%s"`, pick(indexTestEnvs))
+	case 4:
+		return fmt.Sprintf(`env IN ["%s", "%s"]`, pick(indexTestEnvs), pick(indexTestEnvs))
+	case 5:
+		return fmt.Sprintf(`partnerId IS "%s"`, pick(indexTestPartners))
+	case 6:
+		return fmt.Sprintf(`firmwareVersion IS "%s"`, pick(indexTestVersions))
+	case 7:
+		return `partnerId:ANY EXISTS`
+	default:
+		return `model LIKE "^X"`
+	}
+}
+
+func randomIndexTestRule(rnd *rand.Rand, depth int) string {
+	n := 1 + rnd.Intn(3)
+	text := ""
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			if rnd.Intn(4) == 0 {
+				text += " OR "
+			} else {
+				text += " AND "
+			}
+		}
+		if rnd.Intn(6) == 0 {
+			text += "NOT "
+		}
+		if depth > 0 && rnd.Intn(4) == 0 {
+			text += "(" + randomIndexTestRule(rnd, depth-1) + ")"
+		} else {
+			text += randomIndexTestCondition(rnd)
+		}
+	}
+	return text
+}
+
+func generateIndexTestRules(rnd *rand.Rand, count int) []XRule {
+	rules := make([]XRule, 0, count)
+	for i := 0; i < count; i++ {
+		rule, err := ParseRule(randomIndexTestRule(rnd, 2))
+		if err != nil {
+			panic(err)
+		}
+		rules = append(rules, &traceTestRule{id: fmt.Sprintf("rule-%d", i), rule: rule})
+	}
+	return rules
+}
+
+func generateIndexTestContext(rnd *rand.Rand) map[string]string {
+	context := map[string]string{}
+	if rnd.Intn(10) > 0 {
+		context["model"] = indexTestModels[rnd.Intn(len(indexTestModels))]
+	}
+	if rnd.Intn(10) > 0 {
+		context["env"] = indexTestEnvs[rnd.Intn(len(indexTestEnvs))]
+	}
+	if rnd.Intn(2) > 0 {
+		context["partnerId"] = indexTestPartners[rnd.Intn(len(indexTestPartners))]
+	}
+	context["firmwareVersion"] = indexTestVersions[rnd.Intn(len(indexTestVersions))]
+	return context
+}
+
+// benchmarkRules models a large rule set where almost every rule targets one model
+func generateBenchmarkRules(count int) []XRule {
+	rules := make([]XRule, 0, count)
+	for i := 0; i < count; i++ {
+		text := fmt.Sprintf(`model IS "MODEL%d" AND env IN ["QA", "PROD"] AND partnerId IS "partner%d" AND firmwareVersion LIKE "^1\\."`, i%200, i%7)
+		if i%50 == 0 {
+			text = fmt.Sprintf(`estbMacAddress IS "AA:BB:CC:DD:EE:%02X" OR firmwareVersion IS "2.%d"`, i%256, i)
+		}
+		rule, err := ParseRule(text)
+		if err != nil {
+			panic(err)
+		}
+		rules = append(rules, &traceTestRule{id: fmt.Sprintf("rule-%d", i), rule: rule})
+	}
+	return rules
+}
+
+var benchmarkContext = map[string]string{
+	"model":           "MODEL17",
+	"env":             "PROD",
+	"partnerId":       "partner3",
+	"firmwareVersion": "1.4.2",
+	"estbMacAddress":  "AA:BB:CC:DD:EE:01",
+}
+
+func TestBenchmarkRulesIndexedMatchLinear(t *testing.T) {
+	processor := NewRuleProcessor()
+	rules := generateBenchmarkRules(5000)
+	index := NewRuleIndex(processor, rules)
+	expected := linearFilter(processor, rules, benchmarkContext)
+	assert.Assert(t, len(expected) > 0)
+	assert.DeepEqual(t, ruleIds(expected), ruleIds(index.Filter(benchmarkContext)))
+}
+
+func BenchmarkRuleFilterLinear(b *testing.B) {
+	processor := NewRuleProcessor()
+	rules := generateBenchmarkRules(5000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearFilter(processor, rules, benchmarkContext)
+	}
+}
+
+func BenchmarkRuleFilterIndexed(b *testing.B) {
+	processor := NewRuleProcessor()
+	index := NewRuleIndex(processor, generateBenchmarkRules(5000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Filter(benchmarkContext)
+	}
+}
+
+func BenchmarkRuleIndexBuild(b *testing.B) {
+	processor := NewRuleProcessor()
+	rules := generateBenchmarkRules(5000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewRuleIndex(processor, rules)
+	}
+}
@@ -0,0 +1,332 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mergeableFreeArgTypes are the free arg types whose IS and IN compare the value against each
+// fixed value on its own, so several of them on one free arg can be merged into one IN
+var mergeableFreeArgTypes = map[string]bool{
+	StandardFreeArgTypeString: true,
+	AuxFreeArgTypeIpAddress:   true,
+	AuxFreeArgTypeMacAddress:  true,
+}
+
+// normalExpr is a rule as a boolean expression, either a condition or the AND/OR of its parts.
+// Negations are only kept on conditions.
+type normalExpr struct {
+	relation  string
+	parts     []*normalExpr
+	condition *Condition
+	negated   bool
+	key       string
+}
+
+func (e *normalExpr) isCondition() bool {
+	return e.condition != nil
+}
+
+// NormalizeRule returns the canonical form of a rule: nested parts with the same relation are
+// flattened, negations are pushed down to the conditions, IS and IN conditions on the same free
+// arg are merged into one IN, duplicate conditions are dropped and the parts are sorted.
+// The result evaluates the same as the rule for every context and r is left unchanged.
+// A rule with an empty part or a relation other than AND and OR is returned as it is.
+// It is meant for equivalence and indexing, CompareRules orders the rules as they are written.
+func NormalizeRule(r *Rule) *Rule {
+	if r.IsEmpty() {
+		return r
+	}
+	expr, ok := newNormalExpr(r)
+	if !ok {
+		return r
+	}
+	rule := simplifyExpr(expr).toRule()
+	rule.Relation = r.Relation
+	rule.Xxid = r.Xxid
+	return &rule
+}
+
+// NormalizedRuleKey returns the text of the normalized rule, rules with the same key are
+// structurally equal
+func NormalizedRuleKey(r *Rule) string {
+	if r.IsEmpty() {
+		return ""
+	}
+	expr, ok := newNormalExpr(r)
+	if !ok {
+		return r.String()
+	}
+	return simplifyExpr(expr).key
+}
+
+func newNormalExpr(r *Rule) (*normalExpr, bool) {
+	var expr *normalExpr
+	if r.Condition != nil {
+		expr = &normalExpr{condition: r.Condition}
+	} else {
+		if len(r.CompoundParts) == 0 {
+			return nil, false
+		}
+		// an AND with a false result ends the evaluation, so OR binds tighter:
+		// a AND b OR c is a AND (b OR c)
+		conjuncts := []*normalExpr{}
+		disjuncts := []*normalExpr{}
+		for i := range r.CompoundParts {
+			part, ok := newNormalExpr(&r.CompoundParts[i])
+			if !ok {
+				return nil, false
+			}
+			if i > 0 {
+				relation := r.CompoundParts[i].Relation
+				if relation != RelationAnd && relation != RelationOr {
+					return nil, false
+				}
+				if relation == RelationAnd {
+					conjuncts = append(conjuncts, newCompoundExpr(RelationOr, disjuncts))
+					disjuncts = []*normalExpr{}
+				}
+			}
+			disjuncts = append(disjuncts, part)
+		}
+		conjuncts = append(conjuncts, newCompoundExpr(RelationOr, disjuncts))
+		expr = newCompoundExpr(RelationAnd, conjuncts)
+	}
+	if r.Negated {
+		expr = expr.negate()
+	}
+	return expr, true
+}
+
+func newCompoundExpr(relation string, parts []*normalExpr) *normalExpr {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return &normalExpr{relation: relation, parts: parts}
+}
+
+// negate applies De Morgan's laws, so only conditions end up negated
+func (e *normalExpr) negate() *normalExpr {
+	if e.isCondition() {
+		return &normalExpr{condition: e.condition, negated: !e.negated}
+	}
+	relation := RelationAnd
+	if e.relation == RelationAnd {
+		relation = RelationOr
+	}
+	parts := make([]*normalExpr, len(e.parts))
+	for i, part := range e.parts {
+		parts[i] = part.negate()
+	}
+	return &normalExpr{relation: relation, parts: parts}
+}
+
+func simplifyExpr(e *normalExpr) *normalExpr {
+	if e.isCondition() {
+		result := &normalExpr{condition: normalizeCondition(e.condition), negated: e.negated}
+		result.key = conditionKey(result.condition, result.negated)
+		return result
+	}
+
+	parts := []*normalExpr{}
+	for _, part := range e.parts {
+		part = simplifyExpr(part)
+		if !part.isCondition() && part.relation == e.relation {
+			parts = append(parts, part.parts...)
+		} else {
+			parts = append(parts, part)
+		}
+	}
+	parts = mergeConditions(e.relation, parts)
+
+	unique := []*normalExpr{}
+	seen := map[string]bool{}
+	for _, part := range parts {
+		if !seen[part.key] {
+			seen[part.key] = true
+			unique = append(unique, part)
+		}
+	}
+	if len(unique) == 1 {
+		return unique[0]
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		if unique[i].isCondition() != unique[j].isCondition() {
+			return unique[i].isCondition()
+		}
+		return unique[i].key < unique[j].key
+	})
+
+	keys := make([]string, len(unique))
+	for i, part := range unique {
+		keys[i] = part.key
+	}
+	return &normalExpr{
+		relation: e.relation,
+		parts:    unique,
+		key:      LeftParan + strings.Join(keys, SpaceChar+e.relation+SpaceChar) + RightParan,
+	}
+}
+
+// mergeConditions merges "a IS x OR a IN [y, z]" into "a IN [x, y, z]" and, by De Morgan,
+// "NOT a IS x AND NOT a IS y" into "NOT a IN [x, y]"
+func mergeConditions(relation string, parts []*normalExpr) []*normalExpr {
+	negated := relation == RelationAnd
+	groups := map[string][]int{}
+	for i, part := range parts {
+		if part.isCondition() && part.negated == negated && setValues(part.condition) != nil {
+			freeArg := part.condition.FreeArg
+			name := freeArg.Type + ":" + freeArg.Name
+			groups[name] = append(groups[name], i)
+		}
+	}
+
+	merged := map[int]*normalExpr{}
+	dropped := map[int]bool{}
+	for _, indexes := range groups {
+		if len(indexes) < 2 {
+			continue
+		}
+		values := []string{}
+		for _, i := range indexes {
+			values = append(values, setValues(parts[i].condition)...)
+			dropped[i] = true
+		}
+		values = sortedUniqueValues(values)
+		freeArg := parts[indexes[0]].condition.FreeArg
+		condition := NewCondition(NewFreeArg(freeArg.Type, freeArg.Name), StandardOperationIn, NewFixedArg(values))
+		if len(values) == 1 {
+			condition = NewCondition(NewFreeArg(freeArg.Type, freeArg.Name), StandardOperationIs, NewFixedArg(values[0]))
+		}
+		merged[indexes[0]] = &normalExpr{condition: condition, negated: negated, key: conditionKey(condition, negated)}
+	}
+	if len(merged) == 0 {
+		return parts
+	}
+
+	result := []*normalExpr{}
+	for i, part := range parts {
+		if expr, ok := merged[i]; ok {
+			result = append(result, expr)
+		} else if !dropped[i] {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// setValues returns the values of an IS or IN condition that can be merged, or nil
+func setValues(condition *Condition) []string {
+	freeArg := condition.FreeArg
+	if freeArg == nil || condition.FixedArg == nil || !mergeableFreeArgTypes[freeArg.Type] {
+		return nil
+	}
+	switch condition.Operation {
+	case StandardOperationIs:
+		if condition.FixedArg.IsStringValue() {
+			return []string{*condition.FixedArg.Bean.Value.JLString}
+		}
+	case StandardOperationIn:
+		if condition.FixedArg.IsCollectionValue() {
+			return condition.FixedArg.Collection.Value
+		}
+	}
+	return nil
+}
+
+// normalizeCondition copies a condition, the values of IN are sorted and deduplicated
+func normalizeCondition(condition *Condition) *Condition {
+	result := &Condition{Operation: condition.Operation}
+	if condition.FreeArg != nil {
+		result.FreeArg = NewFreeArg(condition.FreeArg.Type, condition.FreeArg.Name)
+	}
+	fixedArg := condition.FixedArg
+	switch {
+	case fixedArg == nil:
+	case fixedArg.Collection != nil && fixedArg.Bean == nil:
+		values := append([]string{}, fixedArg.Collection.Value...)
+		if condition.Operation == StandardOperationIn {
+			values = sortedUniqueValues(values)
+		}
+		result.FixedArg = NewFixedArg(values)
+	case fixedArg.IsStringValue():
+		result.FixedArg = NewFixedArg(*fixedArg.Bean.Value.JLString)
+	case fixedArg.IsDoubleValue():
+		result.FixedArg = NewFixedArg(*fixedArg.Bean.Value.JLDouble)
+	default:
+		result.FixedArg = fixedArg.Copy()
+	}
+	return result
+}
+
+func sortedUniqueValues(values []string) []string {
+	sort.Strings(values)
+	unique := []string{}
+	for i, value := range values {
+		if i == 0 || value != values[i-1] {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+func conditionKey(condition *Condition, negated bool) string {
+	var sb strings.Builder
+	if negated {
+		sb.WriteString(keywordNot + SpaceChar)
+	}
+	if freeArg := condition.FreeArg; freeArg != nil {
+		sb.WriteString(strconv.Quote(freeArg.Name))
+		if freeArg.Type != StandardFreeArgTypeString && freeArg.Type != "" {
+			sb.WriteString(":" + freeArg.Type)
+		}
+	}
+	sb.WriteString(SpaceChar + condition.Operation)
+	fixedArg := condition.FixedArg
+	switch {
+	case fixedArg == nil:
+	case fixedArg.Collection != nil && fixedArg.Bean == nil:
+		values := make([]string, len(fixedArg.Collection.Value))
+		for i, v := range fixedArg.Collection.Value {
+			values[i] = strconv.Quote(v)
+		}
+		sb.WriteString(" [" + strings.Join(values, ", ") + "]")
+	case fixedArg.IsStringValue():
+		sb.WriteString(SpaceChar + strconv.Quote(*fixedArg.Bean.Value.JLString))
+	case fixedArg.IsDoubleValue():
+		sb.WriteString(SpaceChar + strconv.FormatFloat(*fixedArg.Bean.Value.JLDouble, 'g', -1, 64))
+	}
+	return sb.String()
+}
+
+func (e *normalExpr) toRule() Rule {
+	if e.isCondition() {
+		return Rule{Condition: e.condition, Negated: e.negated}
+	}
+	parts := make([]Rule, len(e.parts))
+	for i, part := range e.parts {
+		parts[i] = part.toRule()
+		if i > 0 {
+			parts[i].Relation = e.relation
+		}
+	}
+	return Rule{CompoundParts: parts}
+}
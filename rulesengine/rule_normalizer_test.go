@@ -0,0 +1,87 @@
+package rulesengine
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestNormalizeRule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`model IS "X1"`, `model IS "X1"`},
+		{`(model IS "X1" AND (env IS "QA" AND partnerId IS "P1"))`, `env IS "QA" AND model IS "X1" AND partnerId IS "P1"`},
+		{`NOT (model IS "X1" OR env IS "QA")`, `NOT env IS "QA" AND NOT model IS "X1"`},
+		{`model IS "X2" OR model IS "X1" OR model IN ["X3", "X1"]`, `model IN ["X1", "X2", "X3"]`},
+		{`NOT model IS "X1" AND NOT model IS "X2"`, `NOT model IN ["X1", "X2"]`},
+		{`model IS "X1" AND model IS "X1"`, `model IS "X1"`},
+		{`env IN ["QA", "DEV", "QA"]`, `env IN ["DEV", "QA"]`},
+		{`model IS "X1" AND model IS "X2"`, `model IS "X1" AND model IS "X2"`},
+		{`(env IS "QA" OR env IS "DEV") AND model IS "X1"`, `env IN ["DEV", "QA"] AND model IS "X1"`},
+		{`model IS "X1" AND env IS "QA" OR env IS "DEV"`, `env IN ["DEV", "QA"] AND model IS "X1"`},
+	}
+	for _, test := range tests {
+		rule, err := ParseRule(test.input)
+		assert.NilError(t, err)
+		original := rule.String()
+		assert.Equal(t, test.expected, NormalizeRule(rule).String(), test.input)
+		assert.Equal(t, original, rule.String(), "input must not change")
+	}
+}
+
+func TestNormalizeRuleKeepsSemantics(t *testing.T) {
+	inputs := []string{
+		`model IS "X1" OR env IS "QA" AND model IS "X2"`,
+		`NOT (model IS "X1" AND NOT (env IS "QA" OR env IS "DEV"))`,
+		`model IS "X1" OR model IS "X2" AND NOT env IN ["QA"]`,
+		`NOT model IS "X1" AND NOT model IN ["X2"] OR env IS "DEV"`,
+		`(model IS "X1" OR (model IS "X2" OR env IS "QA")) AND NOT (env IS "DEV" AND model IS "X1")`,
+	}
+	contexts := []map[string]string{}
+	for _, model := range []string{"", "X1", "X2", "X3"} {
+		for _, env := range []string{"", "QA", "DEV"} {
+			context := map[string]string{}
+			if model != "" {
+				context["model"] = model
+			}
+			if env != "" {
+				context["env"] = env
+			}
+			contexts = append(contexts, context)
+		}
+	}
+
+	processor := NewRuleProcessor()
+	for _, input := range inputs {
+		rule, err := ParseRule(input)
+		assert.NilError(t, err)
+		normalized := NormalizeRule(rule)
+		for _, context := range contexts {
+			assert.Equal(t, processor.Evaluate(rule, context, nil), processor.Evaluate(normalized, context, nil), "%v %v", input, context)
+		}
+	}
+}
+
+func TestNormalizeRuleWithEmptyPartIsUnchanged(t *testing.T) {
+	rule := &Rule{CompoundParts: []Rule{{}, {Relation: RelationAnd}}}
+	assert.Assert(t, NormalizeRule(rule) == rule)
+	assert.Assert(t, NormalizeRule(nil) == nil)
+}
+
+func TestEqualComplexRulesIgnoresStructure(t *testing.T) {
+	rule1, err := ParseRule(`model IS "X1" AND (env IS "QA" OR env IS "DEV")`)
+	assert.NilError(t, err)
+	rule2, err := ParseRule(`env IN ["DEV", "QA"] AND model IS "X1"`)
+	assert.NilError(t, err)
+	rule3, err := ParseRule(`NOT (NOT model IS "X1" OR NOT env IN ["QA", "DEV"])`)
+	assert.NilError(t, err)
+	rule4, err := ParseRule(`model IS "X1" AND env IN ["QA"]`)
+	assert.NilError(t, err)
+
+	assert.Assert(t, EqualComplexRules(rule1, rule2))
+	assert.Assert(t, EqualComplexRules(rule1, rule3))
+	assert.Assert(t, !EqualComplexRules(rule1, rule4))
+	assert.Equal(t, NormalizedRuleKey(rule1), NormalizedRuleKey(rule3))
+}
@@ -33,6 +33,9 @@ type RuleProcessor struct {
 func NewRuleProcessor() *RuleProcessor {
 	evaluators := GetStandardEvaluators()
 	evaluators = append(evaluators, GetAuxEvaluators()...)
+	evaluators = append(evaluators, GetVersionEvaluators()...)
+	evaluators = append(evaluators, GetStringMatchEvaluators()...)
+	evaluators = append(evaluators, GetScheduleEvaluators()...)
 	evaluatorMap := make(map[string]IConditionEvaluator)
 	for _, evaluator := range evaluators {
 		ttype := evaluator.FreeArgType()
@@ -45,15 +48,29 @@ func NewRuleProcessor() *RuleProcessor {
 	}
 }
 
+// AddEvaluators adds evaluators, replacing those of the same free arg type and operation
 func (p *RuleProcessor) AddEvaluators(evs []IConditionEvaluator) {
-	p.evaluators = append(p.evaluators, evs...)
 	for _, evaluator := range evs {
 		ttype := evaluator.FreeArgType()
 		op := evaluator.Operation()
+		if _, ok := p.evaluatorMap[ttype+"_"+op]; ok {
+			p.removeEvaluators(ttype, op)
+		}
+		p.evaluators = append(p.evaluators, evaluator)
 		p.evaluatorMap[ttype+"_"+op] = evaluator
 	}
 }
 
+func (p *RuleProcessor) removeEvaluators(ttype string, op string) {
+	evaluators := p.evaluators[:0]
+	for _, evaluator := range p.evaluators {
+		if evaluator.FreeArgType() != ttype || evaluator.Operation() != op {
+			evaluators = append(evaluators, evaluator)
+		}
+	}
+	p.evaluators = evaluators
+}
+
 func (p *RuleProcessor) Size() int {
 	return len(p.evaluators)
 }
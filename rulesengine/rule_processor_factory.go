@@ -78,6 +78,7 @@ func NewRuleProcessorFactory() *RuleProcessorFactory {
 	customizedEvaluators = append(customizedEvaluators, percEval)
 
 	processor.AddEvaluators(customizedEvaluators)
+	processor.AddEvaluators(GetRegisteredEvaluators())
 
 	return &RuleProcessorFactory{
 		Processor: processor,
@@ -94,7 +95,8 @@ func evalRange(freeArgValue string, fixedArgValueItf interface{}) bool {
 		return false
 	}
 
-	elements := strings.Split(fixedArgValue, "-")
+	percentRange, salt := SplitPercentSalt(fixedArgValue)
+	elements := strings.Split(percentRange, "-")
 	if len(elements) != 2 {
 		return false
 	}
@@ -107,5 +109,5 @@ func evalRange(freeArgValue string, fixedArgValueItf interface{}) bool {
 	if err != nil || highRange <= 0 {
 		return false
 	}
-	return !FitsPercent(freeArgValue, lowRange) && FitsPercent(freeArgValue, highRange)
+	return FitsSaltedPercentRange(freeArgValue, lowRange, highRange, salt)
 }
@@ -0,0 +1,187 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+const (
+	propertyTestRules    = 300
+	propertyTestContexts = 20
+)
+
+func hasRelation(r *Rule, relation string) bool {
+	for _, part := range r.GetCompoundParts() {
+		if part.GetRelation() == relation {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNegationInvertsEvaluation(t *testing.T) {
+	processor := NewRuleProcessor()
+	generator := NewRuleGenerator(1)
+	for i := 0; i < propertyTestRules; i++ {
+		r := generator.Rule()
+		negated := Not(*r)
+		for j := 0; j < propertyTestContexts; j++ {
+			context := generator.Context()
+			result := processor.Evaluate(r, context, nil)
+			assert.Equal(t, !result, processor.Evaluate(&negated, context, nil), "%v %v", r, context)
+			if !r.IsCompound() {
+				assert.Equal(t, !result, processor.Evaluate(r, context, nil, !r.IsNegated()), "%v %v", r, context)
+			}
+		}
+	}
+}
+
+func TestDoubleNegationIsIdentity(t *testing.T) {
+	processor := NewRuleProcessor()
+	generator := NewRuleGenerator(2)
+	for i := 0; i < propertyTestRules; i++ {
+		r := generator.Rule()
+		twice := Not(Not(*r))
+		assert.Assert(t, r.Equals(&twice), r.String())
+		assert.Equal(t, r.String(), twice.String())
+		for j := 0; j < propertyTestContexts; j++ {
+			context := generator.Context()
+			assert.Equal(t, processor.Evaluate(r, context, nil), processor.Evaluate(&twice, context, nil), "%v %v", r, context)
+		}
+	}
+}
+
+// AndRules and OrRules append to the parts of a compound base, so they follow the XConf
+// precedence of the parts: a negated base or an OR after AND parts binds differently
+func TestAndRulesOrRulesMatchBooleanSemantics(t *testing.T) {
+	processor := NewRuleProcessor()
+	generator := NewRuleGenerator(3)
+	for i := 0; i < propertyTestRules; i++ {
+		a, b := generator.Rule(), generator.Rule()
+		if a.IsCompound() && a.IsNegated() {
+			continue
+		}
+		and := AndRules(*a, *b)
+		or := OrRules(*a, *b)
+		for j := 0; j < propertyTestContexts; j++ {
+			context := generator.Context()
+			resultA := processor.Evaluate(a, context, nil)
+			resultB := processor.Evaluate(b, context, nil)
+			assert.Equal(t, resultA && resultB, processor.Evaluate(&and, context, nil), "%v AND %v %v", a, b, context)
+			if !hasRelation(a, RelationAnd) {
+				assert.Equal(t, resultA || resultB, processor.Evaluate(&or, context, nil), "%v OR %v %v", a, b, context)
+			}
+		}
+	}
+}
+
+func TestAndRulesOrRulesDoNotShareParts(t *testing.T) {
+	processor := NewRuleProcessor()
+	a, err := ParseRule(`model IS "X1" OR model IS "X2"`)
+	assert.NilError(t, err)
+	a.CompoundParts = append(make([]Rule, 0, 4), a.CompoundParts...)
+	env, err := ParseRule(`env IS "QA"`)
+	assert.NilError(t, err)
+	partner, err := ParseRule(`partnerId IS "cox"`)
+	assert.NilError(t, err)
+
+	and := AndRules(*a, *env)
+	or := OrRules(*a, *partner)
+	assert.Equal(t, `model IS "X1" OR model IS "X2" AND env IS "QA"`, and.String())
+	assert.Equal(t, `model IS "X1" OR model IS "X2" OR partnerId IS "cox"`, or.String())
+	assert.Equal(t, `model IS "X1" OR model IS "X2"`, a.String())
+	assert.Assert(t, !processor.Evaluate(&and, map[string]string{"model": "X1", "env": "DEV"}, nil))
+}
+
+func TestOrRulesFollowsXconfPrecedence(t *testing.T) {
+	processor := NewRuleProcessor()
+	a, err := ParseRule(`model IS "X1" AND env IS "QA"`)
+	assert.NilError(t, err)
+	b, err := ParseRule(`partnerId IS "cox"`)
+	assert.NilError(t, err)
+	or := OrRules(*a, *b)
+	assert.Equal(t, `model IS "X1" AND env IS "QA" OR partnerId IS "cox"`, or.String())
+	// evaluated as model IS "X1" AND (env IS "QA" OR partnerId IS "cox")
+	assert.Assert(t, !processor.Evaluate(&or, map[string]string{"partnerId": "cox"}, nil))
+	assert.Assert(t, processor.Evaluate(&or, map[string]string{"model": "X1", "partnerId": "cox"}, nil))
+}
+
+func TestRuleStringParsesToEquivalentRule(t *testing.T) {
+	processor := NewRuleProcessor()
+	generator := NewRuleGenerator(4)
+	for i := 0; i < propertyTestRules; i++ {
+		r := generator.Rule()
+		parsed, err := ParseRule(r.String())
+		assert.NilError(t, err, r.String())
+		reparsed, err := ParseRule(parsed.String())
+		assert.NilError(t, err, parsed.String())
+		assert.Assert(t, parsed.Equals(reparsed), parsed.String())
+		for j := 0; j < propertyTestContexts; j++ {
+			context := generator.Context()
+			assert.Equal(t, processor.Evaluate(r, context, nil), processor.Evaluate(parsed, context, nil), "%v %v", r, context)
+		}
+	}
+}
+
+func TestRuleGeneratorIsDeterministic(t *testing.T) {
+	g1, g2 := NewRuleGenerator(5), NewRuleGenerator(5)
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, g1.Rule().String(), g2.Rule().String())
+		assert.DeepEqual(t, g1.Context(), g2.Context())
+	}
+}
+
+func TestOptimizedEvaluatorsMatchLinearEvaluation(t *testing.T) {
+	processor := NewRuleProcessor()
+	candidates := map[string]Evaluator{
+		"index": func(r *Rule, context map[string]string) bool {
+			xrule := &traceTestRule{id: "rule", rule: r}
+			return NewRuleIndex(processor, []XRule{xrule}).Evaluate(xrule, context, nil)
+		},
+		"trace": func(r *Rule, context map[string]string) bool {
+			result, _ := processor.EvaluateWithTrace(r, context)
+			return result
+		},
+		"normalized": func(r *Rule, context map[string]string) bool {
+			return processor.Evaluate(NormalizeRule(r), context, nil)
+		},
+	}
+	for name, candidate := range candidates {
+		mismatches := CheckEvaluator(processor, candidate, NewRuleGenerator(6), propertyTestRules, propertyTestContexts)
+		assert.Equal(t, 0, len(mismatches), "%v: %v", name, mismatches)
+	}
+}
+
+func TestCheckEvaluatorReportsMismatches(t *testing.T) {
+	processor := NewRuleProcessor()
+	ignoresNegation := func(r *Rule, context map[string]string) bool {
+		return processor.Evaluate(r, context, nil) != r.IsNegated()
+	}
+	mismatches := CheckEvaluator(processor, ignoresNegation, NewRuleGenerator(7), propertyTestRules, propertyTestContexts)
+	assert.Equal(t, MaxEvaluationMismatches, len(mismatches))
+	for _, mismatch := range mismatches {
+		r, err := ParseRule(mismatch.Rule)
+		assert.NilError(t, err)
+		assert.Assert(t, r.IsNegated())
+		assert.Equal(t, mismatch.Expected, processor.Evaluate(r, mismatch.Context, nil))
+		assert.Equal(t, !mismatch.Expected, mismatch.Actual)
+	}
+}
@@ -0,0 +1,155 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// DefaultMaxRuleStats is the default number of rules with their own counters
+	DefaultMaxRuleStats = 5000
+	// OtherRuleId collects the counts of the rules beyond the limit
+	OtherRuleId = "other"
+)
+
+// RuleStat counts how often a rule was evaluated, matched the context and was applied to the response
+type RuleStat struct {
+	RuleType  string  `json:"ruleType"`
+	Id        string  `json:"id"`
+	Name      string  `json:"name,omitempty"`
+	Evaluated int64   `json:"evaluated"`
+	Matched   int64   `json:"matched"`
+	Applied   int64   `json:"applied"`
+	HitRate   float64 `json:"hitRate"`
+}
+
+type ruleStatsKey struct {
+	ruleType string
+	id       string
+}
+
+type ruleCounter struct {
+	name      string
+	evaluated int64
+	matched   int64
+	applied   int64
+}
+
+var (
+	// ruleStats holds a *ruleCounter per ruleStatsKey, at most maxRuleStats of them plus one per rule type for OtherRuleId
+	ruleStats      sync.Map
+	ruleStatsCount int64
+	maxRuleStats   int64 = DefaultMaxRuleStats
+)
+
+// SetMaxRuleStats limits the number of rules with their own counters, which bounds the cardinality of the rule metrics
+func SetMaxRuleStats(max int) {
+	if max <= 0 {
+		max = DefaultMaxRuleStats
+	}
+	atomic.StoreInt64(&maxRuleStats, int64(max))
+}
+
+// RecordRuleEvaluation counts an evaluation of xrule and whether it matched
+func RecordRuleEvaluation(xrule XRule, matched bool) {
+	counter := getRuleCounter(xrule)
+	atomic.AddInt64(&counter.evaluated, 1)
+	if matched {
+		atomic.AddInt64(&counter.matched, 1)
+	}
+}
+
+// RecordRuleApplied counts a response that was built from xrule
+func RecordRuleApplied(xrule XRule) {
+	atomic.AddInt64(&getRuleCounter(xrule).applied, 1)
+}
+
+func getRuleCounter(xrule XRule) *ruleCounter {
+	key := ruleStatsKey{ruleType: xrule.GetRuleType(), id: xrule.GetId()}
+	if counter, ok := ruleStats.Load(key); ok {
+		return counter.(*ruleCounter)
+	}
+	name := xrule.GetName()
+	if atomic.LoadInt64(&ruleStatsCount) >= atomic.LoadInt64(&maxRuleStats) {
+		key.id, name = OtherRuleId, ""
+	}
+	counter, loaded := ruleStats.LoadOrStore(key, &ruleCounter{name: name})
+	if !loaded && key.id != OtherRuleId {
+		atomic.AddInt64(&ruleStatsCount, 1)
+	}
+	return counter.(*ruleCounter)
+}
+
+// GetRuleStats returns the counters sorted by rule type and then by the number of evaluations
+func GetRuleStats() []RuleStat {
+	stats := []RuleStat{}
+	ruleStats.Range(func(k, v interface{}) bool {
+		key := k.(ruleStatsKey)
+		counter := v.(*ruleCounter)
+		stat := RuleStat{
+			RuleType:  key.ruleType,
+			Id:        key.id,
+			Name:      counter.name,
+			Evaluated: atomic.LoadInt64(&counter.evaluated),
+			Matched:   atomic.LoadInt64(&counter.matched),
+			Applied:   atomic.LoadInt64(&counter.applied),
+		}
+		if stat.Evaluated > 0 {
+			stat.HitRate = float64(stat.Matched) / float64(stat.Evaluated)
+		}
+		stats = append(stats, stat)
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].RuleType != stats[j].RuleType {
+			return stats[i].RuleType < stats[j].RuleType
+		}
+		if stats[i].Evaluated != stats[j].Evaluated {
+			return stats[i].Evaluated > stats[j].Evaluated
+		}
+		return stats[i].Id < stats[j].Id
+	})
+	return stats
+}
+
+// ResetRuleStats drops all counters
+func ResetRuleStats() {
+	ruleStats.Range(func(key, value interface{}) bool {
+		ruleStats.Delete(key)
+		return true
+	})
+	atomic.StoreInt64(&ruleStatsCount, 0)
+}
+
+// ResetRuleStatsOfType drops the counters of one rule type, the rule type is case insensitive
+func ResetRuleStatsOfType(ruleType string) {
+	ruleStats.Range(func(k, v interface{}) bool {
+		key := k.(ruleStatsKey)
+		if !strings.EqualFold(key.ruleType, ruleType) {
+			return true
+		}
+		if _, loaded := ruleStats.LoadAndDelete(key); loaded && key.id != OtherRuleId {
+			atomic.AddInt64(&ruleStatsCount, -1)
+		}
+		return true
+	})
+}
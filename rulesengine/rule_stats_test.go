@@ -0,0 +1,99 @@
+package rulesengine
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+type statsTestRule struct {
+	traceTestRule
+	ruleType string
+}
+
+func (r *statsTestRule) GetRuleType() string { return r.ruleType }
+
+func findRuleStat(ruleType string, id string) (RuleStat, bool) {
+	for _, stat := range GetRuleStats() {
+		if stat.RuleType == ruleType && stat.Id == id {
+			return stat, true
+		}
+	}
+	return RuleStat{}, false
+}
+
+func TestRuleStats(t *testing.T) {
+	ResetRuleStats()
+	defer ResetRuleStats()
+
+	processor := NewRuleProcessor()
+	rules := parseIndexTestRules(t, `model IS "X1"`, `model IS "X2"`)
+	var tracer *RuleTracer
+	for _, model := range []string{"X1", "X1", "X2", "X3"} {
+		context := map[string]string{"model": model}
+		for _, rule := range rules {
+			if tracer.Evaluate(processor, rule, context, nil) {
+				RecordRuleApplied(rule)
+			}
+		}
+	}
+
+	stat, ok := findRuleStat("TestRule", "rule-0")
+	assert.Assert(t, ok)
+	assert.Equal(t, "name-rule-0", stat.Name)
+	assert.Equal(t, int64(4), stat.Evaluated)
+	assert.Equal(t, int64(2), stat.Matched)
+	assert.Equal(t, int64(2), stat.Applied)
+	assert.Equal(t, 0.5, stat.HitRate)
+
+	index := NewRuleIndex(processor, rules)
+	index.Filter(map[string]string{"model": "X2"})
+	stat, _ = findRuleStat("TestRule", "rule-1")
+	assert.Equal(t, int64(5), stat.Evaluated)
+	assert.Equal(t, int64(2), stat.Matched)
+
+	ResetRuleStats()
+	_, ok = findRuleStat("TestRule", "rule-0")
+	assert.Assert(t, !ok)
+}
+
+func TestRuleStatsAreBounded(t *testing.T) {
+	ResetRuleStats()
+	SetMaxRuleStats(3)
+	defer func() {
+		SetMaxRuleStats(DefaultMaxRuleStats)
+		ResetRuleStats()
+	}()
+
+	for i := 0; i < 10; i++ {
+		rule := &statsTestRule{traceTestRule: traceTestRule{id: fmt.Sprintf("rule-%d", i)}, ruleType: "BoundedRule"}
+		RecordRuleEvaluation(rule, true)
+	}
+	stats := GetRuleStats()
+	assert.Equal(t, 4, len(stats))
+	other, ok := findRuleStat("BoundedRule", OtherRuleId)
+	assert.Assert(t, ok)
+	assert.Equal(t, int64(7), other.Evaluated)
+	assert.Equal(t, "", other.Name)
+}
+
+func TestRuleStatsWithoutStats(t *testing.T) {
+	ResetRuleStats()
+	defer ResetRuleStats()
+
+	processor := NewRuleProcessor()
+	rules := parseIndexTestRules(t, `model IS "X1"`)
+	context := map[string]string{"model": "X1"}
+	var tracer *RuleTracer
+	assert.Assert(t, tracer.EvaluateWithoutStats(processor, rules[0], context, nil))
+	tracer = NewRuleTracer()
+	assert.Assert(t, tracer.EvaluateWithoutStats(processor, rules[0], context, nil))
+	assert.Equal(t, 1, len(tracer.Rules))
+	_, ok := findRuleStat("TestRule", "rule-0")
+	assert.Assert(t, !ok)
+
+	assert.Assert(t, tracer.Evaluate(processor, rules[0], context, nil))
+	stat, _ := findRuleStat("TestRule", "rule-0")
+	assert.Equal(t, int64(1), stat.Evaluated)
+}
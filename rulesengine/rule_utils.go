@@ -55,7 +55,11 @@ func contains(carray []conditionCount, cond Condition) int {
 	return -1
 }
 
-func hashCodeFromValue(itf interface{}) (float64, bool) {
+// PercentSaltSeparator separates the percentage and the salt of a PERCENT or RANGE fixed arg, as in
+// "25@spring-rollout" or "10-35@spring-rollout"
+const PercentSaltSeparator = "@"
+
+func hashCodeFromValue(itf interface{}, salt string) (float64, bool) {
 	var bbytes []byte
 
 	switch ty := itf.(type) {
@@ -67,13 +71,23 @@ func hashCodeFromValue(itf interface{}) (float64, bool) {
 	default:
 		return 0, false
 	}
+	// without a salt the hash is unchanged, so existing rollouts keep their devices
+	if len(salt) > 0 {
+		bbytes = append([]byte(salt+PercentSaltSeparator), bbytes...)
+	}
 
 	hashCode := float64(int64(siphash.Sum64(bbytes, &SipHashKey))) + voffset
 	return hashCode, true
 }
 
 func GetPercentHash(itf interface{}) (float64, bool) {
-	hashCode, ok := hashCodeFromValue(itf)
+	return GetSaltedPercentHash(itf, "")
+}
+
+// GetSaltedPercentHash returns the bucket of the value in [0, 100], the value fits a percentage
+// with the same salt when its bucket is not above the percentage
+func GetSaltedPercentHash(itf interface{}, salt string) (float64, bool) {
+	hashCode, ok := hashCodeFromValue(itf, salt)
 	if !ok {
 		return 0, false
 	}
@@ -82,7 +96,13 @@ func GetPercentHash(itf interface{}) (float64, bool) {
 }
 
 func FitsPercent(itf interface{}, percent float64) bool {
-	hashCode, ok := hashCodeFromValue(itf)
+	return FitsSaltedPercent(itf, percent, "")
+}
+
+// FitsSaltedPercent is FitsPercent with the value hashed together with salt, so rollouts with
+// different salts select independent sets of devices
+func FitsSaltedPercent(itf interface{}, percent float64, salt string) bool {
+	hashCode, ok := hashCodeFromValue(itf, salt)
 	if !ok {
 		return false
 	}
@@ -91,6 +111,17 @@ func FitsPercent(itf interface{}, percent float64) bool {
 	return hashCode <= limit
 }
 
+// FitsSaltedPercentRange checks that the value is above lowRange and fits highRange
+func FitsSaltedPercentRange(itf interface{}, lowRange float64, highRange float64, salt string) bool {
+	return !FitsSaltedPercent(itf, lowRange, salt) && FitsSaltedPercent(itf, highRange, salt)
+}
+
+// SplitPercentSalt splits a PERCENT or RANGE fixed arg into its percentage and its salt
+func SplitPercentSalt(value string) (string, string) {
+	percent, salt, _ := strings.Cut(value, PercentSaltSeparator)
+	return strings.TrimSpace(percent), strings.TrimSpace(salt)
+}
+
 func Copy(r Rule) Rule {
 	// rp := &r
 	result := Rule{}
@@ -160,6 +191,7 @@ func (r *Rule) IsEmpty() bool {
 	return false
 }
 
+// EqualComplexRules compares the normalized rules, so the order of the parts does not matter
 func EqualComplexRules(rule1 *Rule, rule2 *Rule) bool {
 	if rule1.IsEmpty() && rule2.IsEmpty() {
 		return true
@@ -167,27 +199,7 @@ func EqualComplexRules(rule1 *Rule, rule2 *Rule) bool {
 	if rule1 == nil || rule2 == nil {
 		return false
 	}
-	result := false
-	flattenedRule1 := FlattenRule(*rule1)
-	flattenedRule2 := FlattenRule(*rule2)
-	if len(flattenedRule1) != len(flattenedRule2) {
-		return false
-	}
-	if len(flattenedRule1) > 1 &&
-		allRulesHaveSameRelation(flattenedRule1) &&
-		allRulesHaveSameRelation(flattenedRule2) {
-		sameRelation := flattenedRule1[len(flattenedRule1)-1].GetRelation()
-		flattenedRule1[0].SetRelation(sameRelation)
-		flattenedRule2[0].SetRelation(sameRelation)
-		result = equalNonCompoundRulesCollections(flattenedRule1, flattenedRule2)
-		sameRelation = ""
-		flattenedRule1[0].SetRelation(sameRelation)
-		flattenedRule2[0].SetRelation(sameRelation)
-	} else {
-		result = equalNonCompoundRulesCollectionsRegardingTheOrder(flattenedRule1, flattenedRule2)
-	}
-
-	return result
+	return NormalizedRuleKey(rule1) == NormalizedRuleKey(rule2)
 }
 
 func equalNonCompoundRulesCollections(list1 []Rule, list2 []Rule) bool {
@@ -300,10 +312,13 @@ func And(r Rule) Rule {
 	return result
 }
 
+// AndRules appends compound to the parts of base with an AND relation
 func AndRules(base Rule, compound Rule) Rule {
 	return addRelatedCompound(base, compound, RelationAnd)
 }
 
+// OrRules appends compound to the parts of base with an OR relation. Like every part list it is
+// evaluated with the XConf precedence, so OrRules of a AND b with c is a AND (b OR c).
 func OrRules(base Rule, compound Rule) Rule {
 	return addRelatedCompound(base, compound, RelationOr)
 }
@@ -321,6 +336,8 @@ func addRelatedCompound(base Rule, compound Rule, relation string) Rule {
 		result.AddCompoundPart(Copy(base))
 	} else {
 		result = base
+		// copy the parts so that results of the same base do not share them
+		result.SetCompoundParts(append(make([]Rule, 0, len(base.CompoundParts)+1), base.CompoundParts...))
 	}
 
 	compound.SetRelation(relation)
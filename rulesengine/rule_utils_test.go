@@ -18,6 +18,7 @@
 package rulesengine
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -219,6 +220,18 @@ func TestCompareRules(t *testing.T) {
 	assert.Equal(t, adata, 0)
 }
 
+func TestCompareRulesKeepsTheOrderOfTheParts(t *testing.T) {
+	r1, err := ParseRule(`model IS "X1" AND env LIKE "QA.*"`)
+	assert.NilError(t, err)
+	r2, err := ParseRule(`env IN_LIST "envs" AND model IS "X2"`)
+	assert.NilError(t, err)
+
+	// the first parts as written decide, IS sorts after IN_LIST, whatever order the normalized rules have
+	assert.Equal(t, CompareRules(*r1, *r2), 1)
+	assert.Equal(t, CompareRules(*r2, *r1), -1)
+	assert.Assert(t, getFirstChild(*NormalizeRule(r1)).Condition.Operation != getFirstChild(*r1).Condition.Operation)
+}
+
 func TestToConditionsNotCompoundRule(t *testing.T) {
 	ruleTest := createRule("", "fixedArg")
 	actualResult := ToConditions(ruleTest)
@@ -1991,3 +2004,89 @@ func TestEqualFixedArgCondition(t *testing.T) {
 		assert.Equal(t, true, result) // Should match case insensitively
 	})
 }
+
+func TestFitsSaltedPercent(t *testing.T) {
+	cpeMac := "7A:86:0A:C2:6D:7B"
+	hash, ok := GetSaltedPercentHash(cpeMac, "")
+	assert.Assert(t, ok)
+	unsalted, _ := GetPercentHash(cpeMac)
+	assert.Equal(t, unsalted, hash)
+
+	salted, ok := GetSaltedPercentHash(cpeMac, "spring-rollout")
+	assert.Assert(t, ok)
+	assert.Assert(t, salted >= 0.0 && salted <= 100.0)
+	assert.Assert(t, salted != unsalted)
+	again, _ := GetSaltedPercentHash(cpeMac, "spring-rollout")
+	assert.Equal(t, salted, again)
+
+	assert.Assert(t, FitsSaltedPercent(cpeMac, salted, "spring-rollout"))
+	assert.Assert(t, !FitsSaltedPercent(cpeMac, salted-0.001, "spring-rollout"))
+	assert.Assert(t, FitsSaltedPercentRange(cpeMac, salted-0.001, salted+0.001, "spring-rollout"))
+	assert.Assert(t, !FitsSaltedPercentRange(cpeMac, salted, 100, "spring-rollout"))
+}
+
+func TestSaltedPercentSelectsIndependentDevices(t *testing.T) {
+	inBoth, inFirst := 0, 0
+	for i := 0; i < 2000; i++ {
+		mac := fmt.Sprintf("AA:BB:CC:DD:%02X:%02X", i/256, i%256)
+		first := FitsSaltedPercent(mac, 10, "rollout-1")
+		if first {
+			inFirst++
+			if FitsSaltedPercent(mac, 10, "rollout-2") {
+				inBoth++
+			}
+		}
+	}
+	// about 10% of the first rollout is expected in the second one, not all of it
+	assert.Assert(t, inFirst > 100, inFirst)
+	assert.Assert(t, inBoth < inFirst/3, "%v of %v", inBoth, inFirst)
+}
+
+func TestSaltedPercentConditions(t *testing.T) {
+	processor := NewRuleProcessorFactory().RuleProcessor()
+	cpeMac := "7A:86:0A:C2:6D:7B"
+	salted, _ := GetSaltedPercentHash(cpeMac, "exp1")
+	context := map[string]string{"estbMacAddress": cpeMac, "estbIP": "10.0.0.1"}
+
+	testCases := []struct {
+		rule     string
+		expected bool
+	}{
+		{`estbMacAddress PERCENT "22.2"`, true},
+		{`estbMacAddress PERCENT "22.1"`, false},
+		{fmt.Sprintf(`estbMacAddress PERCENT "%v@exp1"`, salted+0.01), true},
+		{fmt.Sprintf(`estbMacAddress PERCENT "%v@exp1"`, salted-0.01), false},
+		{`estbMacAddress RANGE "22.1-22.3"`, true},
+		{fmt.Sprintf(`estbMacAddress RANGE "%v-%v@exp1"`, salted-0.01, salted+0.01), true},
+		{`estbMacAddress RANGE "22.1-22.3@exp1"`, salted > 22.1 && salted <= 22.3},
+	}
+	for _, tc := range testCases {
+		rule, err := ParseRule(tc.rule)
+		assert.NilError(t, err)
+		assert.Equal(t, tc.expected, processor.Evaluate(rule, context, nil), tc.rule)
+	}
+
+	ipHash, _ := GetSaltedPercentHash("10.0.0.1", "exp1")
+	rule, err := ParseRule(fmt.Sprintf(`estbIP:IP_ADDRESS PERCENT "%v@exp1"`, ipHash+0.01))
+	assert.NilError(t, err)
+	assert.Assert(t, processor.Evaluate(rule, context, nil))
+}
+
+func TestAddRelatedCompoundDoesNotShareParts(t *testing.T) {
+	condition := func(value string) Rule {
+		return Rule{Condition: NewCondition(NewFreeArg(StandardFreeArgTypeString, "model"), StandardOperationIs, NewFixedArg(value))}
+	}
+	// spare capacity in the parts of base would be shared by appending to them in place
+	base := Rule{CompoundParts: make([]Rule, 0, 4)}
+	base.AddCompoundPart(condition("X1"))
+	base.AddCompoundPart(condition("X2"))
+
+	andRule := AndRules(base, condition("X3"))
+	orRule := OrRules(base, condition("X4"))
+
+	assert.Equal(t, len(base.CompoundParts), 2)
+	assert.Equal(t, andRule.CompoundParts[2].Condition.FixedArg.String(), NewFixedArg("X3").String())
+	assert.Equal(t, andRule.CompoundParts[2].Relation, RelationAnd)
+	assert.Equal(t, orRule.CompoundParts[2].Condition.FixedArg.String(), NewFixedArg("X4").String())
+	assert.Equal(t, orRule.CompoundParts[2].Relation, RelationOr)
+}
@@ -0,0 +1,436 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/common"
+)
+
+const (
+	scheduleDateFormat = "2006-01-02"
+	minutesPerDay      = 24 * 60
+)
+
+// scheduleLocalTimeFormats are the formats of the device local time in the free arg. The firmware context
+// has it without a zone, and its properties as time.Time.String() labeled UTC, so only the wall clock is used.
+// An RFC 3339 time is an instant and is converted to the device time zone
+var scheduleLocalTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"01/02/2006 15:04:05",
+	"1/2/2006 15:04",
+}
+
+var scheduleWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// scheduleNow is the evaluation time when the context has no time
+var scheduleNow = time.Now
+
+var (
+	// schedules caches parsed fixed args, locations caches time zones by name
+	schedules sync.Map
+	locations sync.Map
+)
+
+// ScheduleEvaluator implements SCHEDULE. The fixed arg is one or more recurring windows separated
+// by ';', or a collection of them, and the condition holds when the device-local time is in any
+// window. A window has optional days, optional dates and an optional time range, for instance
+// "Mon-Fri 01:00-04:00", "Sat,Sun", "2026-12-24..2026-12-26 22:00-02:00" or "Daily 03:00-05:00".
+// A time range ending before it starts runs into the next day, the days and dates name the day it starts.
+//
+// The free arg names the context time. The context time of firmware requests is already the device local
+// time. The current time, used when the context has none, and RFC 3339 times are converted to the device
+// time zone from the timezone or timeZoneOffset context values.
+type ScheduleEvaluator struct {
+	freeArgType string
+}
+
+func GetScheduleEvaluators() []IConditionEvaluator {
+	return []IConditionEvaluator{
+		NewScheduleEvaluator(StandardFreeArgTypeString),
+		NewScheduleEvaluator(AuxFreeArgTypeTime),
+	}
+}
+
+func NewScheduleEvaluator(freeArgType string) *ScheduleEvaluator {
+	return &ScheduleEvaluator{
+		freeArgType: freeArgType,
+	}
+}
+
+func (e *ScheduleEvaluator) FreeArgType() string {
+	return e.freeArgType
+}
+
+func (e *ScheduleEvaluator) Operation() string {
+	return StandardOperationSchedule
+}
+
+func (e *ScheduleEvaluator) Evaluate(condition *Condition, context map[string]string) bool {
+	windows := scheduleValues(condition.GetFixedArg())
+	if len(windows) == 0 {
+		return false
+	}
+	now, ok := scheduleTime(context[condition.GetFreeArg().GetName()], DeviceLocation(context))
+	if !ok {
+		return false
+	}
+
+	for _, value := range windows {
+		schedule, err := getSchedule(value)
+		if err == nil && schedule.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate rejects a missing fixed arg and windows that cannot be parsed
+func (e *ScheduleEvaluator) Validate(fixedArg *FixedArg) error {
+	values := scheduleValues(fixedArg)
+	if len(values) == 0 {
+		return fmt.Errorf("%v needs a string or a collection of strings", StandardOperationSchedule)
+	}
+	return ValidateSchedules(values)
+}
+
+// ValidateSchedules returns the error of the first window that cannot be parsed
+func ValidateSchedules(windows []string) error {
+	for _, value := range windows {
+		if _, err := getSchedule(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InSchedules returns true when the wall clock of t is in any of the windows, windows that cannot be parsed are ignored
+func InSchedules(windows []string, t time.Time) bool {
+	for _, value := range windows {
+		if schedule, err := getSchedule(value); err == nil && schedule.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextScheduleStart returns the first start of any of the windows after t, in the location of t.
+// It returns false when no window starts within a year
+func NextScheduleStart(windows []string, t time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, value := range windows {
+		schedule, err := getSchedule(value)
+		if err != nil {
+			continue
+		}
+		if start, ok := schedule.nextStart(t); ok && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// DeviceLocation returns the time zone of the device, from the timezone name or else from the
+// timeZoneOffset of the context, and UTC when neither can be used
+func DeviceLocation(context map[string]string) *time.Location {
+	if name := context[common.TIME_ZONE]; len(name) > 0 {
+		if location, ok := loadLocation(name); ok {
+			return location
+		}
+	}
+	if offset := context[common.TIME_ZONE_OFFSET]; len(offset) > 0 {
+		if location, ok := ParseTimeZoneOffset(offset); ok {
+			return location
+		}
+	}
+	return time.UTC
+}
+
+// ParseTimeZoneOffset parses "+05:30", "-04:00" or the unsigned "05:30", which is east of UTC as
+// it is for the local time of firmware contexts
+func ParseTimeZoneOffset(offset string) (*time.Location, bool) {
+	sign := 1
+	value := offset
+	if strings.HasPrefix(offset, "+") {
+		value = offset[1:]
+	} else if strings.HasPrefix(offset, "-") {
+		sign, value = -1, offset[1:]
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return nil, false
+	}
+	hours, err1 := strconv.Atoi(parts[0])
+	minutes, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || hours < 0 || hours > 23 || minutes < 0 || minutes > 59 {
+		return nil, false
+	}
+	return time.FixedZone("UTC"+offset, sign*(hours*3600+minutes*60)), true
+}
+
+func loadLocation(name string) (*time.Location, bool) {
+	if cached, ok := locations.Load(name); ok {
+		location, _ := cached.(*time.Location)
+		return location, location != nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		// names are cached as unknown too, they come from devices and accounts
+		location = nil
+	}
+	locations.Store(name, location)
+	return location, location != nil
+}
+
+// scheduleTime returns the time of value in the device location, a local time keeps its wall clock
+func scheduleTime(value string, location *time.Location) (time.Time, bool) {
+	if len(value) == 0 {
+		return scheduleNow().In(location), true
+	}
+	// time.Time.String() ends with the monotonic clock reading
+	if i := strings.Index(value, " m="); i > 0 {
+		value = value[:i]
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(location), true
+	}
+	for _, format := range scheduleLocalTimeFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), location), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func scheduleValues(fixedArg *FixedArg) []string {
+	var values []string
+	switch value := fixedArg.GetValue().(type) {
+	case string:
+		values = strings.Split(value, ";")
+	case []string:
+		values = value
+	}
+	windows := []string{}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			windows = append(windows, value)
+		}
+	}
+	return windows
+}
+
+// schedule is one parsed window
+type schedule struct {
+	weekdays map[time.Weekday]bool
+	dates    []dateRange
+	start    int
+	end      int
+}
+
+type dateRange struct {
+	from string
+	to   string
+}
+
+func getSchedule(value string) (*schedule, error) {
+	if cached, ok := schedules.Load(value); ok {
+		return cached.(*schedule), nil
+	}
+	s, err := parseSchedule(value)
+	if err != nil {
+		return nil, err
+	}
+	schedules.Store(value, s)
+	return s, nil
+}
+
+func parseSchedule(value string) (*schedule, error) {
+	s := &schedule{start: 0, end: minutesPerDay}
+	var hasDays, hasDates, hasTime bool
+	for _, token := range strings.Fields(value) {
+		var err error
+		switch {
+		case strings.Contains(token, ":"):
+			if hasTime {
+				return nil, fmt.Errorf("schedule '%v' has more than one time range", value)
+			}
+			hasTime = true
+			s.start, s.end, err = parseTimeRange(token)
+		case token[0] >= '0' && token[0] <= '9':
+			if hasDates {
+				return nil, fmt.Errorf("schedule '%v' has more than one list of dates", value)
+			}
+			hasDates = true
+			s.dates, err = parseDateRanges(token)
+		default:
+			if hasDays {
+				return nil, fmt.Errorf("schedule '%v' has more than one list of days", value)
+			}
+			hasDays = true
+			s.weekdays, err = parseWeekdays(token)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("schedule '%v' is invalid: %v", value, err)
+		}
+	}
+	if !hasDays && !hasDates && !hasTime {
+		return nil, fmt.Errorf("schedule '%v' is empty", value)
+	}
+	return s, nil
+}
+
+func parseTimeRange(token string) (int, int, error) {
+	parts := strings.Split(token, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("time range '%v' is not HH:MM-HH:MM", token)
+	}
+	start, err := parseMinuteOfDay(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseMinuteOfDay(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == minutesPerDay {
+		return 0, 0, fmt.Errorf("time range '%v' starts at 24:00", token)
+	}
+	return start, end, nil
+}
+
+func parseMinuteOfDay(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 || len(parts[1]) != 2 {
+		return 0, fmt.Errorf("time '%v' is not HH:MM", value)
+	}
+	hours, err1 := strconv.Atoi(parts[0])
+	minutes, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || hours < 0 || minutes < 0 || minutes > 59 || hours*60+minutes > minutesPerDay {
+		return 0, fmt.Errorf("time '%v' is not HH:MM", value)
+	}
+	return hours*60 + minutes, nil
+}
+
+func parseDateRanges(token string) ([]dateRange, error) {
+	ranges := []dateRange{}
+	for _, item := range strings.Split(token, ",") {
+		parts := strings.Split(item, "..")
+		if len(parts) > 2 {
+			return nil, fmt.Errorf("date range '%v' is not YYYY-MM-DD..YYYY-MM-DD", item)
+		}
+		for _, part := range parts {
+			if _, err := time.Parse(scheduleDateFormat, part); err != nil {
+				return nil, fmt.Errorf("date '%v' is not YYYY-MM-DD", part)
+			}
+		}
+		r := dateRange{from: parts[0], to: parts[len(parts)-1]}
+		if r.to < r.from {
+			return nil, fmt.Errorf("date range '%v' ends before it starts", item)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func parseWeekdays(token string) (map[time.Weekday]bool, error) {
+	lower := strings.ToLower(token)
+	if lower == "daily" || lower == "*" {
+		return nil, nil
+	}
+	weekdays := map[time.Weekday]bool{}
+	for _, item := range strings.Split(lower, ",") {
+		parts := strings.Split(item, "-")
+		if len(parts) > 2 {
+			return nil, fmt.Errorf("day range '%v' is not Mon-Fri", item)
+		}
+		from, ok := scheduleWeekdays[parts[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown day '%v'", parts[0])
+		}
+		to, ok := scheduleWeekdays[parts[len(parts)-1]]
+		if !ok {
+			return nil, fmt.Errorf("unknown day '%v'", parts[len(parts)-1])
+		}
+		// a range such as Fri-Mon wraps around the weekend
+		for day := from; ; day = (day + 1) % 7 {
+			weekdays[day] = true
+			if day == to {
+				break
+			}
+		}
+	}
+	return weekdays, nil
+}
+
+// contains compares the wall clock of t, so a window keeps its local hours across DST changes
+func (s *schedule) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	year, month, day := t.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	if s.start < s.end {
+		return minute >= s.start && minute < s.end && s.matchesDay(today)
+	}
+	// the window runs past midnight, it started either today or yesterday
+	if minute >= s.start && s.matchesDay(today) {
+		return true
+	}
+	return minute < s.end && s.matchesDay(today.AddDate(0, 0, -1))
+}
+
+func (s *schedule) nextStart(t time.Time) (time.Time, bool) {
+	year, month, day := t.Date()
+	for i := 0; i <= 366; i++ {
+		if !s.matchesDay(time.Date(year, month, day+i, 0, 0, 0, 0, time.UTC)) {
+			continue
+		}
+		if start := time.Date(year, month, day+i, s.start/60, s.start%60, 0, 0, t.Location()); start.After(t) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (s *schedule) matchesDay(day time.Time) bool {
+	if s.weekdays != nil && !s.weekdays[day.Weekday()] {
+		return false
+	}
+	if s.dates == nil {
+		return true
+	}
+	date := day.Format(scheduleDateFormat)
+	for _, r := range s.dates {
+		if date >= r.from && date <= r.to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package rulesengine
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func setScheduleNow(t *testing.T, now time.Time) {
+	saved := scheduleNow
+	scheduleNow = func() time.Time { return now }
+	t.Cleanup(func() { scheduleNow = saved })
+}
+
+func TestScheduleEvaluator(t *testing.T) {
+	processor := NewRuleProcessor()
+	testCases := []struct {
+		rule     string
+		time     string
+		timezone string
+		expected bool
+	}{
+		// 2026-10-16 is a Friday
+		{`time SCHEDULE "Mon-Fri 01:00-04:00"`, "10/16/2026 02:30:00", "UTC", true},
+		{`time SCHEDULE "Mon-Fri 01:00-04:00"`, "10/16/2026 04:00:00", "UTC", false},
+		{`time SCHEDULE "Mon-Fri 01:00-04:00"`, "10/17/2026 02:30:00", "UTC", false},
+		{`time SCHEDULE "Sat,Sun"`, "10/17/2026 23:59:00", "UTC", true},
+		{`time SCHEDULE "Fri-Mon"`, "10/19/2026 12:00:00", "UTC", true},
+		{`time SCHEDULE "Fri-Mon"`, "10/20/2026 12:00:00", "UTC", false},
+		// a context time is already local, an RFC 3339 time is converted, the device is 4 hours behind UTC in October
+		{`time SCHEDULE "Mon-Fri 01:00-04:00"`, "10/16/2026 02:30:00", "America/New_York", true},
+		{`time SCHEDULE "Mon-Fri 01:00-04:00"`, "2026-10-16 02:30:00 +0000 UTC", "America/New_York", true},
+		{`time SCHEDULE "Mon-Fri 01:00-04:00"`, "2026-10-16T06:30:00Z", "America/New_York", true},
+		{`time SCHEDULE "Mon-Fri 01:00-04:00"`, "2026-10-16T02:30:00Z", "America/New_York", false},
+		// past midnight, the window belongs to the day it starts
+		{`time SCHEDULE "Fri 22:00-02:00"`, "10/17/2026 01:00:00", "UTC", true},
+		{`time SCHEDULE "Fri 22:00-02:00"`, "10/16/2026 01:00:00", "UTC", false},
+		{`time SCHEDULE "Fri 22:00-02:00"`, "10/16/2026 23:00:00", "UTC", true},
+		{`time SCHEDULE "2026-12-24..2026-12-26"`, "12/25/2026 10:00:00", "UTC", true},
+		{`time SCHEDULE "2026-12-24,2026-12-31 00:00-06:00"`, "12/31/2026 05:00:00", "UTC", true},
+		{`time SCHEDULE "2026-12-24,2026-12-31 00:00-06:00"`, "12/30/2026 05:00:00", "UTC", false},
+		{`time SCHEDULE "Mon 01:00-02:00; Daily 03:00-05:00"`, "10/16/2026 04:00:00", "UTC", true},
+		{`time SCHEDULE ["Mon 01:00-02:00", "Fri 03:00-05:00"]`, "10/16/2026 04:00:00", "UTC", true},
+		{`time SCHEDULE "Mon-Fri 01:00-04:00"`, "not a time", "UTC", false},
+		{`time SCHEDULE "Someday"`, "10/16/2026 02:30:00", "UTC", false},
+	}
+	for _, tc := range testCases {
+		rule, err := ParseRule(tc.rule)
+		assert.NilError(t, err)
+		context := map[string]string{"time": tc.time, "timezone": tc.timezone}
+		assert.Equal(t, tc.expected, processor.Evaluate(rule, context, nil), "%v at %v %v", tc.rule, tc.time, tc.timezone)
+	}
+}
+
+func TestScheduleEvaluatorDST(t *testing.T) {
+	processor := NewRuleProcessor()
+	rule, err := ParseRule(`time SCHEDULE "Daily 01:00-04:00"`)
+	assert.NilError(t, err)
+
+	// 02:30 local is 06:30 UTC in summer and 07:30 UTC in winter
+	for utc, expected := range map[string]bool{
+		"2026-07-01T06:30:00Z": true,
+		"2026-07-01T08:30:00Z": false,
+		"2026-01-15T07:30:00Z": true,
+		"2026-01-15T09:30:00Z": false,
+	} {
+		context := map[string]string{"time": utc, "timezone": "America/New_York"}
+		assert.Equal(t, expected, processor.Evaluate(rule, context, nil), utc)
+	}
+}
+
+func TestScheduleEvaluatorCurrentTime(t *testing.T) {
+	setScheduleNow(t, time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	processor := NewRuleProcessor()
+
+	// RFC and DCM contexts have no time, they use the current time
+	rule, err := ParseRule(`time SCHEDULE "Fri 16:00-18:00"`)
+	assert.NilError(t, err)
+	assert.Assert(t, !processor.Evaluate(rule, map[string]string{}, nil))
+	assert.Assert(t, processor.Evaluate(rule, map[string]string{"timeZoneOffset": "+05:00"}, nil))
+	assert.Assert(t, !processor.Evaluate(rule, map[string]string{"timeZoneOffset": "-05:00"}, nil))
+	// an unsigned offset is east of UTC, as for the firmware context time
+	assert.Assert(t, processor.Evaluate(rule, map[string]string{"timeZoneOffset": "05:00"}, nil))
+	// an unknown time zone name falls back to the offset
+	assert.Assert(t, processor.Evaluate(rule, map[string]string{"timezone": "UTC-8", "timeZoneOffset": "05:00"}, nil))
+
+	rule, err = ParseRule(`time:TIME SCHEDULE "Fri"`)
+	assert.NilError(t, err)
+	assert.Assert(t, processor.Evaluate(rule, map[string]string{}, nil))
+}
+
+func TestDeviceLocation(t *testing.T) {
+	assert.Equal(t, time.UTC, DeviceLocation(map[string]string{}))
+	assert.Equal(t, "Europe/Paris", DeviceLocation(map[string]string{"timezone": "Europe/Paris"}).String())
+	assert.Equal(t, time.UTC, DeviceLocation(map[string]string{"timezone": "Nowhere/City"}))
+
+	_, offset := time.Date(2026, 1, 1, 0, 0, 0, 0, DeviceLocation(map[string]string{"timeZoneOffset": "+05:30"})).Zone()
+	assert.Equal(t, 5*3600+30*60, offset)
+	_, offset = time.Date(2026, 1, 1, 0, 0, 0, 0, DeviceLocation(map[string]string{"timeZoneOffset": "05:00"})).Zone()
+	assert.Equal(t, 5*3600, offset)
+	_, offset = time.Date(2026, 1, 1, 0, 0, 0, 0, DeviceLocation(map[string]string{"timeZoneOffset": "-8:0"})).Zone()
+	assert.Equal(t, -8*3600, offset)
+	assert.Equal(t, time.UTC, DeviceLocation(map[string]string{"timeZoneOffset": "5"}))
+	assert.Equal(t, time.UTC, DeviceLocation(map[string]string{"timeZoneOffset": "25:30"}))
+}
+
+func TestScheduleEvaluatorValidate(t *testing.T) {
+	evaluator := NewScheduleEvaluator(StandardFreeArgTypeString)
+	for _, valid := range []interface{}{
+		"Mon-Fri 01:00-04:00",
+		"Daily",
+		"00:00-24:00",
+		"2026-12-24..2026-12-26 22:00-02:00",
+		[]string{"Sat,Sun", "Wed 01:00-02:00"},
+	} {
+		assert.NilError(t, evaluator.Validate(NewFixedArg(valid)), "%v", valid)
+	}
+	for _, invalid := range []interface{}{
+		"",
+		"Someday",
+		"Mon-Fri 01:00-25:00",
+		"Mon 1:0-2:00",
+		"24:00-01:00",
+		"2026-12-26..2026-12-24",
+		"2026-13-01",
+		"Mon Tue",
+		"01:00-02:00 03:00-04:00",
+		10.0,
+	} {
+		assert.Assert(t, evaluator.Validate(NewFixedArg(invalid)) != nil, "%v", invalid)
+	}
+
+	rule, err := ParseRule(`time SCHEDULE "Mon-Fro"`)
+	assert.NilError(t, err)
+	assert.ErrorContains(t, ValidateRule(rule), "unknown day 'fro'")
+}
+
+func TestNextScheduleStart(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.NilError(t, err)
+	// a Friday afternoon in New York
+	now := time.Date(2026, 10, 16, 15, 0, 0, 0, newYork)
+
+	testCases := []struct {
+		windows  []string
+		expected time.Time
+	}{
+		{[]string{"Daily 01:00-04:00"}, time.Date(2026, 10, 17, 1, 0, 0, 0, newYork)},
+		{[]string{"Mon-Fri 01:00-04:00"}, time.Date(2026, 10, 19, 1, 0, 0, 0, newYork)},
+		{[]string{"Fri 22:00-02:00"}, time.Date(2026, 10, 16, 22, 0, 0, 0, newYork)},
+		{[]string{"Sat,Sun"}, time.Date(2026, 10, 17, 0, 0, 0, 0, newYork)},
+		{[]string{"Mon 01:00-02:00", "Sun 03:00-05:00"}, time.Date(2026, 10, 18, 3, 0, 0, 0, newYork)},
+		// the DST change on November 1st keeps the local hours
+		{[]string{"2026-11-02 02:00-04:00"}, time.Date(2026, 11, 2, 2, 0, 0, 0, newYork)},
+		{[]string{"Someday", "Daily 23:00-01:00"}, time.Date(2026, 10, 16, 23, 0, 0, 0, newYork)},
+	}
+	for _, tc := range testCases {
+		start, ok := NextScheduleStart(tc.windows, now)
+		assert.Assert(t, ok, "%v", tc.windows)
+		assert.Assert(t, start.Equal(tc.expected), "%v: %v", tc.windows, start)
+	}
+
+	_, ok := NextScheduleStart([]string{"2026-01-01"}, now)
+	assert.Assert(t, !ok)
+	_, ok = NextScheduleStart([]string{"Someday"}, now)
+	assert.Assert(t, !ok)
+
+	assert.Assert(t, InSchedules([]string{"Mon 01:00-02:00", "Fri 14:00-16:00"}, now))
+	assert.Assert(t, !InSchedules([]string{"Daily 01:00-04:00"}, now))
+	assert.NilError(t, ValidateSchedules([]string{"Daily 01:00-04:00"}))
+	assert.ErrorContains(t, ValidateSchedules([]string{"Daily 01:00-04:00", "Someday"}), "Someday")
+}
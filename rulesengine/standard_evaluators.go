@@ -110,9 +110,10 @@ func GetStandardEvaluators() []IConditionEvaluator {
 		func(freeArgValue string, fixedArgValue interface{}) bool {
 			// sometimes the value comes back in quotes as a string, and sometimes without quotes as a float
 			if percentString, ok := fixedArgValue.(string); ok {
+				percentString, salt := SplitPercentSalt(percentString)
 				percent, err := strconv.ParseFloat(percentString, 64)
 				if err == nil {
-					return FitsPercent(freeArgValue, percent)
+					return FitsSaltedPercent(freeArgValue, percent, salt)
 				}
 			}
 			if percent, ok := fixedArgValue.(float64); ok {
@@ -0,0 +1,160 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// FixedArgValidator is implemented by evaluators that can reject a fixed arg when rules are loaded
+type FixedArgValidator interface {
+	Validate(fixedArg *FixedArg) error
+}
+
+// stringPattern is a fixed arg prepared for one StringMatchEvaluator
+type stringPattern struct {
+	regex *regexp.Regexp
+	affix string
+	err   error
+}
+
+// stringPatterns caches prepared patterns by operation and fixed arg value. Patterns come from
+// rules, so the cache is bounded by the number of distinct patterns in the rule tables.
+var stringPatterns sync.Map
+
+// StringMatchEvaluator implements REGEX, STARTS_WITH and ENDS_WITH and their _IGNORE_CASE variants.
+// The fixed arg is a single pattern or a collection of patterns, any of which may match.
+type StringMatchEvaluator struct {
+	operation  string
+	ignoreCase bool
+}
+
+func GetStringMatchEvaluators() []IConditionEvaluator {
+	return []IConditionEvaluator{
+		NewStringMatchEvaluator(StandardOperationRegex, false),
+		NewStringMatchEvaluator(StandardOperationRegexIgnoreCase, true),
+		NewStringMatchEvaluator(StandardOperationStartsWith, false),
+		NewStringMatchEvaluator(StandardOperationStartsWithIgnoreCase, true),
+		NewStringMatchEvaluator(StandardOperationEndsWith, false),
+		NewStringMatchEvaluator(StandardOperationEndsWithIgnoreCase, true),
+	}
+}
+
+func NewStringMatchEvaluator(operation string, ignoreCase bool) *StringMatchEvaluator {
+	return &StringMatchEvaluator{
+		operation:  operation,
+		ignoreCase: ignoreCase,
+	}
+}
+
+func (e *StringMatchEvaluator) FreeArgType() string {
+	return StandardFreeArgTypeString
+}
+
+func (e *StringMatchEvaluator) Operation() string {
+	return e.operation
+}
+
+func (e *StringMatchEvaluator) Evaluate(condition *Condition, context map[string]string) bool {
+	freeArgValue, ok := context[condition.GetFreeArg().GetName()]
+	if !ok || len(freeArgValue) == 0 {
+		return false
+	}
+	if e.ignoreCase && !e.isRegex() {
+		freeArgValue = strings.ToLower(freeArgValue)
+	}
+	for _, value := range stringMatchValues(condition.GetFixedArg()) {
+		pattern := e.pattern(value)
+		if pattern.err == nil && e.match(pattern, freeArgValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate rejects a missing fixed arg and invalid regular expressions
+func (e *StringMatchEvaluator) Validate(fixedArg *FixedArg) error {
+	values := stringMatchValues(fixedArg)
+	if len(values) == 0 {
+		return fmt.Errorf("%v needs a string or a collection of strings", e.operation)
+	}
+	for _, value := range values {
+		if err := e.pattern(value).err; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *StringMatchEvaluator) isRegex() bool {
+	return e.operation == StandardOperationRegex || e.operation == StandardOperationRegexIgnoreCase
+}
+
+func (e *StringMatchEvaluator) match(pattern *stringPattern, value string) bool {
+	switch e.operation {
+	case StandardOperationRegex, StandardOperationRegexIgnoreCase:
+		return pattern.regex.MatchString(value)
+	case StandardOperationStartsWith, StandardOperationStartsWithIgnoreCase:
+		return strings.HasPrefix(value, pattern.affix)
+	case StandardOperationEndsWith, StandardOperationEndsWithIgnoreCase:
+		return strings.HasSuffix(value, pattern.affix)
+	}
+	return false
+}
+
+func (e *StringMatchEvaluator) pattern(value string) *stringPattern {
+	key := e.operation + "\x00" + value
+	if cached, ok := stringPatterns.Load(key); ok {
+		return cached.(*stringPattern)
+	}
+
+	pattern := &stringPattern{}
+	if e.isRegex() {
+		expr := value
+		if e.ignoreCase {
+			expr = "(?i)" + expr
+		}
+		pattern.regex, pattern.err = regexp.Compile(expr)
+		if pattern.err != nil {
+			pattern.err = fmt.Errorf("%v has an invalid pattern '%v': %v", e.operation, value, pattern.err)
+		}
+	} else {
+		if len(value) == 0 {
+			pattern.err = fmt.Errorf("%v has an empty pattern", e.operation)
+		}
+		pattern.affix = value
+		if e.ignoreCase {
+			pattern.affix = strings.ToLower(value)
+		}
+	}
+	cached, _ := stringPatterns.LoadOrStore(key, pattern)
+	return cached.(*stringPattern)
+}
+
+func stringMatchValues(fixedArg *FixedArg) []string {
+	switch value := fixedArg.GetValue().(type) {
+	case string:
+		return []string{value}
+	case []string:
+		return value
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package rulesengine
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestStringMatchEvaluators(t *testing.T) {
+	processor := NewRuleProcessor()
+	testCases := []struct {
+		rule     string
+		value    string
+		expected bool
+	}{
+		{`partnerId STARTS_WITH "comcast"`, "comcast-x1", true},
+		{`partnerId STARTS_WITH "comcast"`, "COMCAST-x1", false},
+		{`partnerId STARTS_WITH_IGNORE_CASE "comcast"`, "COMCAST-x1", true},
+		{`partnerId STARTS_WITH ["cox", "sky"]`, "sky-de", true},
+		{`partnerId STARTS_WITH ["cox", "sky"]`, "comcast", false},
+		{`serialNum ENDS_WITH "AB"`, "1234AB", true},
+		{`serialNum ENDS_WITH "AB"`, "1234ab", false},
+		{`serialNum ENDS_WITH_IGNORE_CASE ["xy", "AB"]`, "1234ab", true},
+		{`model REGEX "^TG16[0-9]{2}G$"`, "TG1682G", true},
+		{`model REGEX "^TG16[0-9]{2}G$"`, "TG1682GX", false},
+		{`model REGEX "^tg16"`, "TG1682G", false},
+		{`model REGEX_IGNORE_CASE "^tg16"`, "TG1682G", true},
+		{`model REGEX_IGNORE_CASE ["^px", "^TG1"]`, "tg1682g", true},
+		{`model REGEX "[invalid"`, "[invalid", false},
+		{`model STARTS_WITH "TG"`, "", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.rule+" "+tc.value, func(t *testing.T) {
+			rule, err := ParseRule(tc.rule)
+			assert.NilError(t, err)
+			context := map[string]string{"partnerId": tc.value, "serialNum": tc.value, "model": tc.value}
+			assert.Equal(t, tc.expected, processor.Evaluate(rule, context, nil))
+		})
+	}
+
+	rule, err := ParseRule(`NOT model STARTS_WITH "TG"`)
+	assert.NilError(t, err)
+	assert.Assert(t, processor.Evaluate(rule, map[string]string{}, nil))
+}
+
+func TestStringMatchEvaluatorValidate(t *testing.T) {
+	processor := NewRuleProcessor()
+	testCases := []struct {
+		rule  string
+		valid bool
+	}{
+		{`model REGEX "^TG16[0-9]{2}G$"`, true},
+		{`model REGEX "[invalid"`, false},
+		{`model REGEX_IGNORE_CASE ["^px", "(unclosed"]`, false},
+		{`partnerId STARTS_WITH "comcast"`, true},
+		{`partnerId STARTS_WITH ""`, false},
+		{`partnerId ENDS_WITH_IGNORE_CASE ["a", ""]`, false},
+		{`partnerId ENDS_WITH []`, false},
+		{`partnerId ENDS_WITH 12`, false},
+		{`model IS "X" AND NOT (env IS "QA" OR model REGEX "(")`, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.rule, func(t *testing.T) {
+			rule, err := ParseRule(tc.rule)
+			assert.NilError(t, err)
+			err = processor.Validate(rule)
+			assert.Equal(t, tc.valid, err == nil, "%v", err)
+		})
+	}
+
+	rule, err := ParseRule(`model REGEX "[invalid"`)
+	assert.NilError(t, err)
+	assert.ErrorContains(t, ValidateRule(rule), "REGEX has an invalid pattern '[invalid'")
+}
+
+func TestStringMatchEvaluatorPatternCache(t *testing.T) {
+	evaluator := NewStringMatchEvaluator(StandardOperationRegexIgnoreCase, true)
+	pattern := evaluator.pattern("^abc")
+	assert.Assert(t, pattern.regex != nil)
+	assert.Assert(t, pattern == evaluator.pattern("^abc"))
+
+	// the same fixed arg is prepared separately per operation
+	assert.Assert(t, pattern != NewStringMatchEvaluator(StandardOperationRegex, false).pattern("^abc"))
+	assert.Equal(t, "comcast", NewStringMatchEvaluator(StandardOperationStartsWithIgnoreCase, true).pattern("COMCAST").affix)
+}
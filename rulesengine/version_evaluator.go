@@ -0,0 +1,239 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package rulesengine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// versionModelFreeArg is the context key used to pick the VersionScheme of the device
+	versionModelFreeArg = "model"
+)
+
+var defaultVersionTokenRe = regexp.MustCompile(`[0-9]+|[A-Za-z]+`)
+
+// VersionScheme tells how versions of a model family are parsed. The capture groups of
+// Pattern, in order, are the components that are compared. A nil Pattern splits the version
+// into runs of digits and runs of letters, so "_VBN_2203_sprint_20220315224820sdy" becomes
+// VBN, 2203, sprint, 20220315224820, sdy.
+type VersionScheme struct {
+	Models  *regexp.Regexp
+	Pattern *regexp.Regexp
+}
+
+var (
+	versionSchemes      []VersionScheme
+	versionSchemesMutex sync.RWMutex
+)
+
+// SetVersionSchemes replaces the model specific version schemes, the first scheme whose
+// Models matches the model of the device is used
+func SetVersionSchemes(schemes []VersionScheme) {
+	versionSchemesMutex.Lock()
+	defer versionSchemesMutex.Unlock()
+	versionSchemes = schemes
+}
+
+// ParseVersionSchemes parses "<model regex>=<version regex>" pairs separated by ';'
+func ParseVersionSchemes(str string) ([]VersionScheme, error) {
+	schemes := []VersionScheme{}
+	for _, item := range strings.Split(str, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pair := strings.SplitN(item, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("version scheme '%v' is not in the format <model regex>=<version regex>", item)
+		}
+		models, err := regexp.Compile(pair[0])
+		if err != nil {
+			return nil, fmt.Errorf("version scheme '%v' has an invalid model regex: %v", item, err)
+		}
+		pattern, err := regexp.Compile(pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("version scheme '%v' has an invalid version regex: %v", item, err)
+		}
+		if pattern.NumSubexp() == 0 {
+			return nil, fmt.Errorf("version scheme '%v' has no capture group in the version regex", item)
+		}
+		schemes = append(schemes, VersionScheme{Models: models, Pattern: pattern})
+	}
+	return schemes, nil
+}
+
+// GetVersionScheme returns the scheme for the model, or the default scheme
+func GetVersionScheme(model string) VersionScheme {
+	versionSchemesMutex.RLock()
+	defer versionSchemesMutex.RUnlock()
+	for _, scheme := range versionSchemes {
+		if scheme.Models != nil && scheme.Models.MatchString(model) {
+			return scheme
+		}
+	}
+	return VersionScheme{}
+}
+
+// Parse splits a version into its components, returning false when the version does not fit the scheme
+func (s VersionScheme) Parse(version string) ([]string, bool) {
+	if s.Pattern == nil {
+		tokens := defaultVersionTokenRe.FindAllString(version, -1)
+		return tokens, len(tokens) > 0
+	}
+	groups := s.Pattern.FindStringSubmatch(version)
+	if groups == nil {
+		return nil, false
+	}
+	return groups[1:], true
+}
+
+// Compare compares two versions and returns -1, 0 or 1
+func (s VersionScheme) Compare(version1 string, version2 string) (int, error) {
+	tokens1, ok := s.Parse(version1)
+	if !ok {
+		return 0, fmt.Errorf("unable to parse version '%v'", version1)
+	}
+	tokens2, ok := s.Parse(version2)
+	if !ok {
+		return 0, fmt.Errorf("unable to parse version '%v'", version2)
+	}
+	return compareVersionTokens(tokens1, tokens2), nil
+}
+
+// compareVersionTokens compares numbers numerically and everything else case-insensitively,
+// numbers come before words and a version sorts before any longer version it is a prefix of
+func compareVersionTokens(tokens1 []string, tokens2 []string) int {
+	for i := 0; i < len(tokens1) && i < len(tokens2); i++ {
+		if c := compareVersionToken(tokens1[i], tokens2[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(tokens1) < len(tokens2):
+		return -1
+	case len(tokens1) > len(tokens2):
+		return 1
+	}
+	return 0
+}
+
+func compareVersionToken(token1 string, token2 string) int {
+	isNum1 := isVersionNumber(token1)
+	isNum2 := isVersionNumber(token2)
+	switch {
+	case isNum1 && isNum2:
+		// compare by length first so numbers of any size work
+		token1 = strings.TrimLeft(token1, "0")
+		token2 = strings.TrimLeft(token2, "0")
+		if len(token1) != len(token2) {
+			if len(token1) < len(token2) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(token1, token2)
+	case isNum1:
+		return -1
+	case isNum2:
+		return 1
+	}
+	return strings.Compare(strings.ToLower(token1), strings.ToLower(token2))
+}
+
+func isVersionNumber(token string) bool {
+	if len(token) == 0 {
+		return false
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// VersionEvaluator compares version free args with the VersionScheme of the device model
+type VersionEvaluator struct {
+	operation  string
+	evaluation FnEvaluation
+}
+
+func GetVersionEvaluators() []IConditionEvaluator {
+	return []IConditionEvaluator{
+		NewVersionEvaluator(StandardOperationGt, func(i int) bool { return i > 0 }),
+		NewVersionEvaluator(StandardOperationGte, func(i int) bool { return i >= 0 }),
+		NewVersionEvaluator(StandardOperationLt, func(i int) bool { return i < 0 }),
+		NewVersionEvaluator(StandardOperationLte, func(i int) bool { return i <= 0 }),
+		NewVersionEvaluator(StandardOperationRange, nil),
+	}
+}
+
+func NewVersionEvaluator(operation string, fn FnEvaluation) *VersionEvaluator {
+	return &VersionEvaluator{
+		operation:  operation,
+		evaluation: fn,
+	}
+}
+
+func (e *VersionEvaluator) FreeArgType() string {
+	return AuxFreeArgTypeVersion
+}
+
+func (e *VersionEvaluator) Operation() string {
+	return e.operation
+}
+
+// Evaluate compares the free arg with a version, or for RANGE checks min <= version < max
+// where the fixed arg is the collection [min, max]
+func (e *VersionEvaluator) Evaluate(condition *Condition, context map[string]string) bool {
+	freeArgValue, ok := context[condition.GetFreeArg().GetName()]
+	if !ok || len(freeArgValue) == 0 {
+		return false
+	}
+	scheme := GetVersionScheme(context[versionModelFreeArg])
+
+	if e.operation == StandardOperationRange {
+		bounds, ok := condition.GetFixedArg().GetValue().([]string)
+		if !ok || len(bounds) != 2 {
+			return false
+		}
+		lower, err := scheme.Compare(freeArgValue, bounds[0])
+		if err != nil {
+			return false
+		}
+		upper, err := scheme.Compare(freeArgValue, bounds[1])
+		if err != nil {
+			return false
+		}
+		return lower >= 0 && upper < 0
+	}
+
+	fixedArgValue, ok := condition.GetFixedArg().GetValue().(string)
+	if !ok {
+		return false
+	}
+	result, err := scheme.Compare(freeArgValue, fixedArgValue)
+	if err != nil {
+		return false
+	}
+	return e.evaluation(result)
+}
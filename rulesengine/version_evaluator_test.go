@@ -0,0 +1,110 @@
+package rulesengine
+
+import (
+	"regexp"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestVersionSchemeCompareDefault(t *testing.T) {
+	scheme := VersionScheme{}
+	testCases := []struct {
+		v1       string
+		v2       string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"1.2", "1.2.1", -1},
+		{"2.0", "1.99.99", 1},
+		{"01.002", "1.2", 0},
+		{"_VBN_2203_sprint_20220315224820sdy", "_VBN_2112_sprint_20211215224820sdy", 1},
+		{"_VBN_2203_sprint_20220315224820sdy", "_VBN_2203_sprint_20220401000000sdy", -1},
+		{"TG1682_3.14p1s1_PROD_sey", "TG1682_3.14p1s1_prod_sey", 0},
+		{"1.0.1", "1.0.a", -1},
+		{"99999999999999999999999", "100000000000000000000000", -1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.v1+"_"+tc.v2, func(t *testing.T) {
+			result, err := scheme.Compare(tc.v1, tc.v2)
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expected, result)
+			result, err = scheme.Compare(tc.v2, tc.v1)
+			assert.NilError(t, err)
+			assert.Equal(t, -tc.expected, result)
+		})
+	}
+
+	_, err := scheme.Compare("...", "1.0")
+	assert.Assert(t, err != nil)
+}
+
+func TestParseVersionSchemes(t *testing.T) {
+	schemes, err := ParseVersionSchemes(`^(PX|SX)=_(\d{4})_sprint_(\d{14}); ^TG=^TG\d+_(\d+)\.(\d+)p(\d+)s(\d+)`)
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(schemes))
+	assert.Assert(t, schemes[0].Models.MatchString("PX051AEI"))
+	assert.Assert(t, schemes[1].Models.MatchString("TG1682G"))
+
+	schemes, err = ParseVersionSchemes("")
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(schemes))
+
+	for _, str := range []string{"^PX", "^(PX=_(\\d+)", "^PX=(\\d+", "^PX=\\d+"} {
+		_, err = ParseVersionSchemes(str)
+		assert.Assert(t, err != nil, str)
+	}
+}
+
+func TestVersionSchemePerModel(t *testing.T) {
+	defer SetVersionSchemes(nil)
+	SetVersionSchemes([]VersionScheme{
+		{Models: regexp.MustCompile("^PX"), Pattern: regexp.MustCompile(`_(\d{4})_sprint_(\d{14})`)},
+	})
+
+	// the vendor prefix is not part of the version for PX models
+	scheme := GetVersionScheme("PX051AEI")
+	result, err := scheme.Compare("ARRIS_VBN_2203_sprint_20220315224820sdy", "PACE_VBN_2112_sprint_20220315224820sdy")
+	assert.NilError(t, err)
+	assert.Equal(t, 1, result)
+	_, err = scheme.Compare("1.2.3", "1.2.4")
+	assert.Assert(t, err != nil)
+
+	scheme = GetVersionScheme("TG1682G")
+	assert.Assert(t, scheme.Pattern == nil)
+	result, err = scheme.Compare("ARRIS_VBN_2203_sprint_20220315224820sdy", "PACE_VBN_2112_sprint_20220315224820sdy")
+	assert.NilError(t, err)
+	assert.Equal(t, -1, result)
+}
+
+func TestVersionEvaluators(t *testing.T) {
+	processor := NewRuleProcessor()
+	context := map[string]string{
+		"model":           "X1",
+		"firmwareVersion": "_VBN_2203_sprint_20220315224820sdy",
+	}
+	testCases := []struct {
+		text     string
+		expected bool
+	}{
+		{`firmwareVersion:VERSION LT "_VBN_2204_sprint_20220101000000sdy"`, true},
+		{`firmwareVersion:VERSION LTE "_VBN_2203_sprint_20220315224820sdy"`, true},
+		{`firmwareVersion:VERSION GT "_VBN_2203_sprint_20220315224820sdy"`, false},
+		{`firmwareVersion:VERSION GTE "_VBN_2112_sprint_20211215224820sdy"`, true},
+		{`firmwareVersion:VERSION RANGE ["_VBN_2201_sprint_0", "_VBN_2204_sprint_0"]`, true},
+		{`firmwareVersion:VERSION RANGE ["_VBN_2203_sprint_20220315224820sdy", "_VBN_2204_sprint_0"]`, true},
+		{`firmwareVersion:VERSION RANGE ["_VBN_2201_sprint_0", "_VBN_2203_sprint_20220315224820sdy"]`, false},
+		{`firmwareVersion:VERSION RANGE ["_VBN_2201_sprint_0"]`, false},
+		{`firmwareVersion:VERSION LT 2204`, false},
+		{`missingVersion:VERSION LT "_VBN_2204_sprint_20220101000000sdy"`, false},
+		{`NOT firmwareVersion:VERSION LT "_VBN_2203_sprint_20220101000000sdy"`, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			rule, err := ParseRule(tc.text)
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expected, processor.Evaluate(rule, context, nil))
+		})
+	}
+}
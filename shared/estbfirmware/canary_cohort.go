@@ -0,0 +1,196 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/db"
+)
+
+// CanaryDevice CanaryCohort table, a device selected to get the target firmware of its cohort ahead of the rollout
+type CanaryDevice struct {
+	ID              string `json:"id"` // estb mac
+	CohortId        string `json:"cohortId"`
+	RuleId          string `json:"ruleId"`
+	ConfigId        string `json:"configId"`
+	Model           string `json:"model"`
+	PartnerId       string `json:"partnerId,omitempty"`
+	FirmwareVersion string `json:"firmwareVersion,omitempty"` // version the device was running when selected
+	Updated         int64  `json:"updated"`
+}
+
+// NewCanaryDeviceInf constructor
+func NewCanaryDeviceInf() interface{} {
+	return &CanaryDevice{}
+}
+
+// CanaryCohortId returns the row key of the cohort of a target config, model and partner
+func CanaryCohortId(configId string, model string, partnerId string) string {
+	return fmt.Sprintf("%s_%s_%s", configId, strings.ToUpper(model), strings.ToUpper(partnerId))
+}
+
+// CanaryCohortDao reads and writes the canary cohorts
+type CanaryCohortDao interface {
+	GetCanaryDevice(cohortId string, mac string) (*CanaryDevice, error)
+	GetCanaryCohort(cohortId string) ([]*CanaryDevice, error)
+	SetCanaryDevice(device *CanaryDevice) error
+}
+
+type canaryCohortDaoImpl struct{}
+
+var canaryCohortDao CanaryCohortDao = canaryCohortDaoImpl{}
+
+// GetCanaryCohortDao returns the CanaryCohortDao backed by the CanaryCohort table
+func GetCanaryCohortDao() CanaryCohortDao {
+	return canaryCohortDao
+}
+
+func (d canaryCohortDaoImpl) GetCanaryDevice(cohortId string, mac string) (*CanaryDevice, error) {
+	inst, err := db.GetListingDao().GetOne(db.TABLE_CANARY_COHORT, cohortId, mac)
+	if err != nil {
+		return nil, err
+	}
+	device, ok := inst.(*CanaryDevice)
+	if !ok {
+		return nil, fmt.Errorf("unexpected canary device %T in cohort %s", inst, cohortId)
+	}
+	return device, nil
+}
+
+// GetCanaryCohort returns the devices of the cohort sorted by the time they were selected
+func (d canaryCohortDaoImpl) GetCanaryCohort(cohortId string) ([]*CanaryDevice, error) {
+	list, err := db.GetListingDao().GetAll(db.TABLE_CANARY_COHORT, cohortId)
+	if err != nil {
+		return nil, err
+	}
+	devices := []*CanaryDevice{}
+	for _, inst := range list {
+		if device, ok := inst.(*CanaryDevice); ok {
+			devices = append(devices, device)
+		}
+	}
+	sort.SliceStable(devices, func(i, j int) bool {
+		return devices[i].Updated < devices[j].Updated
+	})
+	return devices, nil
+}
+
+func (d canaryCohortDaoImpl) SetCanaryDevice(device *CanaryDevice) error {
+	jsonData, err := json.Marshal(device)
+	if err != nil {
+		return err
+	}
+	return db.GetListingDao().SetOne(db.TABLE_CANARY_COHORT, device.CohortId, device.ID, jsonData)
+}
+
+type readOnlyCanaryCohortDao struct {
+	CanaryCohortDao
+}
+
+// NewReadOnlyCanaryCohortDao returns a CanaryCohortDao that reads from dao and drops the devices it is asked to add
+func NewReadOnlyCanaryCohortDao(dao CanaryCohortDao) CanaryCohortDao {
+	return readOnlyCanaryCohortDao{CanaryCohortDao: dao}
+}
+
+func (d readOnlyCanaryCohortDao) SetCanaryDevice(device *CanaryDevice) error {
+	return nil
+}
+
+type cachedCanaryCohort struct {
+	devices []*CanaryDevice
+	expires time.Time
+}
+
+type cachedCanaryCohortDao struct {
+	dao     CanaryCohortDao
+	ttl     time.Duration
+	mutex   sync.Mutex
+	cohorts map[string]*cachedCanaryCohort
+}
+
+// NewCachedCanaryCohortDao returns a CanaryCohortDao that reads each cohort from dao once per ttl and answers
+// the device lookups from it. The devices added by other instances are seen once the cohort is read again
+func NewCachedCanaryCohortDao(dao CanaryCohortDao, ttl time.Duration) CanaryCohortDao {
+	return &cachedCanaryCohortDao{
+		dao:     dao,
+		ttl:     ttl,
+		cohorts: map[string]*cachedCanaryCohort{},
+	}
+}
+
+func (d *cachedCanaryCohortDao) GetCanaryDevice(cohortId string, mac string) (*CanaryDevice, error) {
+	devices, err := d.GetCanaryCohort(cohortId)
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		if device.ID == mac {
+			return device, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not in canary cohort %s", mac, cohortId)
+}
+
+// GetCanaryCohort returns the cached devices of the cohort, the slice must not be changed
+func (d *cachedCanaryCohortDao) GetCanaryCohort(cohortId string) ([]*CanaryDevice, error) {
+	now := time.Now()
+	if devices, ok := d.getCachedCohort(cohortId, now); ok {
+		return devices, nil
+	}
+	devices, err := d.dao.GetCanaryCohort(cohortId)
+	if err != nil {
+		return nil, err
+	}
+	d.mutex.Lock()
+	d.cohorts[cohortId] = &cachedCanaryCohort{devices: devices, expires: now.Add(d.ttl)}
+	d.mutex.Unlock()
+	return devices, nil
+}
+
+func (d *cachedCanaryCohortDao) getCachedCohort(cohortId string, now time.Time) ([]*CanaryDevice, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	cached, ok := d.cohorts[cohortId]
+	if !ok || !now.Before(cached.expires) {
+		return nil, false
+	}
+	return cached.devices, true
+}
+
+// SetCanaryDevice adds the device to the cached cohort too. A device another instance already added is kept as it is
+func (d *cachedCanaryCohortDao) SetCanaryDevice(device *CanaryDevice) error {
+	if stored, err := d.dao.GetCanaryDevice(device.CohortId, device.ID); err == nil && stored != nil {
+		*device = *stored
+	} else if err := d.dao.SetCanaryDevice(device); err != nil {
+		return err
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if cached, ok := d.cohorts[device.CohortId]; ok {
+		devices := make([]*CanaryDevice, len(cached.devices), len(cached.devices)+1)
+		copy(devices, cached.devices)
+		cached.devices = append(devices, device)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+type countingCanaryCohortDao struct {
+	devices map[string]*CanaryDevice
+	reads   int
+}
+
+func (d *countingCanaryCohortDao) GetCanaryDevice(cohortId string, mac string) (*CanaryDevice, error) {
+	if device, ok := d.devices[mac]; ok {
+		return device, nil
+	}
+	return nil, fmt.Errorf("%s not found", mac)
+}
+
+func (d *countingCanaryCohortDao) GetCanaryCohort(cohortId string) ([]*CanaryDevice, error) {
+	d.reads++
+	devices := []*CanaryDevice{}
+	for _, device := range d.devices {
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+func (d *countingCanaryCohortDao) SetCanaryDevice(device *CanaryDevice) error {
+	d.devices[device.ID] = device
+	return nil
+}
+
+func TestCachedCanaryCohortDao(t *testing.T) {
+	cohortId := CanaryCohortId("target", "X1", "comcast")
+	stored := &countingCanaryCohortDao{devices: map[string]*CanaryDevice{
+		"AA:AA:AA:AA:AA:AA": {ID: "AA:AA:AA:AA:AA:AA", CohortId: cohortId, Updated: 1},
+	}}
+	dao := NewCachedCanaryCohortDao(stored, time.Minute)
+
+	// the cohort is read once for the lookups and the size
+	device, err := dao.GetCanaryDevice(cohortId, "AA:AA:AA:AA:AA:AA")
+	assert.NilError(t, err)
+	assert.Equal(t, device.Updated, int64(1))
+	_, err = dao.GetCanaryDevice(cohortId, "BB:BB:BB:BB:BB:BB")
+	assert.Assert(t, err != nil)
+	cohort, err := dao.GetCanaryCohort(cohortId)
+	assert.NilError(t, err)
+	assert.Equal(t, len(cohort), 1)
+	assert.Equal(t, stored.reads, 1)
+
+	// an added device is in the cached cohort, a device added by another instance is kept
+	assert.NilError(t, dao.SetCanaryDevice(&CanaryDevice{ID: "BB:BB:BB:BB:BB:BB", CohortId: cohortId, Updated: 2}))
+	stored.devices["CC:CC:CC:CC:CC:CC"] = &CanaryDevice{ID: "CC:CC:CC:CC:CC:CC", CohortId: cohortId, Updated: 3}
+	other := &CanaryDevice{ID: "CC:CC:CC:CC:CC:CC", CohortId: cohortId, Updated: 4}
+	assert.NilError(t, dao.SetCanaryDevice(other))
+	assert.Equal(t, other.Updated, int64(3))
+	assert.Equal(t, stored.devices["CC:CC:CC:CC:CC:CC"].Updated, int64(3))
+	cohort, _ = dao.GetCanaryCohort(cohortId)
+	assert.Equal(t, len(cohort), 3)
+	assert.Equal(t, stored.reads, 1)
+
+	// an expired cohort is read again
+	dao = NewCachedCanaryCohortDao(stored, 0)
+	dao.GetCanaryCohort(cohortId)
+	dao.GetCanaryCohort(cohortId)
+	assert.Equal(t, stored.reads, 3)
+}
@@ -211,6 +211,26 @@ func GetDownloadLocationRoundRobinFilterValOneDB(filterId string) (*DownloadLoca
 	return nil, fmt.Errorf("DownloadLocationRoundRobinFilterValue not found for %v", filterId)
 }
 
+// GetDownloadLocationRoundRobinFilterValAllDB returns the round robin filter values of all application types
+func GetDownloadLocationRoundRobinFilterValAllDB() ([]*DownloadLocationRoundRobinFilterValue, error) {
+	list, err := db.GetCachedSimpleDao().GetAllAsList(db.TABLE_SINGLETON_FILTER_VALUE, 0)
+	if err != nil {
+		return nil, err
+	}
+	filterValues := []*DownloadLocationRoundRobinFilterValue{}
+	for _, inst := range list {
+		switch v := inst.(type) {
+		case *DownloadLocationRoundRobinFilterValue:
+			filterValues = append(filterValues, v)
+		case *SingletonFilterValue:
+			if v.IsDownloadLocationRoundRobinFilterValue() {
+				filterValues = append(filterValues, v.DownloadLocationRoundRobinFilterValue)
+			}
+		}
+	}
+	return filterValues, nil
+}
+
 func GetDefaultDownloadLocationRoundRobinFilterValOneDB() (*DownloadLocationRoundRobinFilterValue, error) {
 	return GetDownloadLocationRoundRobinFilterValOneDB(ROUND_ROBIN_FILTER_SINGLETON_ID)
 }
@@ -106,7 +106,8 @@ func makeIpAddressGroup(id string) *shared.IpAddressGroup {
 // isLegacyIpCondition ...
 func IsLegacyIpCondition(condition re.Condition) bool {
 	if IsLegacyIpFreeArg(condition.GetFreeArg()) && re.StandardOperationIn == condition.GetOperation() {
-		if _, ok := condition.GetFixedArg().GetValue().(shared.IpAddressGroup); ok {
+		switch condition.GetFixedArg().GetValue().(type) {
+		case shared.IpAddressGroup:
 			return true
 		}
 	}
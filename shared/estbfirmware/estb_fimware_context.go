@@ -269,6 +269,10 @@ func (c *ConvertedContext) CreateCapabilitiesList() []Capabilities {
 			capList = append(capList, RebootDecoupled)
 		case "SUPPORTSFULLHTTPURL":
 			capList = append(capList, SupportsFullHttpUrl)
+		case "SUPPORTSFIRMWAREINTEGRITY":
+			capList = append(capList, SupportsFirmwareIntegrity)
+		case "SUPPORTSDELTAFIRMWARE":
+			capList = append(capList, SupportsDeltaFirmware)
 		default:
 			log.Debug(fmt.Sprintf("Unknown capability will be ignored: %s", strCap))
 		}
@@ -300,6 +304,14 @@ func (c *ConvertedContext) IsSupportsFullHttpUrl() bool {
 	return c.isThisCap(SupportsFullHttpUrl)
 }
 
+func (c *ConvertedContext) IsSupportsFirmwareIntegrity() bool {
+	return c.isThisCap(SupportsFirmwareIntegrity)
+}
+
+func (c *ConvertedContext) IsSupportsDeltaFirmware() bool {
+	return c.isThisCap(SupportsDeltaFirmware)
+}
+
 func (c *ConvertedContext) GetEnvConverted() string {
 	return c.Env
 }
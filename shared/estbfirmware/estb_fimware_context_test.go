@@ -66,6 +66,22 @@ func TestContextConvertedGet(t *testing.T) {
 	assert.Equal(t, len(caps), 3)
 }
 
+func TestContextConvertedSupportsFirmwareIntegrity(t *testing.T) {
+	convertCtx := GetContextConverted(map[string]string{common.CAPABILITIES: "RCDL"})
+	assert.Assert(t, !convertCtx.IsSupportsFirmwareIntegrity())
+
+	convertCtx = GetContextConverted(map[string]string{common.CAPABILITIES: "RCDL, supportsFirmwareIntegrity"})
+	assert.Assert(t, convertCtx.IsSupportsFirmwareIntegrity())
+}
+
+func TestContextConvertedSupportsDeltaFirmware(t *testing.T) {
+	convertCtx := GetContextConverted(map[string]string{common.CAPABILITIES: "RCDL, supportsFirmwareIntegrity"})
+	assert.Assert(t, !convertCtx.IsSupportsDeltaFirmware())
+
+	convertCtx = GetContextConverted(map[string]string{common.CAPABILITIES: "supportsDeltaFirmware"})
+	assert.Assert(t, convertCtx.IsSupportsDeltaFirmware())
+}
+
 func TestGetTime(t *testing.T) {
 	contextMap := map[string]string{}
 	contextMap["time"] = "04/15/2021 00:01:43"
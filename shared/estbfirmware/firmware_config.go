@@ -19,6 +19,9 @@ package estbfirmware
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -79,6 +82,16 @@ const (
 	 * domain name.
 	 */
 	SupportsFullHttpUrl = "supportsFullHttpUrl"
+	/**
+	 * Lets Xconf know that the STB verifies the firmware image with the digest, size and signature
+	 * of the firmware config, they are only returned to STBs with this capability.
+	 */
+	SupportsFirmwareIntegrity = "supportsFirmwareIntegrity"
+	/**
+	 * Lets Xconf know that the STB can apply a delta image to its running firmware, the delta of
+	 * its version is returned with the full image as the fallback.
+	 */
+	SupportsDeltaFirmware = "supportsDeltaFirmware"
 )
 
 type Expression struct {
@@ -120,9 +133,24 @@ type FirmwareConfig struct {
 	UpgradeDelay             int64             `json:"upgradeDelay,omitempty"`
 	RebootImmediately        bool              `json:"rebootImmediately"`
 	MandatoryUpdate          bool              `json:"mandatoryUpdate,omitempty"`
+	FirmwareSha256           string            `json:"firmwareSha256,omitempty"`    // hex SHA-256 digest of the image
+	FirmwareSize             int64             `json:"firmwareSize,omitempty"`      // image size in bytes
+	FirmwareSignature        string            `json:"firmwareSignature,omitempty"` // base64 signature of the image
+	DeltaImages              DeltaImages       `json:"deltaImages,omitempty"`
 	Properties               map[string]string `json:"properties,omitempty"`
 }
 
+// DeltaImage is a delta from a source version to the image of a firmware config
+type DeltaImage struct {
+	Filename string `json:"filename"`
+	Location string `json:"location,omitempty"` // the location of the full image when empty
+	Sha256   string `json:"sha256"`             // hex SHA-256 digest of the delta
+	Size     int64  `json:"size,omitempty"`
+}
+
+// DeltaImages are the delta images of a firmware config keyed by source version
+type DeltaImages map[string]*DeltaImage
+
 func (obj *FirmwareConfig) SetApplicationType(appType string) {
 	obj.ApplicationType = appType
 }
@@ -196,6 +224,24 @@ func (obj *FirmwareConfig) Validate() error {
 	if util.IsBlank(obj.FirmwareVersion) {
 		return errors.New("Version is empty")
 	}
+	if obj.FirmwareSha256 != "" {
+		if digest, err := hex.DecodeString(obj.FirmwareSha256); err != nil || len(digest) != sha256.Size {
+			return errors.New("FirmwareSha256 must be a hex SHA-256 digest")
+		}
+	}
+	if obj.FirmwareSize < 0 {
+		return errors.New("FirmwareSize must not be negative")
+	}
+	if obj.FirmwareSignature != "" {
+		if _, err := base64.StdEncoding.DecodeString(obj.FirmwareSignature); err != nil {
+			return errors.New("FirmwareSignature must be base64 encoded")
+		}
+	}
+	for version, delta := range obj.DeltaImages {
+		if err := delta.validate(version, obj.FirmwareVersion); err != nil {
+			return err
+		}
+	}
 	if len(obj.SupportedModelIds) == 0 {
 		return errors.New("Supported model list is empty")
 	}
@@ -252,6 +298,41 @@ func (obj *FirmwareConfig) ValidateName() error {
 	return nil
 }
 
+func (d *DeltaImage) validate(version string, firmwareVersion string) error {
+	if util.IsBlank(version) || d == nil {
+		return errors.New("Delta image needs a source version and an image")
+	}
+	if strings.EqualFold(version, firmwareVersion) {
+		return fmt.Errorf("Delta image source version %s is the firmware version", version)
+	}
+	if util.IsBlank(d.Filename) {
+		return fmt.Errorf("Delta image file name for %s is empty", version)
+	}
+	if digest, err := hex.DecodeString(d.Sha256); err != nil || len(digest) != sha256.Size {
+		return fmt.Errorf("Delta image digest for %s must be a hex SHA-256 digest", version)
+	}
+	if d.Size < 0 {
+		return fmt.Errorf("Delta image size for %s must not be negative", version)
+	}
+	return nil
+}
+
+// GetDeltaImage returns the delta from version to the image of the config, versions are compared ignoring case
+func (fc *FirmwareConfig) GetDeltaImage(version string) *DeltaImage {
+	if version == "" || strings.EqualFold(version, fc.FirmwareVersion) {
+		return nil
+	}
+	if delta, ok := fc.DeltaImages[version]; ok {
+		return delta
+	}
+	for source, delta := range fc.DeltaImages {
+		if strings.EqualFold(source, version) {
+			return delta
+		}
+	}
+	return nil
+}
+
 type FirmwareConfigFacadeResponse map[string]interface{}
 
 func CreateFirmwareConfigFacadeResponse(firmwareConfigFacade FirmwareConfigFacade) FirmwareConfigFacadeResponse {
@@ -314,7 +395,17 @@ func NewFirmwareConfigFromMap(dataMap map[string]interface{}) *FirmwareConfig {
 		case common.UPDATED:
 			fc.Updated = int64(v.(float64))
 		case common.UPGRADE_DELAY:
-			fc.UpgradeDelay = v.(int64)
+			if delay, ok := toInt64(v); ok {
+				fc.UpgradeDelay = delay
+			} else {
+				log.Error(fmt.Sprintf("FirmwareConfigFacade.UnmarshalJSON failed for property %s:%v", k, v))
+			}
+		case common.FIRMWARE_SHA256:
+			fc.FirmwareSha256 = v.(string)
+		case common.FIRMWARE_SIZE:
+			fc.FirmwareSize = int64(v.(float64))
+		case common.FIRMWARE_SIGNATURE:
+			fc.FirmwareSignature = v.(string)
 		case common.REBOOT_IMMEDIATELY:
 			b, ok := v.(bool)
 			if ok {
@@ -355,6 +446,22 @@ func NewFirmwareConfigFromMap(dataMap map[string]interface{}) *FirmwareConfig {
 	return &fc
 }
 
+// toInt64 reads a number decoded from JSON, which is a float64 unless the decoder uses json.Number
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
 type FirmwareConfigResponse struct {
 	ID                string            `json:"id"`
 	Description       string            `json:"description,omitempty"`
@@ -401,6 +508,11 @@ func (fc *FirmwareConfig) ToPropertiesMap() map[string]interface{} {
 	util.PutIfValuePresent(dataMap, common.UPGRADE_DELAY, fc.UpgradeDelay)
 	util.PutIfValuePresent(dataMap, common.REBOOT_IMMEDIATELY, fc.RebootImmediately)
 	util.PutIfValuePresent(dataMap, common.MANDATORY_UPDATE, fc.MandatoryUpdate)
+	util.PutIfValuePresent(dataMap, common.FIRMWARE_SHA256, fc.FirmwareSha256)
+	if fc.FirmwareSize > 0 {
+		dataMap[common.FIRMWARE_SIZE] = fc.FirmwareSize
+	}
+	util.PutIfValuePresent(dataMap, common.FIRMWARE_SIGNATURE, fc.FirmwareSignature)
 
 	return dataMap
 }
@@ -440,6 +552,15 @@ func (fcf *FirmwareConfigFacade) MarshalJSON() ([]byte, error) {
 		common.SUPPORTED_MODEL_IDS,
 		common.MANDATORY_UPDATE,
 		common.UPDATED,
+		common.FIRMWARE_SHA256,
+		common.FIRMWARE_SIZE,
+		common.FIRMWARE_SIGNATURE,
+		common.MAINTENANCE_WINDOW_START,
+		common.DELTA_FROM_VERSION,
+		common.DELTA_FIRMWARE_FILENAME,
+		common.DELTA_FIRMWARE_LOCATION,
+		common.DELTA_FIRMWARE_SHA256,
+		common.DELTA_FIRMWARE_SIZE,
 	}
 
 	buffer := bytes.NewBufferString("{")
@@ -481,6 +602,17 @@ func (fcf *FirmwareConfigFacade) UnmarshalJSON(bytes []byte) error {
 	fc := NewFirmwareConfigFromMap(dataMap)
 	fcf.Properties = fc.ToPropertiesMap()
 
+	// the maintenance window and delta image are set on the facade only, not on the firmware config
+	for _, key := range []string{common.MAINTENANCE_WINDOW_START, common.DELTA_FROM_VERSION, common.DELTA_FIRMWARE_FILENAME,
+		common.DELTA_FIRMWARE_LOCATION, common.DELTA_FIRMWARE_SHA256} {
+		if value, ok := dataMap[key].(string); ok && value != "" {
+			fcf.Properties[key] = value
+		}
+	}
+	if size, ok := toInt64(dataMap[common.DELTA_FIRMWARE_SIZE]); ok && size > 0 {
+		fcf.Properties[common.DELTA_FIRMWARE_SIZE] = size
+	}
+
 	return nil
 }
 
@@ -585,6 +717,29 @@ func (ff *FirmwareConfigFacade) PutIfPresent(key string, value interface{}) {
 	ff.Properties[key] = vstr
 }
 
+// RemoveIntegrityProperties removes the digest, size and signature of the firmware image
+func (ff *FirmwareConfigFacade) RemoveIntegrityProperties() {
+	delete(ff.Properties, common.FIRMWARE_SHA256)
+	delete(ff.Properties, common.FIRMWARE_SIZE)
+	delete(ff.Properties, common.FIRMWARE_SIGNATURE)
+}
+
+// SetDeltaImage adds the delta from version to the response, its location defaults to the location of the full image
+// which stays the fallback
+func (ff *FirmwareConfigFacade) SetDeltaImage(version string, delta *DeltaImage) {
+	location := delta.Location
+	if location == "" {
+		location = ff.GetFirmwareLocation()
+	}
+	ff.Properties[common.DELTA_FROM_VERSION] = version
+	ff.Properties[common.DELTA_FIRMWARE_FILENAME] = delta.Filename
+	ff.PutIfPresent(common.DELTA_FIRMWARE_LOCATION, location)
+	ff.Properties[common.DELTA_FIRMWARE_SHA256] = delta.Sha256
+	if delta.Size > 0 {
+		ff.Properties[common.DELTA_FIRMWARE_SIZE] = delta.Size
+	}
+}
+
 // GetFirmwareDownloadProtocol ...
 func (ff *FirmwareConfigFacade) GetFirmwareDownloadProtocol() string {
 	return ff.GetStringValue(common.FIRMWARE_DOWNLOAD_PROTOCOL)
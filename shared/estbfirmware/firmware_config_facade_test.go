@@ -241,3 +241,53 @@ func TestMinimumFirmwareCheckBean_Creation(t *testing.T) {
 	
 	assert.Assert(t, !bean2.HasMinimumFirmware)
 }
+
+func TestFirmwareConfigFacade_IntegrityProperties(t *testing.T) {
+	config := &FirmwareConfig{
+		FirmwareFilename:  "firmware.bin",
+		FirmwareVersion:   "1.0.0",
+		FirmwareSha256:    "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		FirmwareSize:      1024,
+		FirmwareSignature: "c2lnbmF0dXJl",
+	}
+	facade := NewFirmwareConfigFacade(config)
+	data, err := json.Marshal(facade)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), `{"firmwareFilename":"firmware.bin","firmwareVersion":"1.0.0","rebootImmediately":false,"mandatoryUpdate":false,"updated":0,"firmwareSha256":"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855","firmwareSize":1024,"firmwareSignature":"c2lnbmF0dXJl"}`)
+
+	unmarshalled := &FirmwareConfigFacade{}
+	assert.NilError(t, json.Unmarshal(data, unmarshalled))
+	assert.Equal(t, unmarshalled.Properties["firmwareSize"], int64(1024))
+
+	facade.RemoveIntegrityProperties()
+	response := CreateFirmwareConfigFacadeResponse(*facade)
+	_, ok := response["firmwareSha256"]
+	assert.Assert(t, !ok)
+	_, ok = response["firmwareSize"]
+	assert.Assert(t, !ok)
+	_, ok = response["firmwareSignature"]
+	assert.Assert(t, !ok)
+}
+
+func TestFirmwareConfigFacade_SetDeltaImage(t *testing.T) {
+	config := &FirmwareConfig{
+		FirmwareFilename: "firmware.bin",
+		FirmwareVersion:  "2.0.0",
+		FirmwareLocation: "http://cdn.example.com/full",
+	}
+	facade := NewFirmwareConfigFacade(config)
+	facade.SetDeltaImage("1.0.0", &DeltaImage{Filename: "delta.bin", Sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", Size: 512})
+	data, err := json.Marshal(facade)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), `{"firmwareFilename":"firmware.bin","firmwareLocation":"http://cdn.example.com/full","firmwareVersion":"2.0.0","rebootImmediately":false,"mandatoryUpdate":false,"updated":0,"deltaFromVersion":"1.0.0","deltaFirmwareFilename":"delta.bin","deltaFirmwareLocation":"http://cdn.example.com/full","deltaFirmwareSha256":"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855","deltaFirmwareSize":512}`)
+
+	// a delta with its own location, the full image stays the fallback
+	facade = NewFirmwareConfigFacade(config)
+	facade.SetDeltaImage("1.0.0", &DeltaImage{Filename: "delta.bin", Location: "http://delta.example.com", Sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"})
+	response := CreateFirmwareConfigFacadeResponse(*facade)
+	assert.Equal(t, response["deltaFirmwareLocation"], "http://delta.example.com")
+	assert.Equal(t, response["firmwareLocation"], "http://cdn.example.com/full")
+	assert.Equal(t, response["firmwareFilename"], "firmware.bin")
+	_, ok := response["deltaFirmwareSize"]
+	assert.Assert(t, !ok)
+}
@@ -224,6 +224,73 @@ func TestFirmwareConfig_Validate_EmptySupportedModels(t *testing.T) {
 	assert.ErrorContains(t, err, "Supported model list is empty")
 }
 
+func TestFirmwareConfig_Validate_Integrity(t *testing.T) {
+	config := &FirmwareConfig{
+		Description:       "Valid Description",
+		FirmwareFilename:  "firmware.bin",
+		FirmwareVersion:   "1.0.0",
+		FirmwareSha256:    "not a digest",
+		SupportedModelIds: []string{},
+	}
+	assert.ErrorContains(t, config.Validate(), "FirmwareSha256 must be a hex SHA-256 digest")
+
+	config.FirmwareSha256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	config.FirmwareSize = -1
+	assert.ErrorContains(t, config.Validate(), "FirmwareSize must not be negative")
+
+	config.FirmwareSize = 1024
+	config.FirmwareSignature = "not base64!"
+	assert.ErrorContains(t, config.Validate(), "FirmwareSignature must be base64 encoded")
+
+	config.FirmwareSignature = "c2lnbmF0dXJl"
+	assert.ErrorContains(t, config.Validate(), "Supported model list is empty")
+}
+
+func TestFirmwareConfig_Validate_DeltaImages(t *testing.T) {
+	digest := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	config := &FirmwareConfig{
+		Description:       "Valid Description",
+		FirmwareFilename:  "firmware.bin",
+		FirmwareVersion:   "2.0.0",
+		DeltaImages:       DeltaImages{"2.0.0": {Filename: "delta.bin", Sha256: digest}},
+		SupportedModelIds: []string{},
+	}
+	assert.ErrorContains(t, config.Validate(), "Delta image source version 2.0.0 is the firmware version")
+
+	config.DeltaImages = DeltaImages{"1.0.0": {Sha256: digest}}
+	assert.ErrorContains(t, config.Validate(), "Delta image file name for 1.0.0 is empty")
+
+	config.DeltaImages = DeltaImages{"1.0.0": {Filename: "delta.bin", Sha256: "not a digest"}}
+	assert.ErrorContains(t, config.Validate(), "Delta image digest for 1.0.0 must be a hex SHA-256 digest")
+
+	config.DeltaImages = DeltaImages{"1.0.0": {Filename: "delta.bin", Sha256: digest, Size: -1}}
+	assert.ErrorContains(t, config.Validate(), "Delta image size for 1.0.0 must not be negative")
+
+	config.DeltaImages = DeltaImages{"1.0.0": nil}
+	assert.ErrorContains(t, config.Validate(), "Delta image needs a source version and an image")
+
+	config.DeltaImages = DeltaImages{"1.0.0": {Filename: "delta.bin", Location: "http://cdn.example.com/delta", Sha256: digest}}
+	assert.ErrorContains(t, config.Validate(), "Supported model list is empty")
+}
+
+func TestFirmwareConfig_GetDeltaImage(t *testing.T) {
+	delta := &DeltaImage{Filename: "delta-1.0.bin", Sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}
+	config := &FirmwareConfig{
+		FirmwareVersion: "X1_2.0p1s1_PROD",
+		DeltaImages:     DeltaImages{"X1_1.0p1s1_PROD": delta},
+	}
+	assert.Equal(t, config.GetDeltaImage("X1_1.0p1s1_PROD"), delta)
+	assert.Equal(t, config.GetDeltaImage("x1_1.0P1S1_prod"), delta)
+	assert.Assert(t, config.GetDeltaImage("X1_1.5p1s1_PROD") == nil)
+	assert.Assert(t, config.GetDeltaImage("X1_2.0p1s1_PROD") == nil)
+	assert.Assert(t, config.GetDeltaImage("") == nil)
+	assert.Assert(t, (&FirmwareConfig{FirmwareVersion: "2.0"}).GetDeltaImage("1.0") == nil)
+
+	// the delta images are not properties of the response, only the delta of the device is
+	_, ok := config.ToPropertiesMap()["deltaImages"]
+	assert.Assert(t, !ok)
+}
+
 func TestFirmwareConfigToFirmwareConfigForMacRuleBeanResponse(t *testing.T) {
 	properties := map[string]string{"key": "value"}
 	config := &FirmwareConfig{
@@ -0,0 +1,95 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"strings"
+
+	"github.com/rdkcentral/xconfwebconfig/db"
+)
+
+const (
+	FirmwareOutcomeStageDownload = "download"
+	FirmwareOutcomeStageFlash    = "flash"
+)
+
+// FirmwareOutcomeCount counts the devices that reported an outcome of a firmware version
+type FirmwareOutcomeCount struct {
+	Reports  int `json:"reports"`
+	Failures int `json:"failures"`
+}
+
+// FailurePercentage returns the percentage of the reports that are failures
+func (c *FirmwareOutcomeCount) FailurePercentage() float64 {
+	if c.Reports == 0 {
+		return 0
+	}
+	return float64(c.Failures) * 100 / float64(c.Reports)
+}
+
+// FirmwareOutcomeSummary counts the last outcome reported by each device of a model for a firmware version,
+// by upper case partner id. Devices without a partner are counted under ""
+type FirmwareOutcomeSummary struct {
+	Model           string                           `json:"model"`
+	FirmwareVersion string                           `json:"firmwareVersion"`
+	Partners        map[string]*FirmwareOutcomeCount `json:"partners"`
+}
+
+func NewFirmwareOutcomeSummary(model string, firmwareVersion string, outcomes []*db.FirmwareOutcome) *FirmwareOutcomeSummary {
+	summary := &FirmwareOutcomeSummary{
+		Model:           model,
+		FirmwareVersion: firmwareVersion,
+		Partners:        map[string]*FirmwareOutcomeCount{},
+	}
+	for _, outcome := range outcomes {
+		count := summary.Partners[strings.ToUpper(outcome.Partner)]
+		if count == nil {
+			count = &FirmwareOutcomeCount{}
+			summary.Partners[strings.ToUpper(outcome.Partner)] = count
+		}
+		count.Reports++
+		if !outcome.Success {
+			count.Failures++
+		}
+	}
+	return summary
+}
+
+// GetPartnerCount returns the count of a partner, an empty count when its devices reported nothing
+func (s *FirmwareOutcomeSummary) GetPartnerCount(partnerId string) *FirmwareOutcomeCount {
+	if count, ok := s.Partners[strings.ToUpper(partnerId)]; ok {
+		return count
+	}
+	return &FirmwareOutcomeCount{}
+}
+
+// SetFirmwareOutcomeDB stores the outcome of a device under its upper case model, it expires after ttl seconds
+func SetFirmwareOutcomeDB(outcome *db.FirmwareOutcome, ttl int) error {
+	outcome.Model = strings.ToUpper(outcome.Model)
+	return db.GetDatabaseClient().SetFirmwareOutcome(outcome, ttl)
+}
+
+// GetFirmwareOutcomeSummaryDB counts the outcomes reported for a model and the firmware version of a config
+func GetFirmwareOutcomeSummaryDB(model string, firmwareVersion string) (*FirmwareOutcomeSummary, error) {
+	model = strings.ToUpper(model)
+	outcomes, err := db.GetDatabaseClient().GetFirmwareOutcomes(model, firmwareVersion)
+	if err != nil {
+		return nil, err
+	}
+	return NewFirmwareOutcomeSummary(model, firmwareVersion, outcomes), nil
+}
@@ -0,0 +1,46 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"testing"
+
+	"github.com/rdkcentral/xconfwebconfig/db"
+	"gotest.tools/assert"
+)
+
+func TestNewFirmwareOutcomeSummary(t *testing.T) {
+	summary := NewFirmwareOutcomeSummary("X1", "2.0", []*db.FirmwareOutcome{
+		{EstbMac: "AA:AA:AA:AA:AA:01", Partner: "comcast", Success: true},
+		{EstbMac: "AA:AA:AA:AA:AA:02", Partner: "COMCAST", Success: false},
+		{EstbMac: "AA:AA:AA:AA:AA:03", Partner: "comcast", Success: false, Stage: FirmwareOutcomeStageFlash},
+		{EstbMac: "AA:AA:AA:AA:AA:04", Partner: "cox", Success: true},
+		{EstbMac: "AA:AA:AA:AA:AA:05", Success: false},
+	})
+	assert.Equal(t, len(summary.Partners), 3)
+	assert.DeepEqual(t, summary.GetPartnerCount("Comcast"), &FirmwareOutcomeCount{Reports: 3, Failures: 2})
+	assert.DeepEqual(t, summary.GetPartnerCount("cox"), &FirmwareOutcomeCount{Reports: 1})
+	assert.DeepEqual(t, summary.GetPartnerCount(""), &FirmwareOutcomeCount{Reports: 1, Failures: 1})
+	assert.DeepEqual(t, summary.GetPartnerCount("charter"), &FirmwareOutcomeCount{})
+}
+
+func TestFirmwareOutcomeCountFailurePercentage(t *testing.T) {
+	assert.Equal(t, (&FirmwareOutcomeCount{}).FailurePercentage(), 0.0)
+	assert.Equal(t, (&FirmwareOutcomeCount{Reports: 4, Failures: 1}).FailurePercentage(), 25.0)
+	assert.Equal(t, (&FirmwareOutcomeCount{Reports: 2, Failures: 2}).FailurePercentage(), 100.0)
+}
@@ -0,0 +1,141 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rdkcentral/xconfwebconfig/db"
+)
+
+// ErrNoUpgradePath is returned when the graph has both versions but no path between them
+var ErrNoUpgradePath = errors.New("no upgrade path")
+
+// UpgradeEdge allows devices running FromVersion to upgrade to the firmware of ConfigId
+type UpgradeEdge struct {
+	FromVersion string `json:"fromVersion"`
+	ConfigId    string `json:"configId"`
+}
+
+// FirmwareUpgradeGraph FirmwareUpgradeGraph table, the firmware upgrades allowed for a model.
+// Devices of the model whose version and target version are both in the graph only upgrade along its edges,
+// they get no firmware when the graph has no path
+type FirmwareUpgradeGraph struct {
+	ID      string        `json:"id"` // model id
+	Updated int64         `json:"updated,omitempty"`
+	Edges   []UpgradeEdge `json:"edges"`
+}
+
+// NewFirmwareUpgradeGraphInf constructor
+func NewFirmwareUpgradeGraphInf() interface{} {
+	return &FirmwareUpgradeGraph{}
+}
+
+// UpgradePath is the planned path of a device, Versions starts with the running version and
+// ends with the target version, ConfigIds has the config of each hop
+type UpgradePath struct {
+	Versions  []string `json:"versions"`
+	ConfigIds []string `json:"configIds"`
+}
+
+func (p *UpgradePath) String() string {
+	return strings.Join(p.Versions, " -> ")
+}
+
+// NextConfigId returns the config of the first hop
+func (p *UpgradePath) NextConfigId() string {
+	if len(p.ConfigIds) == 0 {
+		return ""
+	}
+	return p.ConfigIds[0]
+}
+
+type upgradeHop struct {
+	from     string // lower case version the hop starts from
+	version  string
+	configId string
+}
+
+// PlanPath returns the shortest path from currentVersion to targetVersion, nil when the graph does not
+// have both versions or the device already runs the target. getVersion returns the firmware version
+// of a config, edges to configs it cannot find are ignored. Versions are compared ignoring case
+func (g *FirmwareUpgradeGraph) PlanPath(currentVersion string, targetVersion string, getVersion func(configId string) (string, error)) (*UpgradePath, error) {
+	if currentVersion == "" || targetVersion == "" || strings.EqualFold(currentVersion, targetVersion) {
+		return nil, nil
+	}
+	hops := map[string][]upgradeHop{}
+	versions := map[string]bool{}
+	for _, edge := range g.Edges {
+		version, err := getVersion(edge.ConfigId)
+		if err != nil || version == "" {
+			continue
+		}
+		from := strings.ToLower(edge.FromVersion)
+		hops[from] = append(hops[from], upgradeHop{from: from, version: version, configId: edge.ConfigId})
+		versions[from] = true
+		versions[strings.ToLower(version)] = true
+	}
+	current, target := strings.ToLower(currentVersion), strings.ToLower(targetVersion)
+	if !versions[current] || !versions[target] {
+		return nil, nil
+	}
+
+	// breadth first, so the path with the fewest hops wins and ties go to the first edge
+	reachedBy := map[string]upgradeHop{current: {}}
+	queue := []string{current}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+		for _, hop := range hops[from] {
+			to := strings.ToLower(hop.version)
+			if _, ok := reachedBy[to]; ok {
+				continue
+			}
+			reachedBy[to] = hop
+			queue = append(queue, to)
+		}
+	}
+	if _, ok := reachedBy[target]; !ok {
+		return nil, fmt.Errorf("%w from %s to %s for model %s", ErrNoUpgradePath, currentVersion, targetVersion, g.ID)
+	}
+
+	path := &UpgradePath{}
+	for version := target; version != current; version = reachedBy[version].from {
+		hop := reachedBy[version]
+		path.Versions = append([]string{hop.version}, path.Versions...)
+		path.ConfigIds = append([]string{hop.configId}, path.ConfigIds...)
+	}
+	path.Versions = append([]string{currentVersion}, path.Versions...)
+	return path, nil
+}
+
+// GetFirmwareUpgradeGraphOneDB returns the upgrade graph of a model from the cache only. The table is precached
+// and kept current by the cache refresh, so the models without a graph, most of them, do not read the DB
+func GetFirmwareUpgradeGraphOneDB(modelId string) (*FirmwareUpgradeGraph, error) {
+	inst, err := db.GetCachedSimpleDao().GetOneFromCacheOnly(db.TABLE_FIRMWARE_UPGRADE_GRAPH, strings.ToUpper(modelId))
+	if err != nil {
+		return nil, err
+	}
+	graph, ok := inst.(*FirmwareUpgradeGraph)
+	if !ok {
+		return nil, fmt.Errorf("unexpected upgrade graph %T for model %s", inst, modelId)
+	}
+	return graph, nil
+}
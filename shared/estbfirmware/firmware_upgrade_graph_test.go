@@ -0,0 +1,109 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+var upgradeGraphTestVersions = map[string]string{
+	"config2": "2.0",
+	"config3": "3.0",
+	"config4": "4.0",
+	"config5": "5.0",
+}
+
+func upgradeGraphTestVersion(configId string) (string, error) {
+	if version, ok := upgradeGraphTestVersions[configId]; ok {
+		return version, nil
+	}
+	return "", fmt.Errorf("config %s not found", configId)
+}
+
+func upgradeGraphTestGraph() *FirmwareUpgradeGraph {
+	return &FirmwareUpgradeGraph{
+		ID: "X1",
+		Edges: []UpgradeEdge{
+			{FromVersion: "1.0", ConfigId: "config2"},
+			{FromVersion: "2.0", ConfigId: "config3"},
+			{FromVersion: "3.0", ConfigId: "config4"},
+			{FromVersion: "2.0", ConfigId: "config4"},
+			{FromVersion: "4.0", ConfigId: "config2"},
+			{FromVersion: "1.0", ConfigId: "missing"},
+		},
+	}
+}
+
+func TestFirmwareUpgradeGraphPlanPath(t *testing.T) {
+	graph := upgradeGraphTestGraph()
+
+	path, err := graph.PlanPath("1.0", "3.0", upgradeGraphTestVersion)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"1.0", "2.0", "3.0"}, path.Versions)
+	assert.DeepEqual(t, []string{"config2", "config3"}, path.ConfigIds)
+	assert.Equal(t, "config2", path.NextConfigId())
+	assert.Equal(t, "1.0 -> 2.0 -> 3.0", path.String())
+
+	// the shortcut 2.0 -> 4.0 wins over 2.0 -> 3.0 -> 4.0
+	path, err = graph.PlanPath("1.0", "4.0", upgradeGraphTestVersion)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"1.0", "2.0", "4.0"}, path.Versions)
+
+	path, err = graph.PlanPath("3.0", "4.0", upgradeGraphTestVersion)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"config4"}, path.ConfigIds)
+
+	// cycles back to an older version
+	path, err = graph.PlanPath("4.0", "3.0", upgradeGraphTestVersion)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"4.0", "2.0", "3.0"}, path.Versions)
+}
+
+func TestFirmwareUpgradeGraphPlanPathIgnoresCase(t *testing.T) {
+	graph := &FirmwareUpgradeGraph{
+		ID:    "X1",
+		Edges: []UpgradeEdge{{FromVersion: "X1_1.0p1S", ConfigId: "config2"}},
+	}
+	path, err := graph.PlanPath("x1_1.0P1s", "2.0", upgradeGraphTestVersion)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"x1_1.0P1s", "2.0"}, path.Versions)
+}
+
+func TestFirmwareUpgradeGraphPlanPathNotApplicable(t *testing.T) {
+	graph := upgradeGraphTestGraph()
+
+	for _, versions := range [][]string{{"3.0", "3.0"}, {"0.9", "3.0"}, {"1.0", "9.0"}, {"", "3.0"}} {
+		path, err := graph.PlanPath(versions[0], versions[1], upgradeGraphTestVersion)
+		assert.NilError(t, err, versions)
+		assert.Assert(t, path == nil, versions)
+	}
+}
+
+func TestFirmwareUpgradeGraphPlanPathUnreachable(t *testing.T) {
+	graph := upgradeGraphTestGraph()
+	graph.Edges = append(graph.Edges, UpgradeEdge{FromVersion: "5.0", ConfigId: "config2"})
+
+	path, err := graph.PlanPath("1.0", "5.0", upgradeGraphTestVersion)
+	assert.Assert(t, path == nil)
+	assert.Assert(t, errors.Is(err, ErrNoUpgradePath))
+	assert.ErrorContains(t, err, "from 1.0 to 5.0 for model X1")
+}
@@ -0,0 +1,119 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rdkcentral/xconfwebconfig/db"
+	"github.com/rdkcentral/xconfwebconfig/util"
+)
+
+const (
+	LocationHealthSourceProbe  = "probe"
+	LocationHealthSourceReport = "report"
+)
+
+// LocationHealth LocationHealth table, the health of a download location. The id is the location ip
+// of the round robin filter, or the host of its http locations
+type LocationHealth struct {
+	ID      string `json:"id"`
+	Healthy bool   `json:"healthy"`
+	Reason  string `json:"reason,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Updated int64  `json:"updated"`
+	Expires int64  `json:"expires,omitempty"` // epoch millis, 0 never expires
+}
+
+// NewLocationHealthInf constructor
+func NewLocationHealthInf() interface{} {
+	return &LocationHealth{}
+}
+
+func (h *LocationHealth) IsExpired(now time.Time) bool {
+	return h.Expires > 0 && h.Expires <= util.GetTimestamp(now)
+}
+
+func (h *LocationHealth) String() string {
+	state := "healthy"
+	if !h.Healthy {
+		state = "unhealthy"
+	}
+	if h.Reason == "" {
+		return fmt.Sprintf("%s %s (%s)", h.ID, state, h.Source)
+	}
+	return fmt.Sprintf("%s %s (%s: %s)", h.ID, state, h.Source, h.Reason)
+}
+
+// LocationHealthChecker returns the health of a download location, nil when it is unknown
+type LocationHealthChecker interface {
+	GetLocationHealth(location string) *LocationHealth
+}
+
+// LocationHealthMap is a LocationHealthChecker of fixed health, for tests and local setups
+type LocationHealthMap map[string]*LocationHealth
+
+func (m LocationHealthMap) GetLocationHealth(location string) *LocationHealth {
+	return m[location]
+}
+
+type reportedLocationHealth struct{}
+
+// GetReportedLocationHealthChecker returns the LocationHealthChecker of the health reports in the LocationHealth table
+func GetReportedLocationHealthChecker() LocationHealthChecker {
+	return reportedLocationHealth{}
+}
+
+func (r reportedLocationHealth) GetLocationHealth(location string) *LocationHealth {
+	health, err := GetLocationHealthOneDB(location)
+	if err != nil {
+		return nil
+	}
+	return health
+}
+
+func GetLocationHealthOneDB(location string) (*LocationHealth, error) {
+	inst, err := db.GetCachedSimpleDao().GetOne(db.TABLE_LOCATION_HEALTH, location)
+	if err != nil {
+		return nil, err
+	}
+	health, ok := inst.(*LocationHealth)
+	if !ok {
+		return nil, fmt.Errorf("unexpected location health %T for %s", inst, location)
+	}
+	return health, nil
+}
+
+func GetLocationHealthAllDB() ([]*LocationHealth, error) {
+	list, err := db.GetCachedSimpleDao().GetAllAsList(db.TABLE_LOCATION_HEALTH, 0)
+	if err != nil {
+		return nil, err
+	}
+	healths := []*LocationHealth{}
+	for _, inst := range list {
+		if health, ok := inst.(*LocationHealth); ok {
+			healths = append(healths, health)
+		}
+	}
+	return healths, nil
+}
+
+func SetLocationHealthOneDB(health *LocationHealth) error {
+	return db.GetCachedSimpleDao().SetOne(db.TABLE_LOCATION_HEALTH, health.ID, health)
+}
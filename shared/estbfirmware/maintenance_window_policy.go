@@ -0,0 +1,122 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rdkcentral/xconfwebconfig/db"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
+)
+
+const (
+	MaintenanceWindowActionDelay   = "delay"
+	MaintenanceWindowActionSuggest = "suggest"
+)
+
+// MaintenanceWindowPolicy MaintenanceWindowPolicy table, the device-local windows in which devices of a model
+// and/or partner may reboot into new firmware. Windows use the SCHEDULE syntax, such as "Daily 01:00-05:00".
+// Outside them the delay action also sets upgradeDelay to the next window start, suggest only returns the start
+type MaintenanceWindowPolicy struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name,omitempty"`
+	Updated   int64    `json:"updated,omitempty"`
+	Model     string   `json:"model,omitempty"`
+	PartnerId string   `json:"partnerId,omitempty"`
+	Windows   []string `json:"windows"`
+	Action    string   `json:"action,omitempty"` // delay or suggest, delay when empty
+}
+
+// NewMaintenanceWindowPolicyInf constructor
+func NewMaintenanceWindowPolicyInf() interface{} {
+	return &MaintenanceWindowPolicy{}
+}
+
+func (p *MaintenanceWindowPolicy) Validate() error {
+	if p.Model == "" && p.PartnerId == "" {
+		return errors.New("maintenance window policy needs a model or a partner")
+	}
+	if len(p.Windows) == 0 {
+		return errors.New("maintenance window policy needs a window")
+	}
+	if p.Action != "" && p.Action != MaintenanceWindowActionDelay && p.Action != MaintenanceWindowActionSuggest {
+		return fmt.Errorf("maintenance window action must be %s or %s", MaintenanceWindowActionDelay, MaintenanceWindowActionSuggest)
+	}
+	return re.ValidateSchedules(p.Windows)
+}
+
+func (p *MaintenanceWindowPolicy) IsDelay() bool {
+	return p.Action != MaintenanceWindowActionSuggest
+}
+
+// Matches returns true when the model and partner of the policy, those it has, are the device's. Case is ignored
+func (p *MaintenanceWindowPolicy) Matches(model string, partnerId string) bool {
+	if p.Model == "" && p.PartnerId == "" {
+		return false
+	}
+	return (p.Model == "" || strings.EqualFold(p.Model, model)) && (p.PartnerId == "" || strings.EqualFold(p.PartnerId, partnerId))
+}
+
+func (p *MaintenanceWindowPolicy) specificity() int {
+	specificity := 0
+	if p.Model != "" {
+		specificity += 2
+	}
+	if p.PartnerId != "" {
+		specificity++
+	}
+	return specificity
+}
+
+// FindMaintenanceWindowPolicy returns the most specific policy of the device, a model and partner policy before
+// a model policy before a partner policy. Policies as specific as each other are ordered by id
+func FindMaintenanceWindowPolicy(policies []*MaintenanceWindowPolicy, model string, partnerId string) *MaintenanceWindowPolicy {
+	matched := []*MaintenanceWindowPolicy{}
+	for _, policy := range policies {
+		if policy.Matches(model, partnerId) {
+			matched = append(matched, policy)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].specificity() != matched[j].specificity() {
+			return matched[i].specificity() > matched[j].specificity()
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	return matched[0]
+}
+
+func GetMaintenanceWindowPolicyAllDB() ([]*MaintenanceWindowPolicy, error) {
+	list, err := db.GetCachedSimpleDao().GetAllAsList(db.TABLE_MAINTENANCE_WINDOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	policies := []*MaintenanceWindowPolicy{}
+	for _, inst := range list {
+		if policy, ok := inst.(*MaintenanceWindowPolicy); ok {
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
@@ -0,0 +1,58 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestFindMaintenanceWindowPolicy(t *testing.T) {
+	policies := []*MaintenanceWindowPolicy{
+		{ID: "partner", PartnerId: "comcast", Windows: []string{"Daily 02:00-04:00"}},
+		{ID: "model-b", Model: "X1", Windows: []string{"Daily 01:00-05:00"}},
+		{ID: "model-a", Model: "x1", Windows: []string{"Daily 01:00-05:00"}},
+		{ID: "model-partner", Model: "X1", PartnerId: "COMCAST", Windows: []string{"Daily 03:00-04:00"}},
+		{ID: "empty", Windows: []string{"Daily"}},
+	}
+	assert.Equal(t, FindMaintenanceWindowPolicy(policies, "X1", "comcast").ID, "model-partner")
+	assert.Equal(t, FindMaintenanceWindowPolicy(policies, "X1", "cox").ID, "model-a")
+	assert.Equal(t, FindMaintenanceWindowPolicy(policies, "X2", "Comcast").ID, "partner")
+	assert.Assert(t, FindMaintenanceWindowPolicy(policies, "X2", "cox") == nil)
+	assert.Assert(t, FindMaintenanceWindowPolicy(nil, "X1", "comcast") == nil)
+}
+
+func TestMaintenanceWindowPolicyValidate(t *testing.T) {
+	policy := &MaintenanceWindowPolicy{ID: "p", Model: "X1", Windows: []string{"Mon-Fri 01:00-05:00", "Sat,Sun 02:00-06:00"}}
+	assert.NilError(t, policy.Validate())
+	assert.Assert(t, policy.IsDelay())
+
+	policy.Action = MaintenanceWindowActionSuggest
+	assert.NilError(t, policy.Validate())
+	assert.Assert(t, !policy.IsDelay())
+
+	for _, invalid := range []*MaintenanceWindowPolicy{
+		{ID: "p", Windows: []string{"Daily 01:00-05:00"}},
+		{ID: "p", Model: "X1"},
+		{ID: "p", Model: "X1", Windows: []string{"Someday"}},
+		{ID: "p", PartnerId: "comcast", Windows: []string{"Daily 01:00-05:00"}, Action: "reboot"},
+	} {
+		assert.Assert(t, invalid.Validate() != nil, "%+v", invalid)
+	}
+}
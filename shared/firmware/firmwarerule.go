@@ -78,6 +78,7 @@ type ApplicableAction struct {
 	Properties                 map[string]string    `json:"properties,omitempty"` // DefinePropertiesAction
 	ByPassFilters              []string             `json:"byPassFilters,omitempty"`
 	ActivationFirmwareVersions map[string][]string  `json:"activationFirmwareVersions,omitempty"`
+	PercentSalt                string               `json:"percentSalt,omitempty"` // RuleAction, salts the ConfigEntries percent ranges
 }
 
 type TemplateApplicableAction struct {
@@ -185,6 +186,7 @@ func (a ApplicableAction) String() string {
 	Properties=%v,
 	ByPassFilters=%v,
 	ActivationFirmwareVersions=%v,
+	PercentSalt=%v,
   )`,
 		a.Type,
 		a.ActionType,
@@ -200,6 +202,7 @@ func (a ApplicableAction) String() string {
 		a.Properties,
 		a.ByPassFilters,
 		a.ActivationFirmwareVersions,
+		a.PercentSalt,
 	)
 }
 
@@ -576,7 +579,7 @@ func GetFirmwareRulesByApplicationType(applicationType string) ([]*FirmwareRule,
 	filtereddRules := make([]*FirmwareRule, 0, len(rulelst))
 
 	for _, rule := range rulelst {
-		if rule.ApplicationType == applicationType {
+		if rule.ApplicationType == applicationType && re.IsEvaluable(rule) {
 			filtereddRules = append(filtereddRules, rule)
 		}
 	}
@@ -586,6 +589,34 @@ func GetFirmwareRulesByApplicationType(applicationType string) ([]*FirmwareRule,
 	return filtereddRules, nil
 }
 
+// GetFirmwareRuleIndexByApplicationType returns the firmware rules of an application type compiled
+// into a RuleIndex. The index lives in the application cache and is rebuilt after the firmware rule
+// table changes, so nil is returned when the application cache is disabled.
+func GetFirmwareRuleIndexByApplicationType(applicationType string, processor *re.RuleProcessor) *re.RuleIndex {
+	cm := db.GetCacheManager()
+	if !cm.IsApplicationCacheEnabled() {
+		return nil
+	}
+	cacheKey := fmt.Sprintf("%s_%s", "FirmwareRuleIndex", applicationType)
+	cacheInst := cm.ApplicationCacheGet(db.TABLE_FIRMWARE_RULE, cacheKey)
+	if cacheInst != nil {
+		return cacheInst.(*re.RuleIndex)
+	}
+
+	firmwareRules, err := GetFirmwareRulesByApplicationType(applicationType)
+	if err != nil {
+		return nil
+	}
+	rules := make([]re.XRule, 0, len(firmwareRules))
+	for _, firmwareRule := range firmwareRules {
+		rules = append(rules, firmwareRule)
+	}
+	index := re.NewCachedRuleIndex(cacheKey, processor, rules)
+	cm.ApplicationCacheSet(db.TABLE_FIRMWARE_RULE, cacheKey, index)
+
+	return index
+}
+
 func GetEnvModelFirmwareRules(applicationType string) ([]*FirmwareRule, error) {
 	cm := db.GetCacheManager()
 	cacheKey := fmt.Sprintf("%s_%s", "EnvModelFirmwareRuleList", applicationType)
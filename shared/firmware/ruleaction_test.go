@@ -18,6 +18,7 @@
 package firmware
 
 import (
+	"encoding/json"
 	"testing"
 
 	"gotest.tools/assert"
@@ -144,6 +145,19 @@ func TestRuleAction_Fields(t *testing.T) {
 	assert.Assert(t, ruleAction.RebootImmediately)
 }
 
+func TestRuleActionPercentSalt(t *testing.T) {
+	// the salt is the one of the embedded ApplicableAction, which the percent evaluation reads
+	ruleAction := &RuleAction{}
+	assert.NilError(t, json.Unmarshal([]byte(`{"configId": "config-123", "percentSalt": "spring"}`), ruleAction))
+	assert.Equal(t, "spring", ruleAction.ApplicableAction.PercentSalt)
+
+	bbytes, err := json.Marshal(ruleAction)
+	assert.NilError(t, err)
+	decoded := &ApplicableAction{}
+	assert.NilError(t, json.Unmarshal(bbytes, decoded))
+	assert.Equal(t, "spring", decoded.PercentSalt)
+}
+
 // Test NewRuleAction constructor
 func TestNewRuleAction(t *testing.T) {
 	ruleAction := NewRuleAction()
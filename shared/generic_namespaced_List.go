@@ -21,7 +21,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"net"
 	"regexp"
 	"strings"
 	"sync"
@@ -92,13 +91,14 @@ func (obj *GenericNamespacedList) Validate() error {
 	if !IsValidType(obj.TypeName) {
 		return fmt.Errorf("type %s is invalid", obj.TypeName)
 	}
-	itemsSet := util.Set{}
-	itemsSet.Add(obj.Data...)
-	obj.Data = itemsSet.ToSlice()
-
-	if err := ValidateListData(obj.TypeName, obj.Data); err != nil {
+	data, err := normalizeListData(obj.TypeName, obj.Data)
+	if err != nil {
 		return err
 	}
+	// deduplicated after normalizing, which may turn two entries into the same address
+	itemsSet := util.Set{}
+	itemsSet.Add(data...)
+	obj.Data = itemsSet.ToSlice()
 
 	if err := obj.ValidateDataIntersection(); err != nil {
 		return err
@@ -132,20 +132,31 @@ func (obj *GenericNamespacedList) ValidateForAdminService() error {
 }
 
 func ValidateListData(typeName string, listData []string) error {
+	_, err := normalizeListData(typeName, listData)
+	return err
+}
+
+// normalizeListData validates listData and returns a copy of it in which the addresses and CIDR
+// blocks of either family of an IP list are normalized
+func normalizeListData(typeName string, listData []string) ([]string, error) {
 	if !IsValidType(typeName) {
-		return errors.New("Type is invalid")
+		return nil, errors.New("Type is invalid")
 	}
 
 	if len(listData) == 0 {
-		return errors.New("List must not be empty")
+		return nil, errors.New("List must not be empty")
 	}
 
+	listData = append([]string{}, listData...)
 	var invalidAddresses []string
 	if typeName == IP_LIST {
-		for _, ipAddress := range listData {
-			if net.ParseIP(ipAddress) == nil {
+		for i, ipAddress := range listData {
+			normalized, err := NormalizeIpAddress(ipAddress)
+			if err != nil {
 				invalidAddresses = append(invalidAddresses, ipAddress)
+				continue
 			}
+			listData[i] = normalized
 		}
 	} else if typeName == MAC_LIST {
 		for _, mac := range listData {
@@ -155,10 +166,10 @@ func ValidateListData(typeName string, listData []string) error {
 		}
 	}
 	if len(invalidAddresses) > 0 {
-		return fmt.Errorf("List contains invalid address(es): %v", invalidAddresses)
+		return nil, fmt.Errorf("List contains invalid address(es): %v", invalidAddresses)
 	}
 
-	return nil
+	return listData, nil
 }
 
 func ValidateListDataForAdmin(typeName string, listData []string) error {
@@ -404,9 +415,58 @@ func DeleteOneGenericNamedList(id string) error {
 	return nil
 }
 
+// ipListIndexes caches the prefix tree of each IP list by list id. An entry is rebuilt when the
+// list is replaced, for instance by the cache refresh, so lists must not be modified in place.
+var ipListIndexes sync.Map
+
+type ipListIndex struct {
+	data   []string
+	tree   *IpPrefixTree
+	values map[string]struct{}
+}
+
+func (g *GenericNamespacedList) getIpListIndex() *ipListIndex {
+	if cached, ok := ipListIndexes.Load(g.ID); ok {
+		index := cached.(*ipListIndex)
+		if index.isIndexOf(g.Data) {
+			return index
+		}
+	}
+
+	index := &ipListIndex{
+		data:   g.Data,
+		tree:   NewIpPrefixTree(),
+		values: map[string]struct{}{},
+	}
+	for _, s := range g.Data {
+		if !index.tree.Insert(s) {
+			index.values[s] = struct{}{}
+		}
+	}
+	ipListIndexes.Store(g.ID, index)
+	return index
+}
+
+func (index *ipListIndex) isIndexOf(data []string) bool {
+	if len(index.data) != len(data) {
+		return false
+	}
+	return len(data) == 0 || &index.data[0] == &data[0]
+}
+
+// IsInIpRange reports whether the IPv4 or IPv6 address is one of the addresses or in one of the CIDR blocks of the list
 func (g *GenericNamespacedList) IsInIpRange(ipAddressStr string) bool {
-	ipAddressGroup := NewIpAddressGroupWithAddrStrings("foo", "bar", g.Data)
-	return ipAddressGroup.IsInRange(ipAddressStr)
+	return g.getIpListIndex().tree.Contains(ipAddressStr)
+}
+
+// IsInIpListData is IsInIpRange, also matching entries that are not IP addresses by string equality
+func (g *GenericNamespacedList) IsInIpListData(value string) bool {
+	index := g.getIpListIndex()
+	if index.tree.Contains(value) {
+		return true
+	}
+	_, ok := index.values[value]
+	return ok
 }
 
 func (g *GenericNamespacedList) CreateIpAddressGroupResponse() *IpAddressGroup {
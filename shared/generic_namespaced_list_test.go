@@ -18,6 +18,7 @@
 package shared
 
 import (
+	"sort"
 	"testing"
 
 	"gotest.tools/assert"
@@ -366,6 +367,16 @@ func TestValidate_RemovesDuplicates(t *testing.T) {
 	assert.Assert(t, len(list.Data) <= 3) // At most 3 unique values: a, b, c
 }
 
+func TestValidate_NormalizesIpList(t *testing.T) {
+	data := []string{"10.1.2.3/8", "10.0.0.0/8", "2001:DB8::1"}
+	list := NewGenericNamespacedList("test", IP_LIST, data)
+	assert.NilError(t, list.Validate())
+
+	sort.Strings(list.Data)
+	assert.DeepEqual(t, []string{"10.0.0.0/8", "2001:db8::1"}, list.Data)
+	assert.DeepEqual(t, []string{"10.1.2.3/8", "10.0.0.0/8", "2001:DB8::1"}, data)
+}
+
 // Test NewNamespacedListInf
 func TestNewNamespacedListInf(t *testing.T) {
 	obj := NewNamespacedListInf()
@@ -536,3 +547,34 @@ func TestIsInIpRange_InvalidInput(t *testing.T) {
 	assert.Assert(t, !list.IsInIpRange("not-an-ip"))
 	assert.Assert(t, !list.IsInIpRange(""))
 }
+
+func TestValidateListData_IPv6AndCIDR(t *testing.T) {
+	data := []string{"1.2.3.4", "10.1.2.3/8", "2001:DB8::1", "2001:db8:1::5/48"}
+	assert.NilError(t, ValidateListData(IP_LIST, data))
+	// the data of the caller is left as it is
+	assert.DeepEqual(t, []string{"1.2.3.4", "10.1.2.3/8", "2001:DB8::1", "2001:db8:1::5/48"}, data)
+
+	normalized, err := normalizeListData(IP_LIST, data)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"1.2.3.4", "10.0.0.0/8", "2001:db8::1", "2001:db8:1::/48"}, normalized)
+
+	assert.Assert(t, ValidateListData(IP_LIST, []string{"2001:db8::/129"}) != nil)
+}
+
+func TestGenericNamespacedList_IsInIpRange_IPv6(t *testing.T) {
+	ipList := NewGenericNamespacedList("mixed", IP_LIST, []string{"12.34.56.78/31", "2001:558:6027:180::/57"})
+	assert.Assert(t, ipList.IsInIpRange("12.34.56.79"))
+	assert.Assert(t, ipList.IsInIpRange("::ffff:12.34.56.79"))
+	assert.Assert(t, ipList.IsInIpRange("2001:558:6027:180::1234"))
+	assert.Assert(t, !ipList.IsInIpRange("2001:558:4321:180::2345"))
+
+	// a replaced list with the same id is reindexed
+	ipList = NewGenericNamespacedList("mixed", IP_LIST, []string{"2001:558:4321::/48"})
+	assert.Assert(t, !ipList.IsInIpRange("12.34.56.79"))
+	assert.Assert(t, ipList.IsInIpRange("2001:558:4321:180::2345"))
+
+	ipList = NewGenericNamespacedList("values", IP_LIST, []string{"1.2.3.4", "not-an-ip"})
+	assert.Assert(t, !ipList.IsInIpRange("not-an-ip"))
+	assert.Assert(t, ipList.IsInIpListData("not-an-ip"))
+	assert.Assert(t, ipList.IsInIpListData("1.2.3.4"))
+}
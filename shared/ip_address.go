@@ -19,6 +19,7 @@ package shared
 
 import (
 	"net"
+	"net/netip"
 	"strings"
 )
 
@@ -87,25 +88,36 @@ func (a IpAddress) IP() net.IP {
 	return a.ip
 }
 
+// IsInRange reports whether an IP address string or IpAddress equals this address or is in this
+// CIDR block. IPv4 and IPv4-mapped IPv6 addresses match each other.
 func (a IpAddress) IsInRange(itf interface{}) bool {
+	var addr netip.Addr
+	var ok bool
 	switch ty := itf.(type) {
 	case string:
-		inputIp := net.ParseIP(ty)
-		if inputIp == nil {
-			return false
-		}
-
-		if a.ipNet == nil {
-			return a.ip.Equal(inputIp)
-		}
-		return a.ipNet.Contains(inputIp)
+		addr, ok = parseIpAddr(ty)
 	case IpAddress:
-		if a.ipNet == nil {
-			return a.ip.Equal(ty.IP())
-		}
-		return a.ipNet.Contains(ty.IP())
+		addr, ok = netIpToAddr(ty.IP())
+	}
+	if !ok {
+		return false
+	}
+	prefix, ok := a.prefix()
+	return ok && prefix.Contains(addr)
+}
+
+// prefix returns the address or CIDR block as a prefix in the IPv4-mapped IPv6 space
+func (a IpAddress) prefix() (netip.Prefix, bool) {
+	if a.ipNet == nil {
+		addr, ok := netIpToAddr(a.ip)
+		return netip.PrefixFrom(addr, 128), ok
+	}
+	addr, ok := netip.AddrFromSlice(a.ipNet.IP)
+	if !ok {
+		return netip.Prefix{}, false
 	}
-	return false
+	ones, _ := a.ipNet.Mask.Size()
+	return toIpv6Prefix(netip.PrefixFrom(addr, ones)), true
 }
 
 func (a IpAddress) Equals(b IpAddress) bool {
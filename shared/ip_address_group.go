@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	_ "net"
 	_ "strings"
+	"sync/atomic"
 
 	util "github.com/rdkcentral/xconfwebconfig/util"
 )
@@ -31,6 +32,14 @@ type IpAddressGroup struct {
 	Name           string      `json:"name" xml:"name"`
 	IpAddresses    []IpAddress `json:"-" xml:"-"`
 	RawIpAddresses []string    `json:"ipAddresses" xml:"ipAddresses"` // custom unmarshal for IpAddresses
+	tree           atomic.Pointer[ipAddressGroupTree]
+}
+
+// ipAddressGroupTree is the prefix tree of the IpAddresses of a group. The tree is rebuilt when
+// IpAddresses is replaced or its length changes, a change in place needs SetIpAddresses
+type ipAddressGroupTree struct {
+	addresses []IpAddress
+	tree      *IpPrefixTree
 }
 
 func (obj *IpAddressGroup) Clone() (*IpAddressGroup, error) {
@@ -64,11 +73,13 @@ func NewIpAddressGroup(input *IpAddressGroup) *IpAddressGroup {
 	iaddrs := []IpAddress{}
 	iaddrs = append(iaddrs, input.IpAddresses...)
 
-	return &IpAddressGroup{
+	res := &IpAddressGroup{
 		Id:          input.Id,
 		Name:        input.Name,
 		IpAddresses: iaddrs,
 	}
+	res.buildTree()
+	return res
 }
 
 func (g *IpAddressGroup) UnmarshalJSON(bytes []byte) error {
@@ -93,12 +104,50 @@ func (g *IpAddressGroup) SetIpAddresses(addrs []string) {
 			g.IpAddresses = append(g.IpAddresses, *ipaddr)
 		}
 	}
+	g.buildTree()
+}
+
+func (g *IpAddressGroup) buildTree() *IpPrefixTree {
+	tree := &ipAddressGroupTree{addresses: g.IpAddresses, tree: g.newTree()}
+	g.tree.Store(tree)
+	return tree.tree
+}
+
+func (g *IpAddressGroup) getTree() *IpPrefixTree {
+	if tree := g.tree.Load(); tree != nil && tree.isTreeOf(g.IpAddresses) {
+		return tree.tree
+	}
+	return g.buildTree()
+}
+
+func (t *ipAddressGroupTree) isTreeOf(addresses []IpAddress) bool {
+	if len(t.addresses) != len(addresses) {
+		return false
+	}
+	return len(addresses) == 0 || &t.addresses[0] == &addresses[0]
+}
+
+func (g *IpAddressGroup) newTree() *IpPrefixTree {
+	tree := NewIpPrefixTree()
+	for _, ipAddress := range g.IpAddresses {
+		if prefix, ok := ipAddress.prefix(); ok {
+			tree.InsertPrefix(prefix)
+		}
+	}
+	return tree
 }
 
+// IsInRange reports whether any of the IP address strings or IpAddresses is in the group
 func (g *IpAddressGroup) IsInRange(itfs ...interface{}) bool {
+	tree := g.getTree()
 	for _, itf := range itfs {
-		for _, ipAddress := range g.IpAddresses {
-			if ipAddress.IsInRange(itf) {
+		switch ty := itf.(type) {
+		case string:
+			if tree.Contains(ty) {
+				return true
+			}
+		case IpAddress:
+			if addr, ok := netIpToAddr(ty.IP()); ok && tree.ContainsAddr(addr) {
 				return true
 			}
 		}
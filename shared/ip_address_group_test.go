@@ -258,3 +258,40 @@ func TestIpAddressGroup_IsInRange_CIDR(t *testing.T) {
 	assert.Assert(t, !group.IsInRange("192.168.2.1"))
 	assert.Assert(t, !group.IsInRange("192.168.0.1"))
 }
+
+func TestIpAddressGroup_IsInRange_MixedFamilies(t *testing.T) {
+	group := NewIpAddressGroupWithAddrStrings("g1", "Group", []string{"192.168.1.0/24", "2001:558:6027:180::/57", "::ffff:10.0.0.0/120"})
+
+	assert.Assert(t, group.IsInRange("192.168.1.10"))
+	assert.Assert(t, group.IsInRange("::ffff:192.168.1.10"))
+	assert.Assert(t, group.IsInRange("2001:558:6027:180::1234"))
+	assert.Assert(t, group.IsInRange("10.0.0.1"))
+	assert.Assert(t, group.IsInRange(*NewIpAddress("2001:558:6027:1a0::1")))
+	assert.Assert(t, !group.IsInRange("2001:558:4321:180::2345"))
+	assert.Assert(t, !group.IsInRange("10.0.1.1"))
+
+	// IpAddresses set directly are still matched
+	group.IpAddresses = append(group.IpAddresses, *NewIpAddress("2001:db8::1"))
+	assert.Assert(t, group.IsInRange("2001:db8::1"))
+}
+
+func TestIpAddressGroup_IsInRange_ReplacedAddresses(t *testing.T) {
+	group := NewIpAddressGroupWithAddrStrings("g1", "Group", []string{"1.1.1.1", "10.0.0.0/8"})
+	assert.Assert(t, group.IsInRange("1.1.1.1"))
+
+	// replaced with a list of the same length
+	group.IpAddresses = []IpAddress{*NewIpAddress("2.2.2.2"), *NewIpAddress("10.0.0.0/8")}
+	assert.Assert(t, !group.IsInRange("1.1.1.1"))
+	assert.Assert(t, group.IsInRange("2.2.2.2"))
+
+	// the rebuilt tree is kept until the addresses are replaced again
+	tree := group.tree.Load()
+	assert.Assert(t, group.IsInRange("10.1.2.3"))
+	assert.Assert(t, tree == group.tree.Load())
+
+	// grown in place
+	group.IpAddresses = append(group.IpAddresses[:1], *NewIpAddress("2001:db8::/32"), *NewIpAddress("3.3.3.3"))
+	assert.Assert(t, !group.IsInRange("10.1.2.3"))
+	assert.Assert(t, group.IsInRange("2001:db8::1"))
+	assert.Assert(t, group.IsInRange("3.3.3.3"))
+}
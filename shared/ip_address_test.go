@@ -257,3 +257,16 @@ func TestIpAddress_CIDRDetection(t *testing.T) {
 	cidr6 := NewIpAddress("2001:db8::/32")
 	assert.Assert(t, cidr6.IsCidrBlock())
 }
+
+func TestIpAddress_IsInRange_IPv4Mapped(t *testing.T) {
+	ip := NewIpAddress("192.168.1.0/24")
+	assert.Assert(t, ip.IsInRange("::ffff:192.168.1.5"))
+
+	mapped := NewIpAddress("::ffff:192.168.1.0/120")
+	assert.Assert(t, mapped.IsInRange("192.168.1.5"))
+	assert.Assert(t, mapped.IsInRange(*NewIpAddress("192.168.1.5")))
+	assert.Assert(t, !mapped.IsInRange("192.168.2.5"))
+
+	assert.Assert(t, NewIpAddress("1.2.3.4").IsInRange("::ffff:1.2.3.4"))
+	assert.Assert(t, !NewIpAddress("1.2.3.4").IsInRange("::1.2.3.4"))
+}
@@ -0,0 +1,178 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package shared
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// IpPrefixTree is a binary prefix tree of IPv4 and IPv6 addresses and CIDR blocks. IPv4 is kept
+// in the IPv4-mapped IPv6 space, so 10.0.0.1 and ::ffff:10.0.0.1 are the same address and mixed
+// lists need a single lookup. Host addresses are kept in a map, only CIDR blocks are tree nodes.
+type IpPrefixTree struct {
+	root  ipPrefixNode
+	hosts map[netip.Addr]struct{}
+	size  int
+}
+
+type ipPrefixNode struct {
+	children [2]*ipPrefixNode
+	terminal bool
+}
+
+func NewIpPrefixTree() *IpPrefixTree {
+	return &IpPrefixTree{
+		hosts: map[netip.Addr]struct{}{},
+	}
+}
+
+// NewIpPrefixTreeWithAddrStrings builds a tree of addrs, entries that are not an IP address or a CIDR block are skipped
+func NewIpPrefixTreeWithAddrStrings(addrs []string) *IpPrefixTree {
+	tree := NewIpPrefixTree()
+	for _, addr := range addrs {
+		tree.Insert(addr)
+	}
+	return tree
+}
+
+// Insert adds an IP address or a CIDR block and returns false when input is neither
+func (t *IpPrefixTree) Insert(input string) bool {
+	prefix, ok := parseIpPrefix(input)
+	if !ok {
+		return false
+	}
+	t.InsertPrefix(prefix)
+	return true
+}
+
+// InsertPrefix adds a prefix, an IPv4 prefix is added as its IPv4-mapped IPv6 prefix
+func (t *IpPrefixTree) InsertPrefix(prefix netip.Prefix) {
+	prefix = toIpv6Prefix(prefix)
+	t.size++
+	if prefix.IsSingleIP() {
+		t.hosts[prefix.Addr()] = struct{}{}
+		return
+	}
+
+	node := &t.root
+	bytes := prefix.Addr().As16()
+	for i := 0; i < prefix.Bits(); i++ {
+		bit := ipAddrBit(bytes, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &ipPrefixNode{}
+		}
+		node = node.children[bit]
+	}
+	node.terminal = true
+}
+
+// Contains reports whether the IP address input is one of the addresses or in one of the CIDR blocks of the tree
+func (t *IpPrefixTree) Contains(input string) bool {
+	addr, ok := parseIpAddr(input)
+	if !ok {
+		return false
+	}
+	return t.ContainsAddr(addr)
+}
+
+func (t *IpPrefixTree) ContainsAddr(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	addr = toIpv6Addr(addr)
+	if _, ok := t.hosts[addr]; ok {
+		return true
+	}
+
+	node := &t.root
+	bytes := addr.As16()
+	for i := 0; i < 128; i++ {
+		if node.terminal {
+			return true
+		}
+		node = node.children[ipAddrBit(bytes, i)]
+		if node == nil {
+			return false
+		}
+	}
+	return node.terminal
+}
+
+// Len returns the number of entries inserted
+func (t *IpPrefixTree) Len() int {
+	return t.size
+}
+
+// NormalizeIpAddress returns the canonical form of an IP address or a CIDR block, with the host bits of a CIDR block cleared
+func NormalizeIpAddress(input string) (string, error) {
+	ipAddress := NewIpAddress(input)
+	if ipAddress == nil {
+		return "", &net.ParseError{Type: "IP address", Text: input}
+	}
+	return ipAddress.GetAddress(), nil
+}
+
+func ipAddrBit(bytes [16]byte, i int) int {
+	return int(bytes[i/8]>>(7-uint(i%8))) & 1
+}
+
+func toIpv6Addr(addr netip.Addr) netip.Addr {
+	return netip.AddrFrom16(addr.As16())
+}
+
+func toIpv6Prefix(prefix netip.Prefix) netip.Prefix {
+	bits := prefix.Bits()
+	if prefix.Addr().Is4() {
+		bits += 96
+	}
+	return netip.PrefixFrom(toIpv6Addr(prefix.Addr()), bits).Masked()
+}
+
+func parseIpAddr(input string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(input)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return toIpv6Addr(addr), true
+}
+
+func parseIpPrefix(input string) (netip.Prefix, bool) {
+	if strings.Contains(input, "/") {
+		prefix, err := netip.ParsePrefix(input)
+		if err != nil {
+			return netip.Prefix{}, false
+		}
+		return toIpv6Prefix(prefix), true
+	}
+	addr, ok := parseIpAddr(input)
+	if !ok {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(addr, 128), true
+}
+
+// netIpToAddr converts a net.IP, which may hold an IPv4 address in either 4 or 16 bytes
+func netIpToAddr(ip net.IP) (netip.Addr, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	return toIpv6Addr(addr), true
+}
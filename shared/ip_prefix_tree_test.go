@@ -0,0 +1,110 @@
+/**
+ * Copyright 2022 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package shared
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestIpPrefixTree(t *testing.T) {
+	tree := NewIpPrefixTreeWithAddrStrings([]string{
+		"10.0.0.0/8",
+		"192.168.1.1",
+		"2001:558:6027:180::/57",
+		"2001:db8::1",
+		"::ffff:172.16.0.0/108",
+		"not-an-ip",
+	})
+	assert.Equal(t, 5, tree.Len())
+
+	testCases := []struct {
+		address  string
+		expected bool
+	}{
+		{"10.1.2.3", true},
+		{"::ffff:10.1.2.3", true},
+		{"11.0.0.1", false},
+		{"192.168.1.1", true},
+		{"::ffff:192.168.1.1", true},
+		{"192.168.1.2", false},
+		{"2001:558:6027:180::1234", true},
+		{"2001:558:6027:1ff:ffff::1", true},
+		{"2001:558:6027:200::1", false},
+		{"2001:DB8::1", true},
+		{"2001:db8::2", false},
+		{"172.16.5.5", true},
+		{"172.32.0.1", false},
+		{"invalid", false},
+		{"", false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, tree.Contains(tc.address), tc.address)
+	}
+}
+
+func TestIpPrefixTree_DefaultRoutes(t *testing.T) {
+	tree := NewIpPrefixTreeWithAddrStrings([]string{"0.0.0.0/0"})
+	assert.Assert(t, tree.Contains("1.2.3.4"))
+	assert.Assert(t, !tree.Contains("2001:db8::1"))
+
+	tree = NewIpPrefixTreeWithAddrStrings([]string{"::/0"})
+	assert.Assert(t, tree.Contains("1.2.3.4"))
+	assert.Assert(t, tree.Contains("2001:db8::1"))
+}
+
+func TestIpPrefixTree_LargeList(t *testing.T) {
+	addrs := []string{}
+	for i := 0; i < 256; i++ {
+		for j := 0; j < 64; j++ {
+			addrs = append(addrs, fmt.Sprintf("10.%d.%d.1", i, j))
+		}
+		addrs = append(addrs, fmt.Sprintf("2001:db8:%x::/48", i))
+	}
+	tree := NewIpPrefixTreeWithAddrStrings(addrs)
+	assert.Equal(t, len(addrs), tree.Len())
+	assert.Assert(t, tree.Contains("10.255.63.1"))
+	assert.Assert(t, !tree.Contains("10.255.64.1"))
+	assert.Assert(t, tree.Contains("2001:db8:ff::abcd"))
+	assert.Assert(t, !tree.Contains("2001:db8:100::abcd"))
+}
+
+func TestNormalizeIpAddress(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"1.2.3.4", "1.2.3.4"},
+		{"1.2.3.4/24", "1.2.3.0/24"},
+		{"2001:DB8:0:0::1", "2001:db8::1"},
+		{"2001:db8::1/32", "2001:db8::/32"},
+		{"::ffff:1.2.3.4", "1.2.3.4"},
+	}
+	for _, tc := range testCases {
+		normalized, err := NormalizeIpAddress(tc.input)
+		assert.NilError(t, err)
+		assert.Equal(t, tc.expected, normalized)
+	}
+
+	_, err := NormalizeIpAddress("2001:db8::1/129")
+	assert.Assert(t, err != nil)
+	_, err = NormalizeIpAddress("invalid")
+	assert.ErrorContains(t, err, "invalid")
+}
@@ -276,7 +276,9 @@ func GetDCMGenericRuleList() []*DCMGenericRule {
 	for idx := range dmcRuleList {
 		if dmcRuleList[idx] != nil {
 			dmcRule := dmcRuleList[idx].(*DCMGenericRule)
-			all = append(all, dmcRule)
+			if re.IsEvaluable(dmcRule) {
+				all = append(all, dmcRule)
+			}
 		}
 	}
 
@@ -337,6 +337,7 @@ type SettingsResponse struct {
 	EponSettings                      map[string]string          `json:"urn:settings:SettingType:epon,omitempty"`
 	TelemetryProfile                  *PermanentTelemetryProfile `json:"urn:settings:TelemetryProfile,omitempty"`
 	PartnerSettings                   map[string]string          `json:"urn:settings:SettingType:partnersettings,omitempty"`
+	EvaluationTrace                   *re.RuleTracer             `json:"evaluationTrace,omitempty"`
 }
 
 func CreateSettingsResponseObject(settings *Settings) *SettingsResponse {
@@ -929,7 +929,9 @@ func GetTelemetryRuleList() []*TelemetryRule {
 
 	for _, v := range tRuleList {
 		tRule := v.(*TelemetryRule)
-		all = append(all, tRule)
+		if re.IsEvaluable(tRule) {
+			all = append(all, tRule)
+		}
 	}
 
 	if len(all) > 0 {
@@ -983,7 +985,7 @@ func GetTelemetryTwoRuleList() []*TelemetryTwoRule {
 	all := make([]*TelemetryTwoRule, 0, len(tRuleList))
 
 	for _, itf := range tRuleList {
-		if telemetryTwoRule, ok := itf.(*TelemetryTwoRule); ok {
+		if telemetryTwoRule, ok := itf.(*TelemetryTwoRule); ok && re.IsEvaluable(telemetryTwoRule) {
 			all = append(all, telemetryTwoRule)
 		}
 	}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/rdkcentral/xconfwebconfig/common"
 	"github.com/rdkcentral/xconfwebconfig/db"
+	re "github.com/rdkcentral/xconfwebconfig/rulesengine"
 	"github.com/rdkcentral/xconfwebconfig/shared"
 	"github.com/rdkcentral/xconfwebconfig/util"
 
@@ -536,12 +537,15 @@ func GetSortedFeatureRules() []*FeatureRule {
 
 	all := GetFeatureListFunc()
 
-	if len(all) <= 1 {
-		return all
-	}
-
 	var sortedList []*FeatureRule
-	sortedList = append(sortedList, all...)
+	for _, featureRule := range all {
+		if re.IsEvaluable(featureRule) {
+			sortedList = append(sortedList, featureRule)
+		}
+	}
+	if len(sortedList) <= 1 {
+		return sortedList
+	}
 
 	sort.SliceStable(sortedList, func(i, j int) bool {
 		return sortedList[i].Priority < sortedList[j].Priority
@@ -550,3 +554,28 @@ func GetSortedFeatureRules() []*FeatureRule {
 
 	return sortedList
 }
+
+// GetFeatureRuleIndex returns the sorted feature rules compiled into a RuleIndex. The index lives in
+// the application cache and is rebuilt after the feature rule table changes, so nil is returned
+// when the application cache is disabled.
+func GetFeatureRuleIndex(processor *re.RuleProcessor) *re.RuleIndex {
+	cm := db.GetCacheManager()
+	if !cm.IsApplicationCacheEnabled() {
+		return nil
+	}
+	cacheKey := "FeatureRuleIndex"
+	cacheInst := cm.ApplicationCacheGet(db.TABLE_FEATURE_CONTROL_RULE, cacheKey)
+	if cacheInst != nil {
+		return cacheInst.(*re.RuleIndex)
+	}
+
+	featureRules := GetSortedFeatureRules()
+	rules := make([]re.XRule, 0, len(featureRules))
+	for _, featureRule := range featureRules {
+		rules = append(rules, featureRule)
+	}
+	index := re.NewCachedRuleIndex(cacheKey, processor, rules)
+	cm.ApplicationCacheSet(db.TABLE_FEATURE_CONTROL_RULE, cacheKey, index)
+
+	return index
+}